@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// config is the optional JSON configuration of the server, read from the
+// -config file. See config.example.json.
+type config struct {
+	// Rewrites are applied to the request paths of the demo site before
+	// they are routed.
+	Rewrites []http.RewriteRule `json:"rewrites"`
+	// Sites are served by host name. Requests for other hosts go to the
+	// demo site.
+	Sites []siteConfig `json:"sites"`
+}
+
+// loadConfig reads the configuration file at path.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
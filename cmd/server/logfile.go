@@ -0,0 +1,193 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated segments so they sort chronologically.
+const backupTimeFormat = "20060102-150405.000"
+
+// rotatingFile is a log file that is rotated once it grows past maxSize
+// bytes or when interval elapses. Rotated segments are renamed to
+// path.<time>, gzipped when compress is set, and only the newest
+// maxBackups are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64         // Zero to never rotate on size
+	interval   time.Duration // Zero to never rotate on time, aligned to UTC
+	compress   bool
+	maxBackups int // Zero to keep every segment
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+
+	cleanup sync.Mutex // Serializes compressing and pruning old segments
+}
+
+// openRotatingFile opens the log file at path, appending to it when it
+// already exists.
+func openRotatingFile(path string, maxSize int64, interval time.Duration, compress bool, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		compress:   compress,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the current segment and schedules its time-based rotation.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.interval > 0 {
+		f.rotateAt = time.Now().Truncate(f.interval).Add(f.interval)
+	}
+	return nil
+}
+
+// Write appends p to the current segment, rotating it first when it is
+// full or its interval has elapsed.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	full := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	expired := f.interval > 0 && !time.Now().Before(f.rotateAt)
+	if full || expired {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current segment aside and opens a new one.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	backup := f.path + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(f.path, backup); err != nil {
+		// Keep logging to the current segment
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	// Old segments are handled in the background so requests aren't held
+	// up by gzip
+	go func() {
+		f.cleanup.Lock()
+		defer f.cleanup.Unlock()
+		if f.compress {
+			if err := compressFile(backup); err != nil {
+				log.Printf("Error compressing %s: %v", backup, err)
+			}
+		}
+		f.prune()
+	}()
+	return nil
+}
+
+// prune removes the oldest segments beyond maxBackups.
+func (f *rotatingFile) prune() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return
+	}
+	// Leftovers of an interrupted compression aren't segments
+	backups = removeSuffixed(backups, ".gz.tmp")
+	sort.Strings(backups)
+	for len(backups) > f.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			log.Printf("Error removing %s: %v", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+}
+
+// removeSuffixed returns names without the ones ending in suffix.
+func removeSuffixed(names []string, suffix string) []string {
+	kept := names[:0]
+	for _, name := range names {
+		if !strings.HasSuffix(name, suffix) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// compressFile replaces the file at path with a gzipped copy at path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Written under a temporary name so a partial copy is never taken for
+	// a segment
+	tmp := path + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the current segment.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRotatingFile verifies size rollovers, compression of rotated segments and pruning beyond maxBackups.
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxSize    int64
+		compress   bool
+		maxBackups int
+		old        []string // Existing files next to the log, by suffix
+		kept       []string // The old files left once the segments are handled
+		current    string   // Content of the log after the writes
+		segment    string   // Content of the rotated segment, empty when none
+	}{
+		{"under maxSize", 64, false, 0, nil, nil, "first line\nsecond line\n", ""},
+		{"size rollover", 16, false, 0, nil, nil, "second line\n", "first line\n"},
+		{"compressed", 16, true, 0, nil, nil, "second line\n", "first line\n"},
+		{
+			"pruned", 16, false, 2,
+			[]string{"20200101-000000.000", "20200102-000000.000", "20200103-000000.000.gz.tmp"},
+			[]string{"20200102-000000.000", "20200103-000000.000.gz.tmp"},
+			"second line\n", "first line\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			for _, suffix := range tt.old {
+				if err := os.WriteFile(path+"."+suffix, []byte("old\n"), 0o644); err != nil {
+					t.Fatalf("Failed to create %s: %v", suffix, err)
+				}
+			}
+
+			f, err := openRotatingFile(path, tt.maxSize, 0, tt.compress, tt.maxBackups)
+			if err != nil {
+				t.Fatalf("Failed to open the log: %v", err)
+			}
+			defer f.Close()
+			for _, line := range []string{"first line\n", "second line\n"} {
+				if _, err := f.Write([]byte(line)); err != nil {
+					t.Fatalf("Failed to write: %v", err)
+				}
+			}
+
+			// Rotated segments are compressed and pruned in the background
+			rotated := 0
+			if tt.segment != "" {
+				rotated = 1
+			}
+			var kept, segments []string
+			for deadline := time.Now().Add(time.Second); ; {
+				kept, segments = listBackups(t, path, tt.old)
+				done := slices.Equal(kept, tt.kept) && len(segments) == rotated
+				if done && tt.compress && len(segments) == 1 {
+					done = strings.HasSuffix(segments[0], ".gz")
+				}
+				if done || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			f.cleanup.Lock()
+			defer f.cleanup.Unlock()
+
+			if !slices.Equal(kept, tt.kept) {
+				t.Errorf("Expected the old files %v to be left, got %v", tt.kept, kept)
+			}
+			if got := readLog(t, path, false); got != tt.current {
+				t.Errorf("Expected the log to hold %q, got %q", tt.current, got)
+			}
+			if tt.segment == "" {
+				if len(segments) != 0 {
+					t.Errorf("Expected no rotated segment, got %v", segments)
+				}
+				return
+			}
+			if len(segments) != 1 {
+				t.Fatalf("Expected one rotated segment, got %v", segments)
+			}
+			if got := readLog(t, segments[0], tt.compress); got != tt.segment {
+				t.Errorf("Expected the segment %s to hold %q, got %q", segments[0], tt.segment, got)
+			}
+		})
+	}
+}
+
+// listBackups returns the files next to the log at path among old, by
+// suffix, and the paths of the segments rotated since.
+func listBackups(t *testing.T, path string, old []string) (kept, segments []string) {
+	t.Helper()
+	names, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Failed to list the segments: %v", err)
+	}
+	for _, name := range names {
+		if suffix := strings.TrimPrefix(name, path+"."); slices.Contains(old, suffix) {
+			kept = append(kept, suffix)
+		} else {
+			segments = append(segments, name)
+		}
+	}
+	return kept, segments
+}
+
+// readLog returns the content of a log segment, gunzipping it if
+// compressed.
+func readLog(t *testing.T, path string, compressed bool) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if compressed {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Failed to gunzip %s: %v", path, err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
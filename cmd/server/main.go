@@ -9,25 +9,69 @@ import (
 	"time"
 	"encoding/json"
 
-	"github.com/Johanx22x/http-lite/cmd/server/middleware"
 	"github.com/Johanx22x/http-lite/pkg/http"
 )
 
-var port string
+var (
+	port         string
+	configPath   string
+	tlsCert      string
+	tlsKey       string
+	redirectPort string
+
+	accessLog        string
+	accessLogMaxSize int64
+	accessLogRotate  time.Duration
+	accessLogGzip    bool
+	accessLogBackups int
+)
 
 func init() {
 	flag.StringVar(&port, "port", "8080", "Port to listen on")
+	flag.StringVar(&configPath, "config", "", "JSON configuration file, see cmd/server/config.example.json")
+	flag.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file, serves HTTPS together with -tls-key")
+	flag.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	flag.StringVar(&redirectPort, "redirect-port", "", "Port of a plain HTTP listener redirecting to HTTPS, such as 80")
+
+	flag.StringVar(&accessLog, "access-log", "", "Write access logs to this file instead of printing requests to stdout")
+	flag.Int64Var(&accessLogMaxSize, "access-log-max-size", 100, "Rotate the access log once it reaches this many megabytes, 0 to disable")
+	flag.DurationVar(&accessLogRotate, "access-log-rotate", 0, "Rotate the access log at this interval, such as 24h")
+	flag.BoolVar(&accessLogGzip, "access-log-compress", false, "Gzip rotated access logs")
+	flag.IntVar(&accessLogBackups, "access-log-max-backups", 0, "Number of rotated access logs to keep, 0 to keep all")
 }
 
 func main() {
 	// Parse flags
 	flag.Parse()
+	if (tlsCert == "") != (tlsKey == "") {
+		log.Fatalf("-tls-cert and -tls-key must be set together")
+	}
+	if redirectPort != "" && tlsCert == "" {
+		log.Fatalf("-redirect-port requires -tls-cert and -tls-key")
+	}
+
+	cfg := &config{}
+	if configPath != "" {
+		var err error
+		if cfg, err = loadConfig(configPath); err != nil {
+			log.Fatalf("Error loading the configuration: %v", err)
+		}
+	}
 
 	dir := "./cmd/server/website"
 	mux := http.NewServeMux(&dir)
 
-	mux.Use(http.LoggingMiddleware)
-	mux.Use(middleware.CORS)
+	if accessLog != "" {
+		f, err := openRotatingFile(accessLog, accessLogMaxSize<<20, accessLogRotate, accessLogGzip, accessLogBackups)
+		if err != nil {
+			log.Fatalf("Error opening the access log: %v", err)
+		}
+		defer f.Close()
+		mux.Use(http.AccessLog(f))
+	} else {
+		mux.Use(http.LoggingMiddleware)
+	}
+	mux.EnableCORS(http.CORSOptions{AllowedHeaders: []string{"Content-Type", "Authorization"}})
 
 	// US Dollar to CRC exchange rate endpoint
 	mux.AddRoute("/api/exchange", []string{http.GET},
@@ -143,8 +187,32 @@ func main() {
 		},
 	)
 
+	// Rewrites apply before routing
+	var handler http.Handler = mux
+	if len(cfg.Rewrites) > 0 {
+		var err error
+		if handler, err = http.Rewrite(mux, cfg.Rewrites...); err != nil {
+			log.Fatalf("Invalid rewrite rules: %v", err)
+		}
+	}
+	if len(cfg.Sites) > 0 {
+		vhosts, err := newVirtualHosts(cfg.Sites, handler)
+		if err != nil {
+			log.Fatalf("Invalid sites: %v", err)
+		}
+		handler = vhosts
+	}
+
 	// Start server
-	err := http.Run(":"+port, mux)
+	var err error
+	if tlsCert != "" {
+		if redirectPort != "" {
+			go serveRedirect(":"+redirectPort, port)
+		}
+		err = http.RunTLS(":"+port, tlsCert, tlsKey, handler)
+	} else {
+		err = http.Run(":"+port, handler)
+	}
 	if err != nil {
 		log.Fatalf("Error al iniciar el servidor: %v", err)
 		os.Exit(1)
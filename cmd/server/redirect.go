@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"net"
+	"strings"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// redirectToHTTPS redirects every request to the same URL over https://
+// with 308 Permanent Redirect, which keeps the method and body. httpsPort is
+// the port the TLS server listens on.
+func redirectToHTTPS(httpsPort string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Header.Get("Host")
+		if host == "" {
+			http.Error(w, "Missing Host header", http.StatusBadRequest)
+			return
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		switch {
+		case httpsPort != "443":
+			host = net.JoinHostPort(host, httpsPort)
+		case strings.Contains(host, ":"):
+			// IPv6 literals keep their brackets without a port
+			host = "[" + host + "]"
+		}
+
+		w.Header()["Location"] = []string{"https://" + host + r.URL.RequestURI()}
+		w.WriteHeader(http.StatusPermanentRedirect)
+	}
+}
+
+// serveRedirect serves the plain HTTP listener that redirects every request
+// to the HTTPS server on httpsPort.
+func serveRedirect(addr, httpsPort string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Error starting the HTTPS redirect listener: %v", err)
+	}
+	log.Println("Redirecting HTTP on", addr, "to HTTPS")
+	server := http.NewServer(addr, redirectToHTTPS(httpsPort))
+	if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Error serving the HTTPS redirect: %v", err)
+	}
+}
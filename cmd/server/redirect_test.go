@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+	"github.com/Johanx22x/http-lite/pkg/http/httplitetest"
+)
+
+// TestRedirectToHTTPS verifies that requests are redirected to the same URL over HTTPS with 308.
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		host      string
+		target    string
+		location  string
+	}{
+		{"host", "443", "example.com", "/", "https://example.com/"},
+		{"host with port", "443", "example.com:80", "/docs", "https://example.com/docs"},
+		{"query", "443", "example.com", "/search?q=lite&page=2", "https://example.com/search?q=lite&page=2"},
+		{"custom port", "8443", "example.com", "/", "https://example.com:8443/"},
+		{"custom port replaces the port", "8443", "example.com:8080", "/a?b=c", "https://example.com:8443/a?b=c"},
+		{"IPv4", "443", "127.0.0.1:8080", "/", "https://127.0.0.1/"},
+		{"IPv6", "443", "[::1]", "/", "https://[::1]/"},
+		{"IPv6 with port", "443", "[::1]:80", "/", "https://[::1]/"},
+		{"IPv6 custom port", "8443", "[::1]:80", "/", "https://[::1]:8443/"},
+	}
+	for _, tt := range tests {
+		req := httplitetest.NewRequest(http.POST, tt.target, nil)
+		req.Header.Set("Host", tt.host)
+		rec := httplitetest.NewRecorder()
+		redirectToHTTPS(tt.httpsPort)(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s: expected %d to '%s', got %d to '%s'", tt.name, http.StatusPermanentRedirect, tt.location, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	req := httplitetest.NewRequest(http.GET, "/", nil)
+	req.Header.Del("Host")
+	rec := httplitetest.NewRecorder()
+	redirectToHTTPS("443")(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d without a Host header, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
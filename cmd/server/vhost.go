@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// siteConfig is a website served for a set of host names.
+type siteConfig struct {
+	// Hosts are the host names of the site. A leading "*." matches any
+	// subdomain, e.g. "*.example.com".
+	Hosts []string `json:"hosts"`
+	// Static is the directory of the site's static files.
+	Static string `json:"static"`
+	// Upstreams are proxied the requests that match no static file.
+	Upstreams []upstreamConfig `json:"upstreams"`
+	// Strategy balances the upstreams: "round-robin" (the default),
+	// "least-connections" or "weighted".
+	Strategy string `json:"strategy"`
+}
+
+// upstreamConfig is a backend server of a site.
+type upstreamConfig struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// strategies maps the Strategy names of the configuration.
+var strategies = map[string]http.LoadBalancing{
+	"":                  http.RoundRobin,
+	"round-robin":       http.RoundRobin,
+	"least-connections": http.LeastConnections,
+	"weighted":          http.WeightedRoundRobin,
+}
+
+// handler builds the handler serving the site.
+func (site siteConfig) handler() (http.Handler, error) {
+	if site.Static == "" && len(site.Upstreams) == 0 {
+		return nil, fmt.Errorf("site %v has neither static files nor upstreams", site.Hosts)
+	}
+
+	var proxy *http.ReverseProxy
+	if len(site.Upstreams) > 0 {
+		strategy, ok := strategies[site.Strategy]
+		if !ok {
+			return nil, fmt.Errorf("site %v: unknown strategy %q", site.Hosts, site.Strategy)
+		}
+		proxy = &http.ReverseProxy{Strategy: strategy}
+		for _, uc := range site.Upstreams {
+			u, err := http.NewUpstream(uc.URL)
+			if err != nil {
+				return nil, fmt.Errorf("site %v: %w", site.Hosts, err)
+			}
+			u.Weight = uc.Weight
+			proxy.Upstreams = append(proxy.Upstreams, u)
+		}
+		if site.Static == "" {
+			return proxy, nil
+		}
+	}
+
+	// Static files first, the rest goes to the upstreams
+	dir := site.Static
+	mux := http.NewServeMux(&dir)
+	if proxy != nil {
+		mux.SetDefaultHandler(proxy.ServeHTTP)
+	}
+	return mux, nil
+}
+
+// virtualHosts dispatches requests to the site of their Host header.
+type virtualHosts struct {
+	exact    map[string]http.Handler
+	suffixes []hostSuffix // Wildcard hosts, longest first
+	fallback http.Handler // Requests of unknown hosts
+}
+
+// hostSuffix is the handler of a wildcard host.
+type hostSuffix struct {
+	suffix  string // ".example.com" for "*.example.com"
+	handler http.Handler
+}
+
+// newVirtualHosts builds the dispatcher of the configured sites.
+func newVirtualHosts(sites []siteConfig, fallback http.Handler) (*virtualHosts, error) {
+	v := &virtualHosts{exact: make(map[string]http.Handler), fallback: fallback}
+	for _, site := range sites {
+		h, err := site.handler()
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range site.Hosts {
+			host = strings.ToLower(host)
+			if suffix, ok := strings.CutPrefix(host, "*"); ok {
+				for _, s := range v.suffixes {
+					if s.suffix == suffix {
+						return nil, fmt.Errorf("host %s is configured twice", host)
+					}
+				}
+				v.suffixes = append(v.suffixes, hostSuffix{suffix, h})
+				continue
+			}
+			if _, dup := v.exact[host]; dup {
+				return nil, fmt.Errorf("host %s is configured twice", host)
+			}
+			v.exact[host] = h
+		}
+	}
+	// The most specific wildcard wins
+	sort.SliceStable(v.suffixes, func(i, j int) bool { return len(v.suffixes[i].suffix) > len(v.suffixes[j].suffix) })
+	return v, nil
+}
+
+// ServeHTTP passes the request to the site of its host.
+func (v *virtualHosts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := strings.ToLower(r.Header.Get("Host"))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	if h, ok := v.exact[host]; ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	for _, s := range v.suffixes {
+		if strings.HasSuffix(host, s.suffix) {
+			s.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	v.fallback.ServeHTTP(w, r)
+}
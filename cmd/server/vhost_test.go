@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+	"github.com/Johanx22x/http-lite/pkg/http/httplitetest"
+)
+
+// site returns a site for the hosts serving a static directory whose site.txt holds the name.
+func site(t *testing.T, name string, hosts ...string) siteConfig {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "site.txt"), []byte(name), 0o644); err != nil {
+		t.Fatalf("Failed to create the site %s: %v", name, err)
+	}
+	return siteConfig{Hosts: hosts, Static: dir}
+}
+
+// TestVirtualHosts verifies that requests reach the site of their host, exact hosts before the longest matching wildcard.
+func TestVirtualHosts(t *testing.T) {
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fallback"))
+	})
+	v, err := newVirtualHosts([]siteConfig{
+		site(t, "wildcard", "*.example.com"),
+		site(t, "api", "API.example.com"),
+		site(t, "eu", "*.eu.example.com"),
+		site(t, "apex", "example.com", "www.example.org"),
+	}, fallback)
+	if err != nil {
+		t.Fatalf("Failed to build the virtual hosts: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "apex"},
+		{"www.example.org", "apex"},
+		{"api.example.com", "api"},
+		{"API.Example.COM", "api"},
+		{"api.example.com:8080", "api"},
+		{"api.example.com.", "api"},
+		{"api.example.com.:8080", "api"},
+		{"shop.example.com", "wildcard"},
+		{"a.b.example.com", "wildcard"},
+		{"shop.eu.example.com", "eu"},
+		{"eu.example.com", "wildcard"},
+		{"notexample.com", "fallback"},
+		{"example.net", "fallback"},
+		{"[::1]:8080", "fallback"},
+		{"", "fallback"},
+	}
+	for _, tt := range tests {
+		req := httplitetest.NewRequest(http.GET, "/site.txt", nil)
+		req.Header.Set("Host", tt.host)
+		rec := httplitetest.NewRecorder()
+		v.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("Host %q: expected the %s site, got %d '%s'", tt.host, tt.want, rec.Code, got)
+		}
+	}
+}
+
+// TestVirtualHostsInvalid verifies that duplicate hosts and sites serving nothing are rejected.
+func TestVirtualHostsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		sites []siteConfig
+		err   string
+	}{
+		{"duplicate host", []siteConfig{site(t, "a", "example.com"), site(t, "b", "Example.com")}, "host example.com is configured twice"},
+		{"duplicate wildcard", []siteConfig{site(t, "a", "*.example.com"), site(t, "b", "*.example.com")}, "host *.example.com is configured twice"},
+		{"empty site", []siteConfig{{Hosts: []string{"example.com"}}}, "neither static files nor upstreams"},
+		{"unknown strategy", []siteConfig{{Hosts: []string{"example.com"}, Upstreams: []upstreamConfig{{URL: "http://localhost:9000"}}, Strategy: "random"}}, `unknown strategy "random"`},
+	}
+	for _, tt := range tests {
+		_, err := newVirtualHosts(tt.sites, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
@@ -1,40 +1,328 @@
+// Command stress_testing sends load to an HTTP server and reports the
+// throughput, latency percentiles and status codes of its responses:
+//
+//	go run ./cmd/stress_testing -c 50 -n 10000 http://localhost:8080/api/exchange
+//	go run ./cmd/stress_testing -c 20 -d 30s -m POST -body '{"id": "1"}' \
+//		-H 'Content-Type: application/json' http://localhost:8080/api/login/1
+//
+// Requests are spread over the given URLs in turn. A scenario file can
+// instead describe a weighted route mix, think times between requests and
+// stages ramping the number of workers up and down:
+//
+//	go run ./cmd/stress_testing -scenario checkout.json
+//
+// See scenario for the file format. Interrupting the tool stops the load and
+// still prints the report.
+//
+// Reports can also be written as JSON or CSV to track results across
+// commits, along with an HTML summary with a latency histogram:
+//
+//	go run ./cmd/stress_testing -format csv -o results.csv -label "$(git rev-parse --short HEAD)"
+//	go run ./cmd/stress_testing -format json -html report.html
+//
+// CSV reports appended to an existing file leave out the header row.
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
 )
 
-func sendRequest(wg *sync.WaitGroup, url string) {
-	defer wg.Done()
+const defaultTarget = "http://localhost:8080/api/exchange"
 
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Printf("Error al hacer la solicitud a %s: %s\n", url, err)
-		return
+// headerFlags collects the repeatable -H flag.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if _, _, ok := strings.Cut(value, ":"); !ok {
+		return fmt.Errorf("expected 'Key: Value', got %q", value)
 	}
-	defer resp.Body.Close()
+	*h = append(*h, value)
+	return nil
+}
+
+// config describes the load to send.
+type config struct {
+	routes      []route
+	header      http.Header // Added to every route
+	concurrency int
+	requests    int64         // Total requests, unless duration is set
+	duration    time.Duration // How long to send requests, zero to send a number of them
+	stages      []stage       // Ramp of the active workers, empty to keep all of them busy
+	thinkMin    time.Duration // Pause of a worker between its requests
+	thinkMax    time.Duration
+}
 
-	fmt.Printf("Respuesta de %s: %s\n", url, resp.Status)
+// route is a request of the load.
+type route struct {
+	method string
+	url    *url.URL
+	body   string
+	header http.Header
+	weight int
+}
+
+var (
+	concurrency int
+	requests    int64
+	duration    time.Duration
+	method      string
+	body        string
+	headers     headerFlags
+	timeout     time.Duration
+	scenarioArg string
+	format      string
+	outputPath  string
+	htmlPath    string
+	label       string
+)
+
+func init() {
+	flag.IntVar(&concurrency, "c", 50, "Number of concurrent workers")
+	flag.Int64Var(&requests, "n", 1000, "Total number of requests to send")
+	flag.DurationVar(&duration, "d", 0, "Send requests for this long instead of a number of them")
+	flag.StringVar(&method, "m", http.GET, "Request method")
+	flag.StringVar(&body, "body", "", "Request body")
+	flag.Var(&headers, "H", "Request header as 'Key: Value', may be repeated")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each request")
+	flag.StringVar(&format, "format", "text", "Report format: text, json or csv")
+	flag.StringVar(&outputPath, "o", "", "Write the report to this file instead of stdout")
+	flag.StringVar(&htmlPath, "html", "", "Also write an HTML summary of the report to this file")
+	flag.StringVar(&label, "label", "", "Label of the run in JSON, CSV and HTML reports, such as a commit")
+	flag.StringVar(&scenarioArg, "scenario", "", "JSON scenario file with the routes, think times and stages to run")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [url...]\n\nURLs default to %s.\n\n", os.Args[0], defaultTarget)
+		flag.PrintDefaults()
+	}
 }
 
 func main() {
-	var wg sync.WaitGroup
-	routes := []string{
-		"http://localhost:8080/api/exchange",
+	// Parse flags
+	flag.Parse()
+	if concurrency < 1 {
+		log.Fatalf("-c must be at least 1")
+	}
+	if requests < 1 && duration <= 0 {
+		log.Fatalf("-n must be at least 1 unless -d is set")
+	}
+	if format != "text" && format != "json" && format != "csv" {
+		log.Fatalf("-format must be text, json or csv")
 	}
 
-	// Definir la cantidad de solicitudes por ruta
-	const requestsPerRoute = 1000
+	cfg := config{
+		header:      make(http.Header),
+		concurrency: concurrency,
+		requests:    requests,
+		duration:    duration,
+	}
+	for _, h := range headers {
+		key, value, _ := strings.Cut(h, ":")
+		cfg.header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	if scenarioArg != "" {
+		if flag.NArg() > 0 {
+			log.Fatalf("URLs come from the scenario file when -scenario is set")
+		}
+		s, err := loadScenario(scenarioArg)
+		if err != nil {
+			log.Fatalf("Error loading scenario: %v", err)
+		}
+		if err := s.apply(&cfg); err != nil {
+			log.Fatalf("Invalid scenario %s: %v", scenarioArg, err)
+		}
+	} else {
+		targets := flag.Args()
+		if len(targets) == 0 {
+			targets = []string{defaultTarget}
+		}
+		for _, target := range targets {
+			u, err := parseTarget(target)
+			if err != nil {
+				log.Fatal(err)
+			}
+			cfg.routes = append(cfg.routes, route{
+				method: strings.ToUpper(method),
+				url:    u,
+				body:   body,
+				header: cfg.header,
+				weight: 1,
+			})
+		}
+	}
+
+	client := &http.Client{
+		Timeout:             timeout,
+		MaxIdleConnsPerHost: concurrency,
+		MaxRedirects:        -1,
+	}
+	defer client.CloseIdleConnections()
+
+	// Ctrl-C stops the load early, the report is still printed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Progress goes to stderr so machine-readable reports on stdout stay clean
+	fmt.Fprintf(os.Stderr, "Sending %s to %d URL(s) with %d workers...\n", describeLoad(cfg), len(cfg.routes), cfg.concurrency)
+	report := run(ctx, client, cfg)
+
+	if err := writeOutput(report); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+	if htmlPath != "" {
+		if err := writeFile(htmlPath, func(w io.Writer) error { return report.writeHTML(w, label) }); err != nil {
+			log.Fatalf("Error writing HTML summary: %v", err)
+		}
+	}
+}
 
-	for _, route := range routes {
-		for i := 0; i < requestsPerRoute; i++ {
-			wg.Add(1)
-			go sendRequest(&wg, route)
+// writeOutput writes the report to stdout or the -o file. CSV reports are
+// appended to an existing file without repeating the header row.
+func writeOutput(r *report) error {
+	if outputPath == "" {
+		return r.writeReport(os.Stdout, format, label)
+	}
+	if format == "csv" {
+		info, err := os.Stat(outputPath)
+		if err == nil && info.Size() > 0 {
+			f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				return err
+			}
+			if err := r.writeCSV(f, label, false); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
 		}
 	}
+	return writeFile(outputPath, func(w io.Writer) error { return r.writeReport(w, format, label) })
+}
+
+// writeFile creates the file at path and writes it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseTarget parses an absolute http or https URL.
+func parseTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", target)
+	}
+	return u, nil
+}
+
+// describeLoad describes how many requests are sent, or for how long.
+func describeLoad(cfg config) string {
+	if cfg.duration > 0 {
+		return "requests for " + cfg.duration.String()
+	}
+	return fmt.Sprintf("%d requests", cfg.requests)
+}
+
+// run sends the load described by cfg and collects its results.
+func run(ctx context.Context, client *http.Client, cfg config) *report {
+	if cfg.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
 
+	var totalWeight int64
+	for _, r := range cfg.routes {
+		totalWeight += int64(r.weight)
+	}
+
+	var next atomic.Int64
+	results := make([][]result, cfg.concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if w >= activeWorkers(cfg.stages, cfg.concurrency, time.Since(start)) {
+					// Not ramped up yet, or already ramped down
+					pause(ctx, 10*time.Millisecond)
+					continue
+				}
+				i := next.Add(1) - 1
+				if cfg.duration <= 0 && i >= cfg.requests {
+					return
+				}
+				res := send(ctx, client, pickRoute(cfg.routes, totalWeight, i))
+				if res.err != nil && ctx.Err() != nil {
+					// Interrupted by the end of the test, not a failure
+					return
+				}
+				results[w] = append(results[w], res)
+				pause(ctx, thinkTime(cfg))
+			}
+		}()
+	}
 	wg.Wait()
-	fmt.Println("Todas las solicitudes han sido enviadas.")
+
+	return newReport(results, time.Since(start))
+}
+
+// pause waits for d, or until ctx is done.
+func pause(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// send sends a single request and measures it.
+func send(ctx context.Context, client *http.Client, rt *route) result {
+	req := &http.Request{
+		Method: rt.method,
+		URL:    rt.url,
+		Header: rt.header,
+	}
+	if rt.body != "" {
+		req.Body = io.NopCloser(strings.NewReader(rt.body))
+		req.ContentLength = int64(len(rt.body))
+	}
+
+	start := time.Now()
+	res, err := client.Do(req.WithContext(ctx))
+	r := result{target: rt.url.String(), latency: time.Since(start), err: err}
+	if err == nil {
+		r.status = res.StatusCode
+	}
+	return r
 }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the latency histogram buckets.
+// Slower requests fall in a last bucket without bound.
+var histogramBounds = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second,
+}
+
+// bucket counts the latencies up to an upper bound.
+type bucket struct {
+	UpperMs float64 `json:"upperMs"` // Zero for the last bucket
+	Count   int     `json:"count"`
+}
+
+// histogram buckets the latencies of the answered requests, leaving out the
+// empty buckets before the fastest and after the slowest request.
+func (r *report) histogram() []bucket {
+	buckets := make([]bucket, len(histogramBounds)+1)
+	for i, bound := range histogramBounds {
+		buckets[i].UpperMs = milliseconds(bound)
+	}
+	for _, l := range r.latencies {
+		i := sort.Search(len(histogramBounds), func(i int) bool { return l <= histogramBounds[i] })
+		buckets[i].Count++
+	}
+
+	first, last := 0, len(buckets)-1
+	for first < last && buckets[first].Count == 0 {
+		first++
+	}
+	for last > first && buckets[last].Count == 0 {
+		last--
+	}
+	return buckets[first : last+1]
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// jsonReport is the JSON form of a report.
+type jsonReport struct {
+	Label          string             `json:"label,omitempty"`
+	Requests       int                `json:"requests"`
+	Errors         int                `json:"errors"`
+	ElapsedSeconds float64            `json:"elapsedSeconds"`
+	Throughput     float64            `json:"throughput"`
+	LatencyMs      map[string]float64 `json:"latencyMs"`
+	Statuses       map[string]int     `json:"statuses"`
+	ErrorMessages  map[string]int     `json:"errorMessages,omitempty"`
+	Histogram      []bucket           `json:"histogram"`
+}
+
+// writeJSON writes the report as a JSON object.
+func (r *report) writeJSON(w io.Writer, label string) error {
+	out := jsonReport{
+		Label:          label,
+		Requests:       r.Requests,
+		Errors:         r.Errors,
+		ElapsedSeconds: r.Elapsed.Seconds(),
+		Throughput:     r.Throughput,
+		LatencyMs:      make(map[string]float64),
+		Statuses:       make(map[string]int),
+		ErrorMessages:  r.ErrorTypes,
+		Histogram:      r.histogram(),
+	}
+	for _, l := range r.latencyStats() {
+		out.LatencyMs[l.Name] = milliseconds(l.Value)
+	}
+	for status, n := range r.Statuses {
+		out.Statuses[strconv.Itoa(status)] = n
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// csvColumns are the columns of the CSV report, fixed so reports of
+// different runs can be appended to the same file.
+var csvColumns = []string{
+	"label", "requests", "errors", "elapsed_s", "throughput",
+	"min_ms", "mean_ms", "p50_ms", "p90_ms", "p99_ms", "max_ms",
+	"status_1xx", "status_2xx", "status_3xx", "status_4xx", "status_5xx",
+}
+
+// writeCSV writes the report as a CSV row, preceded by the header row when
+// header is set.
+func (r *report) writeCSV(w io.Writer, label string, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+
+	row := []string{
+		label,
+		strconv.Itoa(r.Requests),
+		strconv.Itoa(r.Errors),
+		formatFloat(r.Elapsed.Seconds()),
+		formatFloat(r.Throughput),
+	}
+	for _, l := range r.latencyStats() {
+		row = append(row, formatFloat(milliseconds(l.Value)))
+	}
+	var classes [5]int
+	for status, n := range r.Statuses {
+		if class := status/100 - 1; class >= 0 && class < len(classes) {
+			classes[class] += n
+		}
+	}
+	for _, n := range classes {
+		row = append(row, strconv.Itoa(n))
+	}
+
+	cw.Write(row)
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatFloat formats v with at most three decimals.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":  func(d time.Duration) string { return d.Round(time.Microsecond).String() },
+	"pct": func(n, total int) float64 { return 100 * float64(n) / float64(max(total, 1)) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Load test{{with .Label}} - {{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { padding: 0.2em 0.8em; text-align: left; }
+.bar { background: #4a90d9; height: 1em; }
+</style>
+</head>
+<body>
+<h1>Load test{{with .Label}} - {{.}}{{end}}</h1>
+<table>
+<tr><th>Requests</th><td>{{.Report.Requests}} in {{ms .Report.Elapsed}}</td></tr>
+<tr><th>Throughput</th><td>{{printf "%.1f" .Report.Throughput}} req/s</td></tr>
+<tr><th>Errors</th><td>{{.Report.Errors}}</td></tr>
+</table>
+<h2>Latency</h2>
+<table>
+{{range .Stats}}<tr><th>{{.Name}}</th><td>{{ms .Value}}</td></tr>
+{{end}}</table>
+<h2>Latency histogram</h2>
+<table>
+{{range .Histogram}}<tr><th>{{if .UpperMs}}&le; {{.UpperMs}} ms{{else}}slower{{end}}</th><td>{{.Count}}</td><td style="width: 30em"><div class="bar" style="width: {{pct .Count $.Answered}}%"></div></td></tr>
+{{end}}</table>
+<h2>Status codes</h2>
+<table>
+{{range $status, $n := .Report.Statuses}}<tr><th>{{$status}}</th><td>{{$n}}</td></tr>
+{{end}}</table>
+{{with .Report.ErrorTypes}}<h2>Errors</h2>
+<table>
+{{range $msg, $n := .}}<tr><td>{{$n}}</td><td>{{$msg}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// writeHTML writes a standalone HTML summary of the report.
+func (r *report) writeHTML(w io.Writer, label string) error {
+	return htmlTemplate.Execute(w, map[string]any{
+		"Label":     label,
+		"Report":    r,
+		"Stats":     r.latencyStats(),
+		"Histogram": r.histogram(),
+		"Answered":  len(r.latencies),
+	})
+}
+
+// writeReport writes the report in the given format.
+func (r *report) writeReport(w io.Writer, format, label string) error {
+	switch format {
+	case "text":
+		r.print(w)
+		return nil
+	case "json":
+		return r.writeJSON(w, label)
+	case "csv":
+		return r.writeCSV(w, label, true)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testReport returns a report of four answered requests and a failed one over two seconds.
+func testReport() *report {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	return newReport([][]result{{
+		{status: 200, latency: ms(1)},
+		{status: 201, latency: ms(2)},
+		{status: 404, latency: ms(3)},
+		{status: 503, latency: ms(40)},
+		{err: errors.New("timeout")},
+	}}, 2*time.Second)
+}
+
+// TestHistogram verifies that latencies fall in their buckets, without the empty ones at both ends.
+func TestHistogram(t *testing.T) {
+	got := testReport().histogram()
+	want := []bucket{{1, 1}, {2.5, 1}, {5, 1}, {10, 0}, {25, 0}, {50, 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the buckets %v, got %v", want, got)
+	}
+
+	slow := newReport([][]result{{{status: 200, latency: time.Minute}}}, time.Minute)
+	if got := slow.histogram(); len(got) != 1 || got[0] != (bucket{0, 1}) {
+		t.Errorf("Expected a single unbounded bucket, got %v", got)
+	}
+}
+
+// TestWriteJSON verifies that the JSON report can be read back.
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := writeFile(path, func(w io.Writer) error { return testReport().writeJSON(w, "abc123") }); err != nil {
+		t.Fatalf("Failed to write the report: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the report: %v", err)
+	}
+
+	var got jsonReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, data)
+	}
+	if got.Label != "abc123" || got.Requests != 5 || got.Errors != 1 || got.ElapsedSeconds != 2 || got.Throughput != 2.5 {
+		t.Errorf("Unexpected totals %+v", got)
+	}
+	if got.LatencyMs["min"] != 1 || got.LatencyMs["max"] != 40 || got.LatencyMs["p50"] != 3 || len(got.LatencyMs) != 6 {
+		t.Errorf("Unexpected latencies %v", got.LatencyMs)
+	}
+	if got.Statuses["200"] != 1 || got.Statuses["503"] != 1 || len(got.Statuses) != 4 {
+		t.Errorf("Unexpected statuses %v", got.Statuses)
+	}
+	if got.ErrorMessages["timeout"] != 1 || len(got.Histogram) != 6 {
+		t.Errorf("Unexpected errors %v or histogram %v", got.ErrorMessages, got.Histogram)
+	}
+}
+
+// TestWriteOutputCSV verifies that CSV reports appended to an existing file leave out the header row.
+func TestWriteOutputCSV(t *testing.T) {
+	defer func(path, f, l string) { outputPath, format, label = path, f, l }(outputPath, format, label)
+	outputPath = filepath.Join(t.TempDir(), "results.csv")
+	format = "csv"
+
+	for _, l := range []string{"first", "second"} {
+		label = l
+		if err := writeOutput(testReport()); err != nil {
+			t.Fatalf("Failed to write the %s report: %v", l, err)
+		}
+	}
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open the results: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(rows) != 3 || !slices.Equal(rows[0], csvColumns) {
+		t.Fatalf("Expected the header and two rows, got %v", rows)
+	}
+	want := []string{"first", "5", "1", "2.000", "2.500", "1.000", "11.500", "3.000", "40.000", "40.000", "40.000", "0", "2", "0", "1", "1"}
+	if !slices.Equal(rows[1], want) {
+		t.Errorf("Expected the row %v, got %v", want, rows[1])
+	}
+	if rows[2][0] != "second" || !slices.Equal(rows[2][1:], want[1:]) {
+		t.Errorf("Expected the second run, got %v", rows[2])
+	}
+}
+
+// TestWriteHTML verifies that the HTML summary holds the report and escapes the label.
+func TestWriteHTML(t *testing.T) {
+	var buf strings.Builder
+	if err := testReport().writeHTML(&buf, "<b>v2</b>"); err != nil {
+		t.Fatalf("Failed to write the summary: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"<title>Load test - &lt;b&gt;v2&lt;/b&gt;</title>",
+		"<tr><th>Requests</th><td>5 in 2s</td></tr>",
+		"<tr><th>p90</th><td>40ms</td></tr>",
+		"<tr><th>&le; 2.5 ms</th><td>1</td><td style=\"width: 30em\"><div class=\"bar\" style=\"width: 25%\"></div></td></tr>",
+		"<tr><th>503</th><td>1</td></tr>",
+		"<tr><td>1</td><td>timeout</td></tr>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the summary to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<b>v2</b>") {
+		t.Errorf("Expected the label to be escaped")
+	}
+}
+
+// TestWriteReportFormat verifies that unknown formats are rejected.
+func TestWriteReportFormat(t *testing.T) {
+	if err := testReport().writeReport(io.Discard, "xml", ""); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// result is the outcome of a single request.
+type result struct {
+	target  string
+	status  int // Zero when the request failed
+	latency time.Duration
+	err     error
+}
+
+// report summarizes the results of a load test.
+type report struct {
+	Requests   int
+	Errors     int
+	Elapsed    time.Duration
+	Throughput float64 // Requests per second
+	Statuses   map[int]int
+	ErrorTypes map[string]int
+
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+
+	latencies []time.Duration // Sorted latencies of the answered requests
+}
+
+// newReport summarizes the results collected by every worker.
+func newReport(results [][]result, elapsed time.Duration) *report {
+	r := &report{
+		Elapsed:    elapsed,
+		Statuses:   make(map[int]int),
+		ErrorTypes: make(map[string]int),
+	}
+
+	var total time.Duration
+	for _, worker := range results {
+		for _, res := range worker {
+			r.Requests++
+			if res.err != nil {
+				r.Errors++
+				r.ErrorTypes[res.err.Error()]++
+				continue
+			}
+			r.Statuses[res.status]++
+			r.latencies = append(r.latencies, res.latency)
+			total += res.latency
+		}
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	if n := len(r.latencies); n > 0 {
+		r.Min = r.latencies[0]
+		r.Max = r.latencies[n-1]
+		r.Mean = total / time.Duration(n)
+		r.P50 = percentile(r.latencies, 0.50)
+		r.P90 = percentile(r.latencies, 0.90)
+		r.P99 = percentile(r.latencies, 0.99)
+	}
+	return r
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
+
+// latencyStat is a named latency statistic of a report.
+type latencyStat struct {
+	Name  string
+	Value time.Duration
+}
+
+// latencyStats returns the latency statistics of the report in the order
+// they are reported.
+func (r *report) latencyStats() []latencyStat {
+	return []latencyStat{{"min", r.Min}, {"mean", r.Mean}, {"p50", r.P50}, {"p90", r.P90}, {"p99", r.P99}, {"max", r.Max}}
+}
+
+// print writes the report in a human readable form.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "\nRequests:    %d in %s\n", r.Requests, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Throughput:  %.1f req/s\n", r.Throughput)
+	fmt.Fprintf(w, "Errors:      %d\n", r.Errors)
+
+	fmt.Fprintf(w, "\nLatency:\n")
+	for _, l := range r.latencyStats() {
+		fmt.Fprintf(w, "  %-5s %s\n", l.Name, l.Value.Round(time.Microsecond))
+	}
+
+	fmt.Fprintf(w, "\nStatus codes:\n")
+	statuses := make([]int, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %d  %d\n", status, r.Statuses[status])
+	}
+
+	if len(r.ErrorTypes) > 0 {
+		fmt.Fprintf(w, "\nErrors:\n")
+		messages := make([]string, 0, len(r.ErrorTypes))
+		for msg := range r.ErrorTypes {
+			messages = append(messages, msg)
+		}
+		sort.Strings(messages)
+		for _, msg := range messages {
+			fmt.Fprintf(w, "  %6d  %s\n", r.ErrorTypes[msg], msg)
+		}
+	}
+}
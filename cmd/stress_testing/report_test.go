@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPercentile verifies the nearest-rank percentiles of sorted latencies.
+func TestPercentile(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	ten := []time.Duration{ms(1), ms(2), ms(3), ms(4), ms(5), ms(6), ms(7), ms(8), ms(9), ms(10)}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single p50", []time.Duration{ms(7)}, 0.5, ms(7)},
+		{"single p99", []time.Duration{ms(7)}, 0.99, ms(7)},
+		{"p0", ten, 0, ms(1)},
+		{"p50", ten, 0.5, ms(6)},
+		{"p90", ten, 0.9, ms(9)},
+		{"p99", ten, 0.99, ms(10)},
+		{"p100", ten, 1, ms(10)},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestNewReport verifies the counts, throughput, latencies and status breakdown of a report.
+func TestNewReport(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	timeout := errors.New("timeout")
+	results := [][]result{
+		{
+			{status: 200, latency: ms(40)},
+			{status: 200, latency: ms(10)},
+			{err: timeout},
+		},
+		{
+			{status: 503, latency: ms(30)},
+			{status: 200, latency: ms(20)},
+		},
+		nil,
+	}
+
+	r := newReport(results, 2*time.Second)
+	if r.Requests != 5 || r.Errors != 1 || r.ErrorTypes["timeout"] != 1 {
+		t.Errorf("Expected 5 requests with 1 timeout, got %d with %d errors %v", r.Requests, r.Errors, r.ErrorTypes)
+	}
+	if r.Throughput != 2.5 {
+		t.Errorf("Expected 2.5 req/s, got %v", r.Throughput)
+	}
+	if len(r.Statuses) != 2 || r.Statuses[200] != 3 || r.Statuses[503] != 1 {
+		t.Errorf("Expected 3 200s and 1 503, got %v", r.Statuses)
+	}
+	if r.Min != ms(10) || r.Max != ms(40) || r.Mean != 25*time.Millisecond || r.P50 != ms(30) || r.P90 != ms(40) || r.P99 != ms(40) {
+		t.Errorf("Unexpected latencies min %s mean %s p50 %s p90 %s p99 %s max %s", r.Min, r.Mean, r.P50, r.P90, r.P99, r.Max)
+	}
+}
+
+// TestNewReportEdgeCases verifies reports without answered requests and with a single one.
+func TestNewReportEdgeCases(t *testing.T) {
+	r := newReport(nil, 0)
+	if r.Requests != 0 || r.Throughput != 0 || len(r.Statuses) != 0 {
+		t.Errorf("Expected an empty report, got %+v", r)
+	}
+	for _, l := range r.latencyStats() {
+		if l.Value != 0 {
+			t.Errorf("Expected no %s latency, got %s", l.Name, l.Value)
+		}
+	}
+
+	r = newReport([][]result{{{err: errors.New("connection refused")}}}, time.Second)
+	if r.Requests != 1 || r.Errors != 1 || r.Max != 0 {
+		t.Errorf("Expected a failed request without latencies, got %+v", r)
+	}
+
+	r = newReport([][]result{{{status: 204, latency: 5 * time.Millisecond}}}, 500*time.Millisecond)
+	if r.Throughput != 2 || r.Statuses[204] != 1 {
+		t.Errorf("Expected 2 req/s of 204s, got %v %v", r.Throughput, r.Statuses)
+	}
+	for _, l := range r.latencyStats() {
+		if l.Value != 5*time.Millisecond {
+			t.Errorf("Expected a %s latency of 5ms, got %s", l.Name, l.Value)
+		}
+	}
+}
+
+// TestReportPrint verifies the human readable report.
+func TestReportPrint(t *testing.T) {
+	r := newReport([][]result{{
+		{status: 404, latency: time.Millisecond},
+		{status: 200, latency: 3 * time.Millisecond},
+		{err: errors.New("timeout")},
+	}}, time.Second)
+	var buf bytes.Buffer
+	r.print(&buf)
+
+	out := buf.String()
+	for _, want := range []string{"Requests:    3 in 1s", "Throughput:  3.0 req/s", "Errors:      1", "p50   3ms", "  200  1\n  404  1\n", "     1  timeout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// jsonDuration is a time.Duration read from a JSON string such as "1.5s".
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", s)
+	}
+	*d = jsonDuration(v)
+	return nil
+}
+
+// scenario is the traffic shape read from a scenario file:
+//
+//	{
+//		"baseURL": "http://localhost:8080",
+//		"routes": [
+//			{"url": "/api/exchange", "weight": 8},
+//			{"method": "POST", "url": "/api/login/1", "weight": 2,
+//			 "headers": {"Content-Type": "application/json"}, "body": "{\"id\": \"1\"}"}
+//		],
+//		"thinkTime": {"min": "50ms", "max": "250ms"},
+//		"stages": [
+//			{"duration": "10s", "workers": 50},
+//			{"duration": "1m", "workers": 50},
+//			{"duration": "10s", "workers": 0}
+//		]
+//	}
+type scenario struct {
+	BaseURL   string          `json:"baseURL"`
+	Routes    []scenarioRoute `json:"routes"`
+	ThinkTime struct {
+		Min jsonDuration `json:"min"`
+		Max jsonDuration `json:"max"`
+	} `json:"thinkTime"`
+	Stages []struct {
+		Duration jsonDuration `json:"duration"`
+		Workers  int          `json:"workers"`
+	} `json:"stages"`
+}
+
+// scenarioRoute is a request of the route mix, sent in proportion to its
+// weight.
+type scenarioRoute struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Weight  int               `json:"weight"`
+}
+
+// loadScenario reads and validates a scenario file. Unknown fields are
+// rejected, so a misspelled one isn't silently ignored.
+func loadScenario(path string) (*scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := &scenario{}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(s.Routes) == 0 {
+		return nil, fmt.Errorf("%s: no routes", path)
+	}
+	for _, r := range s.Routes {
+		if r.Weight < 0 {
+			return nil, fmt.Errorf("%s: negative weight for %s", path, r.URL)
+		}
+	}
+	if s.ThinkTime.Max < s.ThinkTime.Min {
+		s.ThinkTime.Max = s.ThinkTime.Min
+	}
+	workers := 0
+	for i, stage := range s.Stages {
+		if stage.Duration <= 0 || stage.Workers < 0 {
+			return nil, fmt.Errorf("%s: stage %d needs a positive duration and workers", path, i+1)
+		}
+		workers = max(workers, stage.Workers)
+	}
+	if len(s.Stages) > 0 && workers == 0 {
+		return nil, fmt.Errorf("%s: no stage has any workers", path)
+	}
+	return s, nil
+}
+
+// apply replaces the load of cfg with the one of the scenario. Headers
+// already in cfg are added to every route.
+func (s *scenario) apply(cfg *config) error {
+	var base *url.URL
+	if s.BaseURL != "" {
+		var err error
+		if base, err = parseTarget(s.BaseURL); err != nil {
+			return err
+		}
+	}
+
+	routes := make([]route, 0, len(s.Routes))
+	for _, r := range s.Routes {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q", r.URL)
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u, err = parseTarget(u.String()); err != nil {
+			return err
+		}
+
+		rt := route{
+			method: strings.ToUpper(r.Method),
+			url:    u,
+			body:   r.Body,
+			header: make(http.Header),
+			weight: r.Weight,
+		}
+		if rt.method == "" {
+			rt.method = http.GET
+		}
+		if rt.weight == 0 {
+			rt.weight = 1
+		}
+		for key, values := range cfg.header {
+			rt.header[key] = append([]string(nil), values...)
+		}
+		for key, value := range r.Headers {
+			rt.header.Set(key, value)
+		}
+		routes = append(routes, rt)
+	}
+	cfg.routes = routes
+
+	cfg.thinkMin = time.Duration(s.ThinkTime.Min)
+	cfg.thinkMax = time.Duration(s.ThinkTime.Max)
+
+	if len(s.Stages) > 0 {
+		cfg.stages = cfg.stages[:0]
+		cfg.duration = 0
+		cfg.concurrency = 0
+		for _, st := range s.Stages {
+			cfg.stages = append(cfg.stages, stage{duration: time.Duration(st.Duration), workers: st.Workers})
+			cfg.duration += time.Duration(st.Duration)
+			cfg.concurrency = max(cfg.concurrency, st.Workers)
+		}
+	}
+	return nil
+}
+
+// stage ramps the number of active workers linearly from the one of the
+// previous stage, or zero, to workers over its duration.
+type stage struct {
+	duration time.Duration
+	workers  int
+}
+
+// activeWorkers returns how many workers should be sending requests after
+// elapsed time. Without stages every worker is active.
+func activeWorkers(stages []stage, concurrency int, elapsed time.Duration) int {
+	if len(stages) == 0 {
+		return concurrency
+	}
+	from := 0
+	for _, st := range stages {
+		if elapsed < st.duration {
+			frac := float64(elapsed) / float64(st.duration)
+			return from + int(math.Round(float64(st.workers-from)*frac))
+		}
+		elapsed -= st.duration
+		from = st.workers
+	}
+	return from
+}
+
+// pickRoute returns the route of the i-th request, cycling through the
+// routes in proportion to their weights.
+func pickRoute(routes []route, totalWeight int64, i int64) *route {
+	n := i % totalWeight
+	for j := range routes {
+		if n < int64(routes[j].weight) {
+			return &routes[j]
+		}
+		n -= int64(routes[j].weight)
+	}
+	return &routes[len(routes)-1]
+}
+
+// thinkTime returns how long a worker pauses between its requests.
+func thinkTime(cfg config) time.Duration {
+	if cfg.thinkMax <= cfg.thinkMin {
+		return cfg.thinkMin
+	}
+	return cfg.thinkMin + rand.N(cfg.thinkMax-cfg.thinkMin+1)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// TestLoadScenario verifies that scenario files are read and their mistakes rejected.
+func TestLoadScenario(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string // Part of the error, empty when the file is valid
+	}{
+		{"valid", `{"routes": [{"url": "/a", "weight": 2}], "thinkTime": {"min": "50ms"}, "stages": [{"duration": "1s", "workers": 5}]}`, ""},
+		{"no stages", `{"routes": [{"url": "/a"}]}`, ""},
+		{"no routes", `{"routes": []}`, "no routes"},
+		{"negative weight", `{"routes": [{"url": "/a", "weight": -1}]}`, "negative weight for /a"},
+		{"unknown field", `{"routes": [{"url": "/a", "wieght": 2}]}`, `unknown field "wieght"`},
+		{"invalid duration", `{"routes": [{"url": "/a"}], "thinkTime": {"min": "soon"}}`, "invalid duration"},
+		{"empty stage", `{"routes": [{"url": "/a"}], "stages": [{}]}`, "stage 1 needs a positive duration"},
+		{"negative workers", `{"routes": [{"url": "/a"}], "stages": [{"duration": "1s", "workers": -1}]}`, "stage 1 needs a positive duration"},
+		{"no workers", `{"routes": [{"url": "/a"}], "stages": [{"duration": "1s", "workers": 0}]}`, "no stage has any workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatalf("Failed to write the scenario: %v", err)
+			}
+
+			s, err := loadScenario(path)
+			if tt.err == "" {
+				if err != nil || s == nil {
+					t.Errorf("Expected a valid scenario, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestScenarioApply verifies that a scenario replaces the routes and load of the flags.
+func TestScenarioApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	data := `{
+		"baseURL": "http://localhost:8080",
+		"routes": [{"url": "/a"}, {"method": "post", "url": "https://example.com/b", "weight": 3, "headers": {"X-Route": "b"}}],
+		"thinkTime": {"min": "20ms", "max": "10ms"},
+		"stages": [{"duration": "10s", "workers": 4}, {"duration": "5s", "workers": 8}]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write the scenario: %v", err)
+	}
+	s, err := loadScenario(path)
+	if err != nil {
+		t.Fatalf("Failed to load the scenario: %v", err)
+	}
+
+	cfg := config{header: make(http.Header), concurrency: 50, requests: 1000}
+	cfg.header.Set("Authorization", "Bearer token")
+	if err := s.apply(&cfg); err != nil {
+		t.Fatalf("Failed to apply the scenario: %v", err)
+	}
+
+	if len(cfg.routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %d", len(cfg.routes))
+	}
+	a, b := cfg.routes[0], cfg.routes[1]
+	if a.method != http.GET || a.url.String() != "http://localhost:8080/a" || a.weight != 1 {
+		t.Errorf("Expected GET http://localhost:8080/a with weight 1, got %s %s with weight %d", a.method, a.url, a.weight)
+	}
+	if b.method != http.POST || b.url.String() != "https://example.com/b" || b.weight != 3 {
+		t.Errorf("Expected POST https://example.com/b with weight 3, got %s %s with weight %d", b.method, b.url, b.weight)
+	}
+	if b.header.Get("X-Route") != "b" || b.header.Get("Authorization") != "Bearer token" || a.header.Get("X-Route") != "" {
+		t.Errorf("Expected the flag headers on every route and the route headers on theirs, got %v and %v", a.header, b.header)
+	}
+	if cfg.thinkMin != 20*time.Millisecond || cfg.thinkMax != 20*time.Millisecond {
+		t.Errorf("Expected a 20ms think time, got %s to %s", cfg.thinkMin, cfg.thinkMax)
+	}
+	if cfg.duration != 15*time.Second || cfg.concurrency != 8 || len(cfg.stages) != 2 {
+		t.Errorf("Expected 8 workers over 15s in 2 stages, got %d over %s in %d", cfg.concurrency, cfg.duration, len(cfg.stages))
+	}
+}
+
+// TestActiveWorkers verifies that stages ramp the active workers linearly from the previous stage.
+func TestActiveWorkers(t *testing.T) {
+	stages := []stage{
+		{10 * time.Second, 10},
+		{20 * time.Second, 10},
+		{10 * time.Second, 0},
+	}
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 0},
+		{time.Second, 1},
+		{5 * time.Second, 5},
+		{10 * time.Second, 10},
+		{25 * time.Second, 10},
+		{30 * time.Second, 10},
+		{32 * time.Second, 8},
+		{39 * time.Second, 1},
+		{40 * time.Second, 0},
+		{time.Hour, 0},
+	}
+	for _, tt := range tests {
+		if got := activeWorkers(stages, 10, tt.elapsed); got != tt.want {
+			t.Errorf("After %s: expected %d workers, got %d", tt.elapsed, tt.want, got)
+		}
+	}
+
+	if got := activeWorkers(nil, 7, time.Hour); got != 7 {
+		t.Errorf("Expected every worker without stages, got %d", got)
+	}
+}
+
+// TestPickRoute verifies that routes are picked in proportion to their weights.
+func TestPickRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+	}{
+		{"single", []int{1}},
+		{"equal", []int{1, 1, 1}},
+		{"weighted", []int{8, 2}},
+		{"uneven", []int{5, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := make([]route, len(tt.weights))
+			var total int64
+			for i, w := range tt.weights {
+				routes[i].weight = w
+				total += int64(w)
+			}
+
+			// Every cycle of total requests hits each route weight times
+			counts := make(map[*route]int)
+			for i := int64(0); i < 10*total; i++ {
+				counts[pickRoute(routes, total, i)]++
+			}
+			for i, w := range tt.weights {
+				if got := counts[&routes[i]]; got != 10*w {
+					t.Errorf("Expected route %d to be picked %d times, got %d", i, 10*w, got)
+				}
+			}
+		})
+	}
+}
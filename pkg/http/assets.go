@@ -0,0 +1,113 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// immutableCachePolicy is sent with fingerprinted assets: their names change
+// whenever their content does, so clients may cache them forever.
+var immutableCachePolicy = CachePolicy{CacheControl: "public, max-age=31536000, immutable"}
+
+// Assets serves the files of a file system under content-hashed names such
+// as app.3fa9d2c1.js, so they can be cached forever and are refetched as
+// soon as they change.
+type Assets struct {
+	prefix  string
+	fsys    fs.FS
+	hashed  map[string]string // logical name -> hashed name
+	logical map[string]string // hashed name -> logical name
+}
+
+// NewAssets hashes every file in fsys. URLs returned by the Assets start
+// with prefix, the URL path the assets are served under.
+func NewAssets(prefix string, fsys fs.FS) (*Assets, error) {
+	a := &Assets{
+		prefix:  "/" + strings.Trim(prefix, "/"),
+		fsys:    fsys,
+		hashed:  make(map[string]string),
+		logical: make(map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		hashedName := fingerprintName(name, sum)
+		a.hashed[name] = hashedName
+		a.logical[hashedName] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Assets hashes the files of fsys and serves them under prefix with
+// immutable caching. Use the returned Assets to resolve file names to URLs.
+func (mux *ServeMux) Assets(prefix string, fsys fs.FS) (*Assets, error) {
+	a, err := NewAssets(prefix, fsys)
+	if err != nil {
+		return nil, err
+	}
+	mux.StaticFS(prefix, a).WithCachePolicy(immutableCachePolicy)
+	return a, nil
+}
+
+// URL returns the URL of the hashed version of the named file, e.g.
+// "/assets/js/app.3fa9d2c1.js" for "js/app.js". Unknown files resolve to
+// their unhashed URL.
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashedName, ok := a.hashed[name]; ok {
+		name = hashedName
+	}
+	return path.Join(a.prefix, name)
+}
+
+// FuncMap returns template functions for resolving asset URLs, to be added
+// with template.Funcs: {{ asset "js/app.js" }}.
+func (a *Assets) FuncMap() map[string]any {
+	return map[string]any{"asset": a.URL}
+}
+
+// Open implements fs.FS over the hashed names, so only fingerprinted URLs
+// are served.
+func (a *Assets) Open(name string) (fs.File, error) {
+	logicalName, ok := a.logical[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return a.fsys.Open(logicalName)
+}
+
+// hashFile returns the hex encoded SHA-256 digest of the named file.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprintName inserts the first characters of sum before the extension
+// of name: "js/app.js" becomes "js/app.3fa9d2c1.js".
+func fingerprintName(name, sum string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + sum[:8] + ext
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"testing/fstest"
+)
+
+// TestFingerprintName verifies that the hash is inserted before the extension.
+func TestFingerprintName(t *testing.T) {
+	sum := "3fa9d2c1deadbeef"
+
+	tests := map[string]string{
+		"app.js":          "app.3fa9d2c1.js",
+		"js/app.min.js":   "js/app.min.3fa9d2c1.js",
+		"LICENSE":         "LICENSE.3fa9d2c1",
+		"img/logo.v2.png": "img/logo.v2.3fa9d2c1.png",
+	}
+	for name, expected := range tests {
+		if got := fingerprintName(name, sum); got != expected {
+			t.Errorf("fingerprintName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+// TestAssets verifies that hashed URLs are served with immutable caching and logical names are not.
+func TestAssets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"js/app.js": {Data: []byte("console.log(1)")},
+	}
+
+	mux := NewServeMux(nil)
+	assets, err := mux.Assets("/assets", fsys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// sha256("console.log(1)") starts with these characters
+	hashedURL := assets.URL("js/app.js")
+	if hashedURL != "/assets/js/app.0a286891.js" {
+		t.Fatalf("Unexpected hashed URL '%s'", hashedURL)
+	}
+	if assets.URL("/js/app.js") != hashedURL {
+		t.Errorf("Expected a leading slash to be ignored")
+	}
+	if u := assets.URL("missing.css"); u != "/assets/missing.css" {
+		t.Errorf("Expected unknown files to keep their name, got '%s'", u)
+	}
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: hashedURL}})
+
+	if res.status != StatusOK || string(res.body) != "console.log(1)" {
+		t.Fatalf("Expected the asset to be served, got %d '%s'", res.status, string(res.body))
+	}
+	if cc := res.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected immutable Cache-Control, got '%s'", cc)
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+		t.Errorf("Expected JavaScript Content-Type, got '%s'", ct)
+	}
+
+	res = &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/assets/js/app.js"}})
+	if res.status != StatusNotFound {
+		t.Errorf("Expected 404 for the unhashed name, got %d", res.status)
+	}
+}
+
+// TestAssetsFuncMap verifies that the asset template function resolves hashed URLs.
+func TestAssetsFuncMap(t *testing.T) {
+	assets, err := NewAssets("static", fstest.MapFS{"style.css": {Data: []byte("body{}")}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	asset, ok := assets.FuncMap()["asset"].(func(string) string)
+	if !ok {
+		t.Fatal("Expected an asset function in the FuncMap")
+	}
+	if asset("style.css") != assets.URL("style.css") {
+		t.Errorf("Expected asset to match URL, got '%s'", asset("style.css"))
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"slices"
+	"strings"
+)
+
+// Principal is the authenticated user or client a request is made by.
+type Principal struct {
+	// ID identifies the principal, such as a user ID or a JWT subject.
+	ID string `json:"id"`
+	// Roles are the roles granted to the principal, such as "admin".
+	Roles []string `json:"roles,omitempty"`
+	// Permissions are the permissions granted to the principal, such as
+	// "items:write".
+	Permissions []string `json:"permissions,omitempty"`
+	// Claims are the claims of the token the principal was authenticated
+	// with, such as the ID token of an OIDC login.
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasPermission reports whether the principal was granted the permission.
+func (p *Principal) HasPermission(permission string) bool {
+	return p != nil && slices.Contains(p.Permissions, permission)
+}
+
+// Authenticator returns the principal of a request from its credentials,
+// such as a JWT, a session cookie or an API key. It returns nil without
+// an error for requests without credentials, and an error for invalid
+// ones.
+type Authenticator func(*Request) (*Principal, error)
+
+// principalKey is the context key of the principal of a request.
+type principalKey struct{}
+
+// Principal returns the principal of the request set by the Authenticate
+// middleware, or nil for unauthenticated requests.
+func (r *Request) Principal() *Principal {
+	p, _ := r.Context().Value(principalKey{}).(*Principal)
+	return p
+}
+
+// WithPrincipal returns a shallow copy of r made by the principal, for
+// authentication middleware other than Authenticate.
+func (r *Request) WithPrincipal(p *Principal) *Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// Authenticate returns middleware resolving the principal of requests with
+// auth, which handlers read with Request.Principal. Requests with invalid
+// credentials are answered with 401 Unauthorized; requests without any
+// are passed on unauthenticated, for RequireRoles and RequirePermissions
+// to turn away where needed.
+func Authenticate(auth Authenticator) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			p, err := auth(r)
+			if err != nil {
+				WriteProblem(w, NewProblem(StatusUnauthorized, err.Error()))
+				return
+			}
+			if p != nil {
+				r = r.WithPrincipal(p)
+			}
+			next(w, r)
+		}
+	}
+}
+
+// APIKeys returns an authenticator of the API keys sent in the header,
+// such as "X-API-Key", mapped to their principal. A key sent as a bearer
+// token of the Authorization header is used when the header is missing.
+func APIKeys(header string, keys map[string]*Principal) Authenticator {
+	return func(r *Request) (*Principal, error) {
+		key := r.Header.Get(header)
+		if key == "" {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") {
+				return nil, nil
+			}
+			key = strings.TrimSpace(token)
+		}
+
+		// Every key is compared, so the time taken doesn't tell them apart
+		var match *Principal
+		for k, p := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				match = p
+			}
+		}
+		if match == nil {
+			return nil, ErrInvalidAPIKey
+		}
+		return match, nil
+	}
+}
+
+// RequireRoles returns middleware letting through the requests of
+// principals granted any of the roles, to be used on the routes or the mux
+// of a group of routes:
+//
+//	mux.Use(http.Authenticate(auth))
+//	mux.AddRoute("/admin/users", []string{http.GET}, listUsers).Use(http.RequireRoles("admin"))
+//
+// Unauthenticated requests are answered with 401 Unauthorized, and the
+// others with 403 Forbidden problem details listing the roles in
+// "required_roles".
+func RequireRoles(roles ...string) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			p := r.Principal()
+			if p == nil {
+				WriteProblem(w, NewProblem(StatusUnauthorized, "Authentication is required"))
+				return
+			}
+			if !slices.ContainsFunc(roles, p.HasRole) {
+				problem := NewProblem(StatusForbidden, "The request requires one of the roles")
+				problem.Extensions = map[string]any{"required_roles": roles}
+				WriteProblem(w, problem)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequirePermissions returns middleware letting through the requests of
+// principals granted all of the permissions, used like RequireRoles.
+// Requests of principals lacking some are answered with 403 Forbidden
+// problem details listing them in "missing_permissions".
+func RequirePermissions(permissions ...string) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			p := r.Principal()
+			if p == nil {
+				WriteProblem(w, NewProblem(StatusUnauthorized, "Authentication is required"))
+				return
+			}
+			var missing []string
+			for _, permission := range permissions {
+				if !p.HasPermission(permission) {
+					missing = append(missing, permission)
+				}
+			}
+			if len(missing) > 0 {
+				problem := NewProblem(StatusForbidden, "The request requires more permissions")
+				problem.Extensions = map[string]any{"missing_permissions": missing}
+				WriteProblem(w, problem)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+// newAuthzMux returns a mux authenticating API keys, with routes requiring roles and permissions.
+func newAuthzMux() *ServeMux {
+	mux := NewServeMux(nil)
+	mux.Use(Authenticate(APIKeys("X-API-Key", map[string]*Principal{
+		"admin-key":  {ID: "ana", Roles: []string{"admin"}, Permissions: []string{"items:read", "items:write"}},
+		"reader-key": {ID: "bob", Roles: []string{"user"}, Permissions: []string{"items:read"}},
+	})))
+	ok := func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.Principal().ID))
+	}
+	mux.AddRoute("/admin", []string{GET}, ok).Use(RequireRoles("admin", "owner"))
+	mux.AddRoute("/items", []string{GET}, ok).Use(RequirePermissions("items:read"))
+	mux.AddRoute("/items", []string{POST}, ok).Use(RequirePermissions("items:read", "items:write"))
+	return mux
+}
+
+// TestRequireRoles verifies that routes are only served to principals with a required role.
+func TestRequireRoles(t *testing.T) {
+	mux := newAuthzMux()
+	for _, tt := range []struct {
+		key    string
+		status int
+	}{
+		{"admin-key", StatusOK},
+		{"reader-key", StatusForbidden},
+		{"", StatusUnauthorized},
+		{"wrong-key", StatusUnauthorized},
+	} {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/admin"}, Header: Header{"X-Api-Key": {tt.key}}})
+		if res.status != tt.status {
+			t.Errorf("Key '%s': expected %d, got %d", tt.key, tt.status, res.status)
+		}
+		if tt.status == StatusForbidden {
+			var problem struct {
+				Status        int      `json:"status"`
+				RequiredRoles []string `json:"required_roles"`
+			}
+			if err := json.Unmarshal(res.body, &problem); err != nil || len(problem.RequiredRoles) != 2 {
+				t.Errorf("Expected the required roles in the problem, got %s", res.body)
+			}
+		}
+	}
+}
+
+// TestRequirePermissions verifies that routes are only served to principals with all the required permissions.
+func TestRequirePermissions(t *testing.T) {
+	mux := newAuthzMux()
+	serve := func(method, auth string) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: method, URL: &url.URL{Path: "/items"}, Header: Header{"Authorization": {auth}}})
+		return res
+	}
+
+	if res := serve(GET, "Bearer reader-key"); res.status != StatusOK || string(res.body) != "bob" {
+		t.Errorf("Expected %d for bob, got %d '%s'", StatusOK, res.status, res.body)
+	}
+	res := serve(POST, "Bearer reader-key")
+	var problem struct {
+		Missing []string `json:"missing_permissions"`
+	}
+	json.Unmarshal(res.body, &problem)
+	if res.status != StatusForbidden || len(problem.Missing) != 1 || problem.Missing[0] != "items:write" {
+		t.Errorf("Expected %d missing items:write, got %d %s", StatusForbidden, res.status, res.body)
+	}
+	if res := serve(POST, "Bearer admin-key"); res.status != StatusOK {
+		t.Errorf("Expected %d for the admin, got %d", StatusOK, res.status)
+	}
+	if res := serve(GET, "Basic YTpi"); res.status != StatusUnauthorized {
+		t.Errorf("Expected %d without an API key, got %d", StatusUnauthorized, res.status)
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// BufferedResponse is a ResponseWriter holding back a whole response, so
+// middleware can inspect and change its status, headers and body before
+// sending it with Send:
+//
+//	func InjectBanner(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+//		return func(w http.ResponseWriter, r *http.Request) {
+//			buf := http.NewBufferedResponse(w)
+//			next(buf, r)
+//			if strings.HasPrefix(buf.Header().Get("Content-Type"), "text/html") {
+//				body := bytes.Replace(buf.Body.Bytes(), []byte("<body>"), banner, 1)
+//				buf.Body.Reset()
+//				buf.Body.Write(body)
+//			}
+//			buf.Send()
+//		}
+//	}
+//
+// Responses are kept in memory, so it doesn't suit large or streamed
+// bodies; flushing and hijacking are not supported.
+type BufferedResponse struct {
+	// StatusCode is the status set by the handler, zero when it wrote
+	// nothing, which is sent as 200 OK.
+	StatusCode int
+	// Body holds the bytes written by the handler.
+	Body bytes.Buffer
+
+	w      ResponseWriter
+	header Header
+	sent   bool
+}
+
+// NewBufferedResponse returns a BufferedResponse that is sent to w.
+func NewBufferedResponse(w ResponseWriter) *BufferedResponse {
+	return &BufferedResponse{w: w, header: make(Header)}
+}
+
+// Header returns the headers of the buffered response. They are added to
+// the ones of the underlying writer when the response is sent.
+func (b *BufferedResponse) Header() Header {
+	return b.header
+}
+
+// Write appends data to the body.
+func (b *BufferedResponse) Write(data []byte) (int, error) {
+	if b.StatusCode == 0 {
+		b.StatusCode = StatusOK
+	}
+	return b.Body.Write(data)
+}
+
+// WriteHeader records the status code. Only the first call has effect.
+func (b *BufferedResponse) WriteHeader(statusCode int) {
+	if b.StatusCode == 0 {
+		b.StatusCode = statusCode
+	}
+}
+
+// SetCookie adds a Set-Cookie header to the buffered response.
+func (b *BufferedResponse) SetCookie(c *Cookie) {
+	addCookie(b.w, b.header, c)
+}
+
+// DeleteCookie adds a Set-Cookie header deleting the cookie to the
+// buffered response.
+func (b *BufferedResponse) DeleteCookie(name string) {
+	b.SetCookie(&Cookie{Name: name, Value: "", MaxAge: -1})
+}
+
+// Cookies returns the cookies set on the buffered response.
+func (b *BufferedResponse) Cookies() []*Cookie {
+	return headerCookies(b.header)
+}
+
+// Reset discards the buffered response, so another one can be written in
+// its place, such as an error page.
+func (b *BufferedResponse) Reset() {
+	b.StatusCode = 0
+	b.Body.Reset()
+	clear(b.header)
+}
+
+// Send writes the buffered response to the underlying writer. The
+// Content-Length is set to the length of the body, which may have been
+// changed, unless the body is empty and the handler set one, as for HEAD
+// requests. Only the first call has effect.
+func (b *BufferedResponse) Send() error {
+	if b.sent {
+		return nil
+	}
+	b.sent = true
+
+	status := b.StatusCode
+	if status == 0 {
+		status = StatusOK
+	}
+	h := b.w.Header()
+	for key, values := range b.header {
+		h[key] = values
+	}
+	if bodyAllowed(status) && (b.Body.Len() > 0 || h.Get("Content-Length") == "") {
+		h["Content-Length"] = []string{strconv.Itoa(b.Body.Len())}
+	}
+	b.w.WriteHeader(status)
+	if b.Body.Len() == 0 {
+		return nil
+	}
+	_, err := b.w.Write(b.Body.Bytes())
+	return err
+}
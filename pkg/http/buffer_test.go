@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+)
+
+// TestBufferedResponse verifies that middleware can change a buffered response before it is sent.
+func TestBufferedResponse(t *testing.T) {
+	inject := func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			buf := NewBufferedResponse(w)
+			next(buf, r)
+			if buf.StatusCode != StatusCreated || buf.Header().Get("Content-Type") != "text/html" {
+				t.Errorf("Expected the handler's status and headers to be captured, got %d %v", buf.StatusCode, buf.Header())
+			}
+			body := bytes.Replace(buf.Body.Bytes(), []byte("<body>"), []byte("<body><p>banner</p>"), 1)
+			buf.Body.Reset()
+			buf.Body.Write(body)
+			buf.Header().Set("X-Injected", "true")
+			buf.Send()
+		}
+	}
+	handler := inject(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", "13")
+		w.SetCookie(&Cookie{Name: "seen", Value: "1"})
+		w.WriteHeader(StatusCreated)
+		w.Write([]byte("<body>hi</body>"))
+	})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	handler(res, &Request{Method: GET, URL: &url.URL{Path: "/"}})
+
+	want := "<body><p>banner</p>hi</body>"
+	if res.status != StatusCreated || string(res.body) != want {
+		t.Errorf("Expected 201 '%s', got %d '%s'", want, res.status, res.body)
+	}
+	h := res.headers
+	if h.Get("Content-Length") != "28" || h.Get("X-Injected") != "true" || h.Get("Set-Cookie") != "seen=1" {
+		t.Errorf("Unexpected headers %v", h)
+	}
+}
+
+// TestBufferedResponseReset verifies that a discarded response is replaced and sent once.
+func TestBufferedResponseReset(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+	buf := NewBufferedResponse(res)
+	buf.Header().Set("X-Secret", "leak")
+	buf.Write([]byte("stack trace"))
+	buf.Reset()
+	Error(buf, "try again later", StatusServiceUnavailable)
+	buf.Send()
+	buf.Send()
+
+	if res.status != StatusServiceUnavailable || res.headers.Get("X-Secret") != "" {
+		t.Errorf("Expected only the replacement response, got %d %v", res.status, res.headers)
+	}
+	if want := `{"title":"Service Unavailable","status":503,"detail":"try again later"}`; string(res.body) != want {
+		t.Errorf("Expected body '%s' once, got '%s'", want, res.body)
+	}
+}
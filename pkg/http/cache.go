@@ -0,0 +1,67 @@
+package http
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy describes the caching headers sent with static files.
+type CachePolicy struct {
+	// CacheControl is the Cache-Control value sent with every file, e.g.
+	// "public, max-age=3600". Nothing is sent when it is empty.
+	CacheControl string
+	// Extensions overrides CacheControl for files with the given extensions
+	// (with their leading dot), e.g. ".html": "no-cache" or
+	// ".js": "public, max-age=31536000, immutable".
+	Extensions map[string]string
+}
+
+// SetCachePolicy sets the caching headers sent with static files.
+func (mux *ServeMux) SetCachePolicy(policy CachePolicy) {
+	mux.cachePolicy = &policy
+}
+
+// apply sets the Cache-Control header for the named file, plus an Expires
+// header for HTTP/1.0 caches when the policy has a max-age.
+func (p *CachePolicy) apply(h Header, name string) {
+	if p == nil {
+		return
+	}
+
+	cacheControl := p.CacheControl
+	if override, ok := p.Extensions[strings.ToLower(filepath.Ext(name))]; ok {
+		cacheControl = override
+	}
+	if cacheControl == "" {
+		return
+	}
+
+	h["Cache-Control"] = []string{cacheControl}
+	if maxAge, ok := parseMaxAge(cacheControl); ok {
+		h["Expires"] = []string{time.Now().Add(maxAge).UTC().Format(TimeFormat)}
+	}
+}
+
+// parseMaxAge extracts the max-age directive of a Cache-Control value.
+func parseMaxAge(cacheControl string) (time.Duration, bool) {
+	return cacheDirective(cacheControl, "max-age")
+}
+
+// cacheDirective extracts a delta-seconds directive of a Cache-Control
+// value, such as max-age or stale-while-revalidate.
+func cacheDirective(cacheControl, directive string) (time.Duration, bool) {
+	for _, d := range strings.Split(cacheControl, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(d), "=")
+		if !ok || !strings.EqualFold(name, directive) {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestCachePolicy verifies that static files get the default and per-extension Cache-Control values.
+func TestCachePolicy(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.SetFileSystem(fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>home</h1>")},
+		"app.3fa9d2.js": {Data: []byte("console.log(1)")},
+		"logo.png":      {Data: []byte("\x89PNG\r\n\x1a\n")},
+	})
+	mux.SetCachePolicy(CachePolicy{
+		CacheControl: "public, max-age=3600",
+		Extensions: map[string]string{
+			".html": "no-cache",
+			".js":   "public, max-age=31536000, immutable",
+		},
+	})
+
+	tests := []struct {
+		path         string
+		cacheControl string
+		expires      time.Duration
+	}{
+		{"/", "no-cache", 0},
+		{"/app.3fa9d2.js", "public, max-age=31536000, immutable", 365 * 24 * time.Hour},
+		{"/logo.png", "public, max-age=3600", time.Hour},
+	}
+
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: tt.path}})
+
+		if cc := res.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control '%s', got '%s'", tt.path, tt.cacheControl, cc)
+		}
+
+		expires := res.Header().Get("Expires")
+		if tt.expires == 0 {
+			if expires != "" {
+				t.Errorf("%s: expected no Expires header, got '%s'", tt.path, expires)
+			}
+			continue
+		}
+		parsed, err := time.Parse(TimeFormat, expires)
+		if err != nil {
+			t.Fatalf("%s: invalid Expires header '%s': %v", tt.path, expires, err)
+		}
+		if diff := time.Until(parsed) - tt.expires; diff > 2*time.Second || diff < -2*time.Second {
+			t.Errorf("%s: expected Expires about %v from now, got '%s'", tt.path, tt.expires, expires)
+		}
+	}
+}
+
+// TestNoCachePolicy verifies that no caching headers are sent without a policy.
+func TestNoCachePolicy(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.SetFileSystem(fstest.MapFS{"index.html": {Data: []byte("<h1>home</h1>")}})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}})
+
+	if cc := res.Header().Get("Cache-Control"); cc != "" {
+		t.Errorf("Expected no Cache-Control header, got '%s'", cc)
+	}
+}
@@ -0,0 +1,73 @@
+package http
+
+import "strings"
+
+// NamedMiddleware is middleware registered under a name, with optional
+// metadata such as its owner or the policy it enforces, so the chain
+// wrapping each route can be audited with Chain, Routes and DumpTree.
+type NamedMiddleware struct {
+	Name       string            `json:"name"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	Middleware Middleware        `json:"-"`
+}
+
+// UseNamed registers named middleware to be applied to all routes. Use
+// names middleware after its function, such as "http.RealIP.func1".
+func (mux *ServeMux) UseNamed(mw NamedMiddleware) {
+	mux.middleware = append(mux.middleware, mw)
+}
+
+// Use registers middleware applied to the route only, inside the
+// middleware of the mux. Like the mux's, middleware registered later wraps
+// the earlier ones.
+func (rt *Route) Use(mws ...Middleware) *Route {
+	for _, mw := range mws {
+		rt.UseNamed(NamedMiddleware{Name: funcName(mw), Middleware: mw})
+	}
+	return rt
+}
+
+// UseNamed registers named middleware applied to the route only, as Use
+// does.
+func (rt *Route) UseNamed(mw NamedMiddleware) *Route {
+	if rt.node.middleware == nil {
+		rt.node.middleware = make(map[string][]NamedMiddleware)
+	}
+	for _, method := range rt.methods {
+		rt.node.middleware[method] = append(rt.node.middleware[method], mw)
+	}
+	return rt
+}
+
+// Chain returns the middleware wrapping the handler of the route with the
+// pattern and method, in the order a request goes through them: the mux's
+// middleware, then the route's. It returns false when there is no such
+// route.
+func (mux *ServeMux) Chain(pattern, method string) ([]NamedMiddleware, bool) {
+	node := mux.root
+	for _, segment := range strings.Split(pattern, "/")[1:] {
+		child, ok := mux.getChild(node, segment)
+		if !ok {
+			return nil, false
+		}
+		node = child
+	}
+	if _, ok := node.handler[method]; !ok {
+		return nil, false
+	}
+	return mux.chain(node, method), true
+}
+
+// chain returns the middleware a request for the method of the route
+// ending at node goes through, outermost first.
+func (mux *ServeMux) chain(node *RouteNode, method string) []NamedMiddleware {
+	route := node.middleware[method]
+	chain := make([]NamedMiddleware, 0, len(mux.middleware)+len(route))
+	for i := len(mux.middleware) - 1; i >= 0; i-- {
+		chain = append(chain, mux.middleware[i])
+	}
+	for i := len(route) - 1; i >= 0; i-- {
+		chain = append(chain, route[i])
+	}
+	return chain
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// tagMiddleware returns middleware appending its tag to the X-Chain response header.
+func tagMiddleware(tag string) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			w.Header().Add("X-Chain", tag)
+			next(w, r)
+		}
+	}
+}
+
+// TestChain verifies that route middleware runs inside the mux's and that the reported chain matches the order requests see.
+func TestChain(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.UseNamed(NamedMiddleware{Name: "inner-global", Middleware: tagMiddleware("inner-global")})
+	mux.UseNamed(NamedMiddleware{Name: "outer-global", Middleware: tagMiddleware("outer-global"), Meta: map[string]string{"owner": "platform"}})
+	mux.AddRoute("/admin/:id", []string{GET, PUT}, func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+	}).UseNamed(NamedMiddleware{Name: "audit", Middleware: tagMiddleware("audit")}).
+		UseNamed(NamedMiddleware{Name: "auth", Middleware: tagMiddleware("auth")})
+	mux.AddRoute("/public", []string{GET}, func(w ResponseWriter, r *Request) {})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/admin/7"}})
+	ran := strings.Join(res.headers.Values("X-Chain"), ",")
+
+	chain, ok := mux.Chain("/admin/:id", GET)
+	if !ok {
+		t.Fatal("Expected the route to be found")
+	}
+	var names []string
+	for _, mw := range chain {
+		names = append(names, mw.Name)
+	}
+	if want := "outer-global,inner-global,auth,audit"; ran != want || strings.Join(names, ",") != want {
+		t.Errorf("Expected the chain %s, ran %s and reported %v", want, ran, names)
+	}
+	if chain[0].Meta["owner"] != "platform" {
+		t.Errorf("Expected the metadata to be kept, got %v", chain[0].Meta)
+	}
+
+	if chain, _ := mux.Chain("/public", GET); len(chain) != 2 {
+		t.Errorf("Expected only the mux middleware on /public, got %v", chain)
+	}
+	if _, ok := mux.Chain("/admin/:id", DELETE); ok {
+		t.Error("Expected no chain for a method without a handler")
+	}
+
+	var b strings.Builder
+	mux.DumpTree(&b)
+	if !strings.Contains(b.String(), "/:id  GET, PUT  use=auth,audit\n") {
+		t.Errorf("Expected the route middleware in the dump, got:\n%s", b.String())
+	}
+	if routes := mux.Routes(); strings.Join(routes[0].Middleware[PUT], ",") != "outer-global,inner-global,auth,audit" {
+		t.Errorf("Expected the chain in the route info, got %v", routes[0].Middleware)
+	}
+}
@@ -0,0 +1,534 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultMaxIdleConnsPerHost is how many kept-alive connections a Client
+// keeps per host when MaxIdleConnsPerHost is zero.
+const defaultMaxIdleConnsPerHost = 2
+
+// Client sends HTTP/1.1 requests and reads their responses. Connections
+// are kept alive and reused between requests to the same host. The zero
+// value is ready to use, and a Client is safe for concurrent use.
+type Client struct {
+	// Timeout limits the whole exchange, from dialing to reading the last
+	// byte of the body. Zero means no timeout; the request's context can
+	// still cancel it.
+	Timeout time.Duration
+
+	// MaxIdleConnsPerHost is how many idle connections are kept per host.
+	// Zero means 2, a negative value disables keep-alive.
+	MaxIdleConnsPerHost int
+
+	// MaxRedirects is how many redirects are followed before Do gives up
+	// with an error. Zero means 10, a negative value disables following
+	// redirects.
+	MaxRedirects int
+
+	// CheckRedirect, when set, is called before following a redirect with
+	// the upcoming request and the requests made so far, oldest first. An
+	// error stops Do: ErrUseLastResponse returns the redirect response
+	// itself, any other error is returned.
+	CheckRedirect func(req *Request, via []*Request) error
+
+	// TLSConfig configures the TLS connections of https URLs, e.g. with
+	// the RootCAs that sign the server's certificate or a client
+	// certificate. The server's certificate is verified against the host
+	// name of the URL, or ServerName when set, unless InsecureSkipVerify
+	// disables verification, which is only meant for tests. When nil, the
+	// system roots are used.
+	TLSConfig *tls.Config
+
+	// DisableCompression stops the Client from asking for compressed
+	// responses. Otherwise requests without an Accept-Encoding header are
+	// sent with "Accept-Encoding: gzip", and the gzip responses to them
+	// are decompressed, see ClientResponse.Uncompressed.
+	DisableCompression bool
+
+	// MaxDecompressedSize bounds the gzip bodies the Client decompresses,
+	// protecting against decompression bombs: past it, Do fails with
+	// ErrResponseTooLarge. Zero means 10 MB.
+	MaxDecompressedSize int64
+
+	// Retry, when set, retries requests that fail with a connection error
+	// or a 5xx status.
+	Retry *RetryPolicy
+
+	mu   sync.Mutex
+	idle map[string][]net.Conn // Idle connections by scheme://host:port
+}
+
+// DefaultClient is the Client used by Get and Post.
+var DefaultClient = &Client{}
+
+// Get sends a GET request with DefaultClient.
+func Get(rawURL string) (*ClientResponse, error) {
+	return DefaultClient.Get(rawURL)
+}
+
+// Post sends a POST request with DefaultClient.
+func Post(rawURL, contentType string, body io.Reader) (*ClientResponse, error) {
+	return DefaultClient.Post(rawURL, contentType, body)
+}
+
+// Get sends a GET request to the URL.
+func (c *Client) Get(rawURL string) (*ClientResponse, error) {
+	req, err := NewRequest(GET, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post sends a POST request to the URL with the given body.
+func (c *Client) Post(rawURL, contentType string, body io.Reader) (*ClientResponse, error) {
+	req, err := NewRequest(POST, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// PostJSON sends a POST request to the URL with v encoded as JSON.
+func (c *Client) PostJSON(rawURL string, v any) (*ClientResponse, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.Post(rawURL, "application/json", bytes.NewReader(data))
+}
+
+// NewRequest returns a request for the URL with the background context,
+// as NewRequestWithContext does.
+func NewRequest(method, rawURL string, body io.Reader) (*Request, error) {
+	return NewRequestWithContext(context.Background(), method, rawURL, body)
+}
+
+// NewRequestWithContext returns a request for the URL, to be sent with a
+// Client or passed to a handler in tests. An empty method means GET. The
+// Host header is set from the URL, and the ContentLength from bodies of
+// type *bytes.Buffer, *bytes.Reader and *strings.Reader, which Client.Do
+// can send again on retries and redirects. It is left zero for other
+// bodies, which Do buffers to find their length, unless it is set to -1 to
+// stream them chunked.
+func NewRequestWithContext(ctx context.Context, method, rawURL string, body io.Reader) (*Request, error) {
+	if ctx == nil {
+		return nil, errors.New("http: nil Context")
+	}
+	if method == "" {
+		method = GET
+	}
+	if strings.IndexFunc(method, func(r rune) bool { return r > 0x7f || !isTokenChar(byte(r)) }) >= 0 {
+		return nil, fmt.Errorf("http: invalid method %q", method)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		Method: method,
+		URL:    u,
+		Proto:  "HTTP/1.1",
+		Header: make(Header),
+		ctx:    ctx,
+	}
+	if u.Host != "" {
+		req.Header.Set("Host", u.Host)
+	}
+	if body == nil {
+		return req, nil
+	}
+
+	switch b := body.(type) {
+	case *bytes.Buffer:
+		req.ContentLength = int64(b.Len())
+	case *bytes.Reader:
+		req.ContentLength = int64(b.Len())
+	case *strings.Reader:
+		req.ContentLength = int64(b.Len())
+	default:
+		rc, ok := body.(io.ReadCloser)
+		if !ok {
+			rc = io.NopCloser(body)
+		}
+		req.Body = rc
+		return req, nil
+	}
+	// Empty bodies are sent as none
+	if req.ContentLength > 0 {
+		req.Body = io.NopCloser(body)
+		req.inMemory = true
+	}
+	return req, nil
+}
+
+// Do sends the request and returns the response with its whole body read
+// into memory, following redirects as described on CheckRedirect. The
+// request is canceled when its context is done or the Client's Timeout
+// passes.
+//
+// The body is sent with a Content-Length when req.ContentLength is
+// positive, chunked when it is -1, and buffered to find its length when it
+// is zero. Requests are retried once on a fresh connection when a reused
+// connection turns out to have been closed by the server, unless their
+// body can't be sent again.
+func (c *Client) Do(req *Request) (*ClientResponse, error) {
+	ctx := req.Context()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	// Bodies of unknown length are buffered so they can be sent with a
+	// Content-Length, and sent again on retries and redirects
+	var body []byte
+	if req.Body != nil {
+		defer req.Body.Close()
+		if req.ContentLength == 0 || req.inMemory && req.ContentLength > 0 {
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+		}
+	}
+	replayable := req.Body == nil || body != nil
+
+	var via []*Request
+	for {
+		res, err := c.sendWithRetries(ctx, req, body, replayable)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := c.nextRedirect(req, res, via, replayable)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return res, nil
+		}
+		via = append(via, req)
+		req = next
+		if next.Body == nil {
+			body, replayable = nil, true
+		}
+	}
+}
+
+// send sends a single request, retrying once when a reused connection
+// turns out to be closed.
+func (c *Client) send(ctx context.Context, req *Request, body []byte, replayable bool) (*ClientResponse, error) {
+	addr, err := hostAddr(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		conn, reused, err := c.getConn(ctx, req.URL.Scheme, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		res, keepAlive, err := c.roundTrip(ctx, conn, req, body)
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if reused && replayable && isStaleConnError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if keepAlive {
+			c.putConn(req.URL.Scheme+"://"+addr, conn)
+		} else {
+			conn.Close()
+		}
+		return res, nil
+	}
+}
+
+// roundTrip writes the request to conn and reads the response. It reports
+// whether the connection can be reused.
+func (c *Client) roundTrip(ctx context.Context, conn net.Conn, req *Request, body []byte) (*ClientResponse, bool, error) {
+	// The end of the context interrupts blocked reads and writes
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	// Compression is only asked for when the caller didn't choose the
+	// codings itself, and not for ranges, which would be of the gzip data
+	acceptGzip := !c.DisableCompression && req.Method != HEAD &&
+		req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == ""
+	if err := writeRequest(conn, req, body, acceptGzip); err != nil {
+		return nil, false, err
+	}
+
+	reader := newBufioReader(conn)
+	defer putBufioReader(reader)
+	maxSize := c.MaxDecompressedSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxDecompressedSize
+	}
+	res, err := readResponse(reader, req, acceptGzip, maxSize)
+	if err != nil {
+		return nil, false, err
+	}
+	if !stop() {
+		// The context ended while the response was read
+		return nil, false, ctx.Err()
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		res.TLS = &state
+	}
+	return res, !res.Close && !hasToken(req.Header.Get("Connection"), "close"), nil
+}
+
+// writeRequest writes the request line, headers and body to w, asking for
+// a gzip response if acceptGzip.
+func writeRequest(w io.Writer, req *Request, body []byte, acceptGzip bool) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	fmt.Fprintf(buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+
+	header := make(Header, len(req.Header)+3)
+	for key, values := range req.Header {
+		header[key] = values
+	}
+	if header.Get("Host") == "" {
+		header.Set("Host", req.URL.Host)
+	}
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", "http-lite")
+	}
+	if acceptGzip {
+		header.Set("Accept-Encoding", "gzip")
+	}
+
+	var src io.Reader
+	switch {
+	case req.Body == nil:
+		if req.Method == POST || req.Method == PUT {
+			header.Set("Content-Length", "0")
+		}
+	case body != nil:
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	case req.ContentLength > 0:
+		header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+		src = io.LimitReader(req.Body, req.ContentLength)
+	default:
+		header.Set("Transfer-Encoding", "chunked")
+		src = req.Body
+	}
+
+	header.Write(buf)
+	buf.WriteString("\r\n")
+
+	// Headers go out with small bodies in a single write
+	if src == nil {
+		_, err := (&net.Buffers{buf.Bytes(), body}).WriteTo(w)
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if req.ContentLength > 0 {
+		_, err := io.Copy(w, src)
+		return err
+	}
+	return writeChunked(w, src)
+}
+
+// writeChunked copies src to w with the chunked transfer coding.
+func writeChunked(w io.Writer, src io.Reader) error {
+	chunk := make([]byte, 32<<10)
+	for {
+		n, err := src.Read(chunk)
+		if n > 0 {
+			if _, werr := fmt.Fprintf(w, "%x\r\n%s\r\n", n, chunk[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			_, err = io.WriteString(w, "0\r\n\r\n")
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readResponse reads a response to req with its whole body, so the
+// connection is free for the next request. gzip bodies are decompressed if
+// acceptGzip, up to maxSize bytes.
+func readResponse(reader *bufio.Reader, req *Request, acceptGzip bool, maxSize int64) (*ClientResponse, error) {
+	res, err := ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	coding := strings.TrimSpace(res.Header.Get("Content-Encoding"))
+	if acceptGzip && len(body) > 0 && (strings.EqualFold(coding, "gzip") || strings.EqualFold(coding, "x-gzip")) {
+		if body, err = gunzip(body, maxSize); err != nil {
+			return nil, fmt.Errorf("failed to decompress the body: %w", err)
+		}
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Uncompressed = true
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// gunzip decompresses a gzip body, failing with ErrResponseTooLarge past
+// maxSize bytes.
+func gunzip(body []byte, maxSize int64) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, maxSize+1))
+	if err == nil && int64(len(data)) > maxSize {
+		return nil, ErrResponseTooLarge
+	}
+	return data, err
+}
+
+// hostAddr returns the host:port to dial for a URL.
+func hostAddr(u *url.URL) (string, error) {
+	var port string
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	default:
+		return "", fmt.Errorf("unsupported protocol scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", errors.New("missing host in URL")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// getConn returns an idle connection to addr, or dials a new one. It
+// reports whether the connection was reused.
+func (c *Client) getConn(ctx context.Context, scheme, addr string) (net.Conn, bool, error) {
+	key := scheme + "://" + addr
+
+	c.mu.Lock()
+	if conns := c.idle[key]; len(conns) > 0 {
+		conn := conns[len(conns)-1]
+		c.idle[key] = conns[:len(conns)-1]
+		c.mu.Unlock()
+		return conn, true, nil
+	}
+	c.mu.Unlock()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil || scheme != "https" {
+		return conn, false, err
+	}
+
+	tlsConn, err := c.handshake(ctx, conn, addr)
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	return tlsConn, false, nil
+}
+
+// handshake starts TLS over conn, verifying the certificate of the host
+// unless the TLSConfig says otherwise.
+func (c *Client) handshake(ctx context.Context, conn net.Conn, addr string) (*tls.Conn, error) {
+	var config *tls.Config
+	if c.TLSConfig != nil {
+		config = c.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// putConn keeps a connection for reuse under its scheme://host:port key,
+// or closes it when enough are idle.
+func (c *Client) putConn(key string, conn net.Conn) {
+	limit := c.MaxIdleConnsPerHost
+	if limit == 0 {
+		limit = defaultMaxIdleConnsPerHost
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.idle == nil {
+		c.idle = make(map[string][]net.Conn)
+	}
+	if len(c.idle[key]) >= limit {
+		conn.Close()
+		return
+	}
+	c.idle[key] = append(c.idle[key], conn)
+}
+
+// CloseIdleConnections closes the kept-alive connections that aren't in use.
+func (c *Client) CloseIdleConnections() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, conns := range c.idle {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	c.idle = nil
+}
+
+// isStaleConnError reports whether err shows that the server closed a
+// kept-alive connection before the request was sent.
+func isStaleConnError(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,316 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientGet verifies a round trip against the server, reusing the connection.
+func TestClientGet(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("hello " + r.URL.Query().Get("name")))
+	}))
+
+	client := &Client{}
+	for i := 0; i < 3; i++ {
+		res, err := client.Get("http://" + addr + "/greet?name=lite")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := readBody(t, res)
+		if res.StatusCode != StatusOK || got != "hello lite" {
+			t.Errorf("Unexpected response %d '%s'", res.StatusCode, got)
+		}
+		if res.Header.Get("X-Path") != "/greet" {
+			t.Errorf("Expected X-Path header, got '%s'", res.Header.Get("X-Path"))
+		}
+	}
+
+	client.mu.Lock()
+	idle := len(client.idle["http://"+addr])
+	client.mu.Unlock()
+	if idle != 1 {
+		t.Errorf("Expected the connection to be kept for reuse, got %d idle", idle)
+	}
+}
+
+// TestClientPostJSON verifies that bodies are sent with their length.
+func TestClientPostJSON(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			Error(w, err.Error(), StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(StatusCreated)
+		w.Write([]byte(payload["name"]))
+	}))
+
+	res, err := (&Client{}).PostJSON("http://"+addr+"/items", map[string]string{"name": "lamp"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := readBody(t, res)
+	if res.StatusCode != StatusCreated || got != "lamp" {
+		t.Errorf("Unexpected response %d '%s'", res.StatusCode, got)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected the JSON content type to be sent, got '%s'", ct)
+	}
+}
+
+// TestClientChunkedRequest verifies that bodies of unknown length can be streamed.
+func TestClientChunkedRequest(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(StatusOK)
+		w.Write(body)
+	}))
+
+	req, _ := NewRequest(POST, "http://"+addr+"/upload", strings.NewReader("streamed body"))
+	req.ContentLength = -1
+	res, err := (&Client{}).Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := readBody(t, res)
+	if got != "streamed body" {
+		t.Errorf("Expected the chunked body to be echoed, got '%s'", got)
+	}
+}
+
+// TestClientDecompression verifies that gzip responses are decompressed unless the caller chose the codings.
+func TestClientDecompression(t *testing.T) {
+	text := strings.Repeat("hello lite ", 200)
+	_, addr, _ := startServer(t, HandlerFunc(Compress(nil)(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(text))
+	})))
+
+	res, err := (&Client{}).Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readBody(t, res); got != text || !res.Uncompressed {
+		t.Errorf("Expected the decompressed body, got %d bytes (uncompressed %v)", len(got), res.Uncompressed)
+	}
+	if res.Header.Get("Content-Encoding") != "" || res.ContentLength != -1 {
+		t.Errorf("Expected no Content-Encoding and an unknown length, got '%s' and %d", res.Header.Get("Content-Encoding"), res.ContentLength)
+	}
+
+	res, err = (&Client{DisableCompression: true}).Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readBody(t, res); got != text || res.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Expected an uncompressed response, got %d bytes", len(got))
+	}
+
+	// Asking for gzip itself, the caller gets it as sent
+	req, _ := NewRequest(GET, "http://"+addr+"/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err = (&Client{}).Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readBody(t, res); got == text || res.Uncompressed || res.Header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected the gzip body as sent, got Content-Encoding '%s' (uncompressed %v)", res.Header.Get("Content-Encoding"), res.Uncompressed)
+	}
+}
+
+// TestClientDecompressionLimit verifies that gzip bodies decompressing past the limit fail instead of being read whole.
+func TestClientDecompressionLimit(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(StatusOK)
+		w.Write(bomb.Bytes())
+	}))
+
+	_, err := (&Client{MaxDecompressedSize: 1 << 10}).Get("http://" + addr + "/")
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+	}
+
+	res, err := (&Client{MaxDecompressedSize: 1 << 20}).Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readBody(t, res); len(got) != 1<<20 {
+		t.Errorf("Expected a body at the limit to be read, got %d bytes", len(got))
+	}
+}
+
+// TestReadResponse verifies the parsing of framed, chunked and unframed responses.
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		method    string
+		body      string
+		length    int64
+		keepAlive bool
+	}{
+		{"content length", "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello", GET, "hello", 5, true},
+		{"chunked", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n", GET, "hello", -1, true},
+		{"until close", "HTTP/1.1 200 OK\r\n\r\nhello", GET, "hello", -1, false},
+		{"connection close", "HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Length: 5\r\n\r\nhello", GET, "hello", 5, false},
+		{"head", "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n", HEAD, "", 0, true},
+		{"no content", "HTTP/1.1 204 No Content\r\n\r\n", GET, "", 0, true},
+		{"continue", "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok", POST, "ok", 2, true},
+	}
+	for _, tt := range tests {
+		res, err := ReadResponse(bufio.NewReader(strings.NewReader(tt.raw)), &Request{Method: tt.method})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got := readBody(t, res); got != tt.body || res.ContentLength != tt.length || res.Close == tt.keepAlive {
+			t.Errorf("%s: expected '%s' of length %d (keep-alive %v), got '%s' of length %d (%v)", tt.name, tt.body, tt.length, tt.keepAlive, got, res.ContentLength, !res.Close)
+		}
+	}
+
+	if _, err := ReadResponse(bufio.NewReader(strings.NewReader("garbage\r\n\r\n")), nil); err == nil {
+		t.Error("Expected an error for a malformed status line")
+	}
+}
+
+// TestReadResponseStreamed verifies that bodies are read off the connection as they are framed, so the next response follows.
+func TestReadResponseStreamed(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("HTTP/1.1 404 Not Found\r\nContent-Length: 4\r\n\r\nnopeHTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhel"))
+	res, err := ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.Status != "404 Not Found" || res.Proto != "HTTP/1.1" || readBody(t, res) != "nope" {
+		t.Errorf("Unexpected first response %s '%s'", res.Proto, res.Status)
+	}
+
+	res, err = ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(res.Body); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF for a truncated body, got %v", err)
+	}
+}
+
+// readBody returns the body of a client response.
+func readBody(t *testing.T, res *ClientResponse) string {
+	t.Helper()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the body: %v", err)
+	}
+	return string(body)
+}
+
+// TestClientTimeout verifies that the Timeout cancels a request to a silent server.
+func TestClientTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	client := &Client{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = client.Get("http://" + ln.Addr().String() + "/")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to give up quickly, took %v", elapsed)
+	}
+}
+
+// TestClientRetriesStaleConnection verifies that a kept-alive connection closed by the server is replaced.
+func TestClientRetriesStaleConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	server.IdleTimeout = 20 * time.Millisecond
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	client := &Client{}
+	for i := 0; i < 2; i++ {
+		res, err := client.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Fatalf("Request %d: unexpected error: %v", i, err)
+		}
+		got := readBody(t, res)
+		if got != "ok" {
+			t.Errorf("Request %d: unexpected body '%s'", i, got)
+		}
+		// Let the server reap the idle connection
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestClientUnsupportedScheme verifies that only http and https URLs are accepted.
+func TestClientUnsupportedScheme(t *testing.T) {
+	if _, err := (&Client{}).Get("ftp://example.com/"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+// TestNewRequest verifies that requests get their Host header and the length of in-memory bodies.
+func TestNewRequest(t *testing.T) {
+	for _, tt := range []struct {
+		body   io.Reader
+		length int64
+	}{
+		{nil, 0},
+		{strings.NewReader("hello"), 5},
+		{bytes.NewReader([]byte("hello!")), 6},
+		{bytes.NewBufferString("hi"), 2},
+		{strings.NewReader(""), 0},
+		{io.MultiReader(strings.NewReader("unknown")), 0},
+	} {
+		req, err := NewRequest("", "http://example.com:8080/items?page=2", tt.body)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if req.Method != GET || req.Header.Get("Host") != "example.com:8080" || req.URL.RequestURI() != "/items?page=2" {
+			t.Errorf("Unexpected request %s %s, Host '%s'", req.Method, req.URL, req.Header.Get("Host"))
+		}
+		if req.ContentLength != tt.length {
+			t.Errorf("Expected ContentLength %d, got %d", tt.length, req.ContentLength)
+		}
+	}
+
+	if _, err := NewRequest("BAD METHOD", "http://example.com/", nil); err == nil {
+		t.Errorf("Expected an error for an invalid method")
+	}
+	if _, err := NewRequestWithContext(nil, GET, "http://example.com/", nil); err == nil {
+		t.Errorf("Expected an error for a nil context")
+	}
+}
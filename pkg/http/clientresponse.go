@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ClientResponse is a response received by a Client, or read with
+// ReadResponse. Response is the one a server writes.
+type ClientResponse struct {
+	Status     string // e.g. "200 OK"
+	StatusCode int    // e.g. 200
+	Proto      string // e.g. "HTTP/1.1"
+	Header     Header
+
+	// Body is the body of the response. Responses without one, such as
+	// those to HEAD requests, have an empty Body. It is always non-nil,
+	// and closing it is up to the caller.
+	Body io.ReadCloser
+
+	// ContentLength is the length of the body, or -1 when it is unknown,
+	// such as for chunked bodies.
+	ContentLength int64
+
+	// Uncompressed reports whether the body was gzip compressed and
+	// decompressed by the Client, which then removes the Content-Encoding
+	// and Content-Length headers.
+	Uncompressed bool
+
+	// Close records whether the connection can't be reused after the
+	// body is read: the server asked to close it, or the body lasts until
+	// it does.
+	Close bool
+
+	// TLS holds the state of the TLS connection the response arrived on,
+	// or nil for plain connections.
+	TLS *tls.ConnectionState
+
+	// Request is the request the response answers. After redirects, it
+	// is the last one.
+	Request *Request
+}
+
+// ReadResponse reads a response to req from a buffered connection, as
+// parseRequest does for requests. Interim 1xx responses are skipped. The
+// Body reads from the reader as it is framed by the headers, so it must be
+// read to its end before the next response on the connection is. A nil
+// req stands for a GET request.
+func ReadResponse(reader *bufio.Reader, req *Request) (*ClientResponse, error) {
+	method := GET
+	if req != nil && req.Method != "" {
+		method = req.Method
+	}
+
+	for {
+		// Parse the status line (e.g., "HTTP/1.1 200 OK")
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		proto, status, _ := strings.Cut(strings.TrimSpace(string(line)), " ")
+		code, _, _ := strings.Cut(status, " ")
+		statusCode, err := strconv.Atoi(code)
+		if !strings.HasPrefix(proto, "HTTP/1.") || err != nil || len(code) != 3 {
+			return nil, fmt.Errorf("malformed status line %q", line)
+		}
+
+		header, err := readHeader(reader, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Interim responses such as 100 Continue precede the final one
+		if isInformational(statusCode) {
+			continue
+		}
+
+		body, contentLength, framed, err := responseBody(header, reader, statusCode, method)
+		if err != nil {
+			return nil, err
+		}
+		return &ClientResponse{
+			Status:        status,
+			StatusCode:    statusCode,
+			Proto:         proto,
+			Header:        header,
+			Body:          body,
+			ContentLength: contentLength,
+			Close:         !framed || proto != "HTTP/1.1" || hasToken(header.Get("Connection"), "close"),
+			Request:       req,
+		}, nil
+	}
+}
@@ -0,0 +1,342 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMinCompressSize is the smallest body compressed when
+// CompressOptions.MinSize isn't set. Smaller bodies gain little.
+const defaultMinCompressSize = 1024
+
+// defaultCompressibleTypes are the media types compressed when
+// CompressOptions.ContentTypes isn't set. Types ending in +json and +xml
+// are compressible too.
+var defaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// CompressOptions configures the Compress middleware.
+type CompressOptions struct {
+	// Level is the gzip and deflate compression level, from flate.BestSpeed
+	// to flate.BestCompression. Zero means the default level.
+	Level int
+	// MinSize is the smallest body, in bytes, worth compressing. Zero means
+	// 1024 bytes.
+	MinSize int
+	// ContentTypes are the media types to compress, where "text/*" matches
+	// any text type. Empty means text, JSON, JavaScript, XML, WebAssembly
+	// and SVG.
+	ContentTypes []string
+	// Encoders adds content codings, such as "br" with a Brotli library.
+	// They are preferred over gzip and deflate when the client accepts
+	// them as much. Codings are case-insensitive, and entries for the
+	// built-in gzip and deflate are ignored.
+	Encoders map[string]func(w io.Writer) io.WriteCloser
+}
+
+// compressor holds the settings and the pooled encoders of a Compress
+// middleware.
+type compressor struct {
+	level        int
+	minSize      int
+	contentTypes []string
+	encoders     map[string]func(io.Writer) io.WriteCloser
+	offers       []string // Codings in order of preference
+	gzipPool     sync.Pool
+	zlibPool     sync.Pool
+}
+
+// Compress returns middleware compressing response bodies with the content
+// coding the client prefers in its Accept-Encoding header, among gzip,
+// deflate and the ones of opts.Encoders. opts may be nil.
+//
+// Only bodies of a compressible content type and of at least MinSize
+// bytes are compressed, unless the handler flushes them earlier. Responses
+// that already have a Content-Encoding, partial content and responses
+// marked Cache-Control: no-transform are left alone. Compressible
+// responses get Vary: Accept-Encoding, and compressed ones lose their
+// Content-Length while their strong ETags become weak.
+func Compress(opts *CompressOptions) Middleware {
+	if opts == nil {
+		opts = &CompressOptions{}
+	}
+	c := &compressor{
+		level:        opts.Level,
+		minSize:      opts.MinSize,
+		contentTypes: opts.ContentTypes,
+		encoders:     make(map[string]func(io.Writer) io.WriteCloser),
+	}
+	if c.level == 0 {
+		c.level = flate.DefaultCompression
+	}
+	if c.minSize <= 0 {
+		c.minSize = defaultMinCompressSize
+	}
+	if len(c.contentTypes) == 0 {
+		c.contentTypes = defaultCompressibleTypes
+	}
+	for coding, encoder := range opts.Encoders {
+		coding = strings.ToLower(coding)
+		if encoder == nil || coding == "gzip" || coding == "deflate" {
+			continue
+		}
+		c.encoders[coding] = encoder
+		c.offers = append(c.offers, coding)
+	}
+	sort.Strings(c.offers)
+	c.offers = append(c.offers, "gzip", "deflate")
+
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				c:              c,
+				coding:         negotiateEncoding(r.Header.Get("Accept-Encoding"), c.offers),
+			}
+			defer cw.finish()
+			next(cw, r)
+		}
+	}
+}
+
+// negotiateEncoding returns the content coding the Accept-Encoding header
+// prefers among the offers, or "" when it accepts none of them. Offers of
+// equal quality are preferred in order, and "*" stands for the codings
+// the header doesn't name.
+func negotiateEncoding(acceptEncoding string, offers []string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "" {
+			qualities[coding] = acceptQuality(params)
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, ok := qualities[offer]
+		if !ok && offer == "gzip" {
+			q, ok = qualities["x-gzip"]
+		}
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether bodies of the content type are compressed.
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		// Events must reach the client as soon as they are flushed
+		return false
+	}
+	for _, t := range c.contentTypes {
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// newEncoder returns an encoder of the coding writing to w.
+func (c *compressor) newEncoder(coding string, w io.Writer) io.WriteCloser {
+	switch coding {
+	case "gzip":
+		if gw, ok := c.gzipPool.Get().(*gzip.Writer); ok {
+			gw.Reset(w)
+			return gw
+		}
+		gw, err := gzip.NewWriterLevel(w, c.level)
+		if err != nil {
+			gw = gzip.NewWriter(w)
+		}
+		return gw
+	case "deflate":
+		if zw, ok := c.zlibPool.Get().(*zlib.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		zw, err := zlib.NewWriterLevel(w, c.level)
+		if err != nil {
+			zw = zlib.NewWriter(w)
+		}
+		return zw
+	}
+	return c.encoders[coding](w)
+}
+
+// releaseEncoder returns a closed encoder to its pool.
+func (c *compressor) releaseEncoder(enc io.WriteCloser) {
+	switch enc := enc.(type) {
+	case *gzip.Writer:
+		c.gzipPool.Put(enc)
+	case *zlib.Writer:
+		c.zlibPool.Put(enc)
+	}
+}
+
+// compressWriter holds back the start of a response until it knows
+// whether to compress it.
+type compressWriter struct {
+	ResponseWriter
+	c       *compressor
+	coding  string // Negotiated coding, "" when the client accepts none
+	status  int
+	buf     []byte         // Body held back before the decision
+	started bool           // Whether the headers were passed on
+	enc     io.WriteCloser // Encoder of a compressed body
+}
+
+// WriteHeader records the status, which is passed on with the first body
+// bytes or when the handler returns. Informational statuses are passed on
+// at once.
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if isInformational(statusCode) {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+// Write compresses the body, or passes it on unchanged when it can't be.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = StatusOK
+	}
+	if w.started {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	if !w.eligible(b) {
+		w.start(false)
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.c.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// eligible reports whether the response may be compressed, given the
+// first bytes of its body, and sets its Vary and Content-Type headers.
+func (w *compressWriter) eligible(b []byte) bool {
+	h := w.Header()
+	if !bodyAllowed(w.status) || w.status == StatusPartialContent || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if hasToken(h.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		// Compressed bodies can't be sniffed later on
+		contentType = DetectContentType(b)
+		h["Content-Type"] = []string{contentType}
+	}
+	if !w.c.compressible(contentType) {
+		return false
+	}
+	if !hasToken(strings.Join(h.Values("Vary"), ","), "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	return w.coding != ""
+}
+
+// start passes the headers on, compressed or not, followed by the body
+// held back so far.
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	h := w.Header()
+	if compress {
+		h["Content-Encoding"] = []string{w.coding}
+		h.Del("Content-Length")
+		if etag := h.Get("Etag"); strings.HasPrefix(etag, `"`) {
+			h.Set("Etag", "W/"+etag)
+		}
+		w.enc = w.c.newEncoder(w.coding, w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// finish sends what was held back once the handler returned, uncompressed
+// as it is too small, and completes the compressed body.
+func (w *compressWriter) finish() {
+	if !w.started {
+		w.start(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.c.releaseEncoder(w.enc)
+		w.enc = nil
+	}
+}
+
+// Flush sends the body written so far, compressing it if it may be
+// compressed, regardless of its size.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		if w.status == 0 && len(w.buf) == 0 {
+			w.status = StatusOK
+		}
+		w.start(len(w.buf) > 0 && w.coding != "")
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	flush(w.ResponseWriter)
+}
+
+// Hijack forwards to the wrapped writers.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := Hijack(w.ResponseWriter)
+	if err == nil {
+		w.started = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer.
+func (w *compressWriter) Unwrap() ResponseWriter {
+	return w.ResponseWriter
+}
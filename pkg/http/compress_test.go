@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// compressRequest serves a request with the accepted encodings through Compress.
+func compressRequest(opts *CompressOptions, acceptEncoding string, handler func(ResponseWriter, *Request)) *MockResponseWriter {
+	req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{}}
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	res := &MockResponseWriter{headers: make(Header)}
+	Compress(opts)(handler)(res, req)
+	return res
+}
+
+// TestNegotiateEncoding verifies that q-values pick the coding, with ties going to the preferred one.
+func TestNegotiateEncoding(t *testing.T) {
+	offers := []string{"br", "gzip", "deflate"}
+	tests := []struct{ header, want string }{
+		{"", ""},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"deflate", "deflate"},
+		{"x-gzip", "gzip"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"gzip;q=0, identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, offers); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// TestCompress verifies that large compressible bodies are compressed with the negotiated coding.
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello lamp ", 200)
+	handler := func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Length", "2200")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body[:100]))
+		w.Write([]byte(body[100:]))
+	}
+
+	for _, coding := range []string{"gzip", "deflate"} {
+		res := compressRequest(nil, coding, handler)
+		h := res.headers
+		if h.Get("Content-Encoding") != coding || h.Get("Content-Length") != "" || h.Get("Etag") != `W/"v1"` || h.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: unexpected headers %v", coding, h)
+		}
+		var r io.Reader
+		var err error
+		if coding == "gzip" {
+			r, err = gzip.NewReader(bytes.NewReader(res.body))
+		} else {
+			r, err = zlib.NewReader(bytes.NewReader(res.body))
+		}
+		if err != nil {
+			t.Fatalf("%s: invalid body: %v", coding, err)
+		}
+		if data, _ := io.ReadAll(r); string(data) != body {
+			t.Errorf("%s: expected the original body once decompressed", coding)
+		}
+	}
+}
+
+// TestCompressSkips verifies the responses that are sent uncompressed.
+func TestCompressSkips(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+	tests := []struct {
+		name, accept, contentType, cacheControl, body string
+		status                                        int
+		vary                                          bool
+	}{
+		{"small", "gzip", "text/plain", "", "tiny", StatusOK, true},
+		{"not accepted", "", "text/plain", "", large, StatusOK, true},
+		{"image", "gzip", "image/png", "", large, StatusOK, false},
+		{"no-transform", "gzip", "text/plain", "public, no-transform", large, StatusOK, false},
+		{"partial", "gzip", "text/plain", "", large, StatusPartialContent, false},
+		{"events", "gzip", "text/event-stream", "", large, StatusOK, false},
+	}
+	for _, tt := range tests {
+		res := compressRequest(nil, tt.accept, func(w ResponseWriter, r *Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			if tt.cacheControl != "" {
+				w.Header().Set("Cache-Control", tt.cacheControl)
+			}
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		})
+		if res.status != tt.status || string(res.body) != tt.body || res.headers.Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected the body unchanged, got %d %v", tt.name, res.status, res.headers)
+		}
+		if vary := res.headers.Get("Vary") == "Accept-Encoding"; vary != tt.vary {
+			t.Errorf("%s: expected Vary to be set: %v, got %v", tt.name, tt.vary, res.headers)
+		}
+	}
+}
+
+// TestCompressEncoders verifies that added encoders are negotiated before the built-in ones.
+func TestCompressEncoders(t *testing.T) {
+	opts := &CompressOptions{
+		MinSize: 1,
+		Encoders: map[string]func(io.Writer) io.WriteCloser{
+			"upper": func(w io.Writer) io.WriteCloser { return &upperWriter{w} },
+		},
+	}
+	res := compressRequest(opts, "gzip, upper", func(w ResponseWriter, r *Request) {
+		w.Write([]byte("<p>lamp</p>"))
+	})
+	if res.headers.Get("Content-Encoding") != "upper" || string(res.body) != "<P>LAMP</P>" {
+		t.Errorf("Expected the added encoder, got %v '%s'", res.headers, res.body)
+	}
+	if ct := res.headers.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected the content type to be sniffed before compressing, got '%s'", ct)
+	}
+
+	opts.Encoders = map[string]func(io.Writer) io.WriteCloser{
+		"UPPER": func(w io.Writer) io.WriteCloser { return &upperWriter{w} },
+		"GZIP":  func(w io.Writer) io.WriteCloser { return &upperWriter{w} },
+	}
+	res = compressRequest(opts, "upper", func(w ResponseWriter, r *Request) {
+		w.Write([]byte("<p>lamp</p>"))
+	})
+	if res.headers.Get("Content-Encoding") != "upper" || string(res.body) != "<P>LAMP</P>" {
+		t.Errorf("Expected the coding of the encoder to be case-insensitive, got %v '%s'", res.headers, res.body)
+	}
+	res = compressRequest(opts, "gzip", func(w ResponseWriter, r *Request) {
+		w.Write([]byte("<p>lamp</p>"))
+	})
+	if res.headers.Get("Content-Encoding") != "gzip" || bytes.Contains(res.body, []byte("LAMP")) {
+		t.Errorf("Expected the built-in gzip encoder, got %v '%s'", res.headers, res.body)
+	}
+}
+
+// upperWriter is a toy content coding upper-casing the body.
+type upperWriter struct{ w io.Writer }
+
+func (u *upperWriter) Write(b []byte) (int, error) { return u.w.Write(bytes.ToUpper(b)) }
+func (u *upperWriter) Close() error                { return nil }
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the conditional headers of a request for
+// the current representation of a resource, identified by its entity tag,
+// such as `"v2"` or `W/"v2"`, and its modification time, either of which
+// may be unset. An empty etag means the resource has no representation, so
+// "If-Match: *" fails and "If-None-Match: *" succeeds.
+//
+// The headers are evaluated in the order of RFC 9110, 13.2.2: If-Match,
+// If-Unmodified-Since, If-None-Match, then If-Modified-Since. When a
+// precondition fails, 412 Precondition Failed, or 304 Not Modified for
+// GET and HEAD requests, is written and CheckPreconditions reports true;
+// the handler must then return without writing a body:
+//
+//	if http.CheckPreconditions(w, r, item.ETag, item.Updated) {
+//		return
+//	}
+//
+// Otherwise the ETag and Last-Modified headers are set for the response.
+func CheckPreconditions(w ResponseWriter, r *Request, etag string, lastModified time.Time) bool {
+	return checkPreconditions(w, r, etag, lastModified, etag != "")
+}
+
+// checkPreconditions is CheckPreconditions for a resource whose existence,
+// which "*" matches, is known apart from its entity tag.
+func checkPreconditions(w ResponseWriter, r *Request, etag string, lastModified time.Time, exists bool) bool {
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	if !lastModified.IsZero() {
+		// HTTP dates have a one second resolution
+		lastModified = lastModified.Truncate(time.Second)
+	}
+
+	h := w.Header()
+	if etag != "" {
+		h["Etag"] = []string{etag}
+	}
+	if !lastModified.IsZero() {
+		h["Last-Modified"] = []string{lastModified.UTC().Format(TimeFormat)}
+	}
+
+	safe := r.Method == GET || r.Method == HEAD
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, exists, false) {
+			w.WriteHeader(StatusPreconditionFailed)
+			return true
+		}
+	} else if since, ok := parseHTTPTime(r.Header.Get("If-Unmodified-Since")); ok && !lastModified.IsZero() && lastModified.After(since) {
+		w.WriteHeader(StatusPreconditionFailed)
+		return true
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !matchETag(ifNoneMatch, etag, exists, true) {
+			return false
+		}
+		if !safe {
+			w.WriteHeader(StatusPreconditionFailed)
+			return true
+		}
+		writeNotModified(w)
+		return true
+	}
+
+	if since, ok := parseHTTPTime(r.Header.Get("If-Modified-Since")); ok && safe && !lastModified.IsZero() && !lastModified.After(since) {
+		writeNotModified(w)
+		return true
+	}
+	return false
+}
+
+// writeNotModified answers 304 Not Modified, without the headers that
+// describe a body.
+func writeNotModified(w ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(StatusNotModified)
+}
+
+// matchETag reports whether the list of entity tags of an If-Match or
+// If-None-Match header matches etag, "*" matching when the resource exists.
+// If-Match compares tags strongly, weak tags never matching, and
+// If-None-Match weakly (RFC 9110, 8.8.3.2).
+func matchETag(list, etag string, exists, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return exists
+	}
+	if etag == "" || !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+		tag := list
+		isWeak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if !strings.HasPrefix(tag, `"`) {
+			// Malformed, skip to the next tag
+			_, list, _ = strings.Cut(list, ",")
+			continue
+		}
+		end := strings.IndexByte(tag[1:], '"')
+		if end < 0 {
+			return false
+		}
+		tag, list = tag[:end+2], tag[end+2:]
+		if tag == opaque && (weak || !isWeak) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestCheckPreconditions verifies the evaluation of conditional headers and their order.
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 10, 4, 12, 0, 0, 0, time.UTC)
+	before := "Thu, 03 Oct 2024 12:00:00 GMT"
+	after := "Sat, 05 Oct 2024 12:00:00 GMT"
+
+	tests := []struct {
+		name, method, etag string
+		header             Header
+		status             int
+	}{
+		{"no conditions", GET, `"v2"`, Header{}, 0},
+		{"if-match", PUT, `"v2"`, Header{"If-Match": {`"v1", "v2"`}}, 0},
+		{"if-match stale", PUT, `"v2"`, Header{"If-Match": {`"v1"`}}, StatusPreconditionFailed},
+		{"if-match weak", PUT, `W/"v2"`, Header{"If-Match": {`W/"v2"`}}, StatusPreconditionFailed},
+		{"if-match any", PUT, `"v2"`, Header{"If-Match": {"*"}}, 0},
+		{"if-match missing", PUT, "", Header{"If-Match": {"*"}}, StatusPreconditionFailed},
+		{"if-match over if-unmodified-since", PUT, `"v2"`, Header{"If-Match": {`"v2"`}, "If-Unmodified-Since": {before}}, 0},
+		{"if-unmodified-since", PUT, `"v2"`, Header{"If-Unmodified-Since": {before}}, StatusPreconditionFailed},
+		{"if-none-match", GET, `"v2"`, Header{"If-None-Match": {`W/"v2"`}}, StatusNotModified},
+		{"if-none-match changed", GET, `"v2"`, Header{"If-None-Match": {`"v1"`}}, 0},
+		{"if-none-match unsafe", POST, "v2", Header{"If-None-Match": {`"v2"`}}, StatusPreconditionFailed},
+		{"if-none-match create", PUT, "", Header{"If-None-Match": {"*"}}, 0},
+		{"if-none-match over if-modified-since", GET, `"v2"`, Header{"If-None-Match": {`"v1"`}, "If-Modified-Since": {after}}, 0},
+		{"if-modified-since", GET, `"v2"`, Header{"If-Modified-Since": {after}}, StatusNotModified},
+		{"if-modified-since changed", GET, `"v2"`, Header{"If-Modified-Since": {before}}, 0},
+		{"if-modified-since unsafe", POST, `"v2"`, Header{"If-Modified-Since": {after}}, 0},
+	}
+	for _, tt := range tests {
+		req := &Request{Method: tt.method, URL: &url.URL{Path: "/items/1"}, Header: tt.header}
+		res := &MockResponseWriter{headers: make(Header)}
+		done := CheckPreconditions(res, req, tt.etag, modified)
+
+		if done != (tt.status != 0) || res.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d (short-circuit %v)", tt.name, tt.status, res.status, done)
+		}
+		if tt.etag == "v2" || tt.etag == `"v2"` {
+			if got := res.headers.Get("Etag"); got != `"v2"` {
+				t.Errorf("%s: expected the ETag header to be set, got '%s'", tt.name, got)
+			}
+		}
+		if got := res.headers.Get("Last-Modified"); got != "Fri, 04 Oct 2024 12:00:00 GMT" {
+			t.Errorf("%s: expected the Last-Modified header to be set, got '%s'", tt.name, got)
+		}
+	}
+}
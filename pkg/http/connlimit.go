@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net"
+	"time"
+)
+
+// rejectWriteTimeout bounds how long writing a rejection may block the
+// accept loop.
+const rejectWriteTimeout = 100 * time.Millisecond
+
+// serviceUnavailableResponse is sent to connections over the limit. It is
+// written directly, the handler never sees the request.
+var serviceUnavailableResponse = []byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n")
+
+// acquireSlot reserves room for a new connection, waiting up to
+// ConnectionQueueTimeout when the server is at its limit. It reports false
+// when no slot became available.
+func (s *Server) acquireSlot() bool {
+	if s.MaxConcurrentConnections <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	if s.slots == nil {
+		s.slots = make(chan struct{}, s.MaxConcurrentConnections)
+	}
+	slots := s.slots
+	s.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if s.ConnectionQueueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(s.ConnectionQueueTimeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// releaseSlot frees the slot taken by a finished connection.
+func (s *Server) releaseSlot() {
+	if s.MaxConcurrentConnections <= 0 {
+		return
+	}
+	<-s.slots
+}
+
+// rejectConn answers a connection over the limit with 503 and closes it.
+func (s *Server) rejectConn(conn net.Conn) {
+	s.rejected.Add(1)
+	conn.SetWriteDeadline(time.Now().Add(rejectWriteTimeout))
+	conn.Write(serviceUnavailableResponse)
+	conn.Close()
+}
+
+// RejectedConnections returns how many connections were turned away because
+// MaxConcurrentConnections was reached.
+func (s *Server) RejectedConnections() uint64 {
+	return s.rejected.Load()
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// holdConnection dials addr and sends a request that the handler will hold open.
+func holdConnection(t *testing.T, addr string) net.Conn {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	return conn
+}
+
+// TestMaxConcurrentConnections verifies that connections over the limit are rejected with 503.
+func TestMaxConcurrentConnections(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(StatusOK)
+	}))
+	server.MaxConcurrentConnections = 1
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	first := holdConnection(t, ln.Addr().String())
+	defer first.Close()
+	<-started
+
+	second := holdConnection(t, ln.Addr().String())
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	response, _ := io.ReadAll(second)
+	if !strings.HasPrefix(string(response), "HTTP/1.1 503 Service Unavailable") {
+		t.Errorf("Expected 503 for the connection over the limit, got '%s'", string(response))
+	}
+	if n := server.RejectedConnections(); n != 1 {
+		t.Errorf("Expected 1 rejected connection, got %d", n)
+	}
+
+	close(release)
+}
+
+// TestConnectionQueueTimeout verifies that connections over the limit wait for a free slot.
+func TestConnectionQueueTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(StatusOK)
+	}))
+	server.MaxConcurrentConnections = 1
+	server.ConnectionQueueTimeout = time.Second
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	first := holdConnection(t, ln.Addr().String())
+	defer first.Close()
+	second := holdConnection(t, ln.Addr().String())
+	defer second.Close()
+
+	for _, conn := range []net.Conn{first, second} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		response, _ := io.ReadAll(conn)
+		if !strings.HasPrefix(string(response), "HTTP/1.1 200 OK") {
+			t.Errorf("Expected queued connection to be served, got '%s'", string(response))
+		}
+	}
+	if n := server.RejectedConnections(); n != 0 {
+		t.Errorf("Expected no rejected connections, got %d", n)
+	}
+}
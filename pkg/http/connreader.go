@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// connReader reads the requests of a server connection. While a handler
+// runs and the request body is read, nothing else would read the
+// connection, so it is watched in the background instead: the request
+// context is canceled once the client closes the connection.
+type connReader struct {
+	conn net.Conn
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	inRead  bool // Whether a background read is running
+	aborted bool // Whether the background read is being stopped
+	hasByte bool // Whether byteBuf holds a byte read in the background
+	byteBuf [1]byte
+	err     error // Error of the background read, returned by the next Read
+}
+
+// newConnReader returns a connReader reading conn.
+func newConnReader(conn net.Conn) *connReader {
+	cr := &connReader{conn: conn}
+	cr.cond = sync.NewCond(&cr.mu)
+	return cr
+}
+
+// Read reads from the connection, first returning what the background
+// read got. It waits for a running background read.
+func (cr *connReader) Read(p []byte) (int, error) {
+	cr.mu.Lock()
+	for cr.inRead {
+		cr.cond.Wait()
+	}
+	if cr.hasByte && len(p) > 0 {
+		p[0] = cr.byteBuf[0]
+		cr.hasByte = false
+		cr.mu.Unlock()
+		return 1, nil
+	}
+	if err := cr.err; err != nil {
+		cr.mu.Unlock()
+		return 0, err
+	}
+	cr.mu.Unlock()
+	return cr.conn.Read(p)
+}
+
+// startBackgroundRead watches the connection until stopBackgroundRead,
+// calling cancel when the client closes it or the read fails. A byte read
+// meanwhile, the start of a pipelined request, is kept for Read.
+func (cr *connReader) startBackgroundRead(cancel context.CancelFunc) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.inRead || cr.hasByte || cr.err != nil {
+		return
+	}
+	cr.inRead = true
+	go cr.backgroundRead(cancel)
+}
+
+// backgroundRead reads a byte off the connection.
+func (cr *connReader) backgroundRead(cancel context.CancelFunc) {
+	n, err := cr.conn.Read(cr.byteBuf[:])
+
+	cr.mu.Lock()
+	if n == 1 {
+		cr.hasByte = true
+	}
+	if err != nil && !(cr.aborted && errors.Is(err, os.ErrDeadlineExceeded)) {
+		cr.err = err
+		cancel()
+	}
+	cr.inRead = false
+	cr.aborted = false
+	cr.mu.Unlock()
+	cr.cond.Broadcast()
+}
+
+// stopBackgroundRead interrupts the background read and waits for it to
+// return, so the connection can be read, or handed over by Hijack.
+func (cr *connReader) stopBackgroundRead() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if !cr.inRead {
+		return
+	}
+	cr.aborted = true
+	cr.conn.SetReadDeadline(time.Unix(1, 0))
+	for cr.inRead {
+		cr.cond.Wait()
+	}
+	cr.conn.SetReadDeadline(time.Time{})
+}
@@ -0,0 +1,47 @@
+package http
+
+import "net"
+
+// ConnState represents the state of a client connection, as reported to
+// Server.ConnState.
+type ConnState int
+
+const (
+	// StateNew is a connection that has just been accepted.
+	StateNew ConnState = iota
+	// StateActive is a connection whose request has been read and is being
+	// handled.
+	StateActive
+	// StateIdle is a connection waiting for its next request between
+	// requests on a kept-alive connection.
+	StateIdle
+	// StateHijacked is a connection taken over by a handler through
+	// Hijacker. It is terminal, the server no longer tracks it.
+	StateHijacked
+	// StateClosed is a closed connection. It is terminal.
+	StateClosed
+)
+
+var connStateNames = map[ConnState]string{
+	StateNew:      "new",
+	StateActive:   "active",
+	StateIdle:     "idle",
+	StateHijacked: "hijacked",
+	StateClosed:   "closed",
+}
+
+// String returns the name of the state.
+func (c ConnState) String() string {
+	return connStateNames[c]
+}
+
+// setState records a connection state change and reports it to the
+// ConnState hook.
+func (s *Server) setState(conn net.Conn, state ConnState) {
+	if state == StateActive || state == StateIdle {
+		s.markIdle(conn, state == StateIdle)
+	}
+	if s.ConnState != nil {
+		s.ConnState(conn, state)
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// connStateRecorder collects the states reported to Server.ConnState.
+type connStateRecorder struct {
+	mu     sync.Mutex
+	states []ConnState
+	done   chan struct{}
+}
+
+func (rec *connStateRecorder) record(conn net.Conn, state ConnState) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.states = append(rec.states, state)
+	if state == StateClosed || state == StateHijacked {
+		close(rec.done)
+	}
+}
+
+// serveWithStates serves a single request with handler and returns the reported states and the raw response.
+func serveWithStates(t *testing.T, handler HandlerFunc) ([]ConnState, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	rec := &connStateRecorder{done: make(chan struct{})}
+	server := NewServer(ln.Addr().String(), handler)
+	server.ConnState = rec.record
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	response, _ := io.ReadAll(conn)
+
+	select {
+	case <-rec.done:
+	case <-time.After(time.Second):
+		t.Fatal("Connection never reached a terminal state")
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.states, string(response)
+}
+
+// TestConnState verifies the lifecycle reported for a regular request.
+func TestConnState(t *testing.T) {
+	states, _ := serveWithStates(t, func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+	})
+
+	expected := []ConnState{StateNew, StateActive, StateClosed}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected states %v, got %v", expected, states)
+	}
+}
+
+// TestHijack verifies that a hijacked connection is left to the handler.
+func TestHijack(t *testing.T) {
+	states, response := serveWithStates(t, func(w ResponseWriter, r *Request) {
+		conn, rw, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		if _, err := w.Write([]byte("late")); err != ErrHijacked {
+			t.Errorf("Expected ErrHijacked, got %v", err)
+		}
+
+		go func() {
+			defer conn.Close()
+			rw.WriteString("raw protocol")
+			rw.Flush()
+		}()
+	})
+
+	if response != "raw protocol" {
+		t.Errorf("Expected the hijacked connection's data, got '%s'", response)
+	}
+	expected := []ConnState{StateNew, StateActive, StateHijacked}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected states %v, got %v", expected, states)
+	}
+}
+
+// TestConnStateString verifies the names of the connection states.
+func TestConnStateString(t *testing.T) {
+	if StateIdle.String() != "idle" || StateHijacked.String() != "hijacked" {
+		t.Errorf("Unexpected state names %q and %q", StateIdle, StateHijacked)
+	}
+}
@@ -1,20 +1,53 @@
 package http
 
 import (
+	"fmt"
+	"log"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Cookie represents an HTTP cookie.
 type Cookie struct {
-	Name     string
-	Value    string
-	Path     string
-	Domain   string
-	Expires  time.Time
+	Name    string
+	Value   string
+	Path    string
+	Domain  string
+	Expires time.Time
+	// MaxAge is how many seconds the cookie lasts. Zero means unset, and a
+	// negative value deletes the cookie now, sent as Max-Age=0.
 	MaxAge   int
 	Secure   bool
 	HttpOnly bool
+	// SameSite restricts sending the cookie with cross-site requests. Zero
+	// leaves it to the browser.
+	SameSite SameSite
+}
+
+// SameSite is the SameSite attribute of a cookie.
+type SameSite int
+
+const (
+	SameSiteLax SameSite = iota + 1
+	SameSiteStrict
+	SameSiteNone // Requires Secure
+)
+
+// sameSiteNames are the attribute values of the SameSite modes.
+var sameSiteNames = map[SameSite]string{
+	SameSiteLax:    "Lax",
+	SameSiteStrict: "Strict",
+	SameSiteNone:   "None",
+}
+
+// cookieDateLayouts are the layouts of the Expires attribute parsed, the
+// preferred one first and then the obsolete ones still in use.
+var cookieDateLayouts = []string{
+	time.RFC1123,
+	"Mon, 02-Jan-2006 15:04:05 MST",
+	time.RFC850,
+	time.ANSIC,
 }
 
 // String returns a string representation of the cookie.
@@ -26,11 +59,16 @@ func (c *Cookie) String() string {
 	if c.Domain != "" {
 		cookieStr += "; Domain=" + c.Domain
 	}
-	if !c.Expires.IsZero() {
-		cookieStr += "; Expires=" + c.Expires.Format(time.RFC1123)
-	}
-	if c.MaxAge > 0 {
-		cookieStr += "; Max-Age=" + strconv.Itoa(c.MaxAge)
+	if c.MaxAge < 0 {
+		// Expires is for the clients predating Max-Age
+		cookieStr += "; Expires=" + time.Unix(0, 0).UTC().Format(time.RFC1123) + "; Max-Age=0"
+	} else {
+		if !c.Expires.IsZero() {
+			cookieStr += "; Expires=" + c.Expires.Format(time.RFC1123)
+		}
+		if c.MaxAge > 0 {
+			cookieStr += "; Max-Age=" + strconv.Itoa(c.MaxAge)
+		}
 	}
 	if c.Secure {
 		cookieStr += "; Secure"
@@ -38,5 +76,133 @@ func (c *Cookie) String() string {
 	if c.HttpOnly {
 		cookieStr += "; HttpOnly"
 	}
+	if name, ok := sameSiteNames[c.SameSite]; ok {
+		cookieStr += "; SameSite=" + name
+	}
 	return cookieStr
 }
+
+// StrictCookies makes the SetCookie method of response writers panic with
+// the error of invalid cookies, to catch them during development. By
+// default they are logged and dropped, as browsers would reject them
+// anyway.
+var StrictCookies = false
+
+// Valid reports why the cookie would be rejected by browsers, or nil. Its
+// name must be a token, and its attributes can't contain semicolons or
+// control characters. Cookies named with the __Secure- prefix must be
+// Secure, and the ones with the __Host- prefix must also have the "/" Path
+// and no Domain, which locks them to the host that set them.
+func (c *Cookie) Valid() error {
+	if c.Name == "" || strings.IndexFunc(c.Name, func(r rune) bool { return r > 0x7f || !isTokenChar(byte(r)) }) >= 0 {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidCookie, c.Name)
+	}
+	for _, attr := range []string{c.Value, c.Path, c.Domain} {
+		if strings.IndexFunc(attr, func(r rune) bool { return r == ';' || isCTL(r) }) >= 0 {
+			return fmt.Errorf("%w: invalid attribute %q of %s", ErrInvalidCookie, attr, c.Name)
+		}
+	}
+
+	// Browsers match prefixes case-insensitively
+	name := strings.ToLower(c.Name)
+	switch {
+	case strings.HasPrefix(name, "__secure-") && !c.Secure:
+		return fmt.Errorf("%w: %s must be Secure", ErrInvalidCookie, c.Name)
+	case strings.HasPrefix(name, "__host-") && (!c.Secure || c.Path != "/" || c.Domain != ""):
+		return fmt.Errorf("%w: %s must be Secure, with Path=/ and no Domain", ErrInvalidCookie, c.Name)
+	case c.SameSite == SameSiteNone && !c.Secure:
+		return fmt.Errorf("%w: %s must be Secure with SameSite=None", ErrInvalidCookie, c.Name)
+	}
+	return nil
+}
+
+// SetCookie adds the cookie to the response, or returns the error of
+// Valid when it is invalid.
+func SetCookie(w ResponseWriter, c *Cookie) error {
+	if err := c.Valid(); err != nil {
+		return err
+	}
+	w.SetCookie(c)
+	return nil
+}
+
+// headerCookies returns the cookies of the Set-Cookie headers in h,
+// skipping the ones that can't be parsed.
+func headerCookies(h Header) []*Cookie {
+	var cookies []*Cookie
+	for _, line := range h.Values("Set-Cookie") {
+		if c, err := ParseSetCookie(line); err == nil {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+// ParseSetCookie parses the value of a Set-Cookie header, such as one
+// received by a client or recorded in a test, so that it round-trips
+// through String. Attributes with invalid values are ignored, as browsers
+// do, and a Max-Age of zero is parsed as -1, deleting the cookie. It
+// returns ErrInvalidCookie when the cookie has no valid name.
+func ParseSetCookie(header string) (*Cookie, error) {
+	parts := strings.Split(header, ";")
+	name, value, ok := strings.Cut(strings.TrimSpace(parts[0]), "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.IndexFunc(name, func(r rune) bool { return r > 0x7f || !isTokenChar(byte(r)) }) >= 0 {
+		return nil, fmt.Errorf("%w: invalid name in %q", ErrInvalidCookie, header)
+	}
+
+	c := &Cookie{Name: name, Value: strings.TrimSpace(value)}
+	for _, attr := range parts[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		val = strings.TrimSpace(val)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "path":
+			c.Path = val
+		case "domain":
+			c.Domain = val
+		case "expires":
+			for _, layout := range cookieDateLayouts {
+				if t, err := time.Parse(layout, val); err == nil {
+					c.Expires = t.UTC()
+					break
+				}
+			}
+		case "max-age":
+			// Zero and negative values both delete the cookie
+			if n, err := strconv.Atoi(val); err == nil && n <= 0 {
+				c.MaxAge = -1
+			} else if err == nil {
+				c.MaxAge = n
+			}
+		case "secure":
+			c.Secure = true
+		case "httponly":
+			c.HttpOnly = true
+		case "samesite":
+			for mode, modeName := range sameSiteNames {
+				if strings.EqualFold(val, modeName) {
+					c.SameSite = mode
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// addCookie adds the Set-Cookie header of a valid cookie to h, for the
+// SetCookie method of the response writer w. Invalid cookies are dropped
+// and logged through the server of w, or panic with StrictCookies.
+func addCookie(w ResponseWriter, h Header, c *Cookie) {
+	if err := c.Valid(); err != nil {
+		if StrictCookies {
+			panic(err)
+		}
+		if res := serverResponse(w); res != nil {
+			res.warnf("%v; dropping the cookie", err)
+		} else {
+			log.Printf("%v; dropping the cookie", err)
+		}
+		return
+	}
+	h.Add("Set-Cookie", c.String())
+}
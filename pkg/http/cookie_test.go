@@ -1,7 +1,10 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -55,7 +58,7 @@ func TestGetCookie(t *testing.T) {
 	}
 
 	// Simulate that the client saves the cookie and sends it in the next request
-	req.Cookies = append(req.Cookies, Cookie{Name: "session_id", Value: "abc123"})
+	req.AddCookie(&Cookie{Name: "session_id", Value: "abc123"})
 
 	// Retrieve the cookie from the Request object
 	cookieValue, err := req.GetCookie("session_id")
@@ -194,6 +197,17 @@ func TestCookieStringSomeOptionalFields(t *testing.T) {
 			},
 			expected: "test=123; Expires=Fri, 04 Oct 2024 00:00:00 UTC; Max-Age=3600",
 		},
+		{
+			name: "Cookie with negative MaxAge",
+			cookie: Cookie{
+				Name:    "test",
+				Value:   "",
+				Path:    "/",
+				MaxAge:  -1,
+				Expires: time.Date(2024, 10, 4, 0, 0, 0, 0, time.UTC),
+			},
+			expected: "test=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,3 +219,227 @@ func TestCookieStringSomeOptionalFields(t *testing.T) {
 		})
 	}
 }
+
+// TestCookiePrefixes verifies that cookies breaking the rules of the
+// __Secure- and __Host- prefixes are dropped by SetCookie.
+func TestCookiePrefixes(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie Cookie
+		valid  bool
+	}{
+		{"Secure prefix", Cookie{Name: "__Secure-id", Value: "1", Secure: true}, true},
+		{"Secure prefix without Secure", Cookie{Name: "__Secure-id", Value: "1"}, false},
+		{"Host prefix", Cookie{Name: "__Host-id", Value: "1", Path: "/", Secure: true}, true},
+		{"Host prefix without Secure", Cookie{Name: "__Host-id", Value: "1", Path: "/"}, false},
+		{"Host prefix with Domain", Cookie{Name: "__Host-id", Value: "1", Path: "/", Domain: "example.com", Secure: true}, false},
+		{"Host prefix with other Path", Cookie{Name: "__Host-id", Value: "1", Path: "/app", Secure: true}, false},
+		{"Host prefix in lower case", Cookie{Name: "__host-id", Value: "1", Secure: true}, false},
+		{"Invalid name", Cookie{Name: "a b", Value: "1"}, false},
+		{"Semicolon in Path", Cookie{Name: "id", Value: "1", Path: "/; Domain=evil.com"}, false},
+		{"SameSite None without Secure", Cookie{Name: "id", Value: "1", SameSite: SameSiteNone}, false},
+		{"SameSite None", Cookie{Name: "id", Value: "1", Secure: true, SameSite: SameSiteNone}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &MockResponseWriter{headers: make(Header)}
+			w.SetCookie(&tt.cookie)
+			if set := w.headers.Get("Set-Cookie") != ""; set != tt.valid {
+				t.Errorf("Expected cookie set to be %v, got %v", tt.valid, set)
+			}
+			if err := tt.cookie.Valid(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+// TestSetCookieFunc verifies that the SetCookie function returns the error
+// of invalid cookies instead of setting them.
+func TestSetCookieFunc(t *testing.T) {
+	w := &MockResponseWriter{headers: make(Header)}
+	err := SetCookie(w, &Cookie{Name: "__Host-id", Value: "1", Secure: true})
+	if !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("Expected ErrInvalidCookie, got %v", err)
+	}
+	if len(w.headers.Values("Set-Cookie")) != 0 {
+		t.Errorf("Expected no Set-Cookie header, got %v", w.headers.Values("Set-Cookie"))
+	}
+
+	if err := SetCookie(w, &Cookie{Name: "__Host-id", Value: "1", Path: "/", Secure: true}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if got := w.headers.Get("Set-Cookie"); got != "__Host-id=1; Path=/; Secure" {
+		t.Errorf("Expected '__Host-id=1; Path=/; Secure', got '%s'", got)
+	}
+}
+
+// TestInvalidCookieLogged verifies that dropped cookies are logged through the server's logger, also behind wrapping writers.
+func TestInvalidCookieLogged(t *testing.T) {
+	res := NewResponseWriter(&MockConn{}).(*Response)
+	var warnings []string
+	res.logf = func(format string, v ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, v...))
+	}
+
+	res.SetCookie(&Cookie{Name: "session", Value: "a;b"})
+	NewInstrumentedWriter(res).SetCookie(&Cookie{Name: "__Host-id", Value: "1"})
+	NewBufferedResponse(res).SetCookie(&Cookie{Name: "__Secure-id", Value: "1"})
+
+	if got := res.Header().Values("Set-Cookie"); len(got) != 0 {
+		t.Errorf("Expected the invalid cookies to be dropped, got %v", got)
+	}
+	if len(warnings) != 3 || !strings.Contains(warnings[0], "session") || !strings.Contains(warnings[1], "__Host-id must be Secure") || !strings.Contains(warnings[2], "__Secure-id") {
+		t.Errorf("Expected a warning per dropped cookie, got %v", warnings)
+	}
+}
+
+// TestStrictCookies verifies that invalid cookies panic with StrictCookies.
+func TestStrictCookies(t *testing.T) {
+	StrictCookies = true
+	defer func() {
+		StrictCookies = false
+		if p := recover(); p == nil {
+			t.Errorf("Expected a panic")
+		} else if err, ok := p.(error); !ok || !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Expected ErrInvalidCookie, got %v", p)
+		}
+	}()
+	w := &MockResponseWriter{headers: make(Header)}
+	w.SetCookie(&Cookie{Name: "__Secure-id", Value: "1"})
+}
+
+// TestDeleteCookieExpires verifies that DeleteCookie sends a cookie
+// expiring it immediately.
+func TestDeleteCookieExpires(t *testing.T) {
+	w := &MockResponseWriter{headers: make(Header)}
+	w.DeleteCookie("session_id")
+	expected := "session_id=; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0"
+	if got := w.headers.Get("Set-Cookie"); got != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, got)
+	}
+}
+
+// TestRequestCookies verifies that the cookies of requests built without
+// the server are parsed from their Cookie headers.
+func TestRequestCookies(t *testing.T) {
+	req := &Request{
+		Method: GET,
+		URL:    &url.URL{Path: "/"},
+		Header: Header{"Cookie": {"a=1; b=2", "c=3"}},
+	}
+	req.AddCookie(&Cookie{Name: "d", Value: "4"})
+
+	cookies := req.Cookies()
+	var pairs []string
+	for _, c := range cookies {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	if got := strings.Join(pairs, " "); got != "a=1 b=2 c=3 d=4" {
+		t.Errorf("Expected cookies a, b, c and d, got '%s'", got)
+	}
+	if c, err := req.GetCookie("c"); err != nil || c.Value != "3" {
+		t.Errorf("Expected c=3, got %v, %v", c, err)
+	}
+}
+
+// TestResponseCookies verifies that middleware and handlers can each set
+// cookies on a response, which Cookies returns in order.
+func TestResponseCookies(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.Use(func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			w.SetCookie(&Cookie{Name: "csrf_token", Value: "xyz", Path: "/", Secure: true})
+			next(w, r)
+		}
+	})
+	mux.AddRoute("/login", []string{POST}, func(w ResponseWriter, r *Request) {
+		w.SetCookie(&Cookie{Name: "session_id", Value: "abc123", Path: "/", MaxAge: 3600, HttpOnly: true})
+		w.DeleteCookie("old")
+		if n := len(w.Cookies()); n != 3 {
+			t.Errorf("Expected 3 cookies queued, got %d", n)
+		}
+		w.WriteHeader(StatusOK)
+	})
+
+	w := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(w, &Request{Method: POST, URL: &url.URL{Path: "/login"}, Header: make(Header)})
+
+	cookies := w.Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("Expected 3 cookies, got %d", len(cookies))
+	}
+	if c := cookies[0]; c.Name != "csrf_token" || c.Value != "xyz" || c.Path != "/" || !c.Secure {
+		t.Errorf("Expected the csrf_token cookie, got %+v", c)
+	}
+	if c := cookies[1]; c.Name != "session_id" || c.MaxAge != 3600 || !c.HttpOnly {
+		t.Errorf("Expected the session_id cookie, got %+v", c)
+	}
+	if c := cookies[2]; c.Name != "old" || c.MaxAge != -1 || c.Expires.Unix() != 0 {
+		t.Errorf("Expected the old cookie to be deleted, got %+v", c)
+	}
+}
+
+// TestParseSetCookie verifies that Set-Cookie headers are parsed with all
+// their attributes, and that cookies round-trip through String.
+func TestParseSetCookie(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected Cookie
+	}{
+		{
+			name:     "Name and value",
+			header:   "session_id=abc123",
+			expected: Cookie{Name: "session_id", Value: "abc123"},
+		},
+		{
+			name:   "All attributes",
+			header: "id=1; Path=/app; Domain=example.com; Expires=Fri, 04 Oct 2024 00:00:00 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=Strict",
+			expected: Cookie{
+				Name: "id", Value: "1", Path: "/app", Domain: "example.com",
+				Expires: time.Date(2024, 10, 4, 0, 0, 0, 0, time.UTC), MaxAge: 3600,
+				Secure: true, HttpOnly: true, SameSite: SameSiteStrict,
+			},
+		},
+		{
+			name:     "Attributes in other cases",
+			header:   "id=1; path=/; secure; samesite=lax",
+			expected: Cookie{Name: "id", Value: "1", Path: "/", Secure: true, SameSite: SameSiteLax},
+		},
+		{
+			name:     "Obsolete date and zero Max-Age",
+			header:   "id=; Expires=Thursday, 01-Jan-70 00:00:00 GMT; Max-Age=0",
+			expected: Cookie{Name: "id", Expires: time.Unix(0, 0).UTC(), MaxAge: -1},
+		},
+		{
+			name:     "Invalid attribute values",
+			header:   "id=1; Expires=never; Max-Age=soon; SameSite=Sometimes",
+			expected: Cookie{Name: "id", Value: "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseSetCookie(tt.header)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if *c != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *c)
+			}
+
+			again, err := ParseSetCookie(c.String())
+			if err != nil || *again != *c {
+				t.Errorf("Expected %+v to round-trip, got %+v, %v", *c, again, err)
+			}
+		})
+	}
+
+	for _, header := range []string{"", "novalue", "=1", "a b=1"} {
+		if _, err := ParseSetCookie(header); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Expected ErrInvalidCookie for '%s', got %v", header, err)
+		}
+	}
+}
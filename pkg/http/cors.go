@@ -0,0 +1,140 @@
+package http
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the cross-origin resource sharing of a mux.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross-origin
+	// requests, such as "https://app.example.com". Empty, or "*", allows
+	// any origin.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed in cross-origin
+	// requests. Empty allows the ones a preflight asks for.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts can read besides the
+	// safelisted ones, such as "ETag".
+	ExposedHeaders []string
+	// AllowCredentials lets cross-origin requests send cookies and
+	// Authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long browsers cache preflight responses. Zero leaves it
+	// to the browser.
+	MaxAge time.Duration
+}
+
+// EnableCORS answers cross-origin requests from the allowed origins with
+// the CORS headers. Preflight requests are answered before any middleware
+// runs, so they don't need credentials, with the methods the route tree
+// has for the requested path instead of a fixed list:
+//
+//	mux.EnableCORS(http.CORSOptions{
+//		AllowedOrigins: []string{"https://app.example.com"},
+//		AllowedHeaders: []string{"Content-Type", "Authorization"},
+//		MaxAge:         time.Hour,
+//	})
+//
+// Preflights for paths without routes are answered with 404 Not Found,
+// and the ones from other origins with 403 Forbidden.
+func (mux *ServeMux) EnableCORS(opts CORSOptions) {
+	mux.cors = &opts
+}
+
+// AllowedMethods returns the sorted methods the routes matching the path
+// handle, or nil when no route matches it.
+func (mux *ServeMux) AllowedMethods(path string) []string {
+	methods := make(map[string]bool)
+	walkTree(mux.root, func(node *RouteNode, _ int) {
+		for method := range node.handler {
+			methods[method] = true
+		}
+	})
+
+	var allowed []string
+	for method := range methods {
+		if _, ok := mux.traverseTree(path, method, mux.root, make(map[string]string)); ok {
+			allowed = append(allowed, method)
+		}
+	}
+	slices.Sort(allowed)
+	return allowed
+}
+
+// serveCORS adds the CORS headers to the responses of cross-origin
+// requests, and answers preflights. It reports whether the request was
+// answered.
+func (mux *ServeMux) serveCORS(w ResponseWriter, r *Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	opts := mux.cors
+	h := w.Header()
+	h.Add("Vary", "Origin")
+
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+	if !opts.allowsOrigin(origin) {
+		if preflight {
+			mux.ServeError(w, r, StatusForbidden)
+			return true
+		}
+		return false
+	}
+
+	// Browsers refuse credentials allowed for any origin, so the origin of
+	// the request is named instead
+	anyOrigin := len(opts.AllowedOrigins) == 0 || slices.Contains(opts.AllowedOrigins, "*")
+	if anyOrigin && !opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(opts.ExposedHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	methods := mux.AllowedMethods(r.URL.Path)
+	if len(methods) == 0 {
+		mux.ServeError(w, r, StatusNotFound)
+		return true
+	}
+	if !slices.Contains(methods, "OPTIONS") {
+		methods = append(methods, "OPTIONS")
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if len(opts.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge/time.Second)))
+	}
+	w.WriteHeader(StatusNoContent)
+	return true
+}
+
+// allowsOrigin reports whether cross-origin requests from the origin are
+// allowed.
+func (opts *CORSOptions) allowsOrigin(origin string) bool {
+	if len(opts.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range opts.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
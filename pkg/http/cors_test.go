@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newCORSMux returns a mux with CORS enabled for one origin, behind middleware requiring credentials.
+func newCORSMux() *ServeMux {
+	mux := NewServeMux(nil)
+	mux.Use(func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	})
+	ok := func(w ResponseWriter, r *Request) { w.WriteHeader(StatusOK) }
+	mux.AddRoute("/items", []string{GET, POST}, ok)
+	mux.AddRoute("/items/:id", []string{GET, PUT, DELETE}, ok)
+	mux.EnableCORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+	return mux
+}
+
+// TestAllowedMethods verifies that the methods of the routes matching a path are returned.
+func TestAllowedMethods(t *testing.T) {
+	mux := newCORSMux()
+	if got := mux.AllowedMethods("/items/42"); !reflect.DeepEqual(got, []string{DELETE, GET, PUT}) {
+		t.Errorf("Expected [DELETE GET PUT], got %v", got)
+	}
+	if got := mux.AllowedMethods("/missing"); got != nil {
+		t.Errorf("Expected no methods, got %v", got)
+	}
+}
+
+// TestCORSPreflight verifies that preflights are answered with the route's methods before the middleware runs.
+func TestCORSPreflight(t *testing.T) {
+	mux := newCORSMux()
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: "OPTIONS", URL: &url.URL{Path: "/items/42"}, Header: Header{
+		"Origin":                         {"https://app.example.com"},
+		"Access-Control-Request-Method":  {PUT},
+		"Access-Control-Request-Headers": {"Authorization, Content-Type"},
+	}})
+
+	if res.status != StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", StatusNoContent, res.status)
+	}
+	for key, expected := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "DELETE, GET, PUT, OPTIONS",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := res.headers.Get(key); got != expected {
+			t.Errorf("Expected %s '%s', got '%s'", key, expected, got)
+		}
+	}
+}
+
+// TestCORSPreflightRejected verifies that preflights from other origins or for unknown paths are refused.
+func TestCORSPreflightRejected(t *testing.T) {
+	mux := newCORSMux()
+	for _, tt := range []struct {
+		origin, path string
+		status       int
+	}{
+		{"https://evil.example.com", "/items", StatusForbidden},
+		{"https://app.example.com", "/missing", StatusNotFound},
+	} {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: "OPTIONS", URL: &url.URL{Path: tt.path}, Header: Header{
+			"Origin":                        {tt.origin},
+			"Access-Control-Request-Method": {GET},
+		}})
+		if res.status != tt.status {
+			t.Errorf("Expected status %d for %s %s, got %d", tt.status, tt.origin, tt.path, res.status)
+		}
+		if got := res.headers.Get("Access-Control-Allow-Methods"); got != "" {
+			t.Errorf("Expected no Access-Control-Allow-Methods, got '%s'", got)
+		}
+	}
+}
+
+// TestCORSRequest verifies that cross-origin requests get the CORS headers, even when middleware turns them away.
+func TestCORSRequest(t *testing.T) {
+	mux := newCORSMux()
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/items"}, Header: Header{"Origin": {"https://app.example.com"}}})
+
+	if res.status != StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", StatusUnauthorized, res.status)
+	}
+	if got := res.headers.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected the origin to be allowed, got '%s'", got)
+	}
+	if got := res.headers.Get("Access-Control-Expose-Headers"); got != "ETag" {
+		t.Errorf("Expected exposed headers 'ETag', got '%s'", got)
+	}
+	if got := res.headers.Get("Vary"); got != "Origin" {
+		t.Errorf("Expected Vary 'Origin', got '%s'", got)
+	}
+}
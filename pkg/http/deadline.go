@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+)
+
+// deadlineWriter is the ResponseWriter of a handler that may outlive the
+// deadline of its request. Once the deadline passed, its writes fail with
+// ErrHandlerTimeout instead of reaching a connection serving another
+// request. The handler's headers are kept apart until it writes them, so
+// the 503 of a timed out handler isn't mixed with them.
+type deadlineWriter struct {
+	mu          sync.Mutex
+	w           ResponseWriter
+	header      Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the headers of the handler's response.
+func (dw *deadlineWriter) Header() Header {
+	return dw.header
+}
+
+// WriteHeader sends the handler's headers, unless the deadline passed.
+func (dw *deadlineWriter) WriteHeader(statusCode int) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.timedOut || dw.wroteHeader {
+		return
+	}
+	if isInformational(statusCode) {
+		WriteInformational(dw.w, statusCode, dw.header)
+		return
+	}
+	dw.writeHeader(statusCode)
+}
+
+// WriteInformational sends an informational response, unless the deadline
+// passed or the final status was written.
+func (dw *deadlineWriter) WriteInformational(statusCode int, header Header) error {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	switch {
+	case dw.timedOut:
+		return ErrHandlerTimeout
+	case dw.wroteHeader:
+		return ErrStatusWritten
+	}
+	return WriteInformational(dw.w, statusCode, header)
+}
+
+// writeHeader hands the headers over to the underlying writer while
+// holding the lock.
+func (dw *deadlineWriter) writeHeader(statusCode int) {
+	dw.wroteHeader = true
+	header := dw.w.Header()
+	clear(header)
+	for key, values := range dw.header {
+		header[key] = values
+	}
+	dw.w.WriteHeader(statusCode)
+}
+
+// Write writes the body, unless the deadline passed.
+func (dw *deadlineWriter) Write(p []byte) (int, error) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.timedOut {
+		return 0, ErrHandlerTimeout
+	}
+	if !dw.wroteHeader {
+		dw.writeHeader(StatusOK)
+	}
+	return dw.w.Write(p)
+}
+
+// SetCookie adds a cookie to the handler's headers.
+func (dw *deadlineWriter) SetCookie(c *Cookie) {
+	addCookie(dw.w, dw.header, c)
+}
+
+// DeleteCookie adds a Set-Cookie header deleting the cookie to the
+// handler's headers.
+func (dw *deadlineWriter) DeleteCookie(name string) {
+	dw.SetCookie(&Cookie{Name: name, Value: "", MaxAge: -1})
+}
+
+// Cookies returns the cookies set in the handler's headers.
+func (dw *deadlineWriter) Cookies() []*Cookie {
+	return headerCookies(dw.header)
+}
+
+// Flush flushes the underlying writer, unless the deadline passed.
+func (dw *deadlineWriter) Flush() {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.timedOut {
+		return
+	}
+	if !dw.wroteHeader {
+		dw.writeHeader(StatusOK)
+	}
+	Flush(dw.w)
+}
+
+// timeout stops the handler's writes and reports whether its response had
+// started.
+func (dw *deadlineWriter) timeout() bool {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	dw.timedOut = true
+	return dw.wroteHeader
+}
+
+// serveWithDeadline runs handler until it returns or the context of the
+// request is done. When the deadline passes first, the request is answered
+// with the mux's 503 Service Unavailable if the handler wrote nothing yet;
+// otherwise, or when the request was canceled, the connection is closed
+// without completing the response. Either way the handler's later writes
+// are discarded. Panics are passed on to the caller while the request is
+// being served.
+func (mux *ServeMux) serveWithDeadline(handler func(ResponseWriter, *Request), w ResponseWriter, r *Request) {
+	dw := &deadlineWriter{w: w, header: make(Header, len(w.Header()))}
+	for key, values := range w.Header() {
+		dw.header[key] = append([]string(nil), values...)
+	}
+
+	type handlerPanic struct {
+		value any
+		stack []byte
+	}
+	done := make(chan struct{})
+	panicked := make(chan handlerPanic, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				panicked <- handlerPanic{v, debug.Stack()}
+				return
+			}
+			close(done)
+		}()
+		handler(dw, r)
+	}()
+
+	ctx := r.Context()
+	select {
+	case <-done:
+		return
+	case p := <-panicked:
+		panic(p.value)
+	case <-ctx.Done():
+	}
+
+	if dw.timeout() || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		abortResponse(w)
+	} else {
+		mux.ServeError(w, r, StatusServiceUnavailable)
+	}
+
+	// The handler is on its own, its panics can only be logged
+	go func() {
+		select {
+		case <-done:
+		case p := <-panicked:
+			mux.logf("http: panic serving %s after its deadline: %v\n%s", r.URL.Path, p.value, p.stack)
+		}
+	}()
+}
+
+// abortResponse makes the server close the connection of w once the
+// handler returns, without sending what is left of the response, which
+// the client would take for a complete one.
+func abortResponse(w ResponseWriter) {
+	for w != nil {
+		if res, ok := w.(*Response); ok {
+			res.mu.Lock()
+			res.keepAlive = false
+			res.aborted = !res.headersSent
+			res.mu.Unlock()
+			return
+		}
+		u, ok := w.(rwUnwrapper)
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
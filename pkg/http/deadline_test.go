@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDeadlineServiceUnavailable verifies that handlers outliving their deadline get a 503 and can't write onto the next request.
+func TestDeadlineServiceUnavailable(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	lateRead := make(chan error, 1)
+	mux := NewServeMux(nil)
+	mux.AddRoute("/slow", []string{POST}, func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Slow", "1")
+		<-r.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+		_, err = r.Body.Read(make([]byte, 1))
+		lateRead <- err
+	}).WithTimeout(10 * time.Millisecond)
+	mux.AddRoute("/fast", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.Write([]byte("fast"))
+	})
+
+	conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+		reader: bufio.NewReader(strings.NewReader("POST /slow HTTP/1.1\r\nHost: localhost\r\nContent-Length: 4\r\n\r\nbody" +
+			"GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+	}}
+	NewServer(":8080", mux).handleConn(context.Background(), conn)
+
+	if err := <-lateWrite; !errors.Is(err, ErrHandlerTimeout) {
+		t.Errorf("Expected the late write to fail with ErrHandlerTimeout, got %v", err)
+	}
+	if err := <-lateRead; !errors.Is(err, ErrBodyReadAfterClose) {
+		t.Errorf("Expected the late read to fail with ErrBodyReadAfterClose, got %v", err)
+	}
+
+	out := conn.written.String()
+	first, second, _ := strings.Cut(out, "HTTP/1.1 200 OK")
+	if !strings.HasPrefix(first, "HTTP/1.1 503 Service Unavailable\r\n") || strings.Contains(first, "X-Slow") {
+		t.Errorf("Expected a plain 503 first, got %q", first)
+	}
+	if !strings.HasSuffix(second, "\r\n\r\nfast") || strings.Contains(out, "late") {
+		t.Errorf("Expected the next request to be answered cleanly, got %q", out)
+	}
+}
+
+// TestDeadlineStartedResponse verifies that a response started before the deadline is dropped with the connection.
+func TestDeadlineStartedResponse(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.AddRoute("/partial", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+	}).WithTimeout(10 * time.Millisecond)
+
+	conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+		reader: bufio.NewReader(strings.NewReader("GET /partial HTTP/1.1\r\nHost: localhost\r\n\r\nGET /partial HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+	}}
+	NewServer(":8080", mux).handleConn(context.Background(), conn)
+
+	if got, _ := io.ReadAll(&conn.written); len(got) != 0 {
+		t.Errorf("Expected the connection to be closed without a response, got %q", got)
+	}
+}
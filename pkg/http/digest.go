@@ -0,0 +1,250 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDigestNonceTTL is how long digest nonces are valid when
+// DigestAuth.NonceTTL is zero.
+const defaultDigestNonceTTL = 5 * time.Minute
+
+// DigestAuth authenticates requests with HTTP Digest access authentication
+// (RFC 7616), using SHA-256 and the "auth" quality of protection, so
+// passwords aren't sent in the clear where TLS isn't available:
+//
+//	auth := http.NewDigestAuth("admin area", func(username string) (string, bool) {
+//		password, ok := passwords[username]
+//		return password, ok
+//	})
+//	mux.AddRoute("/admin", []string{http.GET}, admin).Use(auth.Middleware())
+//
+// Nonces are signed with a random key instead of being stored, and expire
+// after NonceTTL, when clients are asked to retry with a fresh one. Nonce
+// counts are tracked to reject replayed requests. Digest doesn't protect
+// the body of requests nor responses. A DigestAuth with Realm and
+// Credentials set is ready to use without NewDigestAuth.
+type DigestAuth struct {
+	// Realm is shown to users to tell which credentials to use.
+	Realm string
+	// Credentials returns the password of a user, and false for unknown
+	// users.
+	Credentials func(username string) (password string, ok bool)
+	// NonceTTL is how long a nonce can be used. Zero means 5 minutes.
+	NonceTTL time.Duration
+
+	mu        sync.Mutex
+	key       []byte                 // Key signing nonces, generated on first use
+	counts    map[string]digestCount // Nonce to its last count
+	lastPrune time.Time
+}
+
+// digestCount is the last nonce count used with a nonce.
+type digestCount struct {
+	nc      uint64
+	expires time.Time
+}
+
+// NewDigestAuth creates a DigestAuth for the realm, checking passwords with
+// credentials.
+func NewDigestAuth(realm string, credentials func(username string) (password string, ok bool)) *DigestAuth {
+	return &DigestAuth{Realm: realm, Credentials: credentials}
+}
+
+// Middleware returns middleware answering the requests without valid
+// digest credentials with 401 Unauthorized and a challenge. Authenticated
+// requests are passed on with a Principal identified by the username.
+func (d *DigestAuth) Middleware() Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			username, stale, ok := d.authenticate(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", d.challenge(stale))
+				WriteProblem(w, NewProblem(StatusUnauthorized, "Valid digest credentials are required"))
+				return
+			}
+			next(w, r.WithPrincipal(&Principal{ID: username}))
+		}
+	}
+}
+
+// challenge returns the WWW-Authenticate header asking for credentials,
+// with a fresh nonce. It tells the client its nonce was stale when the
+// credentials were otherwise valid.
+func (d *DigestAuth) challenge(stale bool) string {
+	challenge := `Digest realm=` + quoteString(d.Realm) + `, qop="auth", algorithm=SHA-256, nonce="` +
+		d.newNonce() + `", charset=UTF-8`
+	if stale {
+		challenge += ", stale=true"
+	}
+	return challenge
+}
+
+// authenticate checks the digest credentials of the request, returning its
+// username. Stale reports that they would be valid with a fresh nonce.
+func (d *DigestAuth) authenticate(r *Request) (username string, stale, ok bool) {
+	scheme, rest, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return "", false, false
+	}
+	params, ok := parseAuthParams(rest)
+	if !ok || params["realm"] != d.Realm || params["qop"] != "auth" ||
+		!strings.EqualFold(params["algorithm"], "SHA-256") || params["userhash"] == "true" {
+		return "", false, false
+	}
+
+	// The credentials must be for this request
+	uri, err := url.ParseRequestURI(params["uri"])
+	if err != nil || uri.RequestURI() != r.URL.RequestURI() {
+		return "", false, false
+	}
+	nc, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil || len(params["nc"]) != 8 || params["cnonce"] == "" {
+		return "", false, false
+	}
+
+	username = params["username"]
+	password, ok := d.Credentials(username)
+	if !ok {
+		return "", false, false
+	}
+	ha1 := digestHash(username + ":" + d.Realm + ":" + password)
+	ha2 := digestHash(r.Method + ":" + params["uri"])
+	expected := digestHash(ha1 + ":" + params["nonce"] + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(params["response"]))) != 1 {
+		return "", false, false
+	}
+
+	expires, valid := d.checkNonce(params["nonce"])
+	if !valid {
+		return "", false, false
+	}
+	if time.Now().After(expires) {
+		return "", true, false
+	}
+	if !d.useNonce(params["nonce"], nc, expires) {
+		return "", false, false
+	}
+	return username, false, true
+}
+
+// newNonce returns a nonce holding its expiry, signed with the key.
+func (d *DigestAuth) newNonce() string {
+	ttl := d.NonceTTL
+	if ttl <= 0 {
+		ttl = defaultDigestNonceTTL
+	}
+	b := make([]byte, 8+16, 8+16+sha256.Size)
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(ttl).UnixNano()))
+	rand.Read(b[8:])
+	mac := hmac.New(sha256.New, d.nonceKey())
+	mac.Write(b)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(b))
+}
+
+// checkNonce verifies the signature of a nonce and returns its expiry.
+func (d *DigestAuth) checkNonce(nonce string) (time.Time, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(b) != 8+16+sha256.Size {
+		return time.Time{}, false
+	}
+	mac := hmac.New(sha256.New, d.nonceKey())
+	mac.Write(b[:8+16])
+	if !hmac.Equal(mac.Sum(nil), b[8+16:]) {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))), true
+}
+
+// nonceKey returns the key signing nonces, generating it the first time.
+func (d *DigestAuth) nonceKey() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.key == nil {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("http: failed to generate a digest nonce key: " + err.Error())
+		}
+		d.key = key
+	}
+	return d.key
+}
+
+// useNonce records the count of a request with the nonce, reporting false
+// when it isn't higher than the last one, which makes it a replay.
+func (d *DigestAuth) useNonce(nonce string, nc uint64, expires time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(d.lastPrune) > time.Minute {
+		for n, count := range d.counts {
+			if now.After(count.expires) {
+				delete(d.counts, n)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	if last, ok := d.counts[nonce]; ok && nc <= last.nc {
+		return false
+	}
+	if d.counts == nil {
+		d.counts = make(map[string]digestCount)
+	}
+	d.counts[nonce] = digestCount{nc: nc, expires: expires}
+	return true
+}
+
+// digestHash returns the hex encoded SHA-256 digest of s.
+func digestHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAuthParams parses the comma separated key=value parameters of an
+// Authorization header, such as `username="ana", nc=00000001`. Keys are
+// lower cased.
+func parseAuthParams(s string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i := 0; ; {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == ',') {
+			i++
+		}
+		if i == len(s) {
+			return params, true
+		}
+
+		start := i
+		for i < len(s) && isTokenChar(s[i]) {
+			i++
+		}
+		key := strings.ToLower(s[start:i])
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if key == "" || i == len(s) || s[i] != '=' {
+			return nil, false
+		}
+		i++
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		value, n, ok := parseTokenOrQuoted(s[i:])
+		if !ok {
+			return nil, false
+		}
+		params[key] = value
+		i += n
+	}
+}
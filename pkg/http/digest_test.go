@@ -0,0 +1,118 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// digestAuthorization returns the Authorization header answering a digest challenge.
+func digestAuthorization(t *testing.T, challenge, method, uri, username, password string, nc int) string {
+	params, ok := parseAuthParams(strings.TrimPrefix(challenge, "Digest "))
+	if !ok {
+		t.Fatalf("Malformed challenge %q", challenge)
+	}
+	ha1 := digestHash(username + ":" + params["realm"] + ":" + password)
+	ha2 := digestHash(method + ":" + uri)
+	count := fmt.Sprintf("%08x", nc)
+	response := digestHash(ha1 + ":" + params["nonce"] + ":" + count + ":0a4f113b:auth:" + ha2)
+	return fmt.Sprintf(`Digest username="%s", realm="%s", uri="%s", algorithm=SHA-256, nonce="%s", nc=%s, cnonce="0a4f113b", qop=auth, response="%s"`,
+		username, params["realm"], uri, params["nonce"], count, response)
+}
+
+// TestDigestAuth verifies that digest credentials are checked and nonce counts can't be replayed.
+func TestDigestAuth(t *testing.T) {
+	auth := NewDigestAuth("admin area", func(username string) (string, bool) {
+		return "Circle of Life", username == "Mufasa"
+	})
+	handler := auth.Middleware()(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.Principal().ID))
+	})
+	serve := func(authorization string) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		u, _ := url.Parse("/dir/index.html?a=b")
+		handler(res, &Request{Method: GET, URL: u, Header: Header{"Authorization": {authorization}}})
+		return res
+	}
+
+	res := serve("")
+	challenge := res.headers.Get("WWW-Authenticate")
+	if res.status != StatusUnauthorized || !strings.HasPrefix(challenge, `Digest realm="admin area", qop="auth", algorithm=SHA-256`) {
+		t.Fatalf("Expected a challenge, got %d '%s'", res.status, challenge)
+	}
+
+	authorization := digestAuthorization(t, challenge, GET, "/dir/index.html?a=b", "Mufasa", "Circle of Life", 1)
+	if res := serve(authorization); res.status != StatusOK || string(res.body) != "Mufasa" {
+		t.Errorf("Expected %d for Mufasa, got %d '%s'", StatusOK, res.status, res.body)
+	}
+	if res := serve(authorization); res.status != StatusUnauthorized {
+		t.Errorf("Expected a replayed count to be rejected, got %d", res.status)
+	}
+	if res := serve(digestAuthorization(t, challenge, GET, "/dir/index.html?a=b", "Mufasa", "Circle of Life", 2)); res.status != StatusOK {
+		t.Errorf("Expected the next count to be accepted, got %d", res.status)
+	}
+
+	for name, authorization := range map[string]string{
+		"wrong password": digestAuthorization(t, challenge, GET, "/dir/index.html?a=b", "Mufasa", "Hakuna Matata", 3),
+		"unknown user":   digestAuthorization(t, challenge, GET, "/dir/index.html?a=b", "Scar", "Circle of Life", 3),
+		"other URI":      digestAuthorization(t, challenge, GET, "/other", "Mufasa", "Circle of Life", 3),
+		"forged nonce":   digestAuthorization(t, `Digest realm="admin area", nonce="AAAA"`, GET, "/dir/index.html?a=b", "Mufasa", "Circle of Life", 3),
+		"basic":          "Basic TXVmYXNhOkNpcmNsZSBvZiBMaWZl",
+	} {
+		if res := serve(authorization); res.status != StatusUnauthorized {
+			t.Errorf("%s: expected %d, got %d", name, StatusUnauthorized, res.status)
+		}
+	}
+}
+
+// TestDigestAuthZeroValue verifies that a DigestAuth built without NewDigestAuth works and signs its nonces with a key.
+func TestDigestAuthZeroValue(t *testing.T) {
+	auth := &DigestAuth{Realm: "admin area", Credentials: func(string) (string, bool) { return "secret", true }}
+	handler := auth.Middleware()(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+	})
+	serve := func(authorization string) int {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Authorization": {authorization}}})
+		return res.status
+	}
+
+	if status := serve(digestAuthorization(t, auth.challenge(false), GET, "/", "ana", "secret", 1)); status != StatusOK {
+		t.Errorf("Expected %d, got %d", StatusOK, status)
+	}
+
+	// A nonce signed with an empty key must not be accepted
+	b := make([]byte, 8+16, 8+16+sha256.Size)
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(time.Hour).UnixNano()))
+	mac := hmac.New(sha256.New, nil)
+	mac.Write(b)
+	forged := `Digest realm="admin area", nonce="` + base64.RawURLEncoding.EncodeToString(mac.Sum(b)) + `"`
+	if status := serve(digestAuthorization(t, forged, GET, "/", "ana", "secret", 1)); status != StatusUnauthorized {
+		t.Errorf("Expected a nonce signed without the key to be rejected, got %d", status)
+	}
+}
+
+// TestDigestAuthStaleNonce verifies that clients are asked to retry expired nonces.
+func TestDigestAuthStaleNonce(t *testing.T) {
+	auth := NewDigestAuth("admin area", func(string) (string, bool) { return "secret", true })
+	auth.NonceTTL = time.Nanosecond
+	challenge := auth.challenge(false)
+	time.Sleep(time.Millisecond)
+
+	res := &MockResponseWriter{headers: make(Header)}
+	authorization := digestAuthorization(t, challenge, GET, "/", "ana", "secret", 1)
+	auth.Middleware()(func(w ResponseWriter, r *Request) {
+		t.Error("Expected the handler not to run")
+	})(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Authorization": {authorization}}})
+
+	if res.status != StatusUnauthorized || !strings.HasSuffix(res.headers.Get("WWW-Authenticate"), "stale=true") {
+		t.Errorf("Expected a stale challenge, got %d '%s'", res.status, res.headers.Get("WWW-Authenticate"))
+	}
+}
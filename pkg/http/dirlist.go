@@ -0,0 +1,168 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DirListingOptions configures the automatic index rendered for static
+// directories without an index.html file.
+type DirListingOptions struct {
+	// ShowHidden lists entries whose name starts with a dot.
+	ShowHidden bool
+}
+
+// EnableDirListing renders an index for static directories that have no
+// index.html file. Listings are HTML by default, and JSON for requests
+// preferring application/json in their Accept header or with
+// ?format=json, listing the name, size, modification time and type of
+// each entry for file browsers; ?sort=name|size|modified and ?order=desc
+// control the ordering.
+func (mux *ServeMux) EnableDirListing(opts DirListingOptions) {
+	mux.dirListing = &opts
+}
+
+// dirListingEntry describes a single entry of a directory listing.
+type dirListingEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir"`
+	Type    string    `json:"type"` // "file", "dir", "symlink" or "other"
+}
+
+// redirectToDir redirects a directory requested without a trailing slash
+// to its canonical URL when listings are enabled, so relative links in the
+// listing resolve correctly. It reports whether a response was written.
+func (mux *ServeMux) redirectToDir(w ResponseWriter, r *Request) bool {
+	if mux.dirListing == nil || strings.HasSuffix(r.URL.Path, "/") {
+		return false
+	}
+
+	target := r.URL.Path + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	w.Header()["Location"] = []string{target}
+	w.WriteHeader(StatusMovedPermanently)
+	return true
+}
+
+// serveDirListing writes the listing of a directory's entries.
+func (mux *ServeMux) serveDirListing(w ResponseWriter, r *Request, dirEntries []fs.DirEntry) {
+	entries := make([]dirListingEntry, 0, len(dirEntries))
+	for _, e := range dirEntries {
+		if !mux.dirListing.ShowHidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		entry := dirListingEntry{Name: e.Name(), ModTime: info.ModTime().UTC(), IsDir: e.IsDir(), Type: dirEntryType(e.Type())}
+		if !e.IsDir() {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+
+	query := r.URL.Query()
+	sortDirListing(entries, query.Get("sort"), query.Get("order") == "desc")
+
+	format := query.Get("format")
+	if format == "" {
+		w.Header().Add("Vary", "Accept")
+		if negotiate(r.Header.Get("Accept"), []string{"text/html", "application/json"}) == "application/json" {
+			format = "json"
+		}
+	}
+	if format == "json" {
+		data, err := json.Marshal(entries)
+		if err != nil {
+			Error(w, StatusText(StatusInternalServerError), StatusInternalServerError)
+			return
+		}
+		w.Header()["Content-Type"] = []string{"application/json"}
+		w.WriteHeader(StatusOK)
+		w.Write(data)
+		return
+	}
+
+	w.Header()["Content-Type"] = []string{"text/html; charset=utf-8"}
+	w.WriteHeader(StatusOK)
+	w.Write([]byte(renderDirListing(r.URL.Path, entries)))
+}
+
+// dirEntryType returns the type of a directory entry listed in JSON.
+func dirEntryType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	}
+	return "other"
+}
+
+// sortDirListing sorts entries by the given key, keeping directories first.
+func sortDirListing(entries []dirListingEntry, key string, desc bool) {
+	less := func(a, b dirListingEntry) bool {
+		switch key {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case "modified":
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+		}
+		return a.Name < b.Name
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		if desc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+// renderDirListing renders the HTML listing of a directory.
+func renderDirListing(dirPath string, entries []dirListingEntry) string {
+	title := html.EscapeString("Index of " + dirPath)
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>" + title + "</title></head>\n<body>\n")
+	sb.WriteString("<h1>" + title + "</h1>\n<table>\n")
+	sb.WriteString("<tr><th><a href=\"?sort=name\">Name</a></th><th><a href=\"?sort=size\">Size</a></th><th><a href=\"?sort=modified\">Modified</a></th></tr>\n")
+	if dirPath != "/" {
+		sb.WriteString("<tr><td><a href=\"../\">../</a></td><td></td><td></td></tr>\n")
+	}
+
+	for _, e := range entries {
+		name, size := e.Name, strconv.FormatInt(e.Size, 10)
+		if e.IsDir {
+			name, size = name+"/", "-"
+		}
+		href := (&url.URL{Path: name}).String()
+		fmt.Fprintf(&sb, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(href), html.EscapeString(name), size, e.ModTime.Format(TimeFormat))
+	}
+
+	sb.WriteString("</table>\n</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+)
+
+// defaultMaxDecompressedSize bounds decompressed request bodies when
+// DecompressRequest is given no limit.
+const defaultMaxDecompressedSize = 10 << 20
+
+// DecompressRequest returns middleware that decodes request bodies sent
+// with a gzip or deflate Content-Encoding, so handlers read plain bytes.
+// Reading more than maxSize decompressed bytes fails with ErrBodyTooLarge,
+// protecting against decompression bombs; zero means 10 MB.
+//
+// Requests with any other coding are answered with 415 Unsupported Media
+// Type and an Accept-Encoding header listing the supported ones (RFC 7694),
+// and bodies that don't start as their coding says with 400 Bad Request.
+func DecompressRequest(maxSize int64) Middleware {
+	if maxSize <= 0 {
+		maxSize = defaultMaxDecompressedSize
+	}
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			codings := contentCodings(r.Header)
+			if len(codings) == 0 || r.Body == nil {
+				next(w, r)
+				return
+			}
+			for _, coding := range codings {
+				if coding != "gzip" && coding != "x-gzip" && coding != "deflate" {
+					w.Header()["Accept-Encoding"] = []string{"gzip, deflate"}
+					Error(w, "unsupported content coding "+coding, StatusUnsupportedMediaType)
+					return
+				}
+			}
+
+			body := &decodedBody{closers: []io.Closer{r.Body}}
+			src := io.Reader(r.Body)
+			// Codings are listed in the order they were applied
+			for i := len(codings) - 1; i >= 0; i-- {
+				dec, err := newDecoder(codings[i], src)
+				if err != nil {
+					Error(w, "malformed "+codings[i]+" body", StatusBadRequest)
+					return
+				}
+				body.closers = append(body.closers, dec)
+				src = dec
+			}
+			body.r = io.LimitReader(src, maxSize+1)
+			body.remaining = maxSize
+
+			r2 := *r
+			r2.Header = make(Header, len(r.Header))
+			for key, values := range r.Header {
+				r2.Header[key] = values
+			}
+			r2.Header.Del("Content-Encoding")
+			r2.Header.Del("Content-Length")
+			r2.Body = body
+			r2.ContentLength = -1
+			next(w, &r2)
+		}
+	}
+}
+
+// contentCodings returns the content codings of a request, lowercased,
+// without identity.
+func contentCodings(h Header) []string {
+	var codings []string
+	for _, value := range h.Values("Content-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	return codings
+}
+
+// newDecoder returns a reader decoding src. Deflate bodies are zlib
+// streams, but some clients send raw deflate data, which is accepted too.
+func newDecoder(coding string, src io.Reader) (io.ReadCloser, error) {
+	if coding != "deflate" {
+		return gzip.NewReader(src)
+	}
+	br := bufio.NewReader(src)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// A zlib header uses the deflate method and is a multiple of 31
+	if header[0]&0x0f == 8 && (uint(header[0])<<8|uint(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodedBody is a decompressed request body with a size limit.
+type decodedBody struct {
+	r         io.Reader
+	remaining int64
+	closers   []io.Closer // The original body first
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// Close closes the decoders and the original body.
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
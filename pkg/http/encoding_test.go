@@ -0,0 +1,95 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// compressed returns data encoded with the coding.
+func compressed(t *testing.T, coding string, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	io.WriteString(w, data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// decompressRequest sends a body through DecompressRequest to a handler echoing what it read.
+func decompressRequest(maxSize int64, encoding string, body []byte) *MockResponseWriter {
+	handler := DecompressRequest(maxSize)(func(w ResponseWriter, r *Request) {
+		data, err := io.ReadAll(r.Body)
+		if errors.Is(err, ErrBodyTooLarge) {
+			Error(w, err.Error(), StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Content-Encoding") + "|" + string(data)))
+	})
+	req := &Request{
+		Method:        POST,
+		URL:           &url.URL{Path: "/"},
+		Header:        Header{"Content-Encoding": {encoding}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+	res := &MockResponseWriter{headers: make(Header)}
+	handler(res, req)
+	return res
+}
+
+// TestDecompressRequest verifies that gzip and deflate bodies are decoded for the handler.
+func TestDecompressRequest(t *testing.T) {
+	tests := []struct {
+		encoding string
+		body     []byte
+		want     string
+	}{
+		{"gzip", compressed(t, "gzip", "lamp"), "|lamp"},
+		{"x-gzip", compressed(t, "gzip", "lamp"), "|lamp"},
+		{"deflate", compressed(t, "deflate", "lamp"), "|lamp"},
+		{"deflate", compressed(t, "raw", "lamp"), "|lamp"},
+		{"deflate, gzip", compressed(t, "gzip", string(compressed(t, "deflate", "lamp"))), "|lamp"},
+		{"identity", []byte("lamp"), "identity|lamp"},
+	}
+	for _, tt := range tests {
+		res := decompressRequest(0, tt.encoding, tt.body)
+		if string(res.body) != tt.want {
+			t.Errorf("%s: expected '%s', got '%s'", tt.encoding, tt.want, res.body)
+		}
+	}
+}
+
+// TestDecompressRequestErrors verifies the answers to unknown codings, malformed bodies and decompression bombs.
+func TestDecompressRequestErrors(t *testing.T) {
+	res := decompressRequest(0, "br", []byte("lamp"))
+	if res.status != StatusUnsupportedMediaType || res.headers.Get("Accept-Encoding") != "gzip, deflate" {
+		t.Errorf("Expected 415 with the supported codings, got %d %v", res.status, res.headers)
+	}
+
+	if res := decompressRequest(0, "gzip", []byte("lamp")); res.status != StatusBadRequest {
+		t.Errorf("Expected %d for a malformed body, got %d", StatusBadRequest, res.status)
+	}
+
+	bomb := compressed(t, "gzip", strings.Repeat("a", 1<<20))
+	if res := decompressRequest(1024, "gzip", bomb); res.status != StatusRequestEntityTooLarge {
+		t.Errorf("Expected the limit to stop the body, got %d '%.40s'", res.status, res.body)
+	}
+	if res := decompressRequest(1024, "gzip", compressed(t, "gzip", strings.Repeat("a", 1024))); string(res.body) != "|"+strings.Repeat("a", 1024) {
+		t.Errorf("Expected a body of exactly the limit to be read, got %d", res.status)
+	}
+}
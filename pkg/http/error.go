@@ -4,3 +4,57 @@ import "errors"
 
 // ErrCookieNotFound is returned when a cookie is not found.
 var ErrCookieNotFound = errors.New("cookie not found")
+
+// ErrStreamClosed is returned when writing to a closed event stream.
+var ErrStreamClosed = errors.New("event stream closed")
+
+// ErrInvalidSignature is returned when a signed cookie fails verification.
+var ErrInvalidSignature = errors.New("invalid cookie signature")
+
+// ErrInvalidCookie is returned for cookies that browsers would reject,
+// such as a __Host- cookie without the Secure attribute.
+var ErrInvalidCookie = errors.New("http: invalid cookie")
+
+// ErrSessionNotFound is returned by session stores for missing or expired sessions.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrServerClosed is returned by the server's serve loop after Shutdown.
+var ErrServerClosed = errors.New("server closed")
+
+// ErrHijacked is returned when writing to a response whose connection was hijacked.
+var ErrHijacked = errors.New("connection has been hijacked")
+
+// ErrUseLastResponse can be returned by Client.CheckRedirect to stop
+// following redirects and return the redirect response itself.
+var ErrUseLastResponse = errors.New("use last response")
+
+// ErrBodyTooLarge is returned when reading a request body past its limit.
+var ErrBodyTooLarge = errors.New("http: request body too large")
+
+// ErrResponseTooLarge is returned by the Client when a response body
+// decompresses past its limit.
+var ErrResponseTooLarge = errors.New("http: response body too large")
+
+// ErrNotSupported is returned when no ResponseWriter of a chain supports
+// an optional feature, such as flushing or hijacking.
+var ErrNotSupported = errors.New("http: feature not supported")
+
+// ErrBodyReadAfterClose is returned when reading a request body after the
+// server is done with the request, e.g. by a handler that timed out.
+var ErrBodyReadAfterClose = errors.New("http: invalid Read on closed Body")
+
+// ErrHandlerTimeout is returned by the writes of a handler that is still
+// running after the deadline of its request, which was answered already.
+var ErrHandlerTimeout = errors.New("http: handler timeout")
+
+// ErrInvalidAPIKey is returned by the APIKeys authenticator for requests
+// with an unknown API key.
+var ErrInvalidAPIKey = errors.New("http: invalid API key")
+
+// ErrInvalidIDToken is returned by OIDC.VerifyIDToken for ID tokens that
+// fail verification.
+var ErrInvalidIDToken = errors.New("http: invalid ID token")
+
+// ErrStatusWritten is returned when sending an informational response
+// after the final status of the response was written.
+var ErrStatusWritten = errors.New("http: final status already written")
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the time format to use when generating times in HTTP
+// headers, such as Last-Modified. It is like time.RFC1123 but hard-codes
+// GMT as the time zone.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+var (
+	// errInvalidRange is returned for Range headers that can't be parsed;
+	// such headers are ignored and the full content is served.
+	errInvalidRange = errors.New("invalid range")
+	// errNoOverlap is returned when the requested range lies past the end
+	// of the content.
+	errNoOverlap = errors.New("invalid range: failed to overlap")
+)
+
+// ServeFile replies to the request with the contents of the named file.
+// If name is a directory, its index.html file is served instead.
+func ServeFile(w ResponseWriter, r *Request, name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		serveFileError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		serveFileError(w, err)
+		return
+	}
+
+	if info.IsDir() {
+		ServeFile(w, r, filepath.Join(name, "index.html"))
+		return
+	}
+
+	ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// serveFileError maps an error from opening a file to an HTTP error response.
+func serveFileError(w ResponseWriter, err error) {
+	switch {
+	case os.IsNotExist(err):
+		Error(w, StatusText(StatusNotFound), StatusNotFound)
+	case os.IsPermission(err):
+		Error(w, StatusText(StatusForbidden), StatusForbidden)
+	default:
+		Error(w, StatusText(StatusInternalServerError), StatusInternalServerError)
+	}
+}
+
+// ServeContent replies to the request using the content in the provided
+// ReadSeeker. The Content-Type is derived from the extension of name unless
+// the handler already set one, Last-Modified is sent when modtime is not
+// zero, and single byte ranges requested with a Range header are answered
+// with 206 Partial Content. Conditional requests are answered with 304
+// Not Modified and 412 Precondition Failed as by CheckPreconditions, with
+// the ETag header the handler may have set. The content exists, so "*"
+// matches it even without an ETag.
+func ServeContent(w ResponseWriter, r *Request, name string, modtime time.Time, content io.ReadSeeker) {
+	if checkPreconditions(w, r, w.Header().Get("Etag"), modtime, true) {
+		return
+	}
+
+	h := w.Header()
+	if _, ok := h["Content-Type"]; !ok {
+		contentType := ContentTypeByExtension(name)
+		if contentType == "" {
+			// Unknown extension: sniff the first bytes, the content is rewound below
+			buf := make([]byte, sniffLen)
+			n, _ := io.ReadFull(content, buf)
+			contentType = DetectContentType(buf[:n])
+		}
+		h["Content-Type"] = []string{contentType}
+	}
+
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		Error(w, "seeker can't seek", StatusInternalServerError)
+		return
+	}
+
+	h["Accept-Ranges"] = []string{"bytes"}
+
+	statusCode := StatusOK
+	start, length := int64(0), size
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		rangeStart, rangeLength, err := parseRange(rangeHeader, size)
+		switch err {
+		case nil:
+			statusCode = StatusPartialContent
+			start, length = rangeStart, rangeLength
+			h["Content-Range"] = []string{fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size)}
+		case errNoOverlap:
+			h["Content-Range"] = []string{fmt.Sprintf("bytes */%d", size)}
+			Error(w, err.Error(), StatusRequestedRangeNotSatisfiable)
+			return
+		}
+	}
+
+	if _, err := content.Seek(start, io.SeekStart); err != nil {
+		Error(w, "seeker can't seek", StatusInternalServerError)
+		return
+	}
+
+	h["Content-Length"] = []string{strconv.FormatInt(length, 10)}
+	w.WriteHeader(statusCode)
+
+	if r.Method != HEAD {
+		// Files too large to be batched with the headers are handed to the
+		// connection, which can send them with sendfile
+		f, isFile := content.(*os.File)
+		rf, canReadFrom := w.(io.ReaderFrom)
+		if isFile && canReadFrom && length > responseBufferSize {
+			rf.ReadFrom(io.LimitReader(f, length))
+		} else {
+			copyContent(r.Context(), w, content, length)
+		}
+	}
+}
+
+// parseHTTPTime parses a date in any of the three formats allowed by HTTP/1.1.
+func parseHTTPTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{TimeFormat, time.RFC850, time.ANSIC} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// copyBufferSize is the size of the chunks used to stream content.
+const copyBufferSize = 32 * 1024
+
+// copyContent copies n bytes from src to w in fixed-size chunks so large
+// files never have to fit in memory. It stops early when ctx is done or a
+// write fails because the client went away.
+func copyContent(ctx context.Context, w io.Writer, src io.Reader, n int64) (int64, error) {
+	buf := make([]byte, copyBufferSize)
+	var written int64
+	for written < n {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		chunk := buf
+		if remaining := n - written; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+
+		nr, err := src.Read(chunk)
+		if nr > 0 {
+			nw, werr := w.Write(chunk[:nr])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// parseRange parses a single-range "bytes=" Range header against a content
+// of the given size and returns the start offset and length of the range.
+// Multi-range requests are reported as errInvalidRange so the caller falls
+// back to serving the whole content.
+func parseRange(s string, size int64) (int64, int64, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, errInvalidRange
+	}
+	spec := strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return 0, 0, errInvalidRange
+	}
+
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, errInvalidRange
+	}
+	startStr, endStr = strings.TrimSpace(startStr), strings.TrimSpace(endStr)
+
+	if startStr == "" {
+		// Suffix range: the last N bytes.
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errInvalidRange
+		}
+		if n == 0 {
+			return 0, 0, errNoOverlap
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errInvalidRange
+	}
+	if start >= size {
+		return 0, 0, errNoOverlap
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end - start + 1, nil
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestServeContent verifies that ServeContent writes the full content with its metadata.
+func TestServeContent(t *testing.T) {
+	modtime := time.Date(2024, 10, 4, 12, 0, 0, 0, time.UTC)
+	req := &Request{Method: GET, URL: &url.URL{Path: "/app.js"}, Header: make(Header)}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeContent(res, req, "app.js", modtime, strings.NewReader("console.log(1)"))
+
+	if res.status != StatusOK {
+		t.Errorf("Expected status %d, got %d", StatusOK, res.status)
+	}
+	if string(res.body) != "console.log(1)" {
+		t.Errorf("Expected full body, got '%s'", string(res.body))
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+		t.Errorf("Expected Content-Type 'text/javascript; charset=utf-8', got '%s'", ct)
+	}
+	if lm := res.Header().Get("Last-Modified"); lm != "Fri, 04 Oct 2024 12:00:00 GMT" {
+		t.Errorf("Expected Last-Modified 'Fri, 04 Oct 2024 12:00:00 GMT', got '%s'", lm)
+	}
+	if cl := res.Header().Get("Content-Length"); cl != "14" {
+		t.Errorf("Expected Content-Length '14', got '%s'", cl)
+	}
+}
+
+// TestServeContentRange verifies that single byte ranges are answered with 206 Partial Content.
+func TestServeContentRange(t *testing.T) {
+	tests := []struct {
+		rangeHeader  string
+		expectedBody string
+		contentRange string
+	}{
+		{"bytes=0-4", "Hello", "bytes 0-4/13"},
+		{"bytes=7-", "World!", "bytes 7-12/13"},
+		{"bytes=-6", "World!", "bytes 7-12/13"},
+		{"bytes=7-100", "World!", "bytes 7-12/13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeHeader, func(t *testing.T) {
+			req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Range": {tt.rangeHeader}}}
+			res := &MockResponseWriter{headers: make(Header)}
+
+			ServeContent(res, req, "hello.txt", time.Time{}, strings.NewReader("Hello, World!"))
+
+			if res.status != StatusPartialContent {
+				t.Errorf("Expected status %d, got %d", StatusPartialContent, res.status)
+			}
+			if string(res.body) != tt.expectedBody {
+				t.Errorf("Expected body '%s', got '%s'", tt.expectedBody, string(res.body))
+			}
+			if cr := res.Header().Get("Content-Range"); cr != tt.contentRange {
+				t.Errorf("Expected Content-Range '%s', got '%s'", tt.contentRange, cr)
+			}
+		})
+	}
+}
+
+// TestServeContentRangeNotSatisfiable verifies that ranges past the end of the content return 416.
+func TestServeContentRangeNotSatisfiable(t *testing.T) {
+	req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Range": {"bytes=50-60"}}}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeContent(res, req, "hello.txt", time.Time{}, strings.NewReader("Hello, World!"))
+
+	if res.status != StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Expected status %d, got %d", StatusRequestedRangeNotSatisfiable, res.status)
+	}
+	if cr := res.Header().Get("Content-Range"); cr != "bytes */13" {
+		t.Errorf("Expected Content-Range 'bytes */13', got '%s'", cr)
+	}
+}
+
+// TestServeContentInvalidRange verifies that unparseable and multi-range headers fall back to the full content.
+func TestServeContentInvalidRange(t *testing.T) {
+	for _, rangeHeader := range []string{"items=0-1", "bytes=a-b", "bytes=0-1,3-4"} {
+		req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Range": {rangeHeader}}}
+		res := &MockResponseWriter{headers: make(Header)}
+
+		ServeContent(res, req, "hello.txt", time.Time{}, strings.NewReader("Hello, World!"))
+
+		if res.status != StatusOK || string(res.body) != "Hello, World!" {
+			t.Errorf("Range %q: expected full 200 response, got %d '%s'", rangeHeader, res.status, string(res.body))
+		}
+	}
+}
+
+// TestServeFile verifies that ServeFile serves files and directory index files from disk.
+func TestServeFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>index</h1>"), 0644); err != nil {
+		t.Fatalf("Failed to create index file: %v", err)
+	}
+
+	req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: make(Header)}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeFile(res, req, dir)
+
+	if res.status != StatusOK {
+		t.Errorf("Expected status %d, got %d", StatusOK, res.status)
+	}
+	if string(res.body) != "<h1>index</h1>" {
+		t.Errorf("Expected index body, got '%s'", string(res.body))
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected Content-Type 'text/html; charset=utf-8', got '%s'", ct)
+	}
+}
+
+// TestServeFileNotFound verifies that ServeFile responds 404 for missing files.
+func TestServeFileNotFound(t *testing.T) {
+	req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: make(Header)}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeFile(res, req, filepath.Join(t.TempDir(), "missing.txt"))
+
+	if res.status != StatusNotFound {
+		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
+	}
+}
+
+// TestServeContentNotModified verifies that If-Modified-Since is answered with 304 when the content is unchanged.
+func TestServeContentNotModified(t *testing.T) {
+	modtime := time.Date(2024, 10, 4, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		since    string
+		expected int
+	}{
+		{"Fri, 04 Oct 2024 12:00:00 GMT", StatusNotModified},
+		{"Sat, 05 Oct 2024 12:00:00 GMT", StatusNotModified},
+		{"Friday, 04-Oct-24 12:00:00 GMT", StatusNotModified},
+		{"Thu, 03 Oct 2024 12:00:00 GMT", StatusOK},
+		{"not a date", StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"If-Modified-Since": {tt.since}}}
+		res := &MockResponseWriter{headers: make(Header)}
+
+		ServeContent(res, req, "hello.txt", modtime, strings.NewReader("Hello, World!"))
+
+		if res.status != tt.expected {
+			t.Errorf("If-Modified-Since %q: expected status %d, got %d", tt.since, tt.expected, res.status)
+		}
+		if tt.expected == StatusNotModified && len(res.body) != 0 {
+			t.Errorf("If-Modified-Since %q: expected empty body, got '%s'", tt.since, string(res.body))
+		}
+	}
+}
+
+// TestServeContentPreconditionFailed verifies that If-Unmodified-Since is answered with 412 when the content changed.
+func TestServeContentPreconditionFailed(t *testing.T) {
+	modtime := time.Date(2024, 10, 4, 12, 0, 0, 0, time.UTC)
+	req := &Request{Method: PUT, URL: &url.URL{Path: "/"}, Header: Header{"If-Unmodified-Since": {"Thu, 03 Oct 2024 12:00:00 GMT"}}}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeContent(res, req, "hello.txt", modtime, strings.NewReader("Hello, World!"))
+
+	if res.status != StatusPreconditionFailed {
+		t.Errorf("Expected status %d, got %d", StatusPreconditionFailed, res.status)
+	}
+}
+
+// TestServeContentMatchAny verifies that "*" matches content without an ETag, as it exists.
+func TestServeContentMatchAny(t *testing.T) {
+	modtime := time.Date(2024, 10, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		method string
+		header Header
+		status int
+	}{
+		{GET, Header{"If-Match": {"*"}}, StatusOK},
+		{GET, Header{"If-None-Match": {"*"}}, StatusNotModified},
+		{PUT, Header{"If-None-Match": {"*"}}, StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		req := &Request{Method: tt.method, URL: &url.URL{Path: "/"}, Header: tt.header}
+		res := &MockResponseWriter{headers: make(Header)}
+
+		ServeContent(res, req, "hello.txt", modtime, strings.NewReader("Hello, World!"))
+
+		if res.status != tt.status {
+			t.Errorf("%s %v: expected status %d, got %d", tt.method, tt.header, tt.status, res.status)
+		}
+	}
+}
+
+// TestServeContentSniffing verifies that content with an unknown extension is sniffed and fully served.
+func TestServeContentSniffing(t *testing.T) {
+	req := &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: make(Header)}
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ServeContent(res, req, "image.bin", time.Time{}, strings.NewReader("\x89PNG\r\n\x1a\nrest"))
+
+	if ct := res.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected sniffed Content-Type 'image/png', got '%s'", ct)
+	}
+	if string(res.body) != "\x89PNG\r\n\x1a\nrest" {
+		t.Errorf("Expected the full body after sniffing, got '%q'", string(res.body))
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// FormFile is a file sent as part of a multipart upload.
+type FormFile struct {
+	// FieldName is the name of the form field holding the file.
+	FieldName string
+	// FileName is the name of the file as seen by the server.
+	FileName string
+	// ContentType is the type of the file. Empty means
+	// application/octet-stream.
+	ContentType string
+	// Content is read as the upload is sent, so large files are streamed
+	// rather than loaded into memory. It is closed afterwards when it is
+	// an io.Closer.
+	Content io.Reader
+}
+
+// quoteEscaper escapes the parameters of a Content-Disposition header.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// PostForm sends a POST request with data encoded as an
+// application/x-www-form-urlencoded body.
+func (c *Client) PostForm(rawURL string, data url.Values) (*ClientResponse, error) {
+	return c.Post(rawURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
+
+// PostMultipart sends a POST request with a multipart/form-data body made
+// of the fields followed by the files. The body is streamed with the
+// chunked transfer coding as it is produced, so it is neither retried nor
+// sent again when redirected with 307 or 308.
+func (c *Client) PostMultipart(rawURL string, fields url.Values, files ...FormFile) (*ClientResponse, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	req, err := NewRequest(POST, rawURL, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.ContentLength = -1
+
+	// The parts are written as the client reads them. Do closes the pipe
+	// when it gives up, which makes this goroutine return.
+	go func() {
+		pw.CloseWithError(writeMultipart(mw, fields, files))
+	}()
+	return c.Do(req)
+}
+
+// writeMultipart writes the fields and files as parts of mw, then its
+// closing boundary.
+func writeMultipart(mw *multipart.Writer, fields url.Values, files []FormFile) error {
+	for name, values := range fields {
+		for _, value := range values {
+			if err := mw.WriteField(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range files {
+		if err := writeFilePart(mw, f); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeFilePart copies a file into a new part of mw.
+func writeFilePart(mw *multipart.Writer, f FormFile) error {
+	if c, ok := f.Content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(f.FieldName), quoteEscaper.Replace(f.FileName)))
+	h.Set("Content-Type", contentType)
+
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f.Content)
+	return err
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestClientPostForm verifies that forms are sent url-encoded.
+func TestClientPostForm(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		values, err := url.ParseQuery(string(body))
+		if err != nil || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			Error(w, "bad form", StatusBadRequest)
+			return
+		}
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(values.Get("name") + "," + values.Get("note")))
+	}))
+
+	res, err := (&Client{}).PostForm("http://"+addr+"/", url.Values{"name": {"lite"}, "note": {"a&b=c"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := readBody(t, res)
+	if res.StatusCode != StatusOK || got != "lite,a&b=c" {
+		t.Errorf("Unexpected response %d '%s'", res.StatusCode, got)
+	}
+}
+
+// TestClientPostMultipart verifies that fields and streamed files arrive as multipart parts.
+func TestClientPostMultipart(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			Error(w, err.Error(), StatusBadRequest)
+			return
+		}
+
+		var summary strings.Builder
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				Error(w, err.Error(), StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(part)
+			fmt.Fprintf(&summary, "%s|%s|%s|%d;", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), len(data))
+		}
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(summary.String()))
+	}))
+
+	large := strings.Repeat("x", 100<<10)
+	res, err := (&Client{}).PostMultipart("http://"+addr+"/upload",
+		url.Values{"title": {"report"}},
+		FormFile{FieldName: "doc", FileName: `q"1".txt`, ContentType: "text/plain", Content: strings.NewReader("hello")},
+		FormFile{FieldName: "blob", FileName: "data.bin", Content: strings.NewReader(large)},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `title|||6;doc|q"1".txt|text/plain|5;blob|data.bin|application/octet-stream|102400;`
+	got := readBody(t, res)
+	if res.StatusCode != StatusOK || got != expected {
+		t.Errorf("Expected '%s', got %d '%s'", expected, res.StatusCode, got)
+	}
+}
+
+// failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+// TestClientPostMultipartReadError verifies that a failing file aborts the upload.
+func TestClientPostMultipartReadError(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(StatusOK)
+	}))
+
+	_, err := (&Client{}).PostMultipart("http://"+addr+"/", nil, FormFile{FieldName: "f", FileName: "f", Content: failingReader{}})
+	if err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+// errMalformedForwarded is returned for Forwarded headers that can't be
+// parsed.
+var errMalformedForwarded = errors.New("http: malformed Forwarded header")
+
+// ForwardedElement is the information a proxy added to the Forwarded
+// header of a request (RFC 7239). Nodes are IP addresses, with an optional
+// port, like "192.0.2.60", "[2001:db8::1]:4711", an obfuscated identifier
+// like "_gazonk", or "unknown".
+type ForwardedElement struct {
+	// For is the node that sent the request to the proxy.
+	For string
+	// By is the node of the proxy that received the request.
+	By string
+	// Host is the Host header the proxy received.
+	Host string
+	// Proto is the scheme the request was received with, "http" or "https".
+	Proto string
+}
+
+// String formats the element as in a Forwarded header, quoting the values
+// that aren't tokens.
+func (e ForwardedElement) String() string {
+	var b strings.Builder
+	for _, pair := range [...]struct{ key, value string }{
+		{"for", e.For}, {"by", e.By}, {"host", e.Host}, {"proto", e.Proto},
+	} {
+		if pair.value == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(pair.key)
+		b.WriteByte('=')
+		b.WriteString(quoteIfNeeded(pair.value))
+	}
+	return b.String()
+}
+
+// ParseForwarded parses the Forwarded headers of h into their elements,
+// from the one of the farthest proxy to the one of the nearest.
+// Parameters other than for, by, host and proto are ignored.
+func ParseForwarded(h Header) ([]ForwardedElement, error) {
+	var elements []ForwardedElement
+	for _, value := range h.Values("Forwarded") {
+		element := ForwardedElement{}
+		empty := true
+		for i := 0; ; {
+			// Pairs are separated by ";", elements by ","
+			for i < len(value) && (value[i] == ' ' || value[i] == '\t') {
+				i++
+			}
+			if i == len(value) || value[i] == ',' {
+				if !empty {
+					elements = append(elements, element)
+				}
+				if i == len(value) {
+					break
+				}
+				element, empty = ForwardedElement{}, true
+				i++
+				continue
+			}
+			if value[i] == ';' {
+				i++
+				continue
+			}
+
+			start := i
+			for i < len(value) && isTokenChar(value[i]) {
+				i++
+			}
+			key := strings.ToLower(value[start:i])
+			if key == "" || i == len(value) || value[i] != '=' {
+				return nil, errMalformedForwarded
+			}
+			i++
+			v, n, ok := parseTokenOrQuoted(value[i:])
+			if !ok {
+				return nil, errMalformedForwarded
+			}
+			i += n
+
+			switch key {
+			case "for":
+				element.For = v
+			case "by":
+				element.By = v
+			case "host":
+				element.Host = v
+			case "proto":
+				element.Proto = strings.ToLower(v)
+			}
+			empty = false
+		}
+	}
+	return elements, nil
+}
+
+// parseTokenOrQuoted parses the token or quoted string at the start of s,
+// returning its value and how many bytes it took.
+func parseTokenOrQuoted(s string) (string, int, bool) {
+	if s == "" {
+		return "", 0, false
+	}
+	if s[0] != '"' {
+		i := 0
+		for i < len(s) && isTokenChar(s[i]) {
+			i++
+		}
+		return s[:i], i, i > 0
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), i + 1, true
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, false
+			}
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// quoteIfNeeded returns value as a token when it is one, and as a quoted
+// string otherwise.
+func quoteIfNeeded(value string) string {
+	token := value != ""
+	for i := 0; i < len(value) && token; i++ {
+		token = isTokenChar(value[i])
+	}
+	if token {
+		return value
+	}
+	return quoteString(value)
+}
+
+// quoteString returns value as a quoted string.
+func quoteString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// forwardedNode formats an IP address as a Forwarded node, bracketing
+// IPv6 addresses.
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
+
+// nodeAddr returns the IP address of a Forwarded node, which is invalid
+// for obfuscated and unknown nodes.
+func nodeAddr(node string) netip.Addr {
+	host := node
+	if strings.HasPrefix(node, "[") {
+		end := strings.IndexByte(node, ']')
+		if end < 0 {
+			return netip.Addr{}
+		}
+		host = node[1:end]
+	} else if i := strings.IndexByte(node, ':'); i >= 0 {
+		host = node[:i]
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// TestParseForwarded verifies that Forwarded headers are parsed into elements, quoted values included.
+func TestParseForwarded(t *testing.T) {
+	h := Header{"Forwarded": {
+		`for=192.0.2.60;proto=HTTP;by=203.0.113.43, For="[2001:db8:cafe::17]:4711"`,
+		`for=_gazonk; host="example.test;8080" ;secret=x`,
+	}}
+	got, err := ParseForwarded(h)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []ForwardedElement{
+		{For: "192.0.2.60", By: "203.0.113.43", Proto: "http"},
+		{For: "[2001:db8:cafe::17]:4711"},
+		{For: "_gazonk", Host: "example.test;8080"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	for _, value := range []string{`for`, `for="unterminated`, `=x`, `for=a b`} {
+		if _, err := ParseForwarded(Header{"Forwarded": {value}}); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+// TestForwardedElementString verifies that values are quoted when they aren't tokens.
+func TestForwardedElementString(t *testing.T) {
+	e := ForwardedElement{For: "[2001:db8::1]:80", By: "unknown", Host: `a"b`, Proto: "https"}
+	want := `for="[2001:db8::1]:80";by=unknown;host="a\"b";proto=https`
+	if e.String() != want {
+		t.Errorf("Expected %s, got %s", want, e.String())
+	}
+	if parsed, _ := ParseForwarded(Header{"Forwarded": {e.String()}}); len(parsed) != 1 || parsed[0] != e {
+		t.Errorf("Expected the element to survive a round trip, got %+v", parsed)
+	}
+}
+
+// TestResolveClientIPForwarded verifies that the Forwarded header takes precedence over X-Forwarded-For.
+func TestResolveClientIPForwarded(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		forwarded, want string
+	}{
+		{`for=198.51.100.4, for="10.0.0.9:80"`, "198.51.100.4"},
+		{`for="[2001:db8::5]:4711"`, "2001:db8::5"},
+		{`for=_hidden`, "10.0.0.1"},
+		{`for="broken`, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		h := Header{"Forwarded": {tt.forwarded}, "X-Forwarded-For": {"1.2.3.4"}}
+		if got := resolveClientIP("10.0.0.1", h, trusted); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.forwarded, tt.want, got)
+		}
+	}
+}
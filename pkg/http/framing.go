@@ -0,0 +1,329 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// errAmbiguousFraming is returned for requests carrying both
+	// Transfer-Encoding and Content-Length, a classic smuggling vector.
+	errAmbiguousFraming = errors.New("request has both Transfer-Encoding and Content-Length")
+	// errInvalidContentLength is returned for malformed or conflicting
+	// Content-Length values.
+	errInvalidContentLength = errors.New("invalid Content-Length")
+	// errUnsupportedTransferEncoding is returned for transfer codings other
+	// than a single chunked coding.
+	errUnsupportedTransferEncoding = errors.New("unsupported Transfer-Encoding")
+	// errMalformedChunk is returned for chunked bodies that can't be decoded.
+	errMalformedChunk = errors.New("malformed chunked encoding")
+)
+
+// checkHeaderName rejects field names that proxies and servers may
+// interpret differently, such as names with whitespace before the colon
+// (RFC 7230 §3.2.4) or control characters: a name must be a token.
+func checkHeaderName(name []byte) error {
+	if len(name) == 0 {
+		return errors.New("malformed header name")
+	}
+	for _, c := range name {
+		if !isTokenChar(c) {
+			return errors.New("malformed header name")
+		}
+	}
+	return nil
+}
+
+// headerValue returns the field value of a header line following its
+// colon, without its line ending and the optional whitespace around it.
+// Values with control characters other than tabs are rejected, unless
+// lenient, which replaces them by spaces.
+func headerValue(raw []byte, lenient bool) (string, error) {
+	raw = bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte{'\n'}), []byte{'\r'})
+	raw = bytes.Trim(raw, " \t")
+
+	i := bytes.IndexFunc(raw, isCTL)
+	if i < 0 {
+		return string(raw), nil
+	}
+	if !lenient {
+		return "", errors.New("invalid header value")
+	}
+	value := []byte(string(raw))
+	for ; i < len(value); i++ {
+		if isCTL(rune(value[i])) {
+			value[i] = ' '
+		}
+	}
+	return string(value), nil
+}
+
+// isCTL reports whether c is a control character not allowed in field
+// values, any but the horizontal tab.
+func isCTL(c rune) bool {
+	return c < ' ' && c != '\t' || c == 0x7f
+}
+
+// requestBody returns the body of a request as framed by its
+// Transfer-Encoding or Content-Length header, and its length, which is -1
+// for chunked bodies. Requests with ambiguous framing are rejected as
+// required by RFC 7230 §3.3.3, and requests with neither header have no body.
+func requestBody(h Header, reader *bufio.Reader) (io.ReadCloser, int64, error) {
+	te, hasTE := h["Transfer-Encoding"]
+	cl, hasCL := h["Content-Length"]
+
+	switch {
+	case hasTE && hasCL:
+		return nil, 0, errAmbiguousFraming
+	case hasTE:
+		if len(te) != 1 || !strings.EqualFold(strings.TrimSpace(te[0]), "chunked") {
+			return nil, 0, errUnsupportedTransferEncoding
+		}
+		return &connBody{r: &chunkedReader{r: reader}, n: -1}, -1, nil
+	case hasCL:
+		n, err := parseContentLength(cl)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &connBody{r: io.LimitReader(reader, n), n: n}, n, nil
+	default:
+		return io.NopCloser(strings.NewReader("")), 0, nil
+	}
+}
+
+// responseBody returns the body of a response to a request with the given
+// method as framed by its headers, and its length, which is -1 when
+// unknown. Bodies without framing last until the server closes the
+// connection, in which case it reports false.
+func responseBody(h Header, reader *bufio.Reader, statusCode int, method string) (io.ReadCloser, int64, bool, error) {
+	if method == HEAD || !bodyAllowed(statusCode) {
+		return io.NopCloser(strings.NewReader("")), 0, true, nil
+	}
+
+	te, hasTE := h["Transfer-Encoding"]
+	cl, hasCL := h["Content-Length"]
+	switch {
+	case hasTE:
+		if len(te) != 1 || !strings.EqualFold(strings.TrimSpace(te[0]), "chunked") {
+			return nil, 0, false, errUnsupportedTransferEncoding
+		}
+		return io.NopCloser(&chunkedReader{r: reader}), -1, true, nil
+	case hasCL:
+		n, err := parseContentLength(cl)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		return io.NopCloser(&lengthReader{r: reader, n: n}), n, true, nil
+	default:
+		return io.NopCloser(reader), -1, false, nil
+	}
+}
+
+// lengthReader reads a body of n bytes, failing with io.ErrUnexpectedEOF
+// when the connection ends before all of them came.
+type lengthReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the body until n bytes were read.
+func (lr *lengthReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	if err == io.EOF && lr.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// connBody is a request body read from the connection. Reads are
+// serialized and fail once the server is done with the request, as
+// handlers that outlive it, such as timed out ones, may still read.
+type connBody struct {
+	mu     sync.Mutex
+	r      io.Reader
+	n      int64 // Bytes left of a Content-Length body, -1 when chunked
+	closed bool
+	onEOF  func() // Called once the whole body was read
+}
+
+// Read reads from the body, unless the request is over.
+func (b *connBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrBodyReadAfterClose
+	}
+	n, err := b.r.Read(p)
+	if b.n > 0 {
+		b.n -= int64(n)
+	}
+	if err == io.EOF && b.onEOF != nil {
+		b.onEOF()
+		b.onEOF = nil
+	}
+	return n, err
+}
+
+// Close does nothing, the server is done with the body once the handler
+// returned.
+func (b *connBody) Close() error {
+	return nil
+}
+
+// stop makes later reads fail, so the next request on the connection is
+// left alone.
+func (b *connBody) stop() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+}
+
+// remaining returns how many bytes are left of a Content-Length body, -1
+// for chunked bodies.
+func (b *connBody) remaining() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.n
+}
+
+// drainBody discards what is left of a request body, up to limit bytes,
+// and reports whether all of it was read, so the next request on the
+// connection can be.
+func drainBody(body io.Reader, limit int64) bool {
+	if b, ok := body.(*connBody); ok {
+		switch n := b.remaining(); {
+		case n == 0:
+			return true
+		case n > limit:
+			// Known to be too long, don't wait for it
+			return false
+		}
+	}
+	n, err := io.CopyN(io.Discard, body, limit+1)
+	return err == io.EOF && n <= limit
+}
+
+// parseContentLength parses the Content-Length header values. Repeated
+// values, as separate headers or a comma separated list, must all agree.
+func parseContentLength(values []string) (int64, error) {
+	length := int64(-1)
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			// ParseInt accepts signs, which Content-Length doesn't
+			if v == "" || strings.TrimLeft(v, "0123456789") != "" {
+				return 0, errInvalidContentLength
+			}
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || (length != -1 && n != length) {
+				return 0, errInvalidContentLength
+			}
+			length = n
+		}
+	}
+	return length, nil
+}
+
+// chunkedReader decodes a chunked request body.
+type chunkedReader struct {
+	r   *bufio.Reader
+	n   int64 // Bytes left in the current chunk
+	err error
+}
+
+// Read reads the data of the current chunk, moving on to the next chunk
+// when it is exhausted.
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	for cr.err == nil && cr.n == 0 {
+		cr.err = cr.nextChunk()
+	}
+	if cr.err != nil {
+		return 0, cr.err
+	}
+
+	if int64(len(p)) > cr.n {
+		p = p[:cr.n]
+	}
+	n, err := cr.r.Read(p)
+	cr.n -= int64(n)
+
+	switch {
+	case err == io.EOF:
+		cr.err = io.ErrUnexpectedEOF
+	case err != nil:
+		cr.err = err
+	case cr.n == 0:
+		// Every chunk's data is followed by a CRLF
+		cr.err = cr.readCRLF()
+	}
+	return n, nil
+}
+
+// nextChunk reads the size line of the next chunk. After the last chunk it
+// discards the trailer and returns io.EOF.
+func (cr *chunkedReader) nextChunk() error {
+	line, err := cr.readLine()
+	if err != nil {
+		return err
+	}
+
+	// Chunk extensions are ignored
+	size, _, _ := strings.Cut(line, ";")
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 16, 64)
+	if err != nil || n < 0 {
+		return errMalformedChunk
+	}
+	if n > 0 {
+		cr.n = n
+		return nil
+	}
+
+	for {
+		line, err := cr.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+}
+
+// readLine reads a CRLF terminated line of at most the reader's buffer size.
+func (cr *chunkedReader) readLine() (string, error) {
+	line, err := cr.r.ReadSlice('\n')
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", errMalformedChunk
+	}
+	if !strings.HasSuffix(string(line), "\r\n") {
+		return "", errMalformedChunk
+	}
+	return string(line[:len(line)-2]), nil
+}
+
+// readCRLF consumes the CRLF that ends a chunk's data.
+func (cr *chunkedReader) readCRLF() error {
+	line, err := cr.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "" {
+		return errMalformedChunk
+	}
+	return nil
+}
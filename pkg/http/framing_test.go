@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestParseRequestSmuggling verifies that known request smuggling vectors are rejected.
+func TestParseRequestSmuggling(t *testing.T) {
+	tests := map[string]string{
+		"CL.TE":                        "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nG",
+		"TE.CL":                        "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\nContent-Length: 3\r\n\r\n1\r\nG\r\n0\r\n\r\n",
+		"conflicting Content-Length":   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 3\r\nContent-Length: 5\r\n\r\nabcde",
+		"conflicting list":             "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 3, 5\r\n\r\nabcde",
+		"signed Content-Length":        "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: +3\r\n\r\nabc",
+		"obsolete line folding":        "GET / HTTP/1.1\r\nHost: a\r\nX-Folded: a\r\n b\r\n\r\n",
+		"space before colon":           "POST / HTTP/1.1\r\nHost: a\r\nContent-Length : 3\r\n\r\nabc",
+		"obfuscated Transfer-Encoding": "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: xchunked\r\n\r\n",
+		"duplicate Transfer-Encoding":  "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\nTransfer-Encoding: identity\r\n\r\n",
+		"chained Transfer-Encoding":    "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: gzip, chunked\r\n\r\n",
+	}
+
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw))); err == nil {
+				t.Errorf("Expected the request to be rejected")
+			}
+		})
+	}
+}
+
+// TestParseRequestContentLength verifies that the body stops at Content-Length.
+func TestParseRequestContentLength(t *testing.T) {
+	raw := "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nContent-Length: 5\r\n\r\nhelloGET /next HTTP/1.1\r\n\r\n"
+	req, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "hello" || req.ContentLength != 5 {
+		t.Errorf("Expected a 5 byte body 'hello', got %d '%s'", req.ContentLength, string(body))
+	}
+}
+
+// TestParseRequestNoBody verifies that requests without framing headers have an empty body.
+func TestParseRequestNoBody(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nHost: a\r\n\r\nleftover"
+	req, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if body, _ := io.ReadAll(req.Body); len(body) != 0 {
+		t.Errorf("Expected an empty body, got '%s'", string(body))
+	}
+}
+
+// TestParseRequestChunked verifies that chunked bodies are decoded, ignoring extensions and trailers.
+func TestParseRequestChunked(t *testing.T) {
+	raw := "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: Chunked\r\n\r\n" +
+		"5;ext=1\r\nhello\r\n7\r\n, world\r\n0\r\nX-Trailer: 1\r\n\r\nGET /next HTTP/1.1\r\n\r\n"
+	reader := bufio.NewReader(strings.NewReader(raw))
+	req, err := parseRequestWithTimeout(reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error reading body: %v", err)
+	}
+	if string(body) != "hello, world" || req.ContentLength != -1 {
+		t.Errorf("Expected chunked body 'hello, world', got %d '%s'", req.ContentLength, string(body))
+	}
+
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "GET /next HTTP/1.1\r\n\r\n" {
+		t.Errorf("Expected the body to end after the trailer, got '%q'", string(rest))
+	}
+}
+
+// TestChunkedReaderMalformed verifies that malformed chunked bodies fail to read.
+func TestChunkedReaderMalformed(t *testing.T) {
+	for _, raw := range []string{"zz\r\nhello\r\n", "5\r\nhelloXX0\r\n\r\n", "5\r\nhel"} {
+		cr := &chunkedReader{r: bufio.NewReader(strings.NewReader(raw))}
+		if _, err := io.ReadAll(cr); err == nil {
+			t.Errorf("%q: expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// FuzzParseRequest checks that the request parser never panics and that
+// the requests it accepts are consistent. Run with:
+//
+//	go test -fuzz=FuzzParseRequest ./pkg/http
+func FuzzParseRequest(f *testing.F) {
+	for _, seed := range []string{
+		"GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"GET /search?q=a%20b HTTP/1.1\r\nHost: localhost\r\nCookie: a=1; b=2\r\n\r\n",
+		"POST /items HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello",
+		"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5;ext=1\r\nhello\r\n0\r\nTrailer: x\r\n\r\n",
+		// Request smuggling vectors
+		"POST / HTTP/1.1\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+		"POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello!",
+		"POST / HTTP/1.1\r\nContent-Length: +5\r\n\r\nhello",
+		"POST / HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n",
+		"POST / HTTP/1.1\r\nTransfer-Encoding : chunked\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: a\r\n folded\r\n\r\n",
+		"GET / HTTP/1.1\nHost: a\n\n",
+		"GET  /  HTTP/1.1\r\n\r\n",
+		// Header lines longer than the read buffer, and bad UTF-8
+		"GET / HTTP/1.1\r\nX-Big: " + strings.Repeat("a", 4200) + "\r\n\r\n",
+		"GET /\xff\xfe HTTP/1.1\r\nX-Bad: \xc3\x28\r\n\r\n",
+		"GET / HTTP/1.0\r\n\r\n",
+		"\r\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		req, err := parseRequestWithTimeout(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return
+		}
+
+		if req.Method == "" || req.URL == nil || req.Proto != "HTTP/1.1" {
+			t.Fatalf("Incomplete request %+v", req)
+		}
+		for key := range req.Header {
+			if CanonicalHeaderKey(key) != key {
+				t.Fatalf("Header key %q is not canonical", key)
+			}
+		}
+
+		// Ambiguous framing must never be accepted
+		_, hasTE := req.Header["Transfer-Encoding"]
+		_, hasCL := req.Header["Content-Length"]
+		if hasTE && hasCL {
+			t.Fatalf("Accepted both Transfer-Encoding and Content-Length")
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err == nil && req.ContentLength >= 0 && int64(len(body)) > req.ContentLength {
+			t.Fatalf("Read %d body bytes, more than the Content-Length %d", len(body), req.ContentLength)
+		}
+	})
+}
+
+// FuzzParseCookies checks that cookie parsing never panics and yields
+// names and values without separators.
+func FuzzParseCookies(f *testing.F) {
+	for _, seed := range []string{
+		"session=abc",
+		"a=1; b=2;c=3",
+		"a==b; =empty; noequals; ;;",
+		"name=\"quoted value\"",
+		"\xff\xfe=\xc3\x28",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, header string) {
+		for _, c := range parseCookies(header) {
+			if strings.Contains(c.Name, ";") || strings.Contains(c.Value, ";") {
+				t.Fatalf("Cookie %q=%q contains a separator", c.Name, c.Value)
+			}
+			if strings.Contains(c.Name, "=") {
+				t.Fatalf("Cookie name %q contains '='", c.Name)
+			}
+		}
+	})
+}
+
+// FuzzHeader checks that headers written by the server are read back as
+// the same single field, so values can't inject headers.
+func FuzzHeader(f *testing.F) {
+	f.Add("Content-Type", "text/html")
+	f.Add("x-custom", "a\r\nSet-Cookie: injected=1")
+	f.Add("X-Multi", "line\nbreak\rhere")
+	f.Add("X-Empty", "")
+	f.Add("bad key", "value")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if canonicalKey([]byte(key)) != CanonicalHeaderKey(key) {
+			t.Fatalf("canonicalKey(%q) disagrees with CanonicalHeaderKey", key)
+		}
+		if key == "" || strings.IndexFunc(key, func(r rune) bool { return r > 0x7f || !isTokenChar(byte(r)) }) >= 0 {
+			return
+		}
+
+		h := make(Header)
+		h.Add(key, value)
+		var buf bytes.Buffer
+		h.Write(&buf)
+		buf.WriteString("\r\n")
+
+		parsed, err := readHeader(bufio.NewReader(&buf), false)
+		if err != nil {
+			t.Fatalf("Failed to read back %q: %v", buf.String(), err)
+		}
+		if len(parsed) != 1 || len(parsed[CanonicalHeaderKey(key)]) != 1 {
+			t.Fatalf("Expected a single %q field, got %v", key, parsed)
+		}
+	})
+}
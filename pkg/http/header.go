@@ -1,17 +1,107 @@
 package http
 
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
 // Header represents an HTTP header.
 type Header map[string][]string
 
-// Set sets a header field.
+// Set sets a header field, replacing any existing values.
 func (h Header) Set(key, value string) {
+	h[CanonicalHeaderKey(key)] = []string{value}
+}
+
+// Add adds a value to a header field, keeping any existing values.
+func (h Header) Add(key, value string) {
+	key = CanonicalHeaderKey(key)
 	h[key] = append(h[key], value)
 }
 
-// Get returns a header field.
+// Get returns the first value of a header field.
 func (h Header) Get(key string) string {
-	if values, ok := h[key]; ok {
+	if values := h[CanonicalHeaderKey(key)]; len(values) > 0 {
 		return values[0]
 	}
 	return ""
 }
+
+// Values returns all values of a header field.
+func (h Header) Values(key string) []string {
+	return h[CanonicalHeaderKey(key)]
+}
+
+// Del deletes a header field.
+func (h Header) Del(key string) {
+	delete(h, CanonicalHeaderKey(key))
+}
+
+// headerNewlineReplacer replaces line breaks inside header values, which
+// would otherwise terminate the field and let a value inject new headers.
+var headerNewlineReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// Write writes the header in wire format, one "Key: value" line per value.
+// Keys are written in sorted order so the output is deterministic, and line
+// breaks inside values are replaced by spaces since obsolete line folding
+// is not allowed in HTTP/1.1 messages, as are other control characters,
+// which recipients reject.
+func (h Header) Write(w io.Writer) error {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			v = strings.TrimSpace(strings.Map(replaceCTL, headerNewlineReplacer.Replace(v)))
+			if _, err := io.WriteString(w, k+": "+v+"\r\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replaceCTL maps the control characters not allowed in field values to
+// spaces.
+func replaceCTL(r rune) rune {
+	if isCTL(r) {
+		return ' '
+	}
+	return r
+}
+
+// CanonicalHeaderKey returns the canonical format of a header key: the
+// first letter and any letter following a hyphen are upper case, the rest
+// are lower case (e.g. "content-type" becomes "Content-Type"). Keys that
+// contain characters not allowed in a header name are returned unchanged.
+func CanonicalHeaderKey(key string) string {
+	for i := 0; i < len(key); i++ {
+		if !isTokenChar(key[i]) {
+			return key
+		}
+	}
+
+	b := []byte(key)
+	upper := true
+	for i, c := range b {
+		if upper && 'a' <= c && c <= 'z' {
+			b[i] = c - ('a' - 'A')
+		} else if !upper && 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+		upper = c == '-'
+	}
+	return string(b)
+}
+
+// isTokenChar reports whether c may appear in a header field name (RFC 7230 token).
+func isTokenChar(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
@@ -1,10 +1,11 @@
 package http
 
 import (
+	"bytes"
 	"testing"
 )
 
-// TestHeaderSet verifies that the Header's Set method correctly adds and updates header values.
+// TestHeaderSet verifies that the Header's Set method inserts values and replaces existing ones.
 func TestHeaderSet(t *testing.T) {
 	headers := make(Header)
 
@@ -14,15 +15,31 @@ func TestHeaderSet(t *testing.T) {
 		t.Errorf("Expected Content-Type to be 'application/json', got %v", headers["Content-Type"])
 	}
 
-	// Test updating the existing header
+	// Test replacing the existing header
 	headers.Set("Content-Type", "text/html")
-	if len(headers["Content-Type"]) != 2 {
-		t.Errorf("Expected Content-Type to have 2 values, got %d", len(headers["Content-Type"]))
+	if len(headers["Content-Type"]) != 1 {
+		t.Errorf("Expected Content-Type to have 1 value, got %d", len(headers["Content-Type"]))
 	}
 
-	// Check if the last value is correct
-	if headers["Content-Type"][1] != "text/html" {
-		t.Errorf("Expected Content-Type[1] to be 'text/html', got '%s'", headers["Content-Type"][1])
+	// Check if the value was replaced
+	if headers["Content-Type"][0] != "text/html" {
+		t.Errorf("Expected Content-Type[0] to be 'text/html', got '%s'", headers["Content-Type"][0])
+	}
+}
+
+// TestHeaderAdd verifies that the Header's Add method appends values to existing ones.
+func TestHeaderAdd(t *testing.T) {
+	headers := make(Header)
+
+	headers.Add("Set-Cookie", "a=1")
+	headers.Add("Set-Cookie", "b=2")
+
+	if len(headers["Set-Cookie"]) != 2 {
+		t.Fatalf("Expected Set-Cookie to have 2 values, got %d", len(headers["Set-Cookie"]))
+	}
+
+	if headers["Set-Cookie"][0] != "a=1" || headers["Set-Cookie"][1] != "b=2" {
+		t.Errorf("Expected Set-Cookie to be [a=1 b=2], got %v", headers["Set-Cookie"])
 	}
 }
 
@@ -31,8 +48,8 @@ func TestHeaderGet(t *testing.T) {
 	headers := make(Header)
 
 	// Add headers
-	headers.Set("X-Custom-Header", "Value1")
-	headers.Set("X-Custom-Header", "Value2")
+	headers.Add("X-Custom-Header", "Value1")
+	headers.Add("X-Custom-Header", "Value2")
 
 	// Get the header value (should return the first one)
 	value := headers.Get("X-Custom-Header")
@@ -52,8 +69,8 @@ func TestMultipleHeaders(t *testing.T) {
 	headers := make(Header)
 
 	// Add multiple values to a header
-	headers.Set("Accept", "text/html")
-	headers.Set("Accept", "application/json")
+	headers.Add("Accept", "text/html")
+	headers.Add("Accept", "application/json")
 
 	// Verify that both values are present
 	if len(headers["Accept"]) != 2 {
@@ -66,25 +83,22 @@ func TestMultipleHeaders(t *testing.T) {
 	}
 }
 
-// TestHeaderOverwrite verifies that the last value is correctly appended and does not overwrite previous values.
+// TestHeaderOverwrite verifies that Set discards values previously added with Add.
 func TestHeaderOverwrite(t *testing.T) {
 	headers := make(Header)
 
-	// Add a header and then another with the same name
-	headers.Set("Cache-Control", "no-cache")
+	// Add two values and then overwrite them
+	headers.Add("Cache-Control", "no-cache")
+	headers.Add("Cache-Control", "no-store")
 	headers.Set("Cache-Control", "max-age=3600")
 
-	// Verify that both values are present
-	if len(headers["Cache-Control"]) != 2 {
-		t.Errorf("Expected 2 values for 'Cache-Control', got %d", len(headers["Cache-Control"]))
+	// Verify that only the last value is present
+	if len(headers["Cache-Control"]) != 1 {
+		t.Errorf("Expected 1 value for 'Cache-Control', got %d", len(headers["Cache-Control"]))
 	}
 
-	// Verify that the values are correct
-	expectedValues := []string{"no-cache", "max-age=3600"}
-	for i, v := range expectedValues {
-		if headers["Cache-Control"][i] != v {
-			t.Errorf("Expected 'Cache-Control[%d]' to be '%s', got '%s'", i, v, headers["Cache-Control"][i])
-		}
+	if headers.Get("Cache-Control") != "max-age=3600" {
+		t.Errorf("Expected 'Cache-Control' to be 'max-age=3600', got '%s'", headers.Get("Cache-Control"))
 	}
 }
 
@@ -103,3 +117,89 @@ func TestEmptyHeader(t *testing.T) {
 		t.Errorf("Expected empty header map, got %v", headers)
 	}
 }
+
+// TestHeaderCaseInsensitive verifies that header keys are canonicalized on Set, Add and Get.
+func TestHeaderCaseInsensitive(t *testing.T) {
+	headers := make(Header)
+
+	headers.Set("content-type", "application/json")
+	headers.Add("X-REQUEST-ID", "1")
+
+	if headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected 'application/json', got '%s'", headers.Get("Content-Type"))
+	}
+	if headers.Get("CONTENT-TYPE") != "application/json" {
+		t.Errorf("Expected 'application/json', got '%s'", headers.Get("CONTENT-TYPE"))
+	}
+	if _, ok := headers["X-Request-Id"]; !ok {
+		t.Errorf("Expected canonical key 'X-Request-Id', got %v", headers)
+	}
+}
+
+// TestHeaderValuesAndDel verifies that Values returns every value and Del removes the field.
+func TestHeaderValuesAndDel(t *testing.T) {
+	headers := make(Header)
+
+	headers.Add("Accept", "text/html")
+	headers.Add("accept", "application/json")
+
+	values := headers.Values("ACCEPT")
+	if len(values) != 2 || values[0] != "text/html" || values[1] != "application/json" {
+		t.Errorf("Expected [text/html application/json], got %v", values)
+	}
+
+	headers.Del("accept")
+	if len(headers) != 0 {
+		t.Errorf("Expected empty header map after Del, got %v", headers)
+	}
+}
+
+// TestCanonicalHeaderKey verifies the canonical format of header keys.
+func TestCanonicalHeaderKey(t *testing.T) {
+	tests := map[string]string{
+		"content-type":     "Content-Type",
+		"X-FORWARDED-FOR":  "X-Forwarded-For",
+		"www-authenticate": "Www-Authenticate",
+		"bad key":          "bad key",
+		"":                 "",
+	}
+
+	for input, expected := range tests {
+		if actual := CanonicalHeaderKey(input); actual != expected {
+			t.Errorf("CanonicalHeaderKey(%q): expected '%s', got '%s'", input, expected, actual)
+		}
+	}
+}
+
+// TestHeaderWrite verifies that Write produces sorted, byte-for-byte reproducible output.
+func TestHeaderWrite(t *testing.T) {
+	headers := make(Header)
+	headers.Set("X-Zeta", "last")
+	headers.Add("Set-Cookie", "a=1")
+	headers.Add("Set-Cookie", "b=2")
+	headers.Set("Content-Type", "text/plain")
+
+	var buf bytes.Buffer
+	if err := headers.Write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "Content-Type: text/plain\r\nSet-Cookie: a=1\r\nSet-Cookie: b=2\r\nX-Zeta: last\r\n"
+	if buf.String() != expected {
+		t.Errorf("Expected '%q', got '%q'", expected, buf.String())
+	}
+}
+
+// TestHeaderWriteStripsNewlines verifies that line breaks in values can't inject extra header lines.
+func TestHeaderWriteStripsNewlines(t *testing.T) {
+	headers := make(Header)
+	headers.Set("X-Note", "first\r\nInjected: yes")
+
+	var buf bytes.Buffer
+	headers.Write(&buf)
+
+	expected := "X-Note: first Injected: yes\r\n"
+	if buf.String() != expected {
+		t.Errorf("Expected '%q', got '%q'", expected, buf.String())
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package health
+
+import (
+	"context"
+	"errors"
+)
+
+// DiskSpace returns a Checker that fails when the file system holding path
+// has less than minFree bytes available. It is only supported on Unix
+// systems; elsewhere the check always fails.
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return errors.New("disk space checks are not supported on this platform")
+	})
+}
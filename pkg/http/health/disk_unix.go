@@ -0,0 +1,25 @@
+//go:build unix
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace returns a Checker that fails when the file system holding path
+// has less than minFree bytes available.
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return err
+		}
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minFree {
+			return fmt.Errorf("%d bytes free on %s, need %d", free, path, minFree)
+		}
+		return nil
+	})
+}
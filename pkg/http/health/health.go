@@ -0,0 +1,213 @@
+// Package health serves liveness and readiness endpoints for http-lite
+// servers. Liveness (/healthz) reports whether the process is working at
+// all; readiness (/readyz) reports whether it should receive traffic, and
+// fails before the server starts and while it shuts down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// Checker checks a dependency, such as a database or free disk space.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker, e.g. CheckerFunc(db.PingContext).
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Status values reported by the endpoints.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the JSON body served by the endpoints.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Health runs the registered checks for the liveness and readiness endpoints.
+type Health struct {
+	// Timeout bounds how long the checks of a single request may take.
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  map[string]Checker
+	readiness map[string]Checker
+	ready     bool
+	server    *http.Server
+}
+
+// New creates a Health that is ready and has no checks.
+func New() *Health {
+	return &Health{
+		Timeout:   5 * time.Second,
+		liveness:  make(map[string]Checker),
+		readiness: make(map[string]Checker),
+		ready:     true,
+	}
+}
+
+// AddLivenessCheck registers a check served by /healthz. Keep these to
+// failures a restart would fix; a down dependency belongs in readiness.
+func (h *Health) AddLivenessCheck(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness[name] = c
+}
+
+// AddReadinessCheck registers a check served by /readyz.
+func (h *Health) AddReadinessCheck(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness[name] = c
+}
+
+// SetReady marks the application as ready or not ready to serve traffic,
+// e.g. while caches warm up.
+func (h *Health) SetReady(ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready = ready
+}
+
+// Attach ties readiness to the server's lifecycle: not ready until it
+// starts accepting connections, and not ready again once it shuts down.
+func (h *Health) Attach(s *http.Server) {
+	h.mu.Lock()
+	h.server = s
+	h.ready = false
+	h.mu.Unlock()
+
+	s.OnStart(func() { h.SetReady(true) })
+	s.OnShutdown(func() { h.SetReady(false) })
+}
+
+// Register adds the /healthz and /readyz routes to the mux.
+func (h *Health) Register(mux *http.ServeMux) {
+	methods := []string{http.GET, http.HEAD}
+	mux.AddRoute("/healthz", methods, h.ServeLiveness)
+	mux.AddRoute("/readyz", methods, h.ServeReadiness)
+}
+
+// ServeLiveness runs the liveness checks and writes the report.
+func (h *Health) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	// The checks are copied, as they may be added to while they run
+	h.mu.RLock()
+	checks := maps.Clone(h.liveness)
+	h.mu.RUnlock()
+
+	h.serve(w, r, checks, true)
+}
+
+// ServeReadiness runs the readiness checks and writes the report. It fails
+// without running them when the application isn't ready.
+func (h *Health) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	checks := maps.Clone(h.readiness)
+	ready := h.ready && (h.server == nil || !h.server.ShuttingDown())
+	h.mu.RUnlock()
+
+	h.serve(w, r, checks, ready)
+}
+
+// serve runs checks concurrently and writes the aggregated report, with
+// 200 when everything passed and 503 otherwise.
+func (h *Health) serve(w http.ResponseWriter, r *http.Request, checks map[string]Checker, ready bool) {
+	report := Report{Status: StatusFail}
+	if ready {
+		report = h.run(r.Context(), checks)
+	}
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	data, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method != http.HEAD {
+		w.Write(data)
+	}
+}
+
+// run executes every check concurrently within the timeout.
+func (h *Health) run(ctx context.Context, checks map[string]Checker) Report {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, checks[name])
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK}
+	if len(names) > 0 {
+		report.Checks = make(map[string]Result, len(names))
+	}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// runCheck runs a single check, giving up when ctx is done even if the
+// check ignores it.
+func runCheck(ctx context.Context, c Checker) Result {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	return Result{Status: StatusOK}
+}
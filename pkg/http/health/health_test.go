@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+	"github.com/Johanx22x/http-lite/pkg/http/httplitetest"
+)
+
+// serve sends a GET request for path through the mux and decodes the report.
+func serve(t *testing.T, mux *http.ServeMux, path string) (int, Report) {
+	rec := httplitetest.NewRecorder()
+	mux.ServeHTTP(rec, httplitetest.NewRequest(http.GET, path, nil))
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: invalid JSON body '%s': %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+// TestHealthChecks verifies the aggregated report of passing and failing checks.
+func TestHealthChecks(t *testing.T) {
+	h := New()
+	h.AddLivenessCheck("goroutines", CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+	h.AddReadinessCheck("cache", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	mux := http.NewServeMux(nil)
+	h.Register(mux)
+
+	status, report := serve(t, mux, "/healthz")
+	if status != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("Expected healthy liveness, got %d %+v", status, report)
+	}
+
+	status, report = serve(t, mux, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Errorf("Expected failing readiness, got %d %+v", status, report)
+	}
+	if report.Checks["db"].Error != "connection refused" || report.Checks["cache"].Status != StatusOK {
+		t.Errorf("Unexpected check results %+v", report.Checks)
+	}
+}
+
+// TestHealthTimeout verifies that slow checks fail once the timeout expires.
+func TestHealthTimeout(t *testing.T) {
+	h := New()
+	h.Timeout = 10 * time.Millisecond
+	h.AddReadinessCheck("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	mux := http.NewServeMux(nil)
+	h.Register(mux)
+
+	start := time.Now()
+	status, report := serve(t, mux, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Checks["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected the slow check to time out, got %d %+v", status, report)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected the request to return at the timeout")
+	}
+}
+
+// TestHealthConcurrentChecks verifies that checks can be added while the endpoints serve requests.
+func TestHealthConcurrentChecks(t *testing.T) {
+	h := New()
+	mux := http.NewServeMux(nil)
+	h.Register(mux)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			check := CheckerFunc(func(ctx context.Context) error { return nil })
+			h.AddLivenessCheck(fmt.Sprint("live-", i), check)
+			h.AddReadinessCheck(fmt.Sprint("ready-", i), check)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		for _, path := range []string{"/healthz", "/readyz"} {
+			rec := httplitetest.NewRecorder()
+			mux.ServeHTTP(rec, httplitetest.NewRequest(http.GET, path, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: expected %d, got %d", path, http.StatusOK, rec.Code)
+			}
+		}
+	}
+	wg.Wait()
+
+	if status, report := serve(t, mux, "/readyz"); status != http.StatusOK || len(report.Checks) != 100 {
+		t.Errorf("Expected the 100 readiness checks to pass, got %d %+v", status, report)
+	}
+}
+
+// TestHealthAttach verifies that readiness follows the server lifecycle.
+func TestHealthAttach(t *testing.T) {
+	h := New()
+	mux := http.NewServeMux(nil)
+	h.Register(mux)
+
+	server := http.NewServer("", mux)
+	h.Attach(server)
+
+	if status, _ := serve(t, mux, "/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready before start, got %d", status)
+	}
+
+	h.SetReady(true)
+	if status, _ := serve(t, mux, "/readyz"); status != http.StatusOK {
+		t.Errorf("Expected ready, got %d", status)
+	}
+
+	server.Shutdown(context.Background())
+	if status, _ := serve(t, mux, "/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready after shutdown, got %d", status)
+	}
+	if status, _ := serve(t, mux, "/healthz"); status != http.StatusOK {
+		t.Errorf("Expected liveness to keep passing, got %d", status)
+	}
+}
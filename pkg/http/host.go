@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net"
+	"strings"
+)
+
+// AllowHosts returns middleware answering the requests for hosts outside
+// the allowed ones, for servers without Server.AllowedHosts, such as ones
+// run by net/http through the stdhttp adapter. See Server.AllowedHosts for
+// how hosts are matched.
+func AllowHosts(hosts ...string) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if code := checkHost(r.Header.Get("Host"), hosts); code != 0 {
+				Error(w, StatusText(code), code)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// checkHost returns the status answering a request for host, 400 Bad
+// Request without a host and 421 Misdirected Request for one that isn't
+// allowed, or zero when it is allowed.
+func checkHost(host string, allowed []string) int {
+	host = normalizeHost(host)
+	if host == "" {
+		return StatusBadRequest
+	}
+	for _, pattern := range allowed {
+		if matchHost(normalizeHost(pattern), host) {
+			return 0
+		}
+	}
+	return StatusMisdirectedRequest
+}
+
+// matchHost reports whether the normalized host matches pattern. A
+// "*.example.com" pattern matches the subdomains of example.com, but not
+// example.com itself.
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return pattern == host
+}
+
+// normalizeHost returns host in lower case, without its port, the brackets
+// of an IPv6 address and the trailing dot of a fully qualified name.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	} else {
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	return strings.TrimSuffix(host, ".")
+}
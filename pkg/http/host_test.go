@@ -0,0 +1,72 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestCheckHost verifies how hosts are matched against the allowed ones.
+func TestCheckHost(t *testing.T) {
+	allowed := []string{"example.com", "*.Example.org", "[::1]", "127.0.0.1"}
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"example.com", 0},
+		{"EXAMPLE.com:8080", 0},
+		{"example.com.", 0},
+		{"www.example.com", StatusMisdirectedRequest},
+		{"api.example.org", 0},
+		{"a.b.example.org:443", 0},
+		{"example.org", StatusMisdirectedRequest},
+		{"evilexample.org", StatusMisdirectedRequest},
+		{"[::1]:8080", 0},
+		{"127.0.0.1:80", 0},
+		{"attacker.test", StatusMisdirectedRequest},
+		{"", StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := checkHost(tt.host, allowed); got != tt.want {
+			t.Errorf("%q: expected %d, got %d", tt.host, tt.want, got)
+		}
+	}
+}
+
+// TestAllowHosts verifies that the middleware only passes on requests for allowed hosts.
+func TestAllowHosts(t *testing.T) {
+	handler := AllowHosts("example.com")(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+	})
+
+	for host, want := range map[string]int{"example.com": StatusOK, "rebound.test": StatusMisdirectedRequest, "": StatusBadRequest} {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Host": {host}}})
+		if res.status != want {
+			t.Errorf("Host %q: expected %d, got %d", host, want, res.status)
+		}
+	}
+}
+
+// TestServerAllowedHosts verifies that the server rejects requests for other hosts before the handler.
+func TestServerAllowedHosts(t *testing.T) {
+	called := false
+	server := NewServer(":8080", HandlerFunc(func(w ResponseWriter, r *Request) {
+		called = true
+	}))
+	server.AllowedHosts = []string{"*.example.com"}
+
+	conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+		reader: bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+	}}
+	server.handleConn(context.Background(), conn)
+
+	if called {
+		t.Error("Expected the handler not to be called")
+	}
+	if got := conn.written.String(); !strings.HasPrefix(got, "HTTP/1.1 421 Misdirected Request\r\n") {
+		t.Errorf("Expected 421 Misdirected Request, got %q", got)
+	}
+}
@@ -0,0 +1,192 @@
+// Package httplitetest provides a response recorder and request builder
+// for testing http-lite handlers without a network connection:
+//
+//	rec := httplitetest.NewRecorder()
+//	handler(rec, httplitetest.NewRequest(http.GET, "/users/1", nil))
+//	rec.AssertStatus(t, http.StatusOK)
+//	rec.AssertHeader(t, "Content-Type", "application/json")
+package httplitetest
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// ResponseRecorder is an http.ResponseWriter that records the response
+// written by a handler.
+type ResponseRecorder struct {
+	// Code is the status set by WriteHeader. It is 200 when the handler
+	// wrote nothing or only a body.
+	Code int
+	// HeaderMap holds the headers set by the handler.
+	HeaderMap http.Header
+	// Body holds the bytes written by the handler.
+	Body *bytes.Buffer
+	// Flushed is whether the handler flushed the response.
+	Flushed bool
+
+	wroteHeader bool
+}
+
+// NewRecorder returns an initialized ResponseRecorder.
+func NewRecorder() *ResponseRecorder {
+	return &ResponseRecorder{
+		Code:      http.StatusOK,
+		HeaderMap: make(http.Header),
+		Body:      new(bytes.Buffer),
+	}
+}
+
+// Header returns the response headers.
+func (r *ResponseRecorder) Header() http.Header {
+	return r.HeaderMap
+}
+
+// Write records the data as part of the body.
+func (r *ResponseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.Body.Write(data)
+}
+
+// WriteHeader records the status code. Like the server, only the first
+// call has an effect, and none after the body was written.
+func (r *ResponseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.Code = statusCode
+	r.wroteHeader = true
+}
+
+// SetCookie adds a Set-Cookie header, dropping invalid cookies as the
+// server does, or panicking with http.StrictCookies.
+func (r *ResponseRecorder) SetCookie(c *http.Cookie) {
+	if err := c.Valid(); err != nil {
+		if http.StrictCookies {
+			panic(err)
+		}
+		return
+	}
+	r.HeaderMap.Add("Set-Cookie", c.String())
+}
+
+// DeleteCookie adds a Set-Cookie header expiring the named cookie.
+func (r *ResponseRecorder) DeleteCookie(name string) {
+	c := &http.Cookie{Name: name, Value: "", MaxAge: -1}
+	r.HeaderMap.Add("Set-Cookie", c.String())
+}
+
+// Flush records that the response was flushed.
+func (r *ResponseRecorder) Flush() {
+	r.wroteHeader = true
+	r.Flushed = true
+}
+
+// Cookies returns the cookies set by the handler, in the order they were
+// set.
+func (r *ResponseRecorder) Cookies() []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, line := range r.HeaderMap.Values("Set-Cookie") {
+		if c, err := http.ParseSetCookie(line); err == nil {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+// AssertStatus fails the test when the recorded status isn't code.
+func (r *ResponseRecorder) AssertStatus(t testing.TB, code int) {
+	t.Helper()
+	if r.Code != code {
+		t.Errorf("Expected status %d, got %d", code, r.Code)
+	}
+}
+
+// AssertHeader fails the test when the recorded header key doesn't have
+// the value.
+func (r *ResponseRecorder) AssertHeader(t testing.TB, key, value string) {
+	t.Helper()
+	if got := r.HeaderMap.Get(key); got != value {
+		t.Errorf("Expected header %s '%s', got '%s'", key, value, got)
+	}
+}
+
+// AssertBody fails the test when the recorded body isn't body.
+func (r *ResponseRecorder) AssertBody(t testing.TB, body string) {
+	t.Helper()
+	if got := r.Body.String(); got != body {
+		t.Errorf("Expected body '%s', got '%s'", body, got)
+	}
+}
+
+// AssertCookie fails the test when no cookie with the name and value was
+// set. It returns the cookie, or nil when it wasn't found.
+func (r *ResponseRecorder) AssertCookie(t testing.TB, name, value string) *http.Cookie {
+	t.Helper()
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			if c.Value != value {
+				t.Errorf("Expected cookie %s '%s', got '%s'", name, value, c.Value)
+			}
+			return c
+		}
+	}
+	t.Errorf("Expected cookie %s to be set", name)
+	return nil
+}
+
+// NewRequest returns a request for target as the server would pass it to
+// a handler. target is a path such as "/search?q=x" or an absolute URL,
+// whose host becomes the Host header; it defaults to example.com. The
+// body, which may be nil, gets a Content-Length when its size is known and
+// is marked as chunked otherwise.
+// NewRequest panics when target can't be parsed, as it is meant for tests.
+func NewRequest(method, target string, body io.Reader) *http.Request {
+	u, err := url.Parse(target)
+	if err != nil {
+		panic("httplitetest: invalid target " + strconv.Quote(target) + ": " + err.Error())
+	}
+
+	host := u.Host
+	if host == "" {
+		host = "example.com"
+	}
+	req := &http.Request{
+		Method: method,
+		URL:    &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:  "HTTP/1.1",
+		Header: http.Header{"Host": {host}},
+	}
+
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	switch b := body.(type) {
+	case *bytes.Buffer:
+		req.ContentLength = int64(b.Len())
+	case *bytes.Reader:
+		req.ContentLength = int64(b.Len())
+	case *strings.Reader:
+		req.ContentLength = int64(b.Len())
+	default:
+		req.ContentLength = -1
+	}
+	if req.ContentLength > 0 {
+		req.Header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	} else if req.ContentLength < 0 {
+		req.Header.Set("Transfer-Encoding", "chunked")
+	}
+
+	rc, ok := body.(io.ReadCloser)
+	if !ok {
+		rc = io.NopCloser(body)
+	}
+	req.Body = rc
+	return req
+}
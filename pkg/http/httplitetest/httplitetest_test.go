@@ -0,0 +1,109 @@
+package httplitetest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// TestRecorder verifies that the recorder captures what a handler writes.
+func TestRecorder(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.SetCookie(&http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+		w.DeleteCookie("old")
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusInternalServerError) // Ignored
+		w.Write([]byte("created"))
+	}
+
+	rec := NewRecorder()
+	handler(rec, NewRequest(http.POST, "/items", nil))
+
+	rec.AssertStatus(t, http.StatusCreated)
+	rec.AssertHeader(t, "Content-Type", "text/plain")
+	rec.AssertBody(t, "created")
+
+	if c := rec.AssertCookie(t, "session", "abc"); c != nil && (c.Path != "/" || !c.HttpOnly) {
+		t.Errorf("Expected the cookie attributes to be parsed, got %+v", c)
+	}
+	if c := rec.AssertCookie(t, "old", ""); c != nil && c.MaxAge != -1 {
+		t.Errorf("Expected the deleted cookie to have a MaxAge of -1, got %d", c.MaxAge)
+	}
+}
+
+// TestRecorderImplicitStatus verifies that writing a body implies 200 OK.
+func TestRecorderImplicitStatus(t *testing.T) {
+	rec := NewRecorder()
+	rec.Write([]byte("ok"))
+	rec.WriteHeader(http.StatusNotFound)
+
+	rec.AssertStatus(t, http.StatusOK)
+}
+
+// TestAssertionsFail verifies that assertions report mismatches.
+func TestAssertionsFail(t *testing.T) {
+	rec := NewRecorder()
+	rec.WriteHeader(http.StatusNotFound)
+
+	ft := &fakeT{TB: t}
+	rec.AssertStatus(ft, http.StatusOK)
+	rec.AssertHeader(ft, "X-Missing", "value")
+	rec.AssertBody(ft, "body")
+	rec.AssertCookie(ft, "missing", "")
+	if ft.failures != 4 {
+		t.Errorf("Expected 4 failures, got %d", ft.failures)
+	}
+}
+
+// fakeT counts failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failures int
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.failures++
+}
+
+// TestNewRequest verifies the requests built for handlers.
+func TestNewRequest(t *testing.T) {
+	req := NewRequest(http.GET, "/search?q=lite", nil)
+	if req.URL.Path != "/search" || req.URL.Query().Get("q") != "lite" {
+		t.Errorf("Unexpected URL %s", req.URL)
+	}
+	if req.Header.Get("Host") != "example.com" || req.Body == nil || req.ContentLength != 0 {
+		t.Errorf("Unexpected request %+v", req)
+	}
+
+	req = NewRequest(http.POST, "http://api.test/items", strings.NewReader("payload"))
+	if req.Header.Get("Host") != "api.test" || req.URL.Path != "/items" {
+		t.Errorf("Expected the host to come from the URL, got %+v", req)
+	}
+	if req.ContentLength != 7 || req.Header.Get("Content-Length") != "7" {
+		t.Errorf("Expected a Content-Length of 7, got %d", req.ContentLength)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "payload" {
+		t.Errorf("Expected the body, got '%s'", body)
+	}
+
+	req = NewRequest(http.POST, "/", io.MultiReader(bytes.NewReader(nil)))
+	if req.ContentLength != -1 || req.Header.Get("Transfer-Encoding") != "chunked" {
+		t.Errorf("Expected a body of unknown length to be chunked, got %d", req.ContentLength)
+	}
+}
+
+// TestNewRequestInvalidTarget verifies that unparsable targets panic.
+func TestNewRequestInvalidTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+	NewRequest(http.GET, "%zz", nil)
+}
@@ -0,0 +1,64 @@
+package httplitetest
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// closeTimeout is how long Close waits for in-flight requests.
+const closeTimeout = 5 * time.Second
+
+// Server is a real http-lite server listening on a random loopback port,
+// for end-to-end tests of parsing, keep-alive, cookies and streaming.
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:41235,
+	// without a trailing slash.
+	URL string
+	// Config is the running server.
+	Config *http.Server
+
+	listener net.Listener
+	client   *http.Client
+	done     chan struct{}
+}
+
+// NewServer starts a server for handler. Callers should Close it when
+// done, typically with defer or t.Cleanup.
+func NewServer(handler http.Handler) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("httplitetest: failed to listen on a port: " + err.Error())
+	}
+
+	s := &Server{
+		URL:      "http://" + ln.Addr().String(),
+		Config:   http.NewServer(ln.Addr().String(), handler),
+		listener: ln,
+		client:   &http.Client{Timeout: closeTimeout},
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		s.Config.Serve(ln)
+	}()
+	return s
+}
+
+// Client returns a Client configured for requests to the server.
+func (s *Server) Client() *http.Client {
+	return s.client
+}
+
+// Close shuts the server down, waiting for in-flight requests for a
+// few seconds before closing their connections.
+func (s *Server) Close() {
+	s.client.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	s.Config.Shutdown(ctx)
+	<-s.done
+}
@@ -0,0 +1,55 @@
+package httplitetest
+
+import (
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// TestServer verifies a round trip through the real server and client.
+func TestServer(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux(nil)
+	mux.AddRoute("/count", []string{http.GET}, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.SetCookie(&http.Cookie{Name: "visits", Value: strconv.Itoa(requests)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(r.Header.Get("Host")))
+	})
+
+	s := NewServer(mux)
+	defer s.Close()
+
+	for i := 1; i <= 2; i++ {
+		res, err := s.Client().Get(s.URL + "/count")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK || string(body) != s.URL[len("http://"):] {
+			t.Errorf("Unexpected response %d '%s'", res.StatusCode, body)
+		}
+		if cookie := res.Header.Get("Set-Cookie"); cookie != "visits="+strconv.Itoa(i) {
+			t.Errorf("Unexpected cookie '%s'", cookie)
+		}
+	}
+
+	// Both requests were served on the same kept-alive connection
+	if open := s.Config.OpenConnections(); open != 1 {
+		t.Errorf("Expected one open connection, got %d", open)
+	}
+}
+
+// TestServerClose verifies that requests fail once the server is closed.
+func TestServerClose(t *testing.T) {
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	s.Close()
+
+	if _, err := s.Client().Get(s.URL + "/"); err == nil {
+		t.Error("Expected an error after Close")
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHSTSMaxAge is how long browsers remember to use HTTPS when
+// HTTPSOptions.MaxAge is zero.
+const defaultHSTSMaxAge = 365 * 24 * time.Hour
+
+// HTTPSOptions configures the RequireHTTPS middleware.
+type HTTPSOptions struct {
+	// Port is the port of the HTTPS server requests are redirected to.
+	// Zero means 443, which is left out of the redirect.
+	Port int
+
+	// MaxAge is how long browsers only use HTTPS for the host, sent in the
+	// Strict-Transport-Security header of secure responses. Zero means one
+	// year, a negative value omits the header.
+	MaxAge time.Duration
+	// IncludeSubdomains applies the policy to the subdomains of the host.
+	IncludeSubdomains bool
+	// Preload asks for the host to be included in the browsers' preload
+	// lists. It requires IncludeSubdomains and a MaxAge of a year or more.
+	Preload bool
+
+	// TrustedProxies are the addresses of the proxies terminating TLS in
+	// front of the server. The scheme they forwarded, with the Forwarded
+	// header or X-Forwarded-Proto, tells whether the client used HTTPS.
+	// These headers are ignored from other peers.
+	TrustedProxies []netip.Prefix
+}
+
+// RequireHTTPS returns middleware redirecting the requests received over
+// plain HTTP to their https:// equivalent with 308 Permanent Redirect, so
+// the method and body are kept, and adding a Strict-Transport-Security
+// header to the responses of secure ones:
+//
+//	mux.Use(http.RequireHTTPS(http.HTTPSOptions{IncludeSubdomains: true}))
+//
+// Requests without a Host header get 400 Bad Request, as there is nowhere
+// to redirect them to.
+func RequireHTTPS(opts HTTPSOptions) Middleware {
+	hsts := opts.hstsValue()
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if isSecure(r, opts.TrustedProxies) {
+				if hsts != "" {
+					w.Header().Set("Strict-Transport-Security", hsts)
+				}
+				next(w, r)
+				return
+			}
+
+			host := normalizeHost(r.Header.Get("Host"))
+			if host == "" {
+				Error(w, StatusText(StatusBadRequest), StatusBadRequest)
+				return
+			}
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+			if opts.Port != 0 && opts.Port != 443 {
+				host += ":" + strconv.Itoa(opts.Port)
+			}
+			w.Header()["Location"] = []string{"https://" + host + r.URL.RequestURI()}
+			w.WriteHeader(StatusPermanentRedirect)
+		}
+	}
+}
+
+// hstsValue returns the Strict-Transport-Security header of the options,
+// or "" when it is disabled.
+func (opts HTTPSOptions) hstsValue() string {
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultHSTSMaxAge
+	}
+	if maxAge < 0 {
+		return ""
+	}
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if opts.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if opts.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// isSecure reports whether the client sent the request over HTTPS, either
+// to the server itself or to the trusted proxy it came through.
+func isSecure(r *Request, trusted []netip.Prefix) bool {
+	if r.TLS != nil {
+		return true
+	}
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if len(trusted) == 0 || !isTrusted(peer, trusted) {
+		return false
+	}
+
+	// The nearest proxy tells how it was reached
+	if _, ok := r.Header["Forwarded"]; ok {
+		elements, err := ParseForwarded(r.Header)
+		if err != nil || len(elements) == 0 {
+			return false
+		}
+		return elements[len(elements)-1].Proto == "https"
+	}
+	values := r.Header.Values("X-Forwarded-Proto")
+	if len(values) == 0 {
+		return false
+	}
+	protos := strings.Split(values[len(values)-1], ",")
+	return strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https")
+}
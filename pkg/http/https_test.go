@@ -0,0 +1,84 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestRequireHTTPSRedirects verifies that plain HTTP requests are redirected to HTTPS.
+func TestRequireHTTPSRedirects(t *testing.T) {
+	called := false
+	next := func(w ResponseWriter, r *Request) { called = true }
+
+	tests := []struct {
+		opts     HTTPSOptions
+		host     string
+		location string
+	}{
+		{HTTPSOptions{}, "example.com", "https://example.com/items?id=1"},
+		{HTTPSOptions{}, "example.com:8080", "https://example.com/items?id=1"},
+		{HTTPSOptions{Port: 8443}, "example.com:8080", "https://example.com:8443/items?id=1"},
+		{HTTPSOptions{}, "[::1]:8080", "https://[::1]/items?id=1"},
+	}
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		req := &Request{Method: POST, URL: &url.URL{Path: "/items", RawQuery: "id=1"}, Header: Header{"Host": {tt.host}}}
+		RequireHTTPS(tt.opts)(next)(res, req)
+
+		if res.status != StatusPermanentRedirect || res.headers.Get("Location") != tt.location {
+			t.Errorf("%s: expected %d to %s, got %d to %s", tt.host, StatusPermanentRedirect, tt.location, res.status, res.headers.Get("Location"))
+		}
+		if res.headers.Get("Strict-Transport-Security") != "" {
+			t.Errorf("%s: expected no HSTS header over plain HTTP", tt.host)
+		}
+	}
+	if called {
+		t.Error("Expected the handler not to be called")
+	}
+
+	res := &MockResponseWriter{headers: make(Header)}
+	RequireHTTPS(HTTPSOptions{})(next)(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{}})
+	if res.status != StatusBadRequest {
+		t.Errorf("Expected %d without a host, got %d", StatusBadRequest, res.status)
+	}
+}
+
+// TestRequireHTTPSSecure verifies that secure requests are served with a Strict-Transport-Security header.
+func TestRequireHTTPSSecure(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name   string
+		opts   HTTPSOptions
+		req    *Request
+		secure bool
+		hsts   string
+	}{
+		{"tls", HTTPSOptions{}, &Request{TLS: &tls.ConnectionState{}}, true, "max-age=31536000"},
+		{"options", HTTPSOptions{MaxAge: time.Hour, IncludeSubdomains: true, Preload: true}, &Request{TLS: &tls.ConnectionState{}}, true, "max-age=3600; includeSubDomains; preload"},
+		{"no hsts", HTTPSOptions{MaxAge: -1}, &Request{TLS: &tls.ConnectionState{}}, true, ""},
+		{"trusted proxy", HTTPSOptions{TrustedProxies: trusted}, &Request{RemoteAddr: "10.0.0.1:4711", Header: Header{"X-Forwarded-Proto": {"https"}}}, true, "max-age=31536000"},
+		{"forwarded", HTTPSOptions{TrustedProxies: trusted}, &Request{RemoteAddr: "10.0.0.1:4711", Header: Header{"Forwarded": {"proto=http, for=10.0.0.2;proto=https"}}}, true, "max-age=31536000"},
+		{"proxied http", HTTPSOptions{TrustedProxies: trusted}, &Request{RemoteAddr: "10.0.0.1:4711", Header: Header{"X-Forwarded-Proto": {"https, http"}}}, false, ""},
+		{"untrusted proxy", HTTPSOptions{TrustedProxies: trusted}, &Request{RemoteAddr: "198.51.100.1:4711", Header: Header{"X-Forwarded-Proto": {"https"}}}, false, ""},
+	}
+	for _, tt := range tests {
+		called := false
+		tt.req.Method, tt.req.URL = GET, &url.URL{Path: "/"}
+		if tt.req.Header == nil {
+			tt.req.Header = Header{}
+		}
+		tt.req.Header.Set("Host", "example.com")
+		res := &MockResponseWriter{headers: make(Header)}
+		RequireHTTPS(tt.opts)(func(w ResponseWriter, r *Request) { called = true })(res, tt.req)
+
+		if called != tt.secure {
+			t.Errorf("%s: expected secure %v, got %v", tt.name, tt.secure, called)
+		}
+		if got := res.headers.Get("Strict-Transport-Security"); got != tt.hsts {
+			t.Errorf("%s: expected HSTS '%s', got '%s'", tt.name, tt.hsts, got)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LanguageRange is a language range of an Accept-Language header, such as
+// "en-US", with its quality.
+type LanguageRange struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage returns the language ranges of an Accept-Language
+// header, most preferred first. Ranges of equal quality keep their order,
+// and ranges with a zero quality, which the client refuses, are last.
+func ParseAcceptLanguage(acceptLanguage string) []LanguageRange {
+	var ranges []LanguageRange
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		if tag = strings.TrimSpace(tag); tag != "" {
+			ranges = append(ranges, LanguageRange{Tag: tag, Quality: acceptQuality(params)})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Quality > ranges[j].Quality
+	})
+	return ranges
+}
+
+// MatchLanguage returns the language among the supported ones the
+// Accept-Language header prefers, or "" when it accepts none of them. A
+// range matches the languages it is a prefix of, "en" matching "en-GB",
+// and falls back to its own prefixes, "en-US" matching "en", as in the
+// lookup of RFC 4647. Languages are compared case-insensitively and
+// returned as supported.
+func MatchLanguage(acceptLanguage string, supported []string) string {
+	ranges := ParseAcceptLanguage(acceptLanguage)
+	refused := func(lang string) bool {
+		for _, r := range ranges {
+			if r.Quality == 0 && strings.EqualFold(r.Tag, lang) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range ranges {
+		if r.Quality == 0 {
+			break
+		}
+		if r.Tag == "*" {
+			for _, lang := range supported {
+				if !refused(lang) {
+					return lang
+				}
+			}
+			continue
+		}
+		for tag := r.Tag; tag != ""; tag = truncateLanguage(tag) {
+			for _, lang := range supported {
+				if refused(lang) {
+					continue
+				}
+				if strings.EqualFold(lang, tag) || tag == r.Tag && len(lang) > len(tag) &&
+					lang[len(tag)] == '-' && strings.EqualFold(lang[:len(tag)], tag) {
+					return lang
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// truncateLanguage removes the last subtag of a language tag, with the
+// single letter subtag before it: "zh-Hant-TW" becomes "zh-Hant".
+func truncateLanguage(tag string) string {
+	i := strings.LastIndexByte(tag, '-')
+	if i < 0 {
+		return ""
+	}
+	if i >= 2 && tag[i-2] == '-' {
+		i -= 2
+	}
+	return tag[:i]
+}
+
+// Catalog holds the messages of an application by locale, to answer
+// requests in the language their users prefer:
+//
+//	catalog := http.NewCatalog("en")
+//	catalog.Set("en", map[string]string{"greeting": "Hello, %s!"})
+//	catalog.Set("es", map[string]string{"greeting": "¡Hola, %s!"})
+//	mux.Use(catalog.Middleware())
+//
+//	func hello(w http.ResponseWriter, r *http.Request) {
+//		fmt.Fprint(w, catalog.Translate(r.Locale(), "greeting", r.Params["name"]))
+//	}
+//
+// Templates translate with the functions of FuncMap. A Catalog is safe for
+// concurrent use.
+type Catalog struct {
+	// Fallback is the locale used when a request accepts none of the
+	// catalog's, and for messages a locale lacks.
+	Fallback string
+
+	mu       sync.RWMutex
+	locales  []string                     // Locales as set, in order
+	messages map[string]map[string]string // Lower case locale to key to message
+}
+
+// NewCatalog creates an empty catalog falling back to the given locale.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{Fallback: fallback, messages: make(map[string]map[string]string)}
+}
+
+// Set adds the messages of a locale to the catalog, replacing the ones
+// with the same keys.
+func (c *Catalog) Set(locale string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := strings.ToLower(locale)
+	if c.messages[key] == nil {
+		c.messages[key] = make(map[string]string, len(messages))
+		c.locales = append(c.locales, locale)
+	}
+	for k, message := range messages {
+		c.messages[key][k] = message
+	}
+}
+
+// Locales returns the locales of the catalog, in the order they were set.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.locales...)
+}
+
+// Locale returns the locale of the catalog the request prefers according
+// to its Accept-Language header, or the fallback.
+func (c *Catalog) Locale(r *Request) string {
+	if locale := MatchLanguage(r.Header.Get("Accept-Language"), c.Locales()); locale != "" {
+		return locale
+	}
+	return c.Fallback
+}
+
+// Translate returns the message of the key in the locale, formatted with
+// args by fmt.Sprintf when there are any. A message missing in a locale
+// such as "pt-BR" is looked up in "pt", then in the fallback locale; the
+// key itself is returned when there is none.
+func (c *Catalog) Translate(locale, key string, args ...any) string {
+	message, ok := c.lookup(locale, key)
+	if !ok {
+		message, ok = c.lookup(c.Fallback, key)
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// lookup returns the message of the key in the locale or the locales it
+// falls back to.
+func (c *Catalog) lookup(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for tag := strings.ToLower(locale); tag != ""; tag = truncateLanguage(tag) {
+		if message, ok := c.messages[tag][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// FuncMap returns template functions for translating messages, to be
+// added with template.Funcs: {{ t .Locale "greeting" .Name }}.
+func (c *Catalog) FuncMap() map[string]any {
+	return map[string]any{"t": c.Translate}
+}
+
+// localeKey is the context key of the locale of a request.
+type localeKey struct{}
+
+// Locale returns the locale negotiated for the request by the middleware
+// of a Catalog, or "" without it.
+func (r *Request) Locale() string {
+	locale, _ := r.Context().Value(localeKey{}).(string)
+	return locale
+}
+
+// Middleware returns middleware negotiating the locale of requests, which
+// handlers read with Request.Locale. Responses get the locale in their
+// Content-Language header and Vary: Accept-Language.
+func (c *Catalog) Middleware() Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			locale := c.Locale(r)
+			h := w.Header()
+			if locale != "" {
+				h.Set("Content-Language", locale)
+			}
+			if !hasToken(strings.Join(h.Values("Vary"), ","), "Accept-Language") {
+				h.Add("Vary", "Accept-Language")
+			}
+			next(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, locale)))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestParseAcceptLanguage verifies that language ranges are sorted by quality.
+func TestParseAcceptLanguage(t *testing.T) {
+	ranges := ParseAcceptLanguage("fr;q=0.5, en-US, de;q=0, en;q=0.8, *;q=0.5")
+	want := []LanguageRange{{"en-US", 1}, {"en", 0.8}, {"fr", 0.5}, {"*", 0.5}, {"de", 0}}
+	if len(ranges) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, ranges)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("Range %d: expected %v, got %v", i, want[i], ranges[i])
+		}
+	}
+}
+
+// TestMatchLanguage verifies that the preferred supported language is chosen.
+func TestMatchLanguage(t *testing.T) {
+	supported := []string{"en", "es-MX", "pt-BR", "zh-Hant"}
+	for _, tt := range []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"es-mx", "es-MX"},
+		{"en-GB, es;q=0.9", "en"},
+		{"es, en;q=0.5", "es-MX"},
+		{"zh-Hant-TW", "zh-Hant"},
+		{"fr, pt;q=0.3", "pt-BR"},
+		{"fr", ""},
+		{"fr, *;q=0.1", "en"},
+		{"fr, en;q=0, *;q=0.1", "es-MX"},
+	} {
+		if got := MatchLanguage(tt.accept, supported); got != tt.want {
+			t.Errorf("%q: expected '%s', got '%s'", tt.accept, tt.want, got)
+		}
+	}
+}
+
+// TestCatalog verifies that messages are translated with fallbacks.
+func TestCatalog(t *testing.T) {
+	c := NewCatalog("en")
+	c.Set("en", map[string]string{"greeting": "Hello, %s!", "bye": "Bye"})
+	c.Set("pt", map[string]string{"greeting": "Olá, %s!"})
+	c.Set("pt-BR", map[string]string{"bye": "Tchau"})
+
+	for _, tt := range []struct {
+		locale, key string
+		want        string
+	}{
+		{"en", "greeting", "Hello, Ana!"},
+		{"pt-BR", "greeting", "Olá, Ana!"},
+		{"pt-BR", "bye", "Tchau"},
+		{"pt", "bye", "Bye"},
+		{"fr", "greeting", "Hello, Ana!"},
+		{"en", "missing", "missing"},
+	} {
+		var got string
+		if strings.Contains(tt.want, "Ana") {
+			got = c.Translate(tt.locale, tt.key, "Ana")
+		} else {
+			got = c.Translate(tt.locale, tt.key)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: expected '%s', got '%s'", tt.locale, tt.key, tt.want, got)
+		}
+	}
+
+	tmpl := template.Must(template.New("page").Funcs(c.FuncMap()).Parse(`{{ t .Locale "greeting" .Name }}`))
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]string{"Locale": "pt-BR", "Name": "Ana"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if b.String() != "Olá, Ana!" {
+		t.Errorf("Expected the translated template, got '%s'", b.String())
+	}
+}
+
+// TestCatalogMiddleware verifies that the negotiated locale reaches handlers and responses.
+func TestCatalogMiddleware(t *testing.T) {
+	c := NewCatalog("en")
+	c.Set("en", map[string]string{"bye": "Bye"})
+	c.Set("es", map[string]string{"bye": "Adiós"})
+	handler := c.Middleware()(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(c.Translate(r.Locale(), "bye")))
+	})
+
+	for accept, want := range map[string]string{"es-AR, en;q=0.5": "Adiós", "de": "Bye"} {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Accept-Language": {accept}}})
+		if string(res.body) != want {
+			t.Errorf("%s: expected '%s', got '%s'", accept, want, res.body)
+		}
+		if res.headers.Get("Vary") != "Accept-Language" || res.headers.Get("Content-Language") == "" {
+			t.Errorf("%s: expected Vary and Content-Language, got %v", accept, res.headers)
+		}
+	}
+}
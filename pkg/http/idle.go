@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net"
+	"time"
+)
+
+// Bounds of how often the reaper looks for idle connections.
+const (
+	minReapInterval = 10 * time.Millisecond
+	maxReapInterval = 1 * time.Second
+)
+
+// idleTimeout returns how long a kept-alive connection may wait for its
+// next request.
+func (s *Server) idleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return requestReadTimeout
+}
+
+// markIdle records whether a tracked connection is waiting for its next
+// request, and since when. Connections becoming idle during shutdown are
+// closed right away.
+func (s *Server) markIdle(conn net.Conn, idle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conns[conn]; !ok {
+		return
+	}
+	if idle && s.inShutdown {
+		conn.Close()
+		return
+	}
+	var since time.Time
+	if idle {
+		since = time.Now()
+	}
+	s.conns[conn] = since
+}
+
+// OpenConnections returns the number of connections currently open,
+// including idle ones.
+func (s *Server) OpenConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// IdleConnections returns the number of kept-alive connections waiting for
+// their next request.
+func (s *Server) IdleConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idle := 0
+	for _, since := range s.conns {
+		if !since.IsZero() {
+			idle++
+		}
+	}
+	return idle
+}
+
+// closeIdleConns closes the connections idle for at least d. Their
+// handlers notice the closed connection and return.
+func (s *Server) closeIdleConns(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for conn, since := range s.conns {
+		if !since.IsZero() && now.Sub(since) >= d {
+			conn.Close()
+		}
+	}
+}
+
+// startReaper starts, once per server, the goroutine that closes
+// connections idle beyond the idle timeout so clients can't hoard sockets.
+// It stops when the server shuts down.
+func (s *Server) startReaper() {
+	s.reaperOnce.Do(func() {
+		interval := min(max(s.idleTimeout()/4, minReapInterval), maxReapInterval)
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				if s.ShuttingDown() {
+					return
+				}
+				s.closeIdleConns(s.idleTimeout())
+			}
+		}()
+	})
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startIdleServer starts a server with the given idle timeout and returns
+// it with a connection that has completed one kept-alive request.
+func startIdleServer(t *testing.T, idleTimeout time.Duration) (*Server, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Length", "2")
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	server.IdleTimeout = idleTimeout
+	go server.Serve(ln)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	for line, err := reader.ReadString('\n'); line != "\r\n"; line, err = reader.ReadString('\n') {
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+	}
+	if _, err := io.ReadFull(reader, make([]byte, 2)); err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return server, conn
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// TestConnectionGauges verifies that open and idle connections are counted.
+func TestConnectionGauges(t *testing.T) {
+	server, conn := startIdleServer(t, time.Minute)
+
+	if !waitFor(t, func() bool { return server.IdleConnections() == 1 }) {
+		t.Fatalf("Expected one idle connection, got %d", server.IdleConnections())
+	}
+	if open := server.OpenConnections(); open != 1 {
+		t.Errorf("Expected one open connection, got %d", open)
+	}
+
+	conn.Close()
+	if !waitFor(t, func() bool { return server.OpenConnections() == 0 }) {
+		t.Errorf("Expected no open connections after closing, got %d", server.OpenConnections())
+	}
+	if idle := server.IdleConnections(); idle != 0 {
+		t.Errorf("Expected no idle connections after closing, got %d", idle)
+	}
+}
+
+// TestIdleReaper verifies that connections idle beyond IdleTimeout are closed.
+func TestIdleReaper(t *testing.T) {
+	server, conn := startIdleServer(t, 50*time.Millisecond)
+
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the idle connection to be closed, got %v", err)
+	}
+	if !waitFor(t, func() bool { return server.OpenConnections() == 0 }) {
+		t.Errorf("Expected no open connections, got %d", server.OpenConnections())
+	}
+}
+
+// TestShutdownClosesIdleConnections verifies that Shutdown doesn't wait for idle connections.
+func TestShutdownClosesIdleConnections(t *testing.T) {
+	server, _ := startIdleServer(t, time.Minute)
+	waitFor(t, func() bool { return server.IdleConnections() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("Expected idle connections to be closed at once, got %v", err)
+	}
+}
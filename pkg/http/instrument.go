@@ -0,0 +1,99 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+)
+
+// InstrumentedWriter is a ResponseWriter recording the status and the
+// body size of the response written through it, for logging and metrics
+// middleware:
+//
+//	rec := http.NewInstrumentedWriter(w)
+//	next(rec, r)
+//	log.Printf("%s %d %d", r.URL.Path, rec.Status(), rec.BytesWritten())
+//
+// It keeps the optional interfaces of the writers it wraps reachable, so
+// it can be stacked with compression or event streams.
+type InstrumentedWriter struct {
+	ResponseWriter
+	status int
+	size   int64
+}
+
+// NewInstrumentedWriter returns an InstrumentedWriter wrapping w.
+func NewInstrumentedWriter(w ResponseWriter) *InstrumentedWriter {
+	return &InstrumentedWriter{ResponseWriter: w}
+}
+
+// Status returns the status of the response, 200 when only a body was
+// written, and zero when nothing was.
+func (w *InstrumentedWriter) Status() int {
+	return w.status
+}
+
+// BytesWritten returns the number of body bytes written.
+func (w *InstrumentedWriter) BytesWritten() int64 {
+	return w.size
+}
+
+// Unwrap returns the wrapped writer.
+func (w *InstrumentedWriter) Unwrap() ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WriteHeader records the status and forwards it.
+func (w *InstrumentedWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records an implicit 200 status and forwards the data.
+func (w *InstrumentedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush forwards to the wrapped writers, so streaming keeps working.
+func (w *InstrumentedWriter) Flush() {
+	Flush(w.ResponseWriter)
+}
+
+// Hijack forwards to the wrapped writers.
+func (w *InstrumentedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := Hijack(w.ResponseWriter)
+	if err == nil {
+		w.status = StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// ReadFrom forwards to the wrapped writer, so files can still be sent with
+// sendfile.
+func (w *InstrumentedWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	w.size += n
+	return n, err
+}
+
+// writerOnly hides every method of a writer but Write, so io.Copy doesn't
+// call back into ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
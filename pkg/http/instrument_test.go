@@ -0,0 +1,65 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+)
+
+// streamingWriter is a MockResponseWriter supporting flushing and hijacking.
+type streamingWriter struct {
+	MockResponseWriter
+	flushes  int
+	hijacked bool
+}
+
+func (w *streamingWriter) Flush() { w.flushes++ }
+
+func (w *streamingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+// plainWrapper wraps a writer without implementing any optional interface.
+type plainWrapper struct {
+	ResponseWriter
+}
+
+func (w plainWrapper) Unwrap() ResponseWriter { return w.ResponseWriter }
+
+// TestInstrumentedWriter verifies that the status and size are recorded through stacked wrappers.
+func TestInstrumentedWriter(t *testing.T) {
+	base := &streamingWriter{MockResponseWriter: MockResponseWriter{headers: make(Header)}}
+	rec := NewInstrumentedWriter(plainWrapper{base})
+
+	if rec.Status() != 0 {
+		t.Errorf("Expected no status before writing, got %d", rec.Status())
+	}
+	rec.Write([]byte("hello "))
+	rec.WriteHeader(StatusNotFound)
+	rec.Write([]byte("lamp"))
+	if rec.Status() != StatusOK || rec.BytesWritten() != 10 {
+		t.Errorf("Expected 200 and 10 bytes, got %d and %d", rec.Status(), rec.BytesWritten())
+	}
+
+	// Flush and Hijack reach the base writer through the plain wrapper
+	rec.Flush()
+	if base.flushes != 1 {
+		t.Errorf("Expected the flush to reach the base writer, got %d flushes", base.flushes)
+	}
+	if _, _, err := rec.Hijack(); err != nil || !base.hijacked || rec.Status() != StatusSwitchingProtocols {
+		t.Errorf("Expected the hijack to reach the base writer, got %v", err)
+	}
+}
+
+// TestFlushUnsupported verifies that Flush and Hijack report writers without support.
+func TestFlushUnsupported(t *testing.T) {
+	w := plainWrapper{&MockResponseWriter{headers: make(Header)}}
+	if err := Flush(w); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported from Flush, got %v", err)
+	}
+	if _, _, err := Hijack(NewInstrumentedWriter(w)); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported from Hijack, got %v", err)
+	}
+}
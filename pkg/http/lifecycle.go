@@ -0,0 +1,46 @@
+package http
+
+// OnStart registers fn to be called once the server starts accepting
+// connections, e.g. to register with service discovery. Hooks run once,
+// in the order they were registered, the first time Serve is called.
+func (s *Server) OnStart(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onStart = append(s.onStart, fn)
+}
+
+// OnShutdown registers fn to be called by Shutdown once the server stopped
+// accepting connections and in-flight requests finished or were closed,
+// e.g. to close database pools or flush metrics. Hooks run once, in the
+// order they were registered.
+func (s *Server) OnShutdown(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// runStartHooks calls the OnStart hooks the first time it is called.
+func (s *Server) runStartHooks() {
+	s.startOnce.Do(func() {
+		s.mu.Lock()
+		hooks := s.onStart
+		s.mu.Unlock()
+
+		for _, fn := range hooks {
+			fn()
+		}
+	})
+}
+
+// runShutdownHooks calls the OnShutdown hooks the first time it is called.
+func (s *Server) runShutdownHooks() {
+	s.shutdownOnce.Do(func() {
+		s.mu.Lock()
+		hooks := s.onShutdown
+		s.mu.Unlock()
+
+		for _, fn := range hooks {
+			fn()
+		}
+	})
+}
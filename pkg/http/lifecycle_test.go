@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestLifecycleHooks verifies that start and shutdown hooks run once, in order, at the right time.
+func TestLifecycleHooks(t *testing.T) {
+	events := make(chan string, 10)
+	release := make(chan struct{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		events <- "request"
+		<-release
+		events <- "request done"
+		w.WriteHeader(StatusOK)
+	}))
+	server.OnStart(func() { events <- "start 1" })
+	server.OnStart(func() { events <- "start 2" })
+	server.OnShutdown(func() { events <- "shutdown" })
+
+	go server.Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		for len(events) < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		server.Shutdown(context.Background())
+		server.Shutdown(context.Background())
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	io.ReadAll(conn)
+	<-shutdownDone
+	close(events)
+
+	var got []string
+	for e := range events {
+		got = append(got, e)
+	}
+	expected := []string{"start 1", "start 2", "request", "request done", "shutdown"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected events %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Logger receives the internal errors of the server and the mux, such as
+// malformed requests or failed accepts. *log.Logger implements it; use
+// log.New(io.Discard, "", 0) to silence them.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// logf logs an internal server error to ErrorLog, or to the standard logger
+// when it isn't set.
+func (s *Server) logf(format string, v ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// SetErrorLog sets the logger receiving the mux's internal errors, such as
+// static files that exist but can't be read.
+func (mux *ServeMux) SetErrorLog(l Logger) {
+	mux.errorLog = l
+}
+
+// logf logs an internal mux error to its error log, or to the standard
+// logger when none is set.
+func (mux *ServeMux) logf(format string, v ...any) {
+	if mux.errorLog != nil {
+		mux.errorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// logFileError logs errors reading a static file, ignoring missing files
+// which are answered with a plain 404, including paths going through a
+// file as if it were a directory, such as "/favicon.ico/x".
+func (mux *ServeMux) logFileError(name string, err error) {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return
+	}
+	mux.logf("http: failed to read static file %s: %v", name, err)
+}
+
+// AccessLog returns middleware writing a line per request to out in the
+// Common Log Format, followed by the time taken to serve it in seconds.
+// The client host is the one of Request.ClientIP, while the identity and
+// user are unknown and logged as "-":
+//
+//	203.0.113.7 - - [02/Jan/2006:15:04:05 -0700] "GET /api/exchange HTTP/1.1" 200 13 0.000142
+//
+// Lines are written whole, so out may be shared between goroutines.
+func AccessLog(out io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			start := time.Now()
+			rec := NewInstrumentedWriter(w)
+			next(rec, r)
+			status := rec.Status()
+			if status == 0 {
+				status = StatusOK
+			}
+
+			proto := r.Proto
+			if proto == "" {
+				proto = "HTTP/1.1"
+			}
+			host := r.ClientIP()
+			if host == "" {
+				host = "-"
+			}
+			line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d %.6f\n",
+				host, start.Format("02/Jan/2006:15:04:05 -0700"), r.Method, r.URL.RequestURI(), proto,
+				status, rec.BytesWritten(), time.Since(start).Seconds())
+
+			mu.Lock()
+			defer mu.Unlock()
+			io.WriteString(out, line)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// recordingLogger collects logged messages.
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+// failingFS fails to open every file with a non-missing error.
+type failingFS struct{}
+
+func (failingFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("disk failure")}
+}
+
+// TestServerErrorLog verifies that parse errors go to the configured ErrorLog.
+func TestServerErrorLog(t *testing.T) {
+	logger := &recordingLogger{}
+	server := NewServer(":8080", &MockHandler{})
+	server.ErrorLog = logger
+
+	server.handleConn(context.Background(), &MockConnWithReader{reader: bufio.NewReader(strings.NewReader("BADREQUEST\r\n\r\n"))})
+
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "malformed request line") {
+		t.Errorf("Expected the parse error to be logged, got %v", logger.messages)
+	}
+}
+
+// TestMuxErrorLog verifies that unreadable static files are logged but missing ones, or paths through files, are not.
+func TestMuxErrorLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "favicon.ico"), []byte("icon"), 0644); err != nil {
+		t.Fatalf("Failed to create static file: %v", err)
+	}
+
+	logger := &recordingLogger{}
+	mux := NewServeMux(&dir)
+	mux.SetErrorLog(logger)
+	mux.StaticFS("/broken", failingFS{})
+	mux.StaticFS("/empty", fstest.MapFS{})
+	mux.StaticFS("/files", os.DirFS(dir))
+	mux.SetSymlinkPolicy(SymlinksFollow)
+
+	for _, path := range []string{"/broken/app.js", "/empty/app.js", "/favicon.ico/anything", "/files/favicon.ico/anything"} {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: path}})
+		if res.status != StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, StatusNotFound, res.status)
+		}
+	}
+
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "disk failure") {
+		t.Errorf("Expected only the read failure to be logged, got %v", logger.messages)
+	}
+}
+
+// TestAccessLog verifies that AccessLog writes the request, status and size of every response.
+func TestAccessLog(t *testing.T) {
+	var out strings.Builder
+	mux := NewServeMux(nil)
+	mux.Use(AccessLog(&out))
+	mux.AddRoute("/greet", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.Write([]byte("hello"))
+	})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/greet", RawQuery: "name=go"}, Proto: "HTTP/1.1", RemoteAddr: "203.0.113.7:4711"})
+
+	line := out.String()
+	if !strings.HasPrefix(line, "203.0.113.7 - - [") || !strings.Contains(line, `"GET /greet?name=go HTTP/1.1" 200 5 `) || !strings.HasSuffix(line, "\n") {
+		t.Errorf("Unexpected access log line %q", line)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultPollTimeout is how long a long-poll request waits when
+// LongPoll.Timeout is zero.
+const defaultPollTimeout = 30 * time.Second
+
+// LongPoll parks long-poll requests on topics until data is published on
+// them:
+//
+//	updates := http.NewLongPoll()
+//	mux.AddRoute("/rooms/:room/updates", []string{http.GET}, func(w http.ResponseWriter, r *http.Request) {
+//		updates.Serve(w, r, r.Params["room"])
+//	})
+//	...
+//	updates.Publish("lobby", message)
+//
+// Publishing wakes every waiter of the topic at once. Data published while
+// nobody waits is dropped, so clients fetch the current state before they
+// poll again.
+type LongPoll struct {
+	// Timeout is how long a request waits for data before it is answered
+	// with 204 No Content. Zero means 30 seconds.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*pollTopic
+}
+
+// pollTopic is the current generation of the waiters of a topic. Publish
+// closes wake, which releases them all, and starts a new generation.
+type pollTopic struct {
+	wake    chan struct{}
+	value   any // Set before wake is closed
+	waiters int
+}
+
+// NewLongPoll creates a LongPoll without topics.
+func NewLongPoll() *LongPoll {
+	return &LongPoll{topics: make(map[string]*pollTopic)}
+}
+
+// Wait blocks until data is published on the topic and returns it, or
+// until the timeout elapses or ctx is done, returning false. Handlers pass
+// the request context, so waiters of clients that disconnected are
+// released.
+func (p *LongPoll) Wait(ctx context.Context, topic string) (any, bool) {
+	p.mu.Lock()
+	t, ok := p.topics[topic]
+	if !ok {
+		t = &pollTopic{wake: make(chan struct{})}
+		p.topics[topic] = t
+	}
+	t.waiters++
+	p.mu.Unlock()
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultPollTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.wake:
+		return t.value, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-t.wake:
+		// Published while giving up, the data is delivered after all
+		return t.value, true
+	default:
+	}
+	t.waiters--
+	if t.waiters == 0 && p.topics[topic] == t {
+		delete(p.topics, topic)
+	}
+	return nil, false
+}
+
+// Publish wakes the waiters of the topic with v and returns how many
+// there were.
+func (p *LongPoll) Publish(topic string, v any) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.topics[topic]
+	if !ok {
+		return 0
+	}
+	delete(p.topics, topic)
+	t.value = v
+	close(t.wake)
+	return t.waiters
+}
+
+// Waiters returns the number of requests waiting on the topic.
+func (p *LongPoll) Waiters(topic string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.topics[topic]; ok {
+		return t.waiters
+	}
+	return 0
+}
+
+// Serve waits for data on the topic and answers the request with it,
+// encoded as JSON, or with 204 No Content on timeout. Nothing is written
+// when the client went away.
+func (p *LongPoll) Serve(w ResponseWriter, r *Request, topic string) {
+	v, ok := p.Wait(r.Context(), topic)
+	if ok {
+		writeJSON(w, StatusOK, v)
+		return
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+	w.WriteHeader(StatusNoContent)
+}
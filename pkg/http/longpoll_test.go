@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until n requests wait on the topic.
+func waitForWaiters(t *testing.T, p *LongPoll, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for p.Waiters(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiters, got %d", n, p.Waiters(topic))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestLongPollPublish verifies that publishing wakes every waiter of the topic with the data.
+func TestLongPollPublish(t *testing.T) {
+	p := NewLongPoll()
+
+	var wg sync.WaitGroup
+	results := make([]*MockResponseWriter, 3)
+	for i := range results {
+		results[i] = &MockResponseWriter{headers: make(Header)}
+		wg.Add(1)
+		go func(res *MockResponseWriter) {
+			defer wg.Done()
+			p.Serve(res, &Request{Method: GET, URL: &url.URL{Path: "/poll"}}, "lobby")
+		}(results[i])
+	}
+	waitForWaiters(t, p, "lobby", 3)
+
+	if n := p.Publish("other", "ignored"); n != 0 {
+		t.Errorf("Expected no waiters on another topic, got %d", n)
+	}
+	if n := p.Publish("lobby", map[string]string{"msg": "hi"}); n != 3 {
+		t.Errorf("Expected 3 waiters to be woken, got %d", n)
+	}
+	wg.Wait()
+
+	for _, res := range results {
+		if res.status != StatusOK || string(res.body) != `{"msg":"hi"}` {
+			t.Errorf("Expected the published data, got %d '%s'", res.status, res.body)
+		}
+	}
+	if p.Waiters("lobby") != 0 {
+		t.Errorf("Expected no waiters left, got %d", p.Waiters("lobby"))
+	}
+}
+
+// TestLongPollTimeout verifies that requests without data are answered with 204 No Content.
+func TestLongPollTimeout(t *testing.T) {
+	p := NewLongPoll()
+	p.Timeout = 10 * time.Millisecond
+
+	res := &MockResponseWriter{headers: make(Header)}
+	p.Serve(res, &Request{Method: GET, URL: &url.URL{Path: "/poll"}}, "lobby")
+
+	if res.status != StatusNoContent || len(res.body) != 0 {
+		t.Errorf("Expected %d without a body, got %d '%s'", StatusNoContent, res.status, res.body)
+	}
+	if p.Waiters("lobby") != 0 {
+		t.Errorf("Expected the topic to be released, got %d waiters", p.Waiters("lobby"))
+	}
+}
+
+// TestLongPollDisconnect verifies that waiters leave when the client goes away, without a response.
+func TestLongPollDisconnect(t *testing.T) {
+	p := NewLongPoll()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := (&Request{Method: GET, URL: &url.URL{Path: "/poll"}}).WithContext(ctx)
+	res := &MockResponseWriter{headers: make(Header)}
+
+	done := make(chan struct{})
+	go func() {
+		p.Serve(res, req, "lobby")
+		close(done)
+	}()
+	waitForWaiters(t, p, "lobby", 1)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiter to return once the client went away")
+	}
+	if res.status != 0 {
+		t.Errorf("Expected no response, got %d", res.status)
+	}
+	if p.Waiters("lobby") != 0 {
+		t.Errorf("Expected the topic to be released, got %d waiters", p.Waiters("lobby"))
+	}
+}
+
+// TestLongPollClientDisconnect verifies that the waiter of a client closing its connection leaves right away.
+func TestLongPollClientDisconnect(t *testing.T) {
+	p := NewLongPoll()
+	server, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		p.Serve(w, r, "lobby")
+	}))
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.Write([]byte("GET /poll HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	waitForWaiters(t, p, "lobby", 1)
+	conn.Close()
+	waitForWaiters(t, p, "lobby", 0)
+}
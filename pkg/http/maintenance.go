@@ -0,0 +1,133 @@
+package http
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaintenanceRetry is the Retry-After of maintenance responses when
+// Maintenance.RetryAfter is zero.
+const defaultMaintenanceRetry = time.Minute
+
+// Maintenance switches a handler into maintenance mode at runtime, for
+// planned maintenance windows without stopping the process:
+//
+//	m := &http.Maintenance{Allow: []string{"/health", "/admin/*"}}
+//	server := http.NewServer(":8080", m.Handler(mux))
+//	m.Notify(syscall.SIGUSR1)
+//	mux.AddRoute("/admin/maintenance", []string{http.GET, http.PUT, http.DELETE}, m.ServeSwitch)
+//
+// While enabled, requests for paths outside Allow are answered with 503
+// Service Unavailable and a Retry-After header.
+type Maintenance struct {
+	// Allow are the paths still served during maintenance. A path ending
+	// in "*" allows every path starting with what precedes it.
+	Allow []string
+	// RetryAfter tells clients when to come back. Zero means a minute.
+	RetryAfter time.Duration
+	// Page, when set, answers the requests during maintenance, after the
+	// Retry-After header is set. It should write a 503 status. When nil,
+	// the ServeError page of the handler is used if it has one, like a
+	// ServeMux, and problem details otherwise.
+	Page func(ResponseWriter, *Request)
+
+	enabled atomic.Bool
+}
+
+// Enable turns maintenance mode on.
+func (m *Maintenance) Enable() {
+	m.enabled.Store(true)
+}
+
+// Disable turns maintenance mode off.
+func (m *Maintenance) Disable() {
+	m.enabled.Store(false)
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Notify toggles maintenance mode whenever the process receives one of the
+// signals, e.g. syscall.SIGUSR1, until stop is called.
+func (m *Maintenance) Notify(sig ...os.Signal) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig...)
+	go func() {
+		for {
+			select {
+			case <-c:
+				m.enabled.Store(!m.enabled.Load())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// Handler returns a handler passing requests to next unless maintenance
+// mode is on and their path isn't allowed.
+func (m *Maintenance) Handler(next Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if !m.Enabled() || m.allowed(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retry := m.RetryAfter
+		if retry <= 0 {
+			retry = defaultMaintenanceRetry
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		switch eh, ok := next.(interface {
+			ServeError(ResponseWriter, *Request, int)
+		}); {
+		case m.Page != nil:
+			m.Page(w, r)
+		case ok:
+			eh.ServeError(w, r, StatusServiceUnavailable)
+		default:
+			Error(w, StatusText(StatusServiceUnavailable), StatusServiceUnavailable)
+		}
+	})
+}
+
+// ServeSwitch is an endpoint controlling maintenance mode: PUT and POST
+// enable it, DELETE disables it, and every method gets the current state
+// as JSON, such as {"enabled":true}. It should only be reachable by
+// operators, and listed in Allow.
+func (m *Maintenance) ServeSwitch(w ResponseWriter, r *Request) {
+	switch r.Method {
+	case PUT, POST:
+		m.Enable()
+	case DELETE:
+		m.Disable()
+	}
+	writeJSON(w, StatusOK, struct {
+		Enabled bool `json:"enabled"`
+	}{m.Enabled()})
+}
+
+// allowed reports whether the path is served during maintenance.
+func (m *Maintenance) allowed(path string) bool {
+	for _, allow := range m.Allow {
+		if prefix, ok := strings.CutSuffix(allow, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == allow {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestMaintenance verifies that maintenance mode answers the paths outside the allowlist with 503 and Retry-After.
+func TestMaintenance(t *testing.T) {
+	mux := NewServeMux(nil)
+	ok := func(w ResponseWriter, r *Request) { w.WriteHeader(StatusOK) }
+	mux.AddRoute("/items", []string{GET}, ok)
+	mux.AddRoute("/health", []string{GET}, ok)
+	mux.AddRoute("/admin/stats", []string{GET}, ok)
+
+	m := &Maintenance{Allow: []string{"/health", "/admin/*"}, RetryAfter: 90 * time.Second}
+	handler := m.Handler(mux)
+	serve := func(path string) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: path}})
+		return res
+	}
+
+	if res := serve("/items"); res.status != StatusOK {
+		t.Errorf("Expected %d before maintenance, got %d", StatusOK, res.status)
+	}
+
+	m.Enable()
+	res := serve("/items")
+	if res.status != StatusServiceUnavailable || res.headers.Get("Retry-After") != "90" {
+		t.Errorf("Expected %d with Retry-After 90, got %d and '%s'", StatusServiceUnavailable, res.status, res.headers.Get("Retry-After"))
+	}
+	if ct := res.headers.Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected the mux's error page, got %s", ct)
+	}
+	for _, path := range []string{"/health", "/admin/stats"} {
+		if res := serve(path); res.status != StatusOK {
+			t.Errorf("%s: expected %d during maintenance, got %d", path, StatusOK, res.status)
+		}
+	}
+
+	m.Page = func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusServiceUnavailable)
+		w.Write([]byte("back soon"))
+	}
+	if res := serve("/items"); string(res.body) != "back soon" || res.headers.Get("Retry-After") == "" {
+		t.Errorf("Expected the custom page, got '%s'", res.body)
+	}
+
+	m.Disable()
+	if res := serve("/items"); res.status != StatusOK {
+		t.Errorf("Expected %d after maintenance, got %d", StatusOK, res.status)
+	}
+}
+
+// TestMaintenanceSwitch verifies that maintenance mode is toggled by its endpoint.
+func TestMaintenanceSwitch(t *testing.T) {
+	m := &Maintenance{}
+	for _, tt := range []struct {
+		method string
+		want   string
+	}{{PUT, `{"enabled":true}`}, {GET, `{"enabled":true}`}, {DELETE, `{"enabled":false}`}} {
+		res := &MockResponseWriter{headers: make(Header)}
+		m.ServeSwitch(res, &Request{Method: tt.method, URL: &url.URL{Path: "/admin/maintenance"}})
+		if string(res.body) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.method, tt.want, res.body)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package http
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestMaintenanceNotify verifies that signals toggle maintenance mode.
+func TestMaintenanceNotify(t *testing.T) {
+	m := &Maintenance{}
+	stop := m.Notify(syscall.SIGUSR1)
+	defer stop()
+
+	for _, want := range []bool{true, false} {
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		deadline := time.Now().Add(time.Second)
+		for m.Enabled() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if m.Enabled() != want {
+			t.Errorf("Expected the signal to set maintenance mode to %v", want)
+		}
+	}
+}
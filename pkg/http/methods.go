@@ -7,4 +7,5 @@ const (
 	PUT    = "PUT"
 	DELETE = "DELETE"
 	UPDATE = "UPDATE"
+	HEAD   = "HEAD"
 )
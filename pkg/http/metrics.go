@@ -0,0 +1,177 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencySamples is the number of recent latencies kept per route to
+// compute percentiles.
+const latencySamples = 1024
+
+// RouteMetrics is a snapshot of the requests served by a route.
+type RouteMetrics struct {
+	// Pattern is the route pattern, e.g. "/users/:id". Metrics are keyed
+	// by pattern rather than by path so their number stays bounded.
+	Pattern string
+	// Requests is the number of requests served.
+	Requests uint64
+	// Errors is the number of requests answered with a 5xx status.
+	Errors uint64
+	// Statuses counts the requests by response status.
+	Statuses map[int]uint64
+	// P50, P90 and P99 are latency percentiles over the most recent
+	// requests.
+	P50, P90, P99 time.Duration
+}
+
+// ErrorRate returns the fraction of requests answered with a 5xx status.
+func (m RouteMetrics) ErrorRate() float64 {
+	if m.Requests == 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Requests)
+}
+
+// routeMetrics accumulates the metrics of a single route.
+type routeMetrics struct {
+	mu       sync.Mutex
+	requests uint64
+	errors   uint64
+	statuses map[int]uint64
+	samples  [latencySamples]time.Duration
+	next     int // Index of the next sample to overwrite
+}
+
+// observe records a served request.
+func (m *routeMetrics) observe(status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	if status >= 500 {
+		m.errors++
+	}
+	m.statuses[status]++
+	m.samples[m.next%latencySamples] = latency
+	m.next++
+}
+
+// snapshot returns the metrics collected so far.
+func (m *routeMetrics) snapshot(pattern string) RouteMetrics {
+	m.mu.Lock()
+	snap := RouteMetrics{
+		Pattern:  pattern,
+		Requests: m.requests,
+		Errors:   m.errors,
+		Statuses: make(map[int]uint64, len(m.statuses)),
+	}
+	for status, n := range m.statuses {
+		snap.Statuses[status] = n
+	}
+	samples := append([]time.Duration(nil), m.samples[:min(m.next, latencySamples)]...)
+	m.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	snap.P50 = percentile(samples, 0.50)
+	snap.P90 = percentile(samples, 0.90)
+	snap.P99 = percentile(samples, 0.99)
+	return snap
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
+
+// EnableMetrics starts recording the request count, statuses and latency
+// of every route. Requests that match no route, such as static files, are
+// not recorded.
+func (mux *ServeMux) EnableMetrics() {
+	mux.metricsMu.Lock()
+	defer mux.metricsMu.Unlock()
+
+	if mux.metrics == nil {
+		mux.metrics = make(map[string]*routeMetrics)
+	}
+}
+
+// Metrics returns the metrics of every route that has served a request,
+// sorted by pattern. It returns nil unless EnableMetrics was called.
+func (mux *ServeMux) Metrics() []RouteMetrics {
+	mux.metricsMu.RLock()
+	defer mux.metricsMu.RUnlock()
+
+	var snaps []RouteMetrics
+	for pattern, m := range mux.metrics {
+		snaps = append(snaps, m.snapshot(pattern))
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Pattern < snaps[j].Pattern })
+	return snaps
+}
+
+// routeMetrics returns the metrics of a route, or nil when metrics are
+// disabled.
+func (mux *ServeMux) routeMetrics(pattern string) *routeMetrics {
+	mux.metricsMu.RLock()
+	m, ok := mux.metrics[pattern]
+	enabled := mux.metrics != nil
+	mux.metricsMu.RUnlock()
+	if ok || !enabled {
+		return m
+	}
+
+	mux.metricsMu.Lock()
+	defer mux.metricsMu.Unlock()
+	if m, ok = mux.metrics[pattern]; !ok {
+		m = &routeMetrics{statuses: make(map[int]uint64)}
+		mux.metrics[pattern] = m
+	}
+	return m
+}
+
+// ServeMetrics writes the route metrics in the Prometheus text format, to
+// be registered as the metrics endpoint:
+//
+//	mux.AddRoute("/metrics", []string{http.GET}, mux.ServeMetrics)
+func (mux *ServeMux) ServeMetrics(w ResponseWriter, _ *Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(StatusOK)
+	writeMetrics(w, mux.Metrics())
+}
+
+// writeMetrics writes metrics in the Prometheus text format.
+func writeMetrics(w io.Writer, metrics []RouteMetrics) {
+	fmt.Fprintln(w, "# HELP http_requests_total Requests served, by route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, m := range metrics {
+		statuses := make([]int, 0, len(m.Statuses))
+		for status := range m.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "http_requests_total{route=%q,status=\"%d\"} %d\n", m.Pattern, status, m.Statuses[status])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Latency of recent requests, by route.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds summary")
+	for _, m := range metrics {
+		for _, q := range []struct {
+			quantile string
+			value    time.Duration
+		}{{"0.5", m.P50}, {"0.9", m.P90}, {"0.99", m.P99}} {
+			fmt.Fprintf(w, "http_request_duration_seconds{route=%q,quantile=%q} %s\n",
+				m.Pattern, q.quantile, strconv.FormatFloat(q.value.Seconds(), 'g', -1, 64))
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_count{route=%q} %d\n", m.Pattern, m.Requests)
+	}
+}
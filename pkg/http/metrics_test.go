@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRouteMetrics verifies that requests are counted by route pattern and status.
+func TestRouteMetrics(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.EnableMetrics()
+	mux.AddRoute("/users/:id", []string{GET}, func(w ResponseWriter, r *Request) {
+		if r.Params["id"] == "0" {
+			Error(w, "boom", StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("user"))
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/missing"} {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: path}})
+	}
+
+	metrics := mux.Metrics()
+	if len(metrics) != 1 {
+		t.Fatalf("Expected metrics for one route, got %+v", metrics)
+	}
+	m := metrics[0]
+	if m.Pattern != "/users/:id" || m.Requests != 3 || m.Errors != 1 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+	if m.Statuses[StatusOK] != 2 || m.Statuses[StatusInternalServerError] != 1 {
+		t.Errorf("Unexpected status counts: %v", m.Statuses)
+	}
+	if rate := m.ErrorRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("Expected an error rate of 1/3, got %v", rate)
+	}
+}
+
+// TestRouteMetricsDisabled verifies that nothing is recorded unless metrics are enabled.
+func TestRouteMetricsDisabled(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.AddRoute("/", []string{GET}, func(w ResponseWriter, r *Request) {})
+	mux.ServeHTTP(&MockResponseWriter{}, &Request{Method: GET, URL: &url.URL{Path: "/"}})
+
+	if metrics := mux.Metrics(); metrics != nil {
+		t.Errorf("Expected no metrics, got %+v", metrics)
+	}
+}
+
+// TestLatencyPercentiles verifies percentiles over the retained samples.
+func TestLatencyPercentiles(t *testing.T) {
+	m := &routeMetrics{statuses: make(map[int]uint64)}
+	for i := 1; i <= 100; i++ {
+		m.observe(StatusOK, time.Duration(i)*time.Millisecond)
+	}
+
+	snap := m.snapshot("/")
+	if snap.P50 != 51*time.Millisecond || snap.P90 != 90*time.Millisecond || snap.P99 != 99*time.Millisecond {
+		t.Errorf("Unexpected percentiles: p50=%v p90=%v p99=%v", snap.P50, snap.P90, snap.P99)
+	}
+
+	// Old samples are overwritten once the window is full
+	for i := 0; i < latencySamples; i++ {
+		m.observe(StatusOK, time.Second)
+	}
+	if snap := m.snapshot("/"); snap.P50 != time.Second || snap.Requests != 100+latencySamples {
+		t.Errorf("Expected only recent samples, got %+v", snap)
+	}
+}
+
+// TestServeMetrics verifies the Prometheus text output.
+func TestServeMetrics(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.EnableMetrics()
+	mux.AddRoute("/ping", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusNoContent)
+	})
+	mux.AddRoute("/metrics", []string{GET}, mux.ServeMetrics)
+	mux.ServeHTTP(&MockResponseWriter{}, &Request{Method: GET, URL: &url.URL{Path: "/ping"}})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/metrics"}})
+
+	body := string(res.body)
+	for _, expected := range []string{
+		`http_requests_total{route="/ping",status="204"} 1`,
+		`http_request_duration_seconds{route="/ping",quantile="0.99"} `,
+		`http_request_duration_seconds_count{route="/ping"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected '%s' in:\n%s", expected, body)
+		}
+	}
+}
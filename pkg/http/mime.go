@@ -0,0 +1,169 @@
+package http
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sniffLen is the number of bytes DetectContentType considers.
+const sniffLen = 512
+
+var (
+	contentTypesMu sync.RWMutex
+
+	// contentTypes maps lower-case file extensions to content types. It is
+	// seeded with the common web types and extended with AddContentType.
+	contentTypes = map[string]string{
+		".avif":        "image/avif",
+		".bmp":         "image/bmp",
+		".css":         "text/css; charset=utf-8",
+		".csv":         "text/csv; charset=utf-8",
+		".gif":         "image/gif",
+		".gz":          "application/gzip",
+		".htm":         "text/html; charset=utf-8",
+		".html":        "text/html; charset=utf-8",
+		".ico":         "image/x-icon",
+		".jpeg":        "image/jpeg",
+		".jpg":         "image/jpeg",
+		".js":          "text/javascript; charset=utf-8",
+		".json":        "application/json",
+		".map":         "application/json",
+		".md":          "text/markdown; charset=utf-8",
+		".mjs":         "text/javascript; charset=utf-8",
+		".mp3":         "audio/mpeg",
+		".mp4":         "video/mp4",
+		".oga":         "audio/ogg",
+		".ogg":         "audio/ogg",
+		".ogv":         "video/ogg",
+		".otf":         "font/otf",
+		".pdf":         "application/pdf",
+		".png":         "image/png",
+		".svg":         "image/svg+xml",
+		".tar":         "application/x-tar",
+		".ttf":         "font/ttf",
+		".txt":         "text/plain; charset=utf-8",
+		".wasm":        "application/wasm",
+		".wav":         "audio/wav",
+		".webm":        "video/webm",
+		".webmanifest": "application/manifest+json",
+		".webp":        "image/webp",
+		".woff":        "font/woff",
+		".woff2":       "font/woff2",
+		".xml":         "text/xml; charset=utf-8",
+		".yaml":        "application/yaml",
+		".yml":         "application/yaml",
+		".zip":         "application/zip",
+	}
+)
+
+// AddContentType registers the content type served for files with the
+// given extension, replacing any existing mapping. The extension may be
+// given with or without its leading dot and is matched case-insensitively.
+// text/* types without parameters get a UTF-8 charset.
+func AddContentType(ext, contentType string) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, ";") {
+		contentType += "; charset=utf-8"
+	}
+
+	contentTypesMu.Lock()
+	defer contentTypesMu.Unlock()
+	contentTypes[ext] = contentType
+}
+
+// ContentTypeByExtension returns the content type registered for the
+// extension of a file name, or an empty string if there is none.
+func ContentTypeByExtension(name string) string {
+	contentTypesMu.RLock()
+	defer contentTypesMu.RUnlock()
+	return contentTypes[strings.ToLower(filepath.Ext(name))]
+}
+
+// htmlSignatures are the tags that identify an HTML document, matched
+// case-insensitively and followed by a space or ">".
+var htmlSignatures = []string{
+	"<!DOCTYPE HTML", "<HTML", "<HEAD", "<SCRIPT", "<IFRAME", "<H1", "<DIV",
+	"<FONT", "<TABLE", "<A", "<STYLE", "<TITLE", "<B", "<BODY", "<BR", "<P", "<!--",
+}
+
+// magicSignatures map the leading bytes of binary formats to content types.
+var magicSignatures = []struct {
+	prefix      string
+	contentType string
+}{
+	{"%PDF-", "application/pdf"},
+	{"%!PS-Adobe-", "application/postscript"},
+	{"GIF87a", "image/gif"},
+	{"GIF89a", "image/gif"},
+	{"\x89PNG\r\n\x1a\n", "image/png"},
+	{"\xff\xd8\xff", "image/jpeg"},
+	{"BM", "image/bmp"},
+	{"\x00\x00\x01\x00", "image/x-icon"},
+	{"OggS\x00", "application/ogg"},
+	{"ID3", "audio/mpeg"},
+	{"\x1a\x45\xdf\xa3", "video/webm"},
+	{"wOFF", "font/woff"},
+	{"wOF2", "font/woff2"},
+	{"\x1f\x8b\x08", "application/x-gzip"},
+	{"PK\x03\x04", "application/zip"},
+	{"Rar!\x1a\x07", "application/x-rar-compressed"},
+	{"\x00asm", "application/wasm"},
+	{"\xfe\xff", "text/plain; charset=utf-16be"},
+	{"\xff\xfe", "text/plain; charset=utf-16le"},
+	{"\xef\xbb\xbf", "text/plain; charset=utf-8"},
+}
+
+// DetectContentType determines the content type of data by looking at its
+// first 512 bytes for well-known signatures. Data without a recognized
+// signature is reported as UTF-8 text when it contains no binary control
+// bytes, and as application/octet-stream otherwise.
+func DetectContentType(data []byte) string {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+
+	// Markup may be preceded by whitespace
+	markup := bytes.TrimLeft(data, "\t\n\x0c\r ")
+	for _, sig := range htmlSignatures {
+		if len(markup) > len(sig) && bytes.EqualFold(markup[:len(sig)], []byte(sig)) {
+			if next := markup[len(sig)]; next == ' ' || next == '>' {
+				return "text/html; charset=utf-8"
+			}
+		}
+	}
+	if bytes.HasPrefix(markup, []byte("<?xml")) {
+		return "text/xml; charset=utf-8"
+	}
+
+	for _, sig := range magicSignatures {
+		if bytes.HasPrefix(data, []byte(sig.prefix)) {
+			return sig.contentType
+		}
+	}
+
+	// Container formats carry their type a few bytes in
+	if len(data) >= 12 {
+		switch {
+		case string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+			return "image/webp"
+		case string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+			return "audio/wave"
+		case string(data[4:8]) == "ftyp" && string(data[8:12]) == "avif":
+			return "image/avif"
+		case string(data[4:8]) == "ftyp":
+			return "video/mp4"
+		}
+	}
+
+	for _, b := range data {
+		if b <= 0x08 || b == 0x0b || 0x0e <= b && b <= 0x1a || 0x1c <= b && b <= 0x1f {
+			return "application/octet-stream"
+		}
+	}
+	return "text/plain; charset=utf-8"
+}
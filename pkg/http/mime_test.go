@@ -0,0 +1,66 @@
+package http
+
+import (
+	"testing"
+)
+
+// TestContentTypeByExtension verifies the built-in extension table.
+func TestContentTypeByExtension(t *testing.T) {
+	tests := map[string]string{
+		"index.html":   "text/html; charset=utf-8",
+		"STYLE.CSS":    "text/css; charset=utf-8",
+		"app.mjs":      "text/javascript; charset=utf-8",
+		"data.json":    "application/json",
+		"font.woff2":   "font/woff2",
+		"photo.JPG":    "image/jpeg",
+		"unknown.xyz":  "",
+		"no-extension": "",
+	}
+
+	for name, expected := range tests {
+		if actual := ContentTypeByExtension(name); actual != expected {
+			t.Errorf("ContentTypeByExtension(%q): expected '%s', got '%s'", name, expected, actual)
+		}
+	}
+}
+
+// TestAddContentType verifies that custom extensions can be registered.
+func TestAddContentType(t *testing.T) {
+	AddContentType("GLTF", "model/gltf+json")
+	AddContentType(".tmpl", "text/x-template")
+
+	if ct := ContentTypeByExtension("scene.gltf"); ct != "model/gltf+json" {
+		t.Errorf("Expected 'model/gltf+json', got '%s'", ct)
+	}
+	if ct := ContentTypeByExtension("page.tmpl"); ct != "text/x-template; charset=utf-8" {
+		t.Errorf("Expected charset to be added to text type, got '%s'", ct)
+	}
+}
+
+// TestDetectContentType verifies content sniffing for common signatures.
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"HTML", "  <!doctype html><html></html>", "text/html; charset=utf-8"},
+		{"HTML tag", "<p>hello</p>", "text/html; charset=utf-8"},
+		{"XML", "<?xml version=\"1.0\"?><a/>", "text/xml; charset=utf-8"},
+		{"PDF", "%PDF-1.7 ...", "application/pdf"},
+		{"PNG", "\x89PNG\r\n\x1a\n\x00\x00", "image/png"},
+		{"JPEG", "\xff\xd8\xff\xe0", "image/jpeg"},
+		{"WebP", "RIFF\x00\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"MP4", "\x00\x00\x00\x18ftypmp42", "video/mp4"},
+		{"gzip", "\x1f\x8b\x08\x00", "application/x-gzip"},
+		{"Plain text", "just some words", "text/plain; charset=utf-8"},
+		{"Empty", "", "text/plain; charset=utf-8"},
+		{"Binary", "\x00\x01\x02\x03", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		if actual := DetectContentType([]byte(tt.data)); actual != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, actual)
+		}
+	}
+}
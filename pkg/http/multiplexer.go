@@ -1,281 +1,354 @@
-package http
-
-import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-	"sync"
-)
-
-// RouteNode represents a node in the route tree.
-type RouteNode struct {
-	pathSegment string
-	handler     map[string]func(ResponseWriter, *Request) // Method to handler mapping
-	children    sync.Map                                  // Use sync.Map for thread safety
-	isDynamic   bool                                      // True if the segment represents a dynamic value like :id
-}
-
-// ServeMux is an HTTP request multiplexer with a route tree.
-type ServeMux struct {
-	staticDir      *string
-	root           *RouteNode
-	middleware     []Middleware
-	defaultHandler func(ResponseWriter, *Request)
-	errorHandler   func(ResponseWriter, *Request, int) // Custom error handler
-}
-
-// NewServeMux creates a new ServeMux with a root node.
-func NewServeMux(staticDir *string) *ServeMux {
-	return &ServeMux{
-		root: &RouteNode{
-			children: sync.Map{},
-			handler:  make(map[string]func(ResponseWriter, *Request)),
-		},
-		staticDir:  staticDir,
-		middleware: []Middleware{},
-	}
-}
-
-// SetStaticDir establece el directorio estático para el ServeMux.
-func (mux *ServeMux) SetStaticDir(staticDir string) {
-	mux.staticDir = &staticDir
-}
-
-// getOrCreateChild fetches or creates a child node.
-func (mux *ServeMux) getOrCreateChild(node *RouteNode, segment string) *RouteNode {
-	child, exists := mux.getChild(node, segment)
-	if !exists {
-		child = &RouteNode{
-			pathSegment: segment,
-			handler:     make(map[string]func(ResponseWriter, *Request)),
-			children:    sync.Map{},
-		}
-		node.children.Store(segment, child)
-	}
-	return child
-}
-
-// getChild retrieves a child node.
-func (mux *ServeMux) getChild(node *RouteNode, segment string) (*RouteNode, bool) {
-	if child, exists := node.children.Load(segment); exists {
-		return child.(*RouteNode), true
-	}
-	return nil, false
-}
-
-// applyMiddleware applies all middleware in sequence.
-func (mux *ServeMux) applyMiddleware(handler func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
-	for _, mw := range mux.middleware {
-		handler = mw(handler)
-	}
-	return handler
-}
-
-// traverseTree traverses the route tree to find the handler for the given path and method.
-func (mux *ServeMux) traverseTree(path, method string, node *RouteNode, params map[string]string) (func(ResponseWriter, *Request), bool) {
-	segments := strings.Split(path, "/")[1:] // Split the path by "/"
-
-	for _, segment := range segments {
-		child, exists := mux.getChild(node, segment)
-
-		if !exists {
-			// Handle dynamic segment
-			dynamicChild, dynamicExists := mux.getDynamicChild(node)
-			if dynamicExists {
-				dynamicKey := strings.TrimPrefix(dynamicChild.pathSegment, ":") // Get the actual name of the dynamic param
-				params[dynamicKey] = segment                                    // Store the dynamic value in params with the correct key
-				node = dynamicChild
-				continue
-			}
-			return nil, false // No match found
-		}
-
-		node = child // Traverse to the next node
-	}
-
-	// Check if the node has a handler for the given method
-	if handler, exists := node.handler[method]; exists {
-		return handler, true
-	}
-
-	return nil, false // No handler found for the method
-}
-
-// getDynamicChild retrieves a dynamic child node, if it exists.
-func (mux *ServeMux) getDynamicChild(node *RouteNode) (*RouteNode, bool) {
-	// Iterate over children to find a dynamic route (starts with ":")
-	var dynamicChild *RouteNode
-	node.children.Range(func(key, value interface{}) bool {
-		child := value.(*RouteNode)
-		if strings.HasPrefix(child.pathSegment, ":") {
-			dynamicChild = child
-			return false // Stop iteration
-		}
-		return true // Continue iteration
-	})
-	return dynamicChild, dynamicChild != nil
-}
-
-// AddRoute adds a route and method(s) to the tree.
-func (mux *ServeMux) AddRoute(pattern string, methods []string, handler func(ResponseWriter, *Request)) {
-	segments := strings.Split(pattern, "/")[1:] // Split the pattern by "/" and ignore the first empty segment
-	currentNode := mux.root
-
-	for _, segment := range segments {
-		isDynamic := strings.HasPrefix(segment, ":")
-		var childNode *RouteNode
-
-		// Retrieve existing or create new node
-		if isDynamic {
-			childNode = mux.getOrCreateChild(currentNode, segment)
-			childNode.isDynamic = true
-		} else {
-			childNode = mux.getOrCreateChild(currentNode, segment)
-		}
-		currentNode = childNode
-	}
-
-	// Add the handler for each specified HTTP method
-	for _, method := range methods {
-		currentNode.handler[method] = handler
-	}
-}
-
-// Handle asigna un manejador a la ruta especificada para todos los métodos HTTP.
-func (mux *ServeMux) Handle(pattern string, handler func(ResponseWriter, *Request)) {
-	// Aplicar middleware al manejador
-	for _, mw := range mux.middleware {
-		handler = mw(handler)
-	}
-
-	// Asignar la ruta utilizando todos los métodos HTTP
-	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"}
-	mux.AddRoute(pattern, methods, handler)
-}
-
-// ServeHTTP dispatches the request to the appropriate handler by traversing the route tree.
-func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
-	if mux.staticDir != nil && mux.serveStaticFile(w, r) {
-		return
-	}
-
-	params := make(map[string]string)
-	handler, found := mux.traverseTree(r.URL.Path, r.Method, mux.root, params)
-
-	if !found {
-		if mux.errorHandler != nil {
-			mux.errorHandler(w, r, http.StatusNotFound)
-		} else {
-			mux.defaultErrorHandler(w, r, http.StatusNotFound)
-		}
-		return
-	}
-
-	// Set the params in the request
-	r.Params = params
-
-	// Apply middleware
-	handler = mux.applyMiddleware(handler)
-
-	handler(w, r)
-}
-
-// SetDefaultHandler sets a default handler for unregistered routes.
-func (mux *ServeMux) SetDefaultHandler(handler func(ResponseWriter, *Request)) {
-	mux.defaultHandler = handler
-}
-
-// SetErrorHandler sets a custom error handler.
-func (mux *ServeMux) SetErrorHandler(handler func(ResponseWriter, *Request, int)) {
-	mux.errorHandler = handler
-}
-
-// Use registers middleware to be applied to all routes.
-func (mux *ServeMux) Use(mw Middleware) {
-	mux.middleware = append(mux.middleware, mw)
-}
-
-// LoggingMiddleware is a simple middleware that logs the request.
-func LoggingMiddleware(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
-	return func(w ResponseWriter, r *Request) {
-		// Log the request
-		fmt.Printf("Received request: %s %s\n", r.Method, r.URL.Path)
-		next(w, r) // Call the next handler
-	}
-}
-
-// defaultErrorHandler is the default error response for 404 Not Found.
-func (mux *ServeMux) defaultErrorHandler(w ResponseWriter, _ *Request, statusCode int) {
-	w.WriteHeader(statusCode)
-	switch statusCode {
-	case http.StatusNotFound:
-		fmt.Fprintln(w, StatusText(http.StatusNotFound))
-	default:
-		fmt.Fprintln(w, "Error:", statusCode)
-	}
-}
-
-// FileExists checks if a file or directory exists.
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return !os.IsNotExist(err) // Return true if no error (file exists)
-}
-
-func (mux *ServeMux) serveStaticFile(w ResponseWriter, r *Request) bool {
-	// Check if a static directory is set
-	if mux.staticDir == nil {
-		return false
-	}
-
-	// Get the file path from the URL
-	filePath := (*mux.staticDir) + r.URL.Path
-
-	// When the URL ends with a "/", serve the index.html file
-	if strings.HasSuffix(r.URL.Path, "/") {
-		filePath += "index.html"
-	}
-
-	// Check if the file exists
-	if !fileExists(filePath) {
-		return false
-	}
-
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return false
-	}
-
-	w.Header()["Content-Type"] = []string{detectContentType(filePath)}
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
-	return true
-}
-
-// detectContentType returns the content type based on the file data.
-func detectContentType(filePath string) string {
-	// Map of file extensions to content types
-	contentTypes := map[string]string{
-		".html": "text/html",
-		".css":  "text/css",
-		".js":   "application/javascript",
-		".png":  "image/png",
-		".jpg":  "image/jpeg",
-		".jpeg": "image/jpeg",
-		".svg":  "image/svg+xml",
-		".gif":  "image/gif",
-	}
-
-	// Get the file extension
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	// Lookup the content type
-	if contentType, exists := contentTypes[ext]; exists {
-		return contentType
-	}
-
-	// Default to binary data
-	return "application/octet-stream"
-}
+package http
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteNode represents a node in the route tree.
+type RouteNode struct {
+	pathSegment string
+	handler     map[string]func(ResponseWriter, *Request) // Method to handler mapping
+	children    sync.Map                                  // Use sync.Map for thread safety
+	isDynamic   bool                                      // True if the segment represents a dynamic value like :id
+	pattern     string                                    // Pattern of the route ending at this node
+	timeouts    map[string]time.Duration                  // Method to timeout, set with Route.WithTimeout
+	middleware  map[string][]NamedMiddleware              // Method to middleware, set with Route.Use
+}
+
+// ServeMux is an HTTP request multiplexer with a route tree.
+type ServeMux struct {
+	staticDir      *string
+	root           *RouteNode
+	middleware     []NamedMiddleware
+	defaultHandler func(ResponseWriter, *Request)
+	errorHandler   func(ResponseWriter, *Request, int) // Custom error handler
+	statusHandlers map[int]func(ResponseWriter, *Request, int)
+	statusMu       sync.RWMutex
+	symlinkPolicy  SymlinkPolicy
+	fileSystem     fs.FS
+	dirListing     *DirListingOptions
+	cachePolicy    *CachePolicy
+	mounts         []*StaticMount
+	mountsMu       sync.RWMutex
+	errorLog       Logger
+	metrics        map[string]*routeMetrics // Route pattern to metrics, nil when disabled
+	metricsMu      sync.RWMutex
+	timeout        time.Duration // Default timeout of the routes
+	cors           *CORSOptions  // Set with EnableCORS
+}
+
+// NewServeMux creates a new ServeMux with a root node.
+func NewServeMux(staticDir *string) *ServeMux {
+	return &ServeMux{
+		root: &RouteNode{
+			children: sync.Map{},
+			handler:  make(map[string]func(ResponseWriter, *Request)),
+		},
+		staticDir:  staticDir,
+		middleware: []NamedMiddleware{},
+	}
+}
+
+// SetStaticDir establece el directorio estático para el ServeMux.
+func (mux *ServeMux) SetStaticDir(staticDir string) {
+	mux.staticDir = &staticDir
+}
+
+// getOrCreateChild fetches or creates a child node.
+func (mux *ServeMux) getOrCreateChild(node *RouteNode, segment string) *RouteNode {
+	child, exists := mux.getChild(node, segment)
+	if !exists {
+		child = &RouteNode{
+			pathSegment: segment,
+			handler:     make(map[string]func(ResponseWriter, *Request)),
+			children:    sync.Map{},
+		}
+		node.children.Store(segment, child)
+	}
+	return child
+}
+
+// getChild retrieves a child node.
+func (mux *ServeMux) getChild(node *RouteNode, segment string) (*RouteNode, bool) {
+	if child, exists := node.children.Load(segment); exists {
+		return child.(*RouteNode), true
+	}
+	return nil, false
+}
+
+// applyMiddleware applies all middleware in sequence.
+func (mux *ServeMux) applyMiddleware(handler func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+	for _, mw := range mux.middleware {
+		handler = mw.Middleware(handler)
+	}
+	return handler
+}
+
+// traverseTree traverses the route tree to find the node of the route with a handler for the given path and method.
+func (mux *ServeMux) traverseTree(path, method string, node *RouteNode, params map[string]string) (*RouteNode, bool) {
+	segments := strings.Split(path, "/")[1:] // Split the path by "/"
+
+	// The deepest catch-all passed on the way is the fallback of paths
+	// that don't match further down
+	var catchAll *RouteNode
+	var catchAllParams map[string]string
+	fallback := func() (*RouteNode, bool) {
+		if catchAll == nil {
+			return nil, false
+		}
+		clear(params)
+		maps.Copy(params, catchAllParams)
+		return catchAll, true
+	}
+
+	for i, segment := range segments {
+		if c, ok := mux.getCatchAllChild(node); ok {
+			if _, exists := c.handler[method]; exists {
+				catchAll, catchAllParams = c, maps.Clone(params)
+				catchAllParams[c.pathSegment[1:]] = strings.Join(segments[i:], "/")
+			}
+		}
+
+		child, exists := mux.getChild(node, segment)
+
+		if !exists {
+			// Handle dynamic segment
+			dynamicChild, dynamicExists := mux.getDynamicChild(node)
+			if dynamicExists {
+				dynamicKey := strings.TrimPrefix(dynamicChild.pathSegment, ":") // Get the actual name of the dynamic param
+				params[dynamicKey] = segment                                    // Store the dynamic value in params with the correct key
+				node = dynamicChild
+				continue
+			}
+			return fallback() // No match found
+		}
+
+		node = child // Traverse to the next node
+	}
+
+	// Check if the node has a handler for the given method
+	if _, exists := node.handler[method]; exists {
+		return node, true
+	}
+
+	return fallback() // No handler found for the method
+}
+
+// getDynamicChild retrieves a dynamic child node, if it exists.
+func (mux *ServeMux) getDynamicChild(node *RouteNode) (*RouteNode, bool) {
+	// Iterate over children to find a dynamic route (starts with ":")
+	var dynamicChild *RouteNode
+	node.children.Range(func(key, value interface{}) bool {
+		child := value.(*RouteNode)
+		if strings.HasPrefix(child.pathSegment, ":") {
+			dynamicChild = child
+			return false // Stop iteration
+		}
+		return true // Continue iteration
+	})
+	return dynamicChild, dynamicChild != nil
+}
+
+// getCatchAllChild retrieves the catch-all child node, if it exists.
+func (mux *ServeMux) getCatchAllChild(node *RouteNode) (*RouteNode, bool) {
+	var catchAll *RouteNode
+	node.children.Range(func(key, value interface{}) bool {
+		child := value.(*RouteNode)
+		if strings.HasPrefix(child.pathSegment, "*") {
+			catchAll = child
+			return false
+		}
+		return true
+	})
+	return catchAll, catchAll != nil
+}
+
+// AddRoute adds a route and method(s) to the tree. The returned Route
+// sets further options of the route.
+//
+// Segments like ":id" match any single segment, stored in the request's
+// Params under their name. A last segment like "*path" matches the rest
+// of the path, slashes included, when no more specific route does; Params
+// holds it decoded and Request.RawRest as it was sent.
+func (mux *ServeMux) AddRoute(pattern string, methods []string, handler func(ResponseWriter, *Request)) *Route {
+	segments := strings.Split(pattern, "/")[1:] // Split the pattern by "/" and ignore the first empty segment
+	currentNode := mux.root
+
+	for _, segment := range segments {
+		isDynamic := strings.HasPrefix(segment, ":")
+		var childNode *RouteNode
+
+		// Retrieve existing or create new node
+		if isDynamic {
+			childNode = mux.getOrCreateChild(currentNode, segment)
+			childNode.isDynamic = true
+		} else {
+			childNode = mux.getOrCreateChild(currentNode, segment)
+		}
+		currentNode = childNode
+	}
+
+	// Add the handler for each specified HTTP method
+	currentNode.pattern = pattern
+	for _, method := range methods {
+		currentNode.handler[method] = handler
+	}
+	return &Route{node: currentNode, methods: methods}
+}
+
+// Handle asigna un manejador a la ruta especificada para todos los métodos HTTP.
+func (mux *ServeMux) Handle(pattern string, handler func(ResponseWriter, *Request)) *Route {
+	// Aplicar middleware al manejador
+	for _, mw := range mux.middleware {
+		handler = mw.Middleware(handler)
+	}
+
+	// Asignar la ruta utilizando todos los métodos HTTP
+	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"}
+	return mux.AddRoute(pattern, methods, handler)
+}
+
+// ServeHTTP dispatches the request to the appropriate handler by traversing the route tree.
+func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
+	if mux.cors != nil && mux.serveCORS(w, r) {
+		return
+	}
+	if mux.serveStaticFile(w, r) {
+		return
+	}
+
+	params := make(map[string]string)
+	node, found := mux.traverseTree(r.URL.Path, r.Method, mux.root, params)
+
+	if !found {
+		// Routes take precedence over static mounts
+		if mux.serveMounts(w, r) {
+			return
+		}
+
+		if mux.defaultHandler != nil {
+			mux.applyMiddleware(mux.defaultHandler)(w, r)
+			return
+		}
+		mux.ServeError(w, r, StatusNotFound)
+		return
+	}
+
+	// Set the params in the request
+	r.Params = params
+	if strings.HasPrefix(node.pathSegment, "*") {
+		r.rawRest = rawRest(r.URL, params[node.pathSegment[1:]])
+	}
+
+	// The route's deadline applies to its middleware too
+	if timeout := mux.routeTimeout(node, r.Method); timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	// Apply the route's middleware, then the mux's
+	handler := node.handler[r.Method]
+	for _, mw := range node.middleware[r.Method] {
+		handler = mw.Middleware(handler)
+	}
+	handler = mux.applyMiddleware(handler)
+	pattern := node.pattern
+	if _, ok := r.Context().Deadline(); ok {
+		// Handlers outliving the deadline get a 503 answered for them
+		next := handler
+		handler = func(w ResponseWriter, r *Request) { mux.serveWithDeadline(next, w, r) }
+	}
+
+	if m := mux.routeMetrics(pattern); m != nil {
+		rec := NewInstrumentedWriter(w)
+		start := time.Now()
+		handler(rec, r)
+		status := rec.Status()
+		if status == 0 {
+			status = StatusOK
+		}
+		m.observe(status, time.Since(start))
+		return
+	}
+
+	handler(w, r)
+}
+
+// SetDefaultHandler sets a default handler for unregistered routes. It is
+// called, wrapped in the mux's middleware, for requests that match neither
+// a route nor a static file, instead of answering 404 Not Found.
+func (mux *ServeMux) SetDefaultHandler(handler func(ResponseWriter, *Request)) {
+	mux.defaultHandler = handler
+}
+
+// SetErrorHandler sets a custom error handler, used for the statuses
+// without a handler of their own.
+func (mux *ServeMux) SetErrorHandler(handler func(ResponseWriter, *Request, int)) {
+	mux.errorHandler = handler
+}
+
+// HandleStatus sets the error handler of a single status code, such as a
+// custom 404 page, taking precedence over the one of SetErrorHandler. A nil
+// handler removes it.
+func (mux *ServeMux) HandleStatus(statusCode int, handler func(ResponseWriter, *Request, int)) {
+	mux.statusMu.Lock()
+	defer mux.statusMu.Unlock()
+	if handler == nil {
+		delete(mux.statusHandlers, statusCode)
+		return
+	}
+	if mux.statusHandlers == nil {
+		mux.statusHandlers = make(map[int]func(ResponseWriter, *Request, int))
+	}
+	mux.statusHandlers[statusCode] = handler
+}
+
+// ServeError answers the request with the error handler of the status code:
+// the one set with HandleStatus, or else the one of SetErrorHandler, or
+// else a problem details response. Handlers may call it so their errors
+// share the pages of the mux.
+func (mux *ServeMux) ServeError(w ResponseWriter, r *Request, statusCode int) {
+	mux.statusMu.RLock()
+	handler := mux.statusHandlers[statusCode]
+	mux.statusMu.RUnlock()
+
+	switch {
+	case handler != nil:
+		handler(w, r, statusCode)
+	case mux.errorHandler != nil:
+		mux.errorHandler(w, r, statusCode)
+	default:
+		mux.defaultErrorHandler(w, r, statusCode)
+	}
+}
+
+// Use registers middleware to be applied to all routes.
+func (mux *ServeMux) Use(mw Middleware) {
+	mux.UseNamed(NamedMiddleware{Name: funcName(mw), Middleware: mw})
+}
+
+// LoggingMiddleware is a simple middleware that logs the request.
+func LoggingMiddleware(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+	return func(w ResponseWriter, r *Request) {
+		// Log the request
+		fmt.Printf("Received request: %s %s\n", r.Method, r.URL.Path)
+		next(w, r) // Call the next handler
+	}
+}
+
+// defaultErrorHandler is the default error response, a problem details
+// object of the status and request path.
+func (mux *ServeMux) defaultErrorHandler(w ResponseWriter, r *Request, statusCode int) {
+	p := NewProblem(statusCode, "")
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+	WriteProblem(w, p)
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"fmt"
 	"net/url"
 	"testing"
 )
@@ -61,6 +62,46 @@ func TestDynamicRoute(t *testing.T) {
 	}
 }
 
+// TestCatchAllRoute verifies that catch-all segments match the rest of the path, decoded and raw.
+func TestCatchAllRoute(t *testing.T) {
+	mux := NewServeMux(nil)
+	handler := func(name string) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			w.WriteHeader(StatusOK)
+			w.Write([]byte(name + " " + r.Params["path"] + " " + r.RawRest() + " " + r.Params["user"]))
+		}
+	}
+	mux.AddRoute("/files/*path", []string{GET}, handler("files"))
+	mux.AddRoute("/files/docs/readme", []string{GET}, handler("readme"))
+	mux.AddRoute("/users/:user/*path", []string{GET}, handler("user"))
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/files/a/b.txt", StatusOK, "files a/b.txt a/b.txt "},
+		{"/files/a%2Fb/c%20d", StatusOK, "files a/b/c d a%2Fb/c%20d "},
+		{"/files/docs/readme", StatusOK, "readme   "},
+		{"/files/docs/other", StatusOK, "files docs/other docs/other "},
+		{"/files/", StatusOK, "files   "},
+		{"/users/ann/x/y", StatusOK, "user x/y x/y ann"},
+		{"/files", StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.target)
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: u})
+
+		if res.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.status, res.status)
+		}
+		if tt.status == StatusOK && string(res.body) != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.target, tt.body, res.body)
+		}
+	}
+}
+
 // TestRouteNotFound verifies that a 404 is returned when a route is not found.
 func TestRouteNotFound(t *testing.T) {
 	mux := NewServeMux(nil)
@@ -78,10 +119,13 @@ func TestRouteNotFound(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
 	}
 
-	expectedBody := "Not Found\n"
+	expectedBody := `{"title":"Not Found","status":404,"instance":"/nonexistent"}`
 	if string(res.body) != expectedBody {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(res.body))
 	}
+	if ct := res.headers.Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected a problem content type, got '%s'", ct)
+	}
 }
 
 // TestMethodNotAllowed verifies that a 404 is returned if the method is not allowed for the route.
@@ -107,7 +151,7 @@ func TestMethodNotAllowed(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
 	}
 
-	expectedBody := "Not Found\n"
+	expectedBody := `{"title":"Not Found","status":404,"instance":"/api/test"}`
 	if string(res.body) != expectedBody {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(res.body))
 	}
@@ -183,6 +227,25 @@ func TestDefaultHandler(t *testing.T) {
 	}
 }
 
+// TestDefaultHandlerFallback verifies that the default handler answers unmatched requests in place of a 404.
+func TestDefaultHandlerFallback(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.AddRoute("/known", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.Write([]byte("route"))
+	})
+	mux.SetDefaultHandler(func(w ResponseWriter, r *Request) {
+		w.Write([]byte("fallback " + r.URL.Path))
+	})
+
+	for path, want := range map[string]string{"/known": "route", "/other": "fallback /other"} {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: path}})
+		if string(res.body) != want {
+			t.Errorf("%s: expected body '%s', got '%s'", path, want, res.body)
+		}
+	}
+}
+
 // TestErrorHandler verifies that the custom error handler is used.
 func TestErrorHandler(t *testing.T) {
 	mux := NewServeMux(nil)
@@ -293,3 +356,42 @@ func TestAddRouteWithDifferentMethods(t *testing.T) {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(resPost.body))
 	}
 }
+
+// TestHandleStatus verifies that per-status error handlers take precedence over the error handler.
+func TestHandleStatus(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.SetErrorHandler(func(w ResponseWriter, r *Request, statusCode int) {
+		w.WriteHeader(statusCode)
+		fmt.Fprintf(w, "error %d", statusCode)
+	})
+	mux.HandleStatus(StatusNotFound, func(w ResponseWriter, r *Request, statusCode int) {
+		w.WriteHeader(statusCode)
+		w.Write([]byte("no page at " + r.URL.Path))
+	})
+	mux.AddRoute("/upload", []string{POST}, func(w ResponseWriter, r *Request) {
+		mux.ServeError(w, r, StatusRequestEntityTooLarge)
+	})
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{GET, "/missing", StatusNotFound, "no page at /missing"},
+		{POST, "/upload", StatusRequestEntityTooLarge, "error 413"},
+	}
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: tt.method, URL: &url.URL{Path: tt.path}})
+		if res.status != tt.status || string(res.body) != tt.body {
+			t.Errorf("%s: expected %d '%s', got %d '%s'", tt.path, tt.status, tt.body, res.status, res.body)
+		}
+	}
+
+	mux.HandleStatus(StatusNotFound, nil)
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/missing"}})
+	if string(res.body) != "error 404" {
+		t.Errorf("Expected the error handler after removing the 404 handler, got '%s'", res.body)
+	}
+}
@@ -0,0 +1,598 @@
+package http
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultOIDCSessionTTL is how long a login lasts when
+	// OIDC.SessionTTL is zero.
+	defaultOIDCSessionTTL = 24 * time.Hour
+	// oidcLoginTTL is how long a user has to log in at the provider.
+	oidcLoginTTL = 10 * time.Minute
+	// oidcLeeway is the clock skew tolerated when checking the expiry of
+	// ID tokens.
+	oidcLeeway = time.Minute
+	// oidcKeysRefresh is how often the signing keys may be fetched again
+	// for a token signed with an unknown key.
+	oidcKeysRefresh = time.Minute
+)
+
+// OIDC protects a server-rendered application with an external OpenID
+// Connect identity provider, using the authorization code flow with PKCE:
+//
+//	auth := &http.OIDC{
+//		Issuer:       "https://accounts.example.com",
+//		ClientID:     "my-app",
+//		ClientSecret: secret,
+//		RedirectURL:  "https://app.example.com/callback",
+//		Sessions:     http.NewMemorySessionStore(),
+//	}
+//	mux.AddRoute("/login", []string{http.GET}, auth.Login)
+//	mux.AddRoute("/callback", []string{http.GET}, auth.Callback)
+//	mux.AddRoute("/logout", []string{http.POST}, auth.Logout)
+//	mux.AddRoute("/account", []string{http.GET}, account).Use(auth.Middleware())
+//
+// Login redirects users to the provider with a fresh state, nonce and PKCE
+// challenge. Callback exchanges the code with Client, verifies the ID
+// token, and establishes a session held in Sessions, referenced by a
+// cookie. Requests with a session carry a Principal with the claims of the
+// ID token.
+type OIDC struct {
+	// Issuer is the URL of the provider, whose discovery document at
+	// /.well-known/openid-configuration gives the endpoints left empty.
+	Issuer string
+	// ClientID and ClientSecret are the credentials of the application
+	// registered at the provider.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL of the Callback handler, as registered at the
+	// provider.
+	RedirectURL string
+	// Scopes are requested in addition to "openid". Empty means "profile"
+	// and "email".
+	Scopes []string
+
+	// AuthURL, TokenURL and JWKSURL are the authorization, token and key
+	// set endpoints of the provider. Empty ones are discovered.
+	AuthURL  string
+	TokenURL string
+	JWKSURL  string
+
+	// Client calls the provider. When nil, DefaultClient is used.
+	Client *Client
+	// Sessions holds the pending logins and the sessions.
+	Sessions SessionStore
+	// SessionTTL is how long a session lasts. Zero means 24 hours.
+	SessionTTL time.Duration
+	// CookieName names the session cookie. Empty means "oidc_session".
+	CookieName string
+	// LoginPath is where the middleware sends users without a session.
+	// Empty means "/login".
+	LoginPath string
+	// RolesClaim names the claim of the ID token holding the roles of the
+	// principal, such as "roles" or "groups", for RequireRoles.
+	RolesClaim string
+
+	mu          sync.Mutex
+	discovered  bool
+	keys        map[string]crypto.PublicKey // Signing keys by ID
+	keysFetched time.Time
+}
+
+// oidcLogin is a login waiting for the provider to call back.
+type oidcLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Return   string `json:"return"`
+}
+
+// Login redirects the user to the provider to log in. The "return" query
+// parameter is the local path the user is sent back to after Callback,
+// "/" by default.
+func (o *OIDC) Login(w ResponseWriter, r *Request) {
+	if err := o.discover(r.Context()); err != nil {
+		WriteProblem(w, NewProblem(StatusBadGateway, err.Error()))
+		return
+	}
+
+	state, err1 := NewSessionID()
+	verifier, err2 := NewSessionID()
+	nonce, err3 := NewSessionID()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		WriteProblem(w, NewProblem(StatusInternalServerError, err.Error()))
+		return
+	}
+	login, _ := json.Marshal(oidcLogin{Verifier: verifier, Nonce: nonce, Return: localPath(r.URL.Query().Get("return"))})
+	if err := o.Sessions.Save(r.Context(), "login:"+state, login, oidcLoginTTL); err != nil {
+		WriteProblem(w, NewProblem(StatusInternalServerError, err.Error()))
+		return
+	}
+	// The state is bound to the browser, so a login can't be completed in
+	// another one
+	w.SetCookie(o.cookie(o.cookieName()+"_state", state, int(oidcLoginTTL/time.Second)))
+
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.ClientID},
+		"redirect_uri":          {o.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(o.AuthURL, "?") {
+		sep = "&"
+	}
+	w.Header()["Location"] = []string{o.AuthURL + sep + query.Encode()}
+	w.WriteHeader(StatusFound)
+}
+
+// Callback completes a login: it exchanges the authorization code of the
+// provider for tokens, verifies the ID token, establishes the session and
+// sends the user back where Login was called from.
+func (o *OIDC) Callback(w ResponseWriter, r *Request) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		WriteProblem(w, NewProblem(StatusUnauthorized, strings.TrimSpace(e+": "+query.Get("error_description"))))
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.GetCookie(o.cookieName() + "_state")
+	if err != nil || state == "" || !hmac.Equal([]byte(cookie.Value), []byte(state)) {
+		WriteProblem(w, NewProblem(StatusBadRequest, "Invalid login state"))
+		return
+	}
+	w.DeleteCookie(o.cookieName() + "_state")
+	data, err := o.Sessions.Load(r.Context(), "login:"+state)
+	if err != nil {
+		WriteProblem(w, NewProblem(StatusBadRequest, "Expired login"))
+		return
+	}
+	o.Sessions.Delete(r.Context(), "login:"+state)
+	var login oidcLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		WriteProblem(w, NewProblem(StatusInternalServerError, err.Error()))
+		return
+	}
+
+	idToken, err := o.exchange(r.Context(), query.Get("code"), login.Verifier)
+	if err != nil {
+		WriteProblem(w, NewProblem(StatusBadGateway, err.Error()))
+		return
+	}
+	claims, err := o.VerifyIDToken(r.Context(), idToken, login.Nonce)
+	if err != nil {
+		WriteProblem(w, NewProblem(StatusUnauthorized, err.Error()))
+		return
+	}
+
+	id, err := NewSessionID()
+	if err == nil {
+		data, err = json.Marshal(o.principal(claims))
+	}
+	if err == nil {
+		err = o.Sessions.Save(r.Context(), id, data, o.sessionTTL())
+	}
+	if err != nil {
+		WriteProblem(w, NewProblem(StatusInternalServerError, err.Error()))
+		return
+	}
+	w.SetCookie(o.cookie(o.cookieName(), id, int(o.sessionTTL()/time.Second)))
+	w.Header()["Location"] = []string{login.Return}
+	w.WriteHeader(StatusFound)
+}
+
+// Logout ends the session of the user and sends them to "/". Only the
+// local session ends, the user stays logged in at the provider.
+func (o *OIDC) Logout(w ResponseWriter, r *Request) {
+	if cookie, err := r.GetCookie(o.cookieName()); err == nil {
+		o.Sessions.Delete(r.Context(), cookie.Value)
+	}
+	w.DeleteCookie(o.cookieName())
+	w.Header()["Location"] = []string{"/"}
+	w.WriteHeader(StatusFound)
+}
+
+// Middleware returns middleware passing on the requests of logged in
+// users with their Principal. Users without a session are redirected to
+// LoginPath for GET and HEAD requests, and answered with 401
+// Unauthorized otherwise.
+func (o *OIDC) Middleware() Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if p := o.session(r); p != nil {
+				next(w, r.WithPrincipal(p))
+				return
+			}
+			if r.Method != GET && r.Method != HEAD {
+				WriteProblem(w, NewProblem(StatusUnauthorized, "Authentication is required"))
+				return
+			}
+			loginPath := o.LoginPath
+			if loginPath == "" {
+				loginPath = "/login"
+			}
+			w.Header()["Location"] = []string{loginPath + "?" + url.Values{"return": {r.URL.RequestURI()}}.Encode()}
+			w.WriteHeader(StatusFound)
+		}
+	}
+}
+
+// Authenticator returns an authenticator of the session cookie, for the
+// Authenticate middleware, on routes serving anonymous users too.
+func (o *OIDC) Authenticator() Authenticator {
+	return func(r *Request) (*Principal, error) {
+		return o.session(r), nil
+	}
+}
+
+// session returns the principal of the session of the request, or nil.
+func (o *OIDC) session(r *Request) *Principal {
+	cookie, err := r.GetCookie(o.cookieName())
+	if err != nil {
+		return nil
+	}
+	data, err := o.Sessions.Load(r.Context(), cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var p Principal
+	if json.Unmarshal(data, &p) != nil {
+		return nil
+	}
+	return &p
+}
+
+// principal returns the principal identified by the claims of an ID token.
+func (o *OIDC) principal(claims map[string]any) *Principal {
+	p := &Principal{Claims: claims}
+	p.ID, _ = claims["sub"].(string)
+	if roles, ok := claims[o.RolesClaim].([]any); ok && o.RolesClaim != "" {
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				p.Roles = append(p.Roles, s)
+			}
+		}
+	}
+	return p
+}
+
+// exchange trades an authorization code for the ID token of the user.
+func (o *OIDC) exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.RedirectURL},
+		"client_id":     {o.ClientID},
+		"client_secret": {o.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := NewRequestWithContext(ctx, POST, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := o.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("malformed token response: %w", err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if res.StatusCode != StatusOK || token.IDToken == "" {
+		return "", fmt.Errorf("token request failed with status %d", res.StatusCode)
+	}
+	return token.IDToken, nil
+}
+
+// VerifyIDToken verifies the signature of an ID token with the keys of the
+// provider, or the client secret for HS256, and checks that it was issued
+// by Issuer for ClientID, hasn't expired and carries the nonce, unless it
+// is empty. It returns the claims of the token.
+func (o *OIDC) VerifyIDToken(ctx context.Context, token, nonce string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidIDToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidIDToken)
+	}
+	if err := o.verifySignature(ctx, header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidIDToken)
+	}
+	if claims["iss"] != o.Issuer {
+		return nil, fmt.Errorf("%w: issued by %v", ErrInvalidIDToken, claims["iss"])
+	}
+	if !audienceContains(claims["aud"], o.ClientID) {
+		return nil, fmt.Errorf("%w: issued for %v", ErrInvalidIDToken, claims["aud"])
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Add(-oidcLeeway).After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if got, _ := claims["nonce"].(string); nonce != "" && !hmac.Equal([]byte(got), []byte(nonce)) {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// verifySignature verifies the JWS signature of a token's signed part.
+func (o *OIDC) verifySignature(ctx context.Context, alg, kid, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, []byte(o.ClientSecret))
+		mac.Write([]byte(signed))
+		if o.ClientSecret == "" || !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("bad signature")
+		}
+		return nil
+	case "RS256", "ES256":
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	key, err := o.signingKey(ctx, kid)
+	if err != nil {
+		return err
+	}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == "ES256" && len(signature) == 64 {
+			r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("bad signature")
+}
+
+// signingKey returns the key of the provider with the ID, fetching the key
+// set again when it is unknown, at most once a minute. A token without a
+// key ID is verified with the only key of the set.
+func (o *OIDC) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if err := o.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key, ok := o.keys[kid]
+	if !ok && time.Since(o.keysFetched) > oidcKeysRefresh {
+		keys, err := o.fetchKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.keys, o.keysFetched = keys, time.Now()
+		key, ok = keys[kid]
+	}
+	if !ok && kid == "" && len(o.keys) == 1 {
+		for _, k := range o.keys {
+			key, ok = k, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys fetches the RSA and P-256 keys of the provider's key set.
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, o.JWKSURL, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+// discover fills the endpoints left empty from the discovery document of
+// the issuer.
+func (o *OIDC) discover(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovered || o.AuthURL != "" && o.TokenURL != "" && o.JWKSURL != "" {
+		return nil
+	}
+
+	var metadata struct {
+		Issuer   string `json:"issuer"`
+		AuthURL  string `json:"authorization_endpoint"`
+		TokenURL string `json:"token_endpoint"`
+		JWKSURL  string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.Issuer, "/")+"/.well-known/openid-configuration", &metadata); err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+	if metadata.Issuer != o.Issuer {
+		return fmt.Errorf("discovery failed: issuer %q doesn't match", metadata.Issuer)
+	}
+	for _, endpoint := range []struct {
+		field *string
+		value string
+	}{
+		{&o.AuthURL, metadata.AuthURL}, {&o.TokenURL, metadata.TokenURL}, {&o.JWKSURL, metadata.JWKSURL},
+	} {
+		if *endpoint.field == "" {
+			*endpoint.field = endpoint.value
+		}
+	}
+	o.discovered = true
+	return nil
+}
+
+// getJSON fetches a JSON document from the provider.
+func (o *OIDC) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := NewRequestWithContext(ctx, GET, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := o.client().Do(req)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != StatusOK {
+		return fmt.Errorf("%s answered with status %d", rawURL, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// client returns the client calling the provider.
+func (o *OIDC) client() *Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return DefaultClient
+}
+
+// cookieName returns the name of the session cookie.
+func (o *OIDC) cookieName() string {
+	if o.CookieName != "" {
+		return o.CookieName
+	}
+	return "oidc_session"
+}
+
+// sessionTTL returns how long sessions last.
+func (o *OIDC) sessionTTL() time.Duration {
+	if o.SessionTTL > 0 {
+		return o.SessionTTL
+	}
+	return defaultOIDCSessionTTL
+}
+
+// cookie returns a cookie of the login flow, secure when the application
+// is served over HTTPS.
+func (o *OIDC) cookie(name, value string, maxAge int) *Cookie {
+	return &Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(o.RedirectURL, "https://"),
+		HttpOnly: true,
+	}
+}
+
+// PKCEChallenge returns the S256 code challenge of a PKCE code verifier
+// (RFC 7636), such as one made by NewSessionID.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// decodeJWTPart decodes the base64url encoded JSON of a JWT part into v.
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// audienceContains reports whether the aud claim, a string or an array of
+// strings, contains the client ID.
+func audienceContains(aud any, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []any:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// localPath returns the path when it is local to the application, and "/"
+// otherwise, so logins can't redirect users to other sites.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
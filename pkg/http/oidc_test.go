@@ -0,0 +1,222 @@
+package http
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// signJWT returns a JWT of the claims signed with RS256 by key, or HS256 with secret when key is nil.
+func signJWT(t *testing.T, key *rsa.PrivateKey, secret string, claims map[string]any) string {
+	alg := "RS256"
+	if key == nil {
+		alg = "HS256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "key-1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	if key == nil {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// provider holds what the test OpenID provider expects and issues, shared
+// between the test and the provider's handlers.
+type provider struct {
+	mu        sync.Mutex
+	challenge string         // PKCE challenge of the authorization request
+	claims    map[string]any // Claims of the ID tokens, besides the issuer
+}
+
+// startProvider starts an OpenID provider issuing ID tokens signed by key for codes exchanged with the verifier of the challenge.
+func startProvider(t *testing.T, key *rsa.PrivateKey, p *provider) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	issuer := "http://" + ln.Addr().String()
+
+	mux := NewServeMux(nil)
+	mux.AddRoute("/.well-known/openid-configuration", []string{GET}, func(w ResponseWriter, r *Request) {
+		writeJSON(w, StatusOK, map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/authorize",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/jwks",
+		})
+	})
+	mux.AddRoute("/jwks", []string{GET}, func(w ResponseWriter, r *Request) {
+		writeJSON(w, StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.AddRoute("/token", []string{POST}, func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		p.mu.Lock()
+		challenge, claims := p.challenge, maps.Clone(p.claims)
+		p.mu.Unlock()
+		if form.Get("code") != "good-code" || PKCEChallenge(form.Get("code_verifier")) != challenge {
+			writeJSON(w, StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims["iss"] = issuer
+		writeJSON(w, StatusOK, map[string]string{"id_token": signJWT(t, key, "", claims), "token_type": "Bearer"})
+	})
+	go NewServer(ln.Addr().String(), mux).Serve(ln)
+	return issuer
+}
+
+// TestOIDCLogin verifies the authorization code flow from the login redirect to the session.
+func TestOIDCLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	p := &provider{claims: map[string]any{"sub": "user-1", "aud": "app", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"}}}
+	auth := &OIDC{
+		Issuer:      startProvider(t, key, p),
+		ClientID:    "app",
+		RedirectURL: "https://app.example.com/callback",
+		Sessions:    NewMemorySessionStore(),
+		RolesClaim:  "roles",
+		Client:      &Client{Timeout: 5 * time.Second},
+	}
+	protected := auth.Middleware()(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.Principal().ID))
+	})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	protected(res, &Request{Method: GET, URL: &url.URL{Path: "/account", RawQuery: "tab=1"}, Header: make(Header)})
+	if res.status != StatusFound || res.headers.Get("Location") != "/login?return=%2Faccount%3Ftab%3D1" {
+		t.Fatalf("Expected a redirect to the login, got %d '%s'", res.status, res.headers.Get("Location"))
+	}
+
+	res = &MockResponseWriter{headers: make(Header)}
+	auth.Login(res, &Request{Method: GET, URL: &url.URL{Path: "/login", RawQuery: "return=%2Faccount"}, Header: make(Header)})
+	location, err := url.Parse(res.headers.Get("Location"))
+	if res.status != StatusFound || err != nil || location.Path != "/authorize" {
+		t.Fatalf("Expected a redirect to the provider, got %d '%s'", res.status, res.headers.Get("Location"))
+	}
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid profile email" {
+		t.Errorf("Unexpected authorization request %v", query)
+	}
+	p.mu.Lock()
+	p.challenge = query.Get("code_challenge")
+	p.claims["nonce"] = query.Get("nonce")
+	p.mu.Unlock()
+	stateCookie := res.headers.Get("Set-Cookie")
+	if !strings.HasPrefix(stateCookie, "oidc_session_state="+query.Get("state")) || !strings.Contains(stateCookie, "Secure") {
+		t.Errorf("Expected a secure state cookie, got '%s'", stateCookie)
+	}
+
+	callback := func(state string) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		auth.Callback(res, &Request{
+			Method: GET,
+			URL:    &url.URL{Path: "/callback", RawQuery: url.Values{"code": {"good-code"}, "state": {state}}.Encode()},
+			Header: Header{"Cookie": {"oidc_session_state=" + query.Get("state")}},
+		})
+		return res
+	}
+	if res := callback("forged"); res.status != StatusBadRequest {
+		t.Errorf("Expected %d for a forged state, got %d", StatusBadRequest, res.status)
+	}
+	res = callback(query.Get("state"))
+	if res.status != StatusFound || res.headers.Get("Location") != "/account" {
+		t.Fatalf("Expected a redirect back to the account, got %d '%s' %s", res.status, res.headers.Get("Location"), res.body)
+	}
+	if res := callback(query.Get("state")); res.status != StatusBadRequest {
+		t.Errorf("Expected the login state to be used once, got %d", res.status)
+	}
+
+	var session string
+	for _, c := range res.headers.Values("Set-Cookie") {
+		if value, ok := strings.CutPrefix(c, "oidc_session="); ok {
+			session, _, _ = strings.Cut(value, ";")
+		}
+	}
+	req := &Request{Method: GET, URL: &url.URL{Path: "/account"}, Header: Header{"Cookie": {"oidc_session=" + session}}}
+	res = &MockResponseWriter{headers: make(Header)}
+	protected(res, req)
+	if res.status != StatusOK || string(res.body) != "user-1" {
+		t.Errorf("Expected the account of user-1, got %d '%s'", res.status, res.body)
+	}
+	if p, _ := auth.Authenticator()(req); !p.HasRole("admin") || p.Claims["sub"] != "user-1" {
+		t.Errorf("Expected the roles and claims of the ID token, got %+v", p)
+	}
+
+	auth.Logout(&MockResponseWriter{headers: make(Header)}, req)
+	res = &MockResponseWriter{headers: make(Header)}
+	protected(res, req)
+	if res.status != StatusFound {
+		t.Errorf("Expected the session to end on logout, got %d", res.status)
+	}
+}
+
+// TestVerifyIDToken verifies that ID tokens from other issuers, for other clients, expired or replayed are rejected.
+func TestVerifyIDToken(t *testing.T) {
+	auth := &OIDC{Issuer: "https://idp.example.com", ClientID: "app", ClientSecret: "secret", JWKSURL: "unused"}
+	valid := func() map[string]any {
+		return map[string]any{"iss": "https://idp.example.com", "aud": []string{"app", "api"}, "sub": "user-1", "exp": time.Now().Add(time.Hour).Unix(), "nonce": "n-1"}
+	}
+	if claims, err := auth.VerifyIDToken(context.Background(), signJWT(t, nil, "secret", valid()), "n-1"); err != nil || claims["sub"] != "user-1" {
+		t.Errorf("Expected a valid token, got %v %v", claims, err)
+	}
+
+	for name, tt := range map[string]struct {
+		key   string
+		claim string
+		value any
+	}{
+		"wrong secret":   {"other", "", nil},
+		"other issuer":   {"secret", "iss", "https://evil.example.com"},
+		"other audience": {"secret", "aud", "other-app"},
+		"expired":        {"secret", "exp", time.Now().Add(-time.Hour).Unix()},
+		"other nonce":    {"secret", "nonce", "n-2"},
+	} {
+		claims := valid()
+		if tt.claim != "" {
+			claims[tt.claim] = tt.value
+		}
+		if _, err := auth.VerifyIDToken(context.Background(), signJWT(t, nil, tt.key, claims), "n-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%s: expected ErrInvalidIDToken, got %v", name, err)
+		}
+	}
+	payload, _ := json.Marshal(valid())
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+	if _, err := auth.VerifyIDToken(context.Background(), unsigned, ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected unsigned tokens to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"strings"
+	"unicode"
+)
+
+// openAPIVersion is the OpenAPI version of the documents built by
+// ServeMux.OpenAPI.
+const openAPIVersion = "3.0.3"
+
+// OpenAPIDocument is a skeleton OpenAPI 3 document describing the routes
+// of a ServeMux, to be completed with schemas and descriptions. Encoded as
+// JSON, it is a valid document.
+type OpenAPIDocument struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    OpenAPIInfo                             `json:"info"`
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths"` // Path to lower case method to operation
+}
+
+// OpenAPIInfo is the metadata of an API.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIOperation is an operation of an OpenAPIDocument, a route's method.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter is a parameter of an OpenAPIOperation.
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+// OpenAPIResponse is a response of an OpenAPIOperation.
+type OpenAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// openAPIMethods are the methods OpenAPI describes operations of.
+var openAPIMethods = map[string]bool{
+	GET: true, PUT: true, POST: true, DELETE: true, "OPTIONS": true, HEAD: true, "PATCH": true, "TRACE": true,
+}
+
+// OpenAPI returns a skeleton OpenAPI document of the routes of the mux,
+// to bootstrap the documentation of an API:
+//
+//	doc := mux.OpenAPI(http.OpenAPIInfo{Title: "Items", Version: "1.0"})
+//	mux.AddRoute("/openapi.json", []string{http.GET}, doc.ServeHTTP)
+//
+// Every method of a route is an operation with a default response, and
+// dynamic segments like ":id" become required path parameters, "{id}" in
+// the path. A catch-all segment becomes a parameter too, although OpenAPI
+// parameters can't contain slashes. The document describes the routes
+// added before it is built.
+func (mux *ServeMux) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	for _, route := range mux.Routes() {
+		segments := strings.Split(route.Pattern, "/")
+		var params []OpenAPIParameter
+		for i, segment := range segments {
+			if !isParamSegment(segment) {
+				continue
+			}
+			segments[i] = "{" + segment[1:] + "}"
+			param := OpenAPIParameter{Name: segment[1:], In: "path", Required: true, Schema: map[string]any{"type": "string"}}
+			if segmentRank(segment) == 2 {
+				param.Description = "Rest of the path"
+			}
+			params = append(params, param)
+		}
+		path := strings.Join(segments, "/")
+		if path == "" {
+			path = "/"
+		}
+
+		for _, method := range route.Methods {
+			if !openAPIMethods[method] {
+				continue
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			doc.Paths[path][strings.ToLower(method)] = &OpenAPIOperation{
+				OperationID: operationID(method, route.Pattern),
+				Parameters:  params,
+				Responses:   map[string]*OpenAPIResponse{"default": {Description: "Response"}},
+			}
+		}
+	}
+	return doc
+}
+
+// ServeHTTP writes the document as JSON.
+func (doc *OpenAPIDocument) ServeHTTP(w ResponseWriter, r *Request) {
+	writeJSON(w, StatusOK, doc)
+}
+
+// operationID derives an operation ID from a method and pattern, such as
+// "getItemsById" for GET /items/:id.
+func operationID(method, pattern string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(pattern, "/") {
+		if isParamSegment(segment) {
+			b.WriteString("By")
+			segment = segment[1:]
+		}
+		upper := true
+		for _, c := range segment {
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				upper = true
+				continue
+			}
+			if upper {
+				c = unicode.ToUpper(c)
+				upper = false
+			}
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+// TestOpenAPI verifies the skeleton OpenAPI document built from the routes.
+func TestOpenAPI(t *testing.T) {
+	doc := newRoutesMux().OpenAPI(OpenAPIInfo{Title: "Items", Version: "1.0"})
+
+	if doc.OpenAPI != openAPIVersion || doc.Info.Title != "Items" {
+		t.Errorf("Unexpected header %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if len(doc.Paths) != 4 {
+		t.Fatalf("Expected 4 paths, got %v", doc.Paths)
+	}
+	op := doc.Paths["/api/items/{id}"]["put"]
+	if op == nil {
+		t.Fatalf("Expected a put operation on /api/items/{id}, got %v", doc.Paths["/api/items/{id}"])
+	}
+	if op.OperationID != "putApiItemsById" {
+		t.Errorf("Expected operation ID putApiItemsById, got %s", op.OperationID)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" || !op.Parameters[0].Required {
+		t.Errorf("Expected a required id path parameter, got %+v", op.Parameters)
+	}
+	if _, ok := doc.Paths["/files/{path}"]["get"]; !ok {
+		t.Errorf("Expected the catch-all as a parameter, got %v", doc.Paths)
+	}
+
+	res := &MockResponseWriter{headers: make(Header)}
+	doc.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/openapi.json"}})
+	var decoded map[string]any
+	if err := json.Unmarshal(res.body, &decoded); err != nil || decoded["openapi"] != openAPIVersion {
+		t.Errorf("Expected the document as JSON, got '%s' (%v)", res.body, err)
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Strings found in most requests, returned by the parser instead of
+// allocating a copy for every request.
+var (
+	commonMethods = []string{GET, POST, PUT, DELETE, UPDATE, HEAD, "OPTIONS", "PATCH"}
+
+	commonHeaderKeys = make(map[string]string)
+)
+
+func init() {
+	for _, key := range []string{
+		"Accept", "Accept-Encoding", "Accept-Language", "Authorization",
+		"Cache-Control", "Connection", "Content-Length", "Content-Type",
+		"Cookie", "Host", "If-Match", "If-Modified-Since", "If-None-Match",
+		"If-Range", "If-Unmodified-Since", "Origin", "Pragma", "Range",
+		"Referer", "Transfer-Encoding", "Upgrade", "User-Agent",
+		"X-Forwarded-For", "X-Forwarded-Proto", "X-Real-Ip", "X-Request-Id",
+	} {
+		commonHeaderKeys[key] = key
+	}
+}
+
+// maxInternedKey is the length of the longest header key canonicalized
+// without allocating.
+const maxInternedKey = 32
+
+// readLine returns the next line, including its line ending. The slice
+// points into the reader's buffer and is only valid until the next read,
+// unless the line was longer than the buffer.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+
+	// Lines longer than the buffer are collected into a copy
+	buf := append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		line, err = reader.ReadSlice('\n')
+		buf = append(buf, line...)
+	}
+	return buf, err
+}
+
+// readHeader reads header lines up to the empty line that ends them. The
+// values share one backing array, with the capacity of each field's slice
+// limited so appending to it copies. Malformed fields are rejected; when
+// lenient, obsolete line folding is unfolded and control characters in
+// values are replaced by spaces instead.
+func readHeader(reader *bufio.Reader, lenient bool) (Header, error) {
+	headers := make(Header)
+	values := make([]string, 0, 8)
+	lastKey := ""
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+
+		// An empty line marks the end of headers
+		if string(line) == "\r\n" {
+			break
+		}
+
+		// A line starting with whitespace continues the previous field
+		if line[0] == ' ' || line[0] == '\t' {
+			if !lenient || lastKey == "" {
+				return nil, errors.New("obsolete line folding is not allowed")
+			}
+			value, err := headerValue(line, true)
+			if err != nil {
+				return nil, err
+			}
+			if value != "" {
+				folded := headers[lastKey]
+				folded[len(folded)-1] = strings.TrimLeft(folded[len(folded)-1]+" "+value, " ")
+			}
+			continue
+		}
+
+		name, rawValue, ok := bytes.Cut(line, []byte{':'})
+		if !ok {
+			return nil, fmt.Errorf("malformed header line")
+		}
+		if err := checkHeaderName(name); err != nil {
+			return nil, err
+		}
+		value, err := headerValue(rawValue, lenient)
+		if err != nil {
+			return nil, err
+		}
+
+		key := canonicalKey(name)
+		if existing, ok := headers[key]; ok {
+			headers[key] = append(existing, value)
+		} else {
+			values = append(values, value)
+			headers[key] = values[len(values)-1 : len(values) : len(values)]
+		}
+		lastKey = key
+	}
+	return headers, nil
+}
+
+// parseRequestLine splits a request line such as "GET /path HTTP/1.1" into
+// its method, request target and protocol.
+func parseRequestLine(line []byte) (method string, target, proto []byte, ok bool) {
+	line = bytes.TrimSpace(line)
+	m, rest, ok1 := bytes.Cut(line, []byte{' '})
+	target, proto, ok2 := bytes.Cut(bytes.TrimLeft(rest, " \t"), []byte{' '})
+	proto = bytes.TrimLeft(proto, " \t")
+	if !ok1 || !ok2 || len(m) == 0 || len(target) == 0 || len(proto) == 0 {
+		return "", nil, nil, false
+	}
+	return internMethod(m), target, proto, true
+}
+
+// internMethod returns the method as a string, without allocating for the
+// standard methods.
+func internMethod(m []byte) string {
+	for _, method := range commonMethods {
+		if string(m) == method {
+			return method
+		}
+	}
+	return string(m)
+}
+
+// canonicalKey returns CanonicalHeaderKey(string(key)), without allocating
+// for common header keys.
+func canonicalKey(key []byte) string {
+	if len(key) > maxInternedKey {
+		return CanonicalHeaderKey(string(key))
+	}
+
+	var buf [maxInternedKey]byte
+	b := buf[:len(key)]
+	upper := true
+	for i, c := range key {
+		if !isTokenChar(c) {
+			return string(key)
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		} else if !upper && 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b[i] = c
+		upper = c == '-'
+	}
+
+	if interned, ok := commonHeaderKeys[string(b)]; ok {
+		return interned
+	}
+	return string(b)
+}
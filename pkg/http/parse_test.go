@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// TestCanonicalKey verifies that canonicalKey matches CanonicalHeaderKey.
+func TestCanonicalKey(t *testing.T) {
+	keys := []string{
+		"host", "CONTENT-TYPE", "x-custom-header", "User-Agent",
+		"invalid key", strings.Repeat("x-long-", 8),
+	}
+	for _, key := range keys {
+		if got, expected := canonicalKey([]byte(key)), CanonicalHeaderKey(key); got != expected {
+			t.Errorf("canonicalKey(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+}
+
+// TestParseCommonStringsWithoutAllocating verifies that standard methods and common header keys are interned.
+func TestParseCommonStringsWithoutAllocating(t *testing.T) {
+	method, key := []byte("GET"), []byte("content-length")
+	allocs := testing.AllocsPerRun(100, func() {
+		internMethod(method)
+		canonicalKey(key)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+// TestParseRequestLine verifies the splitting of request lines.
+func TestParseRequestLine(t *testing.T) {
+	method, target, proto, ok := parseRequestLine([]byte("POST /a?b=c HTTP/1.1\r\n"))
+	if !ok || method != POST || string(target) != "/a?b=c" || string(proto) != "HTTP/1.1" {
+		t.Errorf("Unexpected parse: %q %q %q %v", method, target, proto, ok)
+	}
+
+	for _, line := range []string{"GET\r\n", "GET /\r\n", " / HTTP/1.1\r\n", "\r\n"} {
+		if _, _, _, ok := parseRequestLine([]byte(line)); ok {
+			t.Errorf("Expected %q to be malformed", line)
+		}
+	}
+}
+
+// TestParseRequestLongHeader verifies that header lines longer than the read buffer are parsed.
+func TestParseRequestLongHeader(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	raw := "GET / HTTP/1.1\r\nHost: localhost\r\nX-Long: " + long + "\r\n\r\n"
+
+	req, err := parseRequestWithTimeout(bufio.NewReaderSize(strings.NewReader(raw), 16))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Header.Get("X-Long") != long {
+		t.Errorf("Expected the long header value, got '%s'", req.Header.Get("X-Long"))
+	}
+}
+
+// TestParseRequestRepeatedHeaders verifies that repeated fields don't overwrite the values of other fields.
+func TestParseRequestRepeatedHeaders(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nAccept: a\r\nHost: localhost\r\naccept: b\r\nX-Other: c\r\n\r\n"
+
+	req, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	accept := req.Header.Values("Accept")
+	if len(accept) != 2 || accept[0] != "a" || accept[1] != "b" {
+		t.Errorf("Expected both Accept values, got %v", accept)
+	}
+	if req.Header.Get("Host") != "localhost" || req.Header.Get("X-Other") != "c" {
+		t.Errorf("Unexpected headers: %v", req.Header)
+	}
+
+	// Adding to a field must not clobber the next one
+	req.Header.Add("Host", "other")
+	if req.Header.Get("X-Other") != "c" {
+		t.Errorf("Expected X-Other to be kept, got '%s'", req.Header.Get("X-Other"))
+	}
+}
+
+// TestParseRequestInvalidHeaders verifies that malformed header fields are rejected.
+func TestParseRequestInvalidHeaders(t *testing.T) {
+	tests := map[string]string{
+		"space in name":        "X Custom: a\r\n",
+		"control in name":      "X-Cus\x01tom: a\r\n",
+		"empty name":           ": a\r\n",
+		"NUL in value":         "X-Custom: a\x00b\r\n",
+		"bare CR in value":     "X-Custom: a\rInjected: b\r\n",
+		"DEL in value":         "X-Custom: a\x7f\r\n",
+		"leading continuation": " X-Custom: a\r\n",
+	}
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			raw := "GET / HTTP/1.1\r\n" + header + "Host: a\r\n\r\n"
+			if _, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw))); err == nil {
+				t.Errorf("Expected the request to be rejected")
+			}
+		})
+	}
+}
+
+// TestParseRequestHeaderWhitespace verifies that whitespace around field values is trimmed and kept inside them.
+func TestParseRequestHeaderWhitespace(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nHost: a\r\nX-Custom: \t a \t b \t\r\nX-Empty:\r\n\r\n"
+	req, err := parseRequestWithTimeout(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := req.Header.Get("X-Custom"); got != "a \t b" {
+		t.Errorf("Expected 'a \\t b', got %q", got)
+	}
+	if values := req.Header.Values("X-Empty"); len(values) != 1 || values[0] != "" {
+		t.Errorf("Expected an empty value, got %q", values)
+	}
+}
+
+// TestParseRequestLenientHeaders verifies that lenient parsing unfolds lines and replaces control characters.
+func TestParseRequestLenientHeaders(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nHost: a\r\nX-Folded: a\r\n  b\r\n\tc\r\nX-Control: a\x00b\r\n\r\n"
+	req, err := readRequestMessage(bufio.NewReader(strings.NewReader(raw)), true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := req.Header.Get("X-Folded"); got != "a b c" {
+		t.Errorf("Expected the folded value 'a b c', got %q", got)
+	}
+	if got := req.Header.Get("X-Control"); got != "a b" {
+		t.Errorf("Expected the control character to be replaced, got %q", got)
+	}
+	if req.Header.Get("Host") != "a" {
+		t.Errorf("Expected the other fields to be kept, got %v", req.Header)
+	}
+
+	for _, raw := range []string{
+		"GET / HTTP/1.1\r\n folded: a\r\n\r\n",
+		"GET / HTTP/1.1\r\nX Custom: a\r\n\r\n",
+		"GET / HTTP/1.1\r\nX-Custom : a\r\n\r\n",
+	} {
+		if _, err := readRequestMessage(bufio.NewReader(strings.NewReader(raw)), true); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+// BenchmarkParseRequestHeaders measures parsing a request with browser-like headers.
+func BenchmarkParseRequestHeaders(b *testing.B) {
+	raw := "GET /index.html HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"User-Agent: Mozilla/5.0 (X11; Linux x86_64)\r\n" +
+		"Accept: text/html,application/xhtml+xml\r\n" +
+		"Accept-Language: en-US,en;q=0.5\r\n" +
+		"Accept-Encoding: gzip, deflate, br\r\n" +
+		"Connection: keep-alive\r\n" +
+		"If-None-Match: \"abc\"\r\n" +
+		"Cache-Control: max-age=0\r\n\r\n"
+	src := strings.NewReader(raw)
+	reader := bufio.NewReader(src)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		src.Reset(raw)
+		reader.Reset(src)
+		if _, err := parseRequestWithTimeout(reader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
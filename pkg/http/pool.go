@@ -0,0 +1,46 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Pools of the buffers needed for every connection and response, so busy
+// servers don't allocate them over and over.
+var (
+	bufioReaderPool sync.Pool
+	bufferPool      = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+// newBufioReader returns a pooled buffered reader reading from r.
+func newBufioReader(r io.Reader) *bufio.Reader {
+	if br, ok := bufioReaderPool.Get().(*bufio.Reader); ok {
+		br.Reset(r)
+		return br
+	}
+	return bufio.NewReader(r)
+}
+
+// putBufioReader returns a reader to the pool once its connection is done.
+func putBufioReader(br *bufio.Reader) {
+	br.Reset(nil)
+	bufioReaderPool.Put(br)
+}
+
+// getBuffer returns an empty pooled buffer.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns a buffer to the pool. Large buffers are dropped so a
+// single huge response doesn't pin its memory.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	bufferPool.Put(buf)
+}
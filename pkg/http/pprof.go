@@ -0,0 +1,170 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultProfileDuration is how long CPU profiles and execution traces run
+// when the request doesn't set the seconds parameter.
+const defaultProfileDuration = 30 * time.Second
+
+// EnablePprof serves the runtime profiles under prefix, e.g.
+// mux.EnablePprof("/debug/pprof"), in the format expected by go tool pprof:
+//
+//	go tool pprof http://localhost:8080/debug/pprof/heap
+//	go tool pprof http://localhost:8080/debug/pprof/profile?seconds=10
+//
+// Profiles reveal the internals of the process, so production servers
+// should pass a middleware that authenticates the request; the middleware
+// wraps the profile handlers in the order given.
+func (mux *ServeMux) EnablePprof(prefix string, mws ...Middleware) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	wrap := func(handler func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		for i := len(mws) - 1; i >= 0; i-- {
+			handler = mws[i](handler)
+		}
+		return handler
+	}
+
+	methods := []string{GET, HEAD}
+	mux.AddRoute(prefix, methods, wrap(pprofIndex))
+	mux.AddRoute(prefix+"/cmdline", methods, wrap(pprofCmdline))
+	mux.AddRoute(prefix+"/profile", methods, wrap(pprofCPU))
+	mux.AddRoute(prefix+"/trace", methods, wrap(pprofTrace))
+	mux.AddRoute(prefix+"/:profile", methods, wrap(pprofProfile))
+}
+
+// pprofIndex lists the available profiles.
+func pprofIndex(w ResponseWriter, r *Request) {
+	// Relative links resolve against the directory form of the URL
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		w.Header().Set("Location", r.URL.Path+"/")
+		w.WriteHeader(StatusMovedPermanently)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head><title>Profiles</title></head>\n<body>\n<ul>\n")
+	for _, p := range pprof.Profiles() {
+		fmt.Fprintf(&buf, "<li><a href=\"%[1]s?debug=1\">%[1]s</a> (%[2]d)</li>\n", p.Name(), p.Count())
+	}
+	buf.WriteString("<li><a href=\"profile\">profile</a> (CPU, 30s)</li>\n")
+	buf.WriteString("<li><a href=\"trace?seconds=5\">trace</a> (5s)</li>\n")
+	buf.WriteString("<li><a href=\"cmdline\">cmdline</a></li>\n")
+	buf.WriteString("</ul>\n</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// pprofCmdline responds with the command line of the process, its
+// arguments separated by NUL bytes.
+func pprofCmdline(w ResponseWriter, _ *Request) {
+	body := strings.Join(os.Args, "\x00")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(StatusOK)
+	w.Write([]byte(body))
+}
+
+// pprofProfile responds with the named profile, such as heap or goroutine.
+// With debug=1 or debug=2 the profile is sent as text, and with gc=1 a
+// garbage collection runs before heap profiles are taken.
+func pprofProfile(w ResponseWriter, r *Request) {
+	name := r.Params["profile"]
+	if name == "" {
+		pprofIndex(w, r)
+		return
+	}
+
+	p := pprof.Lookup(name)
+	if p == nil {
+		Error(w, "Unknown profile", StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	debug, _ := strconv.Atoi(query.Get("debug"))
+	if name == "heap" && query.Get("gc") == "1" {
+		runtime.GC()
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteTo(&buf, debug); err != nil {
+		Error(w, "Could not write profile: "+err.Error(), StatusInternalServerError)
+		return
+	}
+	writeProfile(w, name, debug > 0, buf.Bytes())
+}
+
+// pprofCPU responds with a CPU profile recorded for the number of seconds
+// given by the seconds parameter.
+func pprofCPU(w ResponseWriter, r *Request) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		// Only one CPU profile can run at a time
+		Error(w, "Could not start CPU profile: "+err.Error(), StatusInternalServerError)
+		return
+	}
+	sleepProfile(r)
+	pprof.StopCPUProfile()
+
+	writeProfile(w, "profile", false, buf.Bytes())
+}
+
+// pprofTrace responds with an execution trace recorded for the number of
+// seconds given by the seconds parameter.
+func pprofTrace(w ResponseWriter, r *Request) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		Error(w, "Could not start trace: "+err.Error(), StatusInternalServerError)
+		return
+	}
+	sleepProfile(r)
+	trace.Stop()
+
+	writeProfile(w, "trace", false, buf.Bytes())
+}
+
+// sleepProfile waits for the duration requested by the seconds parameter,
+// or until the request is canceled.
+func sleepProfile(r *Request) {
+	duration := defaultProfileDuration
+	if seconds, err := strconv.ParseFloat(r.URL.Query().Get("seconds"), 64); err == nil && seconds > 0 {
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// writeProfile sends a recorded profile, as text or as a download.
+func writeProfile(w ResponseWriter, name string, text bool, data []byte) {
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(StatusOK)
+	w.Write(data)
+}
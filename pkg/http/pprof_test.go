@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// servePprof sends a GET request for target to mux.
+func servePprof(mux *ServeMux, target string, header Header) *MockResponseWriter {
+	u, _ := url.Parse(target)
+	if header == nil {
+		header = make(Header)
+	}
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: u, Header: header})
+	return res
+}
+
+// TestEnablePprof verifies that the index and named profiles are served.
+func TestEnablePprof(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.EnablePprof("/debug/pprof")
+
+	res := servePprof(mux, "/debug/pprof", nil)
+	if res.status != StatusMovedPermanently || res.headers.Get("Location") != "/debug/pprof/" {
+		t.Errorf("Expected a redirect to the index, got %d '%s'", res.status, res.headers.Get("Location"))
+	}
+
+	res = servePprof(mux, "/debug/pprof/", nil)
+	if res.status != StatusOK || !strings.Contains(string(res.body), `href="goroutine?debug=1"`) {
+		t.Errorf("Expected the index to list the goroutine profile, got %d '%s'", res.status, res.body)
+	}
+
+	res = servePprof(mux, "/debug/pprof/goroutine?debug=1", nil)
+	if res.status != StatusOK || !strings.HasPrefix(string(res.body), "goroutine profile:") {
+		t.Errorf("Expected a text goroutine profile, got %d '%s'", res.status, res.body)
+	}
+
+	res = servePprof(mux, "/debug/pprof/heap", nil)
+	if res.status != StatusOK || res.headers.Get("Content-Type") != "application/octet-stream" || len(res.body) == 0 {
+		t.Errorf("Expected a binary heap profile, got %d '%s'", res.status, res.headers.Get("Content-Type"))
+	}
+
+	res = servePprof(mux, "/debug/pprof/missing", nil)
+	if res.status != StatusNotFound {
+		t.Errorf("Expected 404 for an unknown profile, got %d", res.status)
+	}
+}
+
+// TestEnablePprofCPU verifies that CPU profiles honor the seconds parameter.
+func TestEnablePprofCPU(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.EnablePprof("/debug/pprof")
+
+	res := servePprof(mux, "/debug/pprof/profile?seconds=0.1", nil)
+	if res.status != StatusOK || len(res.body) == 0 {
+		t.Errorf("Expected a CPU profile, got %d with %d bytes", res.status, len(res.body))
+	}
+}
+
+// TestEnablePprofMiddleware verifies that the middleware guards every profile endpoint.
+func TestEnablePprofMiddleware(t *testing.T) {
+	auth := func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				Error(w, StatusText(StatusUnauthorized), StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	mux := NewServeMux(nil)
+	mux.EnablePprof("/debug/pprof/", auth)
+
+	for _, target := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline", "/debug/pprof/profile"} {
+		if res := servePprof(mux, target, nil); res.status != StatusUnauthorized {
+			t.Errorf("Expected 401 for %s, got %d", target, res.status)
+		}
+	}
+
+	header := make(Header)
+	header.Set("Authorization", "Bearer secret")
+	if res := servePprof(mux, "/debug/pprof/cmdline", header); res.status != StatusOK {
+		t.Errorf("Expected 200 with credentials, got %d", res.status)
+	}
+}
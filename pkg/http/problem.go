@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Problem is an RFC 9457 (formerly RFC 7807) problem details object, the
+// application/problem+json body of error responses. It is also an error
+// answered with its status by Typed handlers.
+type Problem struct {
+	// Type is a URI identifying the kind of problem. Empty means
+	// "about:blank", a problem described by its status alone.
+	Type string `json:"type,omitempty"`
+	// Title is a short summary of the kind of problem, the status text
+	// when Type is empty.
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status,omitempty"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI identifying this occurrence, such as the request
+	// path.
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members written alongside the standard
+	// ones, e.g. {"balance": 30}. They can't override the standard ones.
+	Extensions map[string]any `json:"-"`
+}
+
+// NewProblem returns the problem of a status, titled with its status text.
+func NewProblem(statusCode int, detail string) *Problem {
+	return &Problem{Title: StatusText(statusCode), Status: statusCode, Detail: detail}
+}
+
+// Error returns the detail of the problem, or its title.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// StatusCode returns the status of the problem, 500 when it is not set.
+func (p *Problem) StatusCode() int {
+	if p.Status == 0 {
+		return StatusInternalServerError
+	}
+	return p.Status
+}
+
+// MarshalJSON encodes the problem with its extension members.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem // Without this method
+	data, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	members := make(map[string]any, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return nil, err
+	}
+	for key, value := range standard {
+		members[key] = value
+	}
+	return json.Marshal(members)
+}
+
+// WriteProblem writes p as an application/problem+json response with its
+// status code.
+func WriteProblem(w ResponseWriter, p *Problem) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		// Only extensions can fail to encode
+		data, _ = json.Marshal(&Problem{Type: p.Type, Title: p.Title, Status: p.Status, Detail: p.Detail, Instance: p.Instance})
+	}
+	w.Header()["Content-Type"] = []string{"application/problem+json"}
+	w.Header()["Content-Length"] = []string{strconv.Itoa(len(data))}
+	w.WriteHeader(p.StatusCode())
+	w.Write(data)
+}
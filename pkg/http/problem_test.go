@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestProblemExtensions verifies that extension members are written alongside the standard ones, which they can't override.
+func TestProblemExtensions(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+	WriteProblem(res, &Problem{
+		Type:       "https://example.test/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]any{"balance": 30, "status": 200},
+	})
+
+	if res.status != StatusForbidden {
+		t.Errorf("Expected status %d, got %d", StatusForbidden, res.status)
+	}
+	want := `{"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.test/probs/out-of-credit"}`
+	if string(res.body) != want {
+		t.Errorf("Expected body '%s', got '%s'", want, res.body)
+	}
+	if ct := res.headers.Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected a problem content type, got '%s'", ct)
+	}
+}
+
+// TestError verifies that Error writes the message as the detail of a problem, unless it repeats the status text.
+func TestError(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Not Found", `{"title":"Not Found","status":404}`},
+		{"no such item", `{"title":"Not Found","status":404,"detail":"no such item"}`},
+	}
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		Error(res, tt.message, StatusNotFound)
+		if res.status != StatusNotFound || string(res.body) != tt.want {
+			t.Errorf("%s: expected 404 '%s', got %d '%s'", tt.message, tt.want, res.status, res.body)
+		}
+	}
+}
+
+// TestTypedProblem verifies that Typed handlers write a returned *Problem as is.
+func TestTypedProblem(t *testing.T) {
+	handler := Typed(func(ctx context.Context, in struct{}) (struct{}, error) {
+		p := NewProblem(StatusConflict, "item is locked")
+		p.Extensions = map[string]any{"lockedBy": "alice"}
+		return struct{}{}, fmt.Errorf("update: %w", p)
+	})
+	res := serveTyped(handler, "/items/1", "", "", nil)
+
+	want := `{"detail":"item is locked","lockedBy":"alice","status":409,"title":"Conflict"}`
+	if res.status != StatusConflict || string(res.body) != want {
+		t.Errorf("Expected 409 '%s', got %d '%s'", want, res.status, res.body)
+	}
+	var p *Problem
+	if !errors.As(fmt.Errorf("x: %w", NewProblem(StatusGone, "")), &p) || p.StatusCode() != StatusGone {
+		t.Errorf("Expected problems to be errors with a status")
+	}
+}
@@ -0,0 +1,360 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the passive health checks of a ReverseProxy.
+const (
+	defaultMaxFails    = 3
+	defaultFailTimeout = 30 * time.Second
+)
+
+// LoadBalancing is the strategy a ReverseProxy uses to pick the upstream of
+// each request.
+type LoadBalancing int
+
+const (
+	// RoundRobin sends requests to the upstreams in turn.
+	RoundRobin LoadBalancing = iota
+	// LeastConnections sends requests to the upstream with the fewest
+	// requests in flight, relative to its weight.
+	LeastConnections
+	// WeightedRoundRobin sends requests to the upstreams in turn, in
+	// proportion to their weights, spreading each upstream's share evenly.
+	WeightedRoundRobin
+)
+
+// errNoUpstream is returned when every upstream is ejected.
+var errNoUpstream = errors.New("no healthy upstream")
+
+// hopHeaders are the hop-by-hop headers, which apply to a single
+// connection and are not forwarded (RFC 9110, 7.6.1).
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Upstream is a backend server of a ReverseProxy. Each upstream keeps its
+// own pool of connections.
+type Upstream struct {
+	// URL is the base URL requests are forwarded to. Its path, if any, is
+	// prepended to the path of the requests.
+	URL *url.URL
+	// Weight is the upstream's share of the requests with the
+	// LeastConnections and WeightedRoundRobin strategies. Zero means 1.
+	Weight int
+	// MaxIdleConns is how many idle connections are kept to the upstream.
+	// Zero means 2, a negative value disables keep-alive.
+	MaxIdleConns int
+
+	clientOnce sync.Once
+	client     *Client
+	active     atomic.Int64 // Requests in flight
+
+	mu           sync.Mutex
+	fails        int       // Consecutive failures
+	ejectedUntil time.Time // Zero unless ejected
+
+	current int // Running weight of the smooth weighted round-robin, guarded by the proxy
+}
+
+// NewUpstream creates an upstream for the base URL.
+func NewUpstream(rawURL string) (*Upstream, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("http: upstream URL must be an absolute http or https URL: " + rawURL)
+	}
+	return &Upstream{URL: u}, nil
+}
+
+// weight returns the upstream's weight, at least 1.
+func (u *Upstream) weight() int {
+	return max(u.Weight, 1)
+}
+
+// healthy reports whether the upstream may receive requests at now.
+func (u *Upstream) healthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !now.Before(u.ejectedUntil)
+}
+
+// ReverseProxy forwards requests to a set of upstream servers and copies
+// their responses back, balancing the load between them. Register it as
+// a handler, e.g. with mux.SetDefaultHandler(proxy.ServeHTTP).
+//
+// Forwarded requests tell the upstream about the client with a Forwarded
+// header (RFC 7239) as well as the X-Forwarded-For, X-Forwarded-Host and
+// X-Forwarded-Proto headers.
+//
+// Upstreams are health checked passively: one that fails MaxFails requests
+// in a row, with a connection error or a 502, 503 or 504 status, is
+// ejected for FailTimeout before it receives requests again. Requests get
+// 502 Bad Gateway when their upstream fails, and 503 Service Unavailable
+// when every upstream is ejected.
+type ReverseProxy struct {
+	Upstreams []*Upstream
+	Strategy  LoadBalancing
+
+	// MaxFails is how many consecutive failures eject an upstream. Zero
+	// means 3, a negative value disables ejection.
+	MaxFails int
+	// FailTimeout is how long an upstream stays ejected. Zero means 30
+	// seconds.
+	FailTimeout time.Duration
+
+	// Timeout limits each forwarded request. Zero means no timeout.
+	Timeout time.Duration
+
+	// ErrorLog receives the errors of failed upstream requests. When nil,
+	// the standard logger is used.
+	ErrorLog Logger
+
+	mu   sync.Mutex
+	next int // Next upstream of the round-robin
+}
+
+// NewReverseProxy creates a round-robin proxy to the upstream base URLs.
+func NewReverseProxy(rawURLs ...string) (*ReverseProxy, error) {
+	p := &ReverseProxy{}
+	for _, rawURL := range rawURLs {
+		u, err := NewUpstream(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		p.Upstreams = append(p.Upstreams, u)
+	}
+	return p, nil
+}
+
+// ServeHTTP forwards the request to an upstream and writes its response.
+func (p *ReverseProxy) ServeHTTP(w ResponseWriter, r *Request) {
+	u, err := p.pick(time.Now())
+	if err != nil {
+		Error(w, StatusText(StatusServiceUnavailable), StatusServiceUnavailable)
+		return
+	}
+
+	u.active.Add(1)
+	res, err := u.getClient(p.Timeout).Do(p.outgoing(u, r))
+	u.active.Add(-1)
+
+	if err != nil {
+		if r.Context().Err() != nil {
+			// The client went away, the upstream isn't to blame
+			return
+		}
+		p.observe(u, false)
+		p.logf("http: proxy error from %s: %v", u.URL.Host, err)
+		Error(w, StatusText(StatusBadGateway), StatusBadGateway)
+		return
+	}
+	switch res.StatusCode {
+	case StatusBadGateway, StatusServiceUnavailable, StatusGatewayTimeout:
+		p.observe(u, false)
+	default:
+		p.observe(u, true)
+	}
+
+	header := w.Header()
+	for key, values := range res.Header {
+		header[key] = values
+	}
+	// The length is the upstream's, the body is passed on as it came, and
+	// HEAD responses keep announcing the length of the GET one
+	removeHopHeaders(header)
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+// outgoing builds the request forwarded to the upstream.
+func (p *ReverseProxy) outgoing(u *Upstream, r *Request) *Request {
+	target := *u.URL
+	// The escaped path is kept, so encoded slashes and such reach the
+	// upstream as the client sent them
+	target.Path = joinPath(u.URL.Path, r.URL.Path)
+	target.RawPath = joinPath(u.URL.EscapedPath(), r.URL.EscapedPath())
+	target.RawQuery = r.URL.RawQuery
+
+	header := make(Header, len(r.Header)+2)
+	for key, values := range r.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	removeHopHeaders(header)
+	header.Del("Content-Length")
+
+	// The upstream sees its own host, the original one is forwarded
+	host := header.Get("Host")
+	if host != "" {
+		header.Set("X-Forwarded-Host", host)
+		header.Del("Host")
+	}
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	header.Set("X-Forwarded-Proto", proto)
+
+	// The client is appended to the hops of earlier proxies
+	element := ForwardedElement{Host: host, Proto: proto}
+	if client := r.ClientIP(); client != "" {
+		element.For = forwardedNode(client)
+		if prior := header.Values("X-Forwarded-For"); len(prior) > 0 {
+			client = strings.Join(prior, ", ") + ", " + client
+		}
+		header.Set("X-Forwarded-For", client)
+	}
+	header.Add("Forwarded", element.String())
+
+	out := &Request{
+		Method:        r.Method,
+		URL:           &target,
+		Proto:         "HTTP/1.1",
+		Header:        header,
+		Body:          r.Body,
+		ContentLength: r.ContentLength,
+	}
+	return out.WithContext(r.Context())
+}
+
+// removeHopHeaders deletes the hop-by-hop headers, including the ones
+// listed in Connection.
+func removeHopHeaders(h Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// joinPath joins the base path of an upstream and a request path with a
+// single slash.
+func joinPath(base, path string) string {
+	switch {
+	case base == "" || base == "/":
+		return path
+	case strings.HasSuffix(base, "/") && strings.HasPrefix(path, "/"):
+		return base + path[1:]
+	case !strings.HasSuffix(base, "/") && !strings.HasPrefix(path, "/"):
+		return base + "/" + path
+	}
+	return base + path
+}
+
+// getClient returns the client holding the upstream's connection pool.
+func (u *Upstream) getClient(timeout time.Duration) *Client {
+	u.clientOnce.Do(func() {
+		u.client = &Client{
+			Timeout:             timeout,
+			MaxIdleConnsPerHost: u.MaxIdleConns,
+			MaxRedirects:        -1,   // Redirects are the downstream client's business
+			DisableCompression:  true, // So are content codings
+		}
+	})
+	return u.client
+}
+
+// pick chooses the upstream of a request among the healthy ones.
+func (p *ReverseProxy) pick(now time.Time) (*Upstream, error) {
+	healthy := make([]*Upstream, 0, len(p.Upstreams))
+	for _, u := range p.Upstreams {
+		if u.healthy(now) {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, errNoUpstream
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.Strategy {
+	case LeastConnections:
+		// Ties go round-robin so idle upstreams share the load
+		var best *Upstream
+		var bestLoad float64
+		for i := range healthy {
+			u := healthy[(p.next+i)%len(healthy)]
+			load := float64(u.active.Load()) / float64(u.weight())
+			if best == nil || load < bestLoad {
+				best, bestLoad = u, load
+			}
+		}
+		p.next++
+		return best, nil
+
+	case WeightedRoundRobin:
+		// Smooth weighted round-robin: every upstream gains its weight,
+		// the richest one is picked and pays back the total
+		var best *Upstream
+		total := 0
+		for _, u := range healthy {
+			u.current += u.weight()
+			if best == nil || u.current > best.current {
+				best = u
+			}
+			total += u.weight()
+		}
+		best.current -= total
+		return best, nil
+	}
+
+	u := healthy[p.next%len(healthy)]
+	p.next++
+	return u, nil
+}
+
+// observe records the outcome of a request to the upstream, ejecting it
+// after too many consecutive failures.
+func (p *ReverseProxy) observe(u *Upstream, ok bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if ok {
+		u.fails = 0
+		return
+	}
+	maxFails := p.MaxFails
+	if maxFails == 0 {
+		maxFails = defaultMaxFails
+	}
+	u.fails++
+	if maxFails < 0 || u.fails < maxFails {
+		return
+	}
+
+	timeout := p.FailTimeout
+	if timeout <= 0 {
+		timeout = defaultFailTimeout
+	}
+	u.fails = 0
+	u.ejectedUntil = time.Now().Add(timeout)
+	p.logf("http: upstream %s ejected for %s", u.URL.Host, timeout)
+}
+
+// logf logs a proxy error to ErrorLog, or to the standard logger when it
+// isn't set.
+func (p *ReverseProxy) logf(format string, v ...any) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
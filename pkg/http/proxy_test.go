@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startUpstream starts a server answering with its name, the path it was
+// asked for and the request's forwarding headers.
+func startUpstream(t *testing.T, name string, status int) string {
+	server, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Header().Set("X-Forwarded-Host", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("X-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Forwarded", strings.Join(r.Header.Values("Forwarded"), ", "))
+		w.Header().Set("X-Hop", r.Header.Get("X-Hop"))
+		w.Header().Set("Connection", "X-Secret")
+		w.Header().Set("X-Secret", "hidden")
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+	return "http://" + addr
+}
+
+// proxyRequest sends a request through the proxy and returns the response.
+func proxyRequest(t *testing.T, p *ReverseProxy, method, target, body string) *MockResponseWriter {
+	t.Helper()
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("Invalid URL %q: %v", target, err)
+	}
+	req := &Request{
+		Method:        method,
+		URL:           u,
+		Header:        Header{"Host": {"public.test"}, "Connection": {"X-Hop"}, "X-Hop": {"dropped"}, "X-Forwarded-For": {"198.51.100.1"}},
+		RemoteAddr:    "[2001:db8::7]:4711",
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+	res := &MockResponseWriter{headers: make(Header)}
+	p.ServeHTTP(res, req)
+	return res
+}
+
+// TestReverseProxyForwards verifies paths, bodies and headers are forwarded, without hop-by-hop headers.
+func TestReverseProxyForwards(t *testing.T) {
+	p, err := NewReverseProxy(startUpstream(t, "a", StatusCreated) + "/base/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	res := proxyRequest(t, p, POST, "/items?id=1", "lamp")
+	if res.status != StatusCreated || string(res.body) != "lamp" {
+		t.Errorf("Unexpected response %d '%s'", res.status, res.body)
+	}
+	h := res.headers
+	if h.Get("X-Path") != "/base/items?id=1" {
+		t.Errorf("Expected the upstream path to be prepended, got '%s'", h.Get("X-Path"))
+	}
+	if got := proxyRequest(t, p, GET, "/files/a%2Fb", "").headers.Get("X-Path"); got != "/base/files/a%2Fb" {
+		t.Errorf("Expected the escaped path to be kept, got '%s'", got)
+	}
+	if h.Get("X-Forwarded-Host") != "public.test" {
+		t.Errorf("Expected the original host to be forwarded, got '%s'", h.Get("X-Forwarded-Host"))
+	}
+	if h.Get("X-Forwarded-For") != "198.51.100.1, 2001:db8::7" {
+		t.Errorf("Expected the client to be appended to X-Forwarded-For, got '%s'", h.Get("X-Forwarded-For"))
+	}
+	if want := `for="[2001:db8::7]";host=public.test;proto=http`; h.Get("X-Forwarded") != want {
+		t.Errorf("Expected Forwarded '%s', got '%s'", want, h.Get("X-Forwarded"))
+	}
+	if h.Get("X-Hop") != "" || h.Get("X-Secret") != "" || h.Get("Connection") != "" {
+		t.Errorf("Expected hop-by-hop headers to be dropped, got %v", h)
+	}
+}
+
+// TestReverseProxyStrategies verifies how each strategy spreads requests over the upstreams.
+func TestReverseProxyStrategies(t *testing.T) {
+	a, err := NewUpstream(startUpstream(t, "a", StatusOK))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, _ := NewUpstream(startUpstream(t, "b", StatusOK))
+	b.Weight = 3
+
+	tests := []struct {
+		strategy LoadBalancing
+		want     string
+	}{
+		{RoundRobin, "abab"},
+		{WeightedRoundRobin, "babbbabb"},
+		{LeastConnections, "abab"},
+	}
+	for _, tt := range tests {
+		p := &ReverseProxy{Upstreams: []*Upstream{a, b}, Strategy: tt.strategy}
+		var got strings.Builder
+		for range len(tt.want) {
+			got.WriteString(proxyRequest(t, p, GET, "/", "").headers.Get("X-Upstream"))
+		}
+		if got.String() != tt.want {
+			t.Errorf("Strategy %d: expected %s, got %s", tt.strategy, tt.want, got.String())
+		}
+	}
+}
+
+// TestReverseProxyLeastConnections verifies that busy upstreams are avoided.
+func TestReverseProxyLeastConnections(t *testing.T) {
+	a, _ := NewUpstream("http://a.test")
+	b, _ := NewUpstream("http://b.test")
+	a.active.Store(2)
+	b.active.Store(1)
+
+	p := &ReverseProxy{Upstreams: []*Upstream{a, b}, Strategy: LeastConnections}
+	for range 3 {
+		if u, _ := p.pick(time.Now()); u != b {
+			t.Fatalf("Expected the least busy upstream, got %s", u.URL.Host)
+		}
+	}
+}
+
+// TestReverseProxyEjection verifies that failing upstreams are ejected for FailTimeout.
+func TestReverseProxyEjection(t *testing.T) {
+	bad, _ := NewUpstream(startUpstream(t, "bad", StatusServiceUnavailable))
+	good, _ := NewUpstream(startUpstream(t, "good", StatusOK))
+	p := &ReverseProxy{
+		Upstreams:   []*Upstream{bad, good},
+		MaxFails:    2,
+		FailTimeout: time.Hour,
+		ErrorLog:    log.New(io.Discard, "", 0),
+	}
+
+	var got strings.Builder
+	for range 6 {
+		got.WriteString(proxyRequest(t, p, GET, "/", "").headers.Get("X-Upstream") + " ")
+	}
+	if got.String() != "bad good bad good good good " {
+		t.Errorf("Expected bad to be ejected after 2 failures, got %s", got.String())
+	}
+
+	good.mu.Lock()
+	good.ejectedUntil = time.Now().Add(time.Hour)
+	good.mu.Unlock()
+	if res := proxyRequest(t, p, GET, "/", ""); res.status != StatusServiceUnavailable {
+		t.Errorf("Expected %d without healthy upstreams, got %d", StatusServiceUnavailable, res.status)
+	}
+}
+
+// TestReverseProxyContentLength verifies that the upstream Content-Length is kept, for HEAD requests and bodies too large to be buffered.
+func TestReverseProxyContentLength(t *testing.T) {
+	large := strings.Repeat("x", 8<<10)
+	upstream, upstreamAddr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/large" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+			w.Write([]byte(large))
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer upstream.Shutdown(context.Background())
+	p, _ := NewReverseProxy("http://" + upstreamAddr)
+	server, addr, _ := startServer(t, p)
+	defer server.Shutdown(context.Background())
+
+	for _, tt := range []struct{ method, path, length string }{
+		{GET, "/", "11"},
+		{HEAD, "/", "11"},
+		{GET, "/large", strconv.Itoa(len(large))},
+	} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		conn.Write([]byte(tt.method + " " + tt.path + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		res, err := ReadResponse(bufio.NewReader(conn), &Request{Method: tt.method})
+		if err != nil {
+			t.Fatalf("%s %s: unexpected error: %v", tt.method, tt.path, err)
+		}
+		if got := res.Header.Get("Content-Length"); got != tt.length || res.Close {
+			t.Errorf("%s %s: expected Content-Length %s on a kept-alive connection, got '%s' (close %v)", tt.method, tt.path, tt.length, got, res.Close)
+		}
+		conn.Close()
+	}
+}
+
+// TestReverseProxyBadGateway verifies that unreachable upstreams answer 502.
+func TestReverseProxyBadGateway(t *testing.T) {
+	p, _ := NewReverseProxy("http://127.0.0.1:1")
+	p.ErrorLog = log.New(io.Discard, "", 0)
+
+	if res := proxyRequest(t, p, GET, "/", ""); res.status != StatusBadGateway {
+		t.Errorf("Expected %d, got %d", StatusBadGateway, res.status)
+	}
+}
+
+// TestJoinPath verifies that upstream and request paths are joined with a single slash.
+func TestJoinPath(t *testing.T) {
+	tests := []struct{ base, path, want string }{
+		{"", "/a", "/a"},
+		{"/", "/a", "/a"},
+		{"/api", "/a", "/api/a"},
+		{"/api/", "/a", "/api/a"},
+		{"/api", "a", "/api/a"},
+	}
+	for _, tt := range tests {
+		if got := joinPath(tt.base, tt.path); got != tt.want {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", tt.base, tt.path, got, tt.want)
+		}
+	}
+}
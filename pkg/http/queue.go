@@ -0,0 +1,109 @@
+package http
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RequestQueue admits a bounded number of requests at once to a group of
+// routes, queueing the excess for a while and shedding the rest with 503
+// Service Unavailable, so bursts degrade gracefully instead of piling up
+// goroutines and latency. Routes share a queue by using its middleware:
+//
+//	reports := http.NewRequestQueue(4, 16, 2*time.Second)
+//	mux.AddRoute("/reports/:id", []string{http.GET}, report).Use(reports.Middleware())
+//	mux.AddRoute("/reports", []string{http.POST}, create).Use(reports.Middleware())
+type RequestQueue struct {
+	// RetryAfter is the Retry-After of shed requests, in whole seconds.
+	// Zero omits the header.
+	RetryAfter time.Duration
+
+	slots    chan struct{}
+	maxQueue int64
+	maxWait  time.Duration
+	waiting  atomic.Int64
+	shed     atomic.Uint64
+}
+
+// NewRequestQueue creates a queue running up to maxConcurrent requests at
+// once, with up to maxQueue more waiting at most maxWait for their turn.
+// Requests arriving to a full queue are shed right away. A zero maxWait
+// lets requests wait until their context is done, which happens when
+// their client disconnects.
+func NewRequestQueue(maxConcurrent, maxQueue int, maxWait time.Duration) *RequestQueue {
+	return &RequestQueue{
+		slots:    make(chan struct{}, max(maxConcurrent, 1)),
+		maxQueue: int64(max(maxQueue, 0)),
+		maxWait:  maxWait,
+	}
+}
+
+// Middleware returns middleware admitting requests through the queue.
+func (q *RequestQueue) Middleware() Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if !q.acquire(r) {
+				q.shed.Add(1)
+				if q.RetryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int((q.RetryAfter+time.Second-1)/time.Second)))
+				}
+				Error(w, StatusText(StatusServiceUnavailable), StatusServiceUnavailable)
+				return
+			}
+			defer q.release()
+			next(w, r)
+		}
+	}
+}
+
+// acquire takes a slot for the request, waiting in the queue when there
+// is room in it. It reports false when the request is shed.
+func (q *RequestQueue) acquire(r *Request) bool {
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if q.waiting.Add(1) > q.maxQueue {
+		q.waiting.Add(-1)
+		return false
+	}
+	defer q.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if q.maxWait > 0 {
+		timer := time.NewTimer(q.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// release frees the slot of a finished request.
+func (q *RequestQueue) release() {
+	<-q.slots
+}
+
+// Running returns the number of requests being served.
+func (q *RequestQueue) Running() int {
+	return len(q.slots)
+}
+
+// Waiting returns the number of requests waiting in the queue.
+func (q *RequestQueue) Waiting() int {
+	return int(q.waiting.Load())
+}
+
+// Shed returns how many requests were turned away.
+func (q *RequestQueue) Shed() uint64 {
+	return q.shed.Load()
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRequestQueue verifies that excess requests wait in the queue and overflowing ones are shed with 503.
+func TestRequestQueue(t *testing.T) {
+	q := NewRequestQueue(1, 1, time.Second)
+	q.RetryAfter = 2 * time.Second
+	release := make(chan struct{})
+	handler := q.Middleware()(func(w ResponseWriter, r *Request) {
+		<-release
+		w.WriteHeader(StatusOK)
+	})
+	serve := func() *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/reports"}})
+		return res
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*MockResponseWriter, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = serve()
+		}()
+		// The first request runs, the second one waits
+		deadline := time.Now().Add(time.Second)
+		for q.Running()+q.Waiting() != i+1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if q.Running() != 1 || q.Waiting() != 1 {
+		t.Fatalf("Expected 1 running and 1 waiting request, got %d and %d", q.Running(), q.Waiting())
+	}
+
+	res := serve()
+	if res.status != StatusServiceUnavailable || res.headers.Get("Retry-After") != "2" {
+		t.Errorf("Expected %d with Retry-After 2 when the queue is full, got %d", StatusServiceUnavailable, res.status)
+	}
+	if q.Shed() != 1 {
+		t.Errorf("Expected 1 shed request, got %d", q.Shed())
+	}
+
+	close(release)
+	wg.Wait()
+	for i, res := range results {
+		if res.status != StatusOK {
+			t.Errorf("Request %d: expected %d, got %d", i, StatusOK, res.status)
+		}
+	}
+}
+
+// TestRequestQueueMaxWait verifies that queued requests give up after the maximum wait.
+func TestRequestQueueMaxWait(t *testing.T) {
+	q := NewRequestQueue(1, 4, 10*time.Millisecond)
+	q.slots <- struct{}{} // A request that never finishes
+
+	res := &MockResponseWriter{headers: make(Header)}
+	q.Middleware()(func(w ResponseWriter, r *Request) {
+		t.Error("Expected the handler not to run")
+	})(res, &Request{Method: GET, URL: &url.URL{Path: "/reports"}})
+
+	if res.status != StatusServiceUnavailable || res.headers.Get("Retry-After") != "" {
+		t.Errorf("Expected %d without Retry-After, got %d", StatusServiceUnavailable, res.status)
+	}
+	if q.Waiting() != 0 {
+		t.Errorf("Expected the queue to be empty, got %d", q.Waiting())
+	}
+}
+
+// TestRequestQueueClientDisconnect verifies that without maxWait, queued requests leave once their client disconnects.
+func TestRequestQueueClientDisconnect(t *testing.T) {
+	q := NewRequestQueue(1, 4, 0)
+	q.slots <- struct{}{} // A request that never finishes
+
+	server, addr, _ := startServer(t, HandlerFunc(q.Middleware()(func(w ResponseWriter, r *Request) {
+		t.Error("Expected the handler not to run")
+	})))
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.Write([]byte("GET /reports HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	if !waitFor(t, func() bool { return q.Waiting() == 1 }) {
+		t.Fatal("Expected the request to be queued")
+	}
+	conn.Close()
+
+	if !waitFor(t, func() bool { return q.Waiting() == 0 }) {
+		t.Errorf("Expected the request to leave the queue, got %d waiting", q.Waiting())
+	}
+	if q.Shed() != 1 {
+		t.Errorf("Expected the request to be shed, got %d", q.Shed())
+	}
+}
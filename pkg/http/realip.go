@@ -0,0 +1,122 @@
+package http
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the IP address of the client that sent the request.
+// Behind trusted proxies, configured with Server.TrustedProxies or the
+// RealIP middleware, it is the address they forwarded; otherwise it is
+// the address of the peer. It is empty when the request didn't come from
+// a network connection.
+func (r *Request) ClientIP() string {
+	if r.clientIP != "" {
+		return r.clientIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// RealIP returns middleware resolving the client address of requests sent
+// through the trusted proxies, for servers without Server.TrustedProxies,
+// such as ones run by net/http through the stdhttp adapter. The request's
+// RemoteAddr is replaced by the client IP, which ClientIP returns too.
+//
+// Headers from untrusted peers are ignored, so clients can't spoof their
+// address. See Server.TrustedProxies for how they are read.
+func RealIP(trusted ...netip.Prefix) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			ip := resolveClientIP(r.ClientIP(), r.Header, trusted)
+			if ip == "" {
+				next(w, r)
+				return
+			}
+			r2 := *r
+			r2.RemoteAddr = ip
+			r2.clientIP = ip
+			next(w, &r2)
+		}
+	}
+}
+
+// resolveClientIP returns the address of the client of a request coming
+// from peer. When peer is trusted, the forwarded addresses are walked from
+// the nearest one, and the first untrusted address is the client; without
+// Forwarded or X-Forwarded-For, X-Real-IP is used.
+func resolveClientIP(peer string, h Header, trusted []netip.Prefix) string {
+	if len(trusted) == 0 || !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	hops := forwardedHops(h)
+	if len(hops) == 0 {
+		if ip, err := netip.ParseAddr(strings.TrimSpace(h.Get("X-Real-IP"))); err == nil {
+			return ip.Unmap().String()
+		}
+		return peer
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// Whatever lies beyond a malformed hop can't be trusted
+			break
+		}
+		client = ip.Unmap().String()
+		if !isTrusted(client, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// forwardedHops returns the addresses of the clients and proxies a request
+// went through, from the Forwarded header, or X-Forwarded-For without it.
+// Hops that aren't IP addresses are returned as "".
+func forwardedHops(h Header) []string {
+	var hops []string
+	if _, ok := h["Forwarded"]; ok {
+		elements, err := ParseForwarded(h)
+		if err != nil {
+			return []string{""}
+		}
+		for _, e := range elements {
+			if ip := nodeAddr(e.For); ip.IsValid() {
+				hops = append(hops, ip.String())
+			} else {
+				hops = append(hops, "")
+			}
+		}
+		return hops
+	}
+
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// isTrusted reports whether the IP address is in one of the prefixes.
+func isTrusted(addr string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+// TestResolveClientIP verifies that forwarded addresses are only believed from trusted proxies.
+func TestResolveClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	tests := []struct {
+		name, peer string
+		header     Header
+		want       string
+	}{
+		{"direct", "198.51.100.1", Header{}, "198.51.100.1"},
+		{"untrusted peer", "198.51.100.1", Header{"X-Forwarded-For": {"1.2.3.4"}}, "198.51.100.1"},
+		{"trusted peer", "10.0.0.1", Header{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		{"spoofed hops", "10.0.0.1", Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.7", "10.0.0.2"}}, "203.0.113.7"},
+		{"all trusted", "10.0.0.1", Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"malformed hop", "10.0.0.1", Header{"X-Forwarded-For": {"203.0.113.7, junk"}}, "10.0.0.1"},
+		{"real ip", "::1", Header{"X-Real-Ip": {"203.0.113.9"}}, "203.0.113.9"},
+		{"mapped", "::ffff:10.0.0.1", Header{"X-Forwarded-For": {"::ffff:203.0.113.7"}}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		if got := resolveClientIP(tt.peer, tt.header, trusted); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestRealIP verifies that the middleware replaces the address of requests from trusted proxies.
+func TestRealIP(t *testing.T) {
+	var remoteAddr, clientIP string
+	handler := RealIP(netip.MustParsePrefix("10.0.0.0/8"))(func(w ResponseWriter, r *Request) {
+		remoteAddr, clientIP = r.RemoteAddr, r.ClientIP()
+	})
+
+	req := &Request{
+		Method:     GET,
+		URL:        &url.URL{Path: "/"},
+		Header:     Header{"X-Forwarded-For": {"203.0.113.7"}},
+		RemoteAddr: "10.1.2.3:51000",
+	}
+	handler(&MockResponseWriter{headers: make(Header)}, req)
+	if remoteAddr != "203.0.113.7" || clientIP != "203.0.113.7" {
+		t.Errorf("Expected the forwarded client, got RemoteAddr %s and ClientIP %s", remoteAddr, clientIP)
+	}
+	if req.RemoteAddr != "10.1.2.3:51000" || req.ClientIP() != "10.1.2.3" {
+		t.Errorf("Expected the original request to be left alone, got %s", req.RemoteAddr)
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultMaxRedirects is how many redirects a Client follows when
+// MaxRedirects is zero.
+const defaultMaxRedirects = 10
+
+// isRedirect reports whether a status tells the client to follow the
+// Location header.
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case StatusMovedPermanently, StatusFound, StatusSeeOther, StatusTemporaryRedirect, StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// nextRedirect returns the request that follows the redirect res answered
+// req with, or nil when res is to be returned as is.
+//
+// 301, 302 and 303 redirects are followed with a GET without a body, as
+// browsers do, except for HEAD requests. 307 and 308 redirects repeat the
+// method and body; when the body can't be sent again res is returned.
+func (c *Client) nextRedirect(req *Request, res *ClientResponse, via []*Request, replayable bool) (*Request, error) {
+	location := res.Header.Get("Location")
+	if !isRedirect(res.StatusCode) || location == "" {
+		return nil, nil
+	}
+
+	limit := c.MaxRedirects
+	if limit == 0 {
+		limit = defaultMaxRedirects
+	}
+	if limit < 0 {
+		return nil, nil
+	}
+	if len(via) >= limit {
+		return nil, fmt.Errorf("stopped after %d redirects", limit)
+	}
+
+	target, err := req.URL.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Location header %q: %w", location, err)
+	}
+
+	next := &Request{
+		Method:        req.Method,
+		URL:           target,
+		Proto:         req.Proto,
+		Header:        make(Header, len(req.Header)),
+		Body:          req.Body,
+		ContentLength: req.ContentLength,
+		ctx:           req.ctx,
+	}
+	for key, values := range req.Header {
+		next.Header[key] = values
+	}
+
+	if res.StatusCode == StatusTemporaryRedirect || res.StatusCode == StatusPermanentRedirect {
+		if !replayable {
+			return nil, nil
+		}
+		if req.Body != nil {
+			// The buffered body is what gets sent again
+			next.Body = io.NopCloser(bytes.NewReader(nil))
+		}
+	} else {
+		if req.Method != HEAD {
+			next.Method = GET
+		}
+		next.Body = nil
+		next.ContentLength = 0
+		next.Header.Del("Content-Type")
+		next.Header.Del("Content-Length")
+	}
+
+	// Credentials are only sent back to the host they were meant for
+	if !strings.EqualFold(target.Host, req.URL.Host) {
+		next.Header.Del("Authorization")
+		next.Header.Del("Cookie")
+		// An explicit Host header would name the wrong site
+		next.Header.Del("Host")
+	}
+
+	if c.CheckRedirect != nil {
+		if err := c.CheckRedirect(next, append(via, req)); err != nil {
+			if err == ErrUseLastResponse {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// redirectServer starts a server that redirects /from/<status> to /to and
+// echoes the method and body of requests to /to.
+func redirectServer(t *testing.T) string {
+	mux := NewServeMux(nil)
+	mux.Handle("/from/:status", func(w ResponseWriter, r *Request) {
+		status, _ := strconv.Atoi(r.Params["status"])
+		w.Header().Set("Location", "/to")
+		w.WriteHeader(status)
+	})
+	mux.Handle("/to", func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.Method + " " + string(body)))
+	})
+	mux.Handle("/loop", func(w ResponseWriter, r *Request) {
+		w.Header().Set("Location", "/loop")
+		w.WriteHeader(StatusFound)
+	})
+
+	_, addr, _ := startServer(t, mux)
+	return "http://" + addr
+}
+
+// TestClientRedirectMethods verifies the method and body semantics of each redirect status.
+func TestClientRedirectMethods(t *testing.T) {
+	base := redirectServer(t)
+	client := &Client{}
+
+	tests := map[int]string{
+		StatusMovedPermanently:  "GET ",
+		StatusFound:             "GET ",
+		StatusSeeOther:          "GET ",
+		StatusTemporaryRedirect: "POST payload",
+		StatusPermanentRedirect: "POST payload",
+	}
+	for status, expected := range tests {
+		res, err := client.Post(base+"/from/"+strconv.Itoa(status), "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", status, err)
+			continue
+		}
+		got := readBody(t, res)
+		if res.StatusCode != StatusOK || got != expected {
+			t.Errorf("%d: expected '%s', got %d '%s'", status, expected, res.StatusCode, got)
+		}
+	}
+}
+
+// TestClientRedirectLimit verifies that redirect loops stop at MaxRedirects.
+func TestClientRedirectLimit(t *testing.T) {
+	base := redirectServer(t)
+
+	_, err := (&Client{MaxRedirects: 3}).Get(base + "/loop")
+	if err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Errorf("Expected the redirect limit error, got %v", err)
+	}
+
+	res, err := (&Client{MaxRedirects: -1}).Get(base + "/loop")
+	if err != nil || res.StatusCode != StatusFound {
+		t.Errorf("Expected the redirect itself when following is disabled, got %v", err)
+	}
+}
+
+// TestClientCheckRedirect verifies that CheckRedirect sees the chain and can stop it.
+func TestClientCheckRedirect(t *testing.T) {
+	base := redirectServer(t)
+
+	var chain []string
+	client := &Client{CheckRedirect: func(req *Request, via []*Request) error {
+		chain = append(chain, via[len(via)-1].URL.Path+" -> "+req.URL.Path)
+		return ErrUseLastResponse
+	}}
+	res, err := client.Get(base + "/from/302")
+	if err != nil || res.StatusCode != StatusFound {
+		t.Errorf("Expected the 302 response, got %v", err)
+	}
+	if len(chain) != 1 || chain[0] != "/from/302 -> /to" {
+		t.Errorf("Unexpected redirect chain %v", chain)
+	}
+
+	errStop := errors.New("stop")
+	client.CheckRedirect = func(*Request, []*Request) error { return errStop }
+	if _, err := client.Get(base + "/from/302"); !errors.Is(err, errStop) {
+		t.Errorf("Expected the CheckRedirect error, got %v", err)
+	}
+}
+
+// TestClientRedirectStreamedBody verifies that 307 responses are returned when the body can't be resent.
+func TestClientRedirectStreamedBody(t *testing.T) {
+	base := redirectServer(t)
+
+	req, _ := NewRequest(POST, base+"/from/307", io.MultiReader(strings.NewReader("payload")))
+	req.ContentLength = int64(len("payload"))
+	res, err := (&Client{}).Do(req)
+	if err != nil || res.StatusCode != StatusTemporaryRedirect {
+		t.Errorf("Expected the 307 response, got %v", err)
+	}
+}
+
+// TestRedirectDropsCredentials verifies that credentials aren't forwarded to another host.
+func TestRedirectDropsCredentials(t *testing.T) {
+	req, _ := NewRequest(GET, "http://a.example/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res := &ClientResponse{StatusCode: StatusFound, Header: Header{"Location": {"http://b.example/next"}}}
+
+	next, err := (&Client{}).nextRedirect(req, res, nil, true)
+	if err != nil || next == nil {
+		t.Fatalf("Expected a redirect, got %v", err)
+	}
+	if next.URL.String() != "http://b.example/next" || next.Header.Get("Authorization") != "" {
+		t.Errorf("Expected the credentials to be dropped, got %s %v", next.URL, next.Header)
+	}
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"strconv"
+	"strings"
+)
+
+// XML encodes v as XML and writes it with the given status code.
+// The standard XML declaration is written before the document.
+func XML(w ResponseWriter, statusCode int, v interface{}) error {
+	return writeXML(w, statusCode, v, true)
+}
+
+// XMLFragment is like XML but omits the XML declaration.
+func XMLFragment(w ResponseWriter, statusCode int, v interface{}) error {
+	return writeXML(w, statusCode, v, false)
+}
+
+// writeXML encodes v before touching the response so encoding errors can
+// still be reported to the caller with an untouched ResponseWriter.
+func writeXML(w ResponseWriter, statusCode int, v interface{}, declaration bool) error {
+	var buf bytes.Buffer
+	if declaration {
+		buf.WriteString(xml.Header)
+	}
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	w.Header()["Content-Type"] = []string{"application/xml; charset=utf-8"}
+	w.Header()["Content-Length"] = []string{strconv.Itoa(buf.Len())}
+	w.WriteHeader(statusCode)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ErrNotAcceptable is returned by Render when the request accepts none of
+// the formats the value can be rendered in. The response is then a 406 Not
+// Acceptable.
+var ErrNotAcceptable = errors.New("http: no acceptable content type")
+
+// TemplateNamer is implemented by values rendered as HTML, naming the
+// template of the Renderer that displays them.
+type TemplateNamer interface {
+	TemplateName() string
+}
+
+// Renderer writes values in the format a request prefers among JSON, XML
+// and HTML, as negotiated from its Accept header. HTML is only offered for
+// values implementing TemplateNamer when Templates is set.
+type Renderer struct {
+	// Templates holds the HTML templates, executed with the rendered value
+	// as their data.
+	Templates *template.Template
+	// Debug indents JSON and XML output for readability.
+	Debug bool
+}
+
+// DefaultRenderer is the Renderer used by Render.
+var DefaultRenderer = &Renderer{}
+
+// Render writes v with DefaultRenderer.
+func Render(w ResponseWriter, r *Request, statusCode int, v any) error {
+	return DefaultRenderer.Render(w, r, statusCode, v)
+}
+
+// Render encodes v in the format the request prefers and writes it with
+// the given status code. Without an Accept header v is rendered as JSON.
+// Like XML, nothing is written when v cannot be encoded.
+func (rd *Renderer) Render(w ResponseWriter, r *Request, statusCode int, v any) error {
+	offers := []string{"application/json", "application/xml", "text/xml"}
+	name := ""
+	if tn, ok := v.(TemplateNamer); ok && rd.Templates != nil {
+		name = tn.TemplateName()
+		offers = append(offers, "text/html")
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch negotiate(r.Header.Get("Accept"), offers) {
+	case "application/json":
+		enc := json.NewEncoder(&buf)
+		if rd.Debug {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		contentType = "application/json; charset=utf-8"
+	case "application/xml", "text/xml":
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		if rd.Debug {
+			enc.Indent("", "  ")
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		contentType = "application/xml; charset=utf-8"
+	case "text/html":
+		if err := rd.Templates.ExecuteTemplate(&buf, name, v); err != nil {
+			return err
+		}
+		contentType = "text/html; charset=utf-8"
+	default:
+		Error(w, StatusText(StatusNotAcceptable), StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+
+	w.Header()["Content-Type"] = []string{contentType}
+	w.Header()["Content-Length"] = []string{strconv.Itoa(buf.Len())}
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(statusCode)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// negotiate returns the offer the Accept header prefers, the first offer
+// when the header is empty, or "" when none is acceptable. Between media
+// ranges matching an offer, the most specific one gives its quality, and
+// offers of equal quality are preferred in order.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, _ := strings.Cut(part, ";")
+			mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+			s := rangeSpecificity(mediaRange, offer)
+			if s <= specificity {
+				continue
+			}
+			specificity, q = s, acceptQuality(params)
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// rangeSpecificity returns how specifically a media range such as
+// "text/*" matches a content type, or -1 when it doesn't.
+func rangeSpecificity(mediaRange, contentType string) int {
+	switch {
+	case mediaRange == contentType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	}
+	if typ, ok := strings.CutSuffix(mediaRange, "/*"); ok && strings.HasPrefix(contentType, typ+"/") {
+		return 1
+	}
+	return -1
+}
+
+// acceptQuality returns the q parameter of a media range, 1 by default.
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(strings.ToLower(key)) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
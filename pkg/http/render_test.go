@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/xml"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+type xmlItem struct {
+	ID   int    `xml:"id,attr"`
+	Name string `xml:"name"`
+}
+
+// TestXML verifies that XML writes the declaration, the encoded value and the right headers.
+func TestXML(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+
+	if err := XML(res, StatusCreated, xmlItem{ID: 7, Name: "widget"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.status != StatusCreated {
+		t.Errorf("Expected status %d, got %d", StatusCreated, res.status)
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Errorf("Expected XML content type, got '%s'", ct)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<xmlItem id="7"><name>widget</name></xmlItem>`
+	if string(res.body) != expected {
+		t.Errorf("Expected body '%s', got '%s'", expected, string(res.body))
+	}
+}
+
+// TestXMLFragment verifies that XMLFragment omits the XML declaration.
+func TestXMLFragment(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+
+	if err := XMLFragment(res, StatusOK, xmlItem{ID: 1, Name: "a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.HasPrefix(string(res.body), "<?xml") {
+		t.Errorf("Expected no XML declaration, got '%s'", string(res.body))
+	}
+}
+
+// TestXMLEncodeError verifies that nothing is written when the value cannot be encoded.
+func TestXMLEncodeError(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+
+	if err := XML(res, StatusOK, make(chan int)); err == nil {
+		t.Fatal("Expected an encoding error, got none")
+	}
+
+	if res.status != 0 || len(res.body) != 0 {
+		t.Errorf("Expected an untouched response, got status %d and body '%s'", res.status, string(res.body))
+	}
+}
+
+// page is rendered as HTML with the "item" template.
+type page struct {
+	xmlItem
+}
+
+func (page) TemplateName() string { return "item" }
+
+// TestRender verifies that Render picks the format from the Accept header.
+func TestRender(t *testing.T) {
+	rd := &Renderer{Templates: template.Must(template.New("item").Parse(`<h1>{{.Name}}</h1>`))}
+
+	tests := []struct {
+		accept      string
+		v           any
+		status      int
+		contentType string
+		body        string
+	}{
+		{"", xmlItem{ID: 1, Name: "a"}, StatusOK, "application/json; charset=utf-8", `{"ID":1,"Name":"a"}` + "\n"},
+		{"application/xml", xmlItem{ID: 1, Name: "a"}, StatusOK, "application/xml; charset=utf-8", xml.Header + `<xmlItem id="1"><name>a</name></xmlItem>`},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", page{xmlItem{Name: "<b>"}}, StatusOK, "text/html; charset=utf-8", "<h1>&lt;b&gt;</h1>"},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", xmlItem{ID: 2}, StatusOK, "application/xml; charset=utf-8", ""},
+		{"application/*;q=0.5, application/json;q=0", xmlItem{}, StatusOK, "application/xml; charset=utf-8", ""},
+		{"text/html", xmlItem{}, StatusNotAcceptable, "", ""},
+	}
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		err := rd.Render(res, &Request{Header: Header{"Accept": {tt.accept}}}, StatusOK, tt.v)
+
+		if res.status != tt.status {
+			t.Errorf("%q: expected status %d, got %d (%v)", tt.accept, tt.status, res.status, err)
+		}
+		if tt.status == StatusNotAcceptable {
+			if err != ErrNotAcceptable {
+				t.Errorf("%q: expected ErrNotAcceptable, got %v", tt.accept, err)
+			}
+			continue
+		}
+		if ct := res.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%q: expected content type '%s', got '%s'", tt.accept, tt.contentType, ct)
+		}
+		if tt.body != "" && string(res.body) != tt.body {
+			t.Errorf("%q: expected body '%s', got '%s'", tt.accept, tt.body, res.body)
+		}
+	}
+}
+
+// TestRenderDebug verifies that debug mode indents the output.
+func TestRenderDebug(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+	rd := &Renderer{Debug: true}
+	if err := rd.Render(res, &Request{Header: Header{}}, StatusOK, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(res.body) != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Expected indented JSON, got '%s'", res.body)
+	}
+}
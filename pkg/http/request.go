@@ -1,8 +1,11 @@
 package http
 
 import (
+	"context"
+	"crypto/tls"
 	"io"
 	"net/url"
+	"strings"
 )
 
 // Request represents an HTTP request.
@@ -10,16 +13,112 @@ type Request struct {
 	Method  string
 	URL     *url.URL
 	Params  map[string]string
+	rawRest string // Path matched by a catch-all segment, still escaped
 	Proto   string
 	Header  Header
 	Body    io.ReadCloser
-	Cookies []Cookie
+	ctx     context.Context
+
+	// ContentLength is the length of the body, or -1 when it is unknown
+	// because the body is chunked.
+	ContentLength int64
+
+	// RemoteAddr is the network address of the peer that sent the
+	// request, as "IP:port", set by the server. See ClientIP for the
+	// address of the client behind proxies.
+	RemoteAddr string
+	clientIP   string // Client address resolved from trusted proxies
+
+	// TLS holds the state of the TLS connection the request arrived on,
+	// such as the negotiated version, cipher suite, server name and peer
+	// certificates. It is nil for plain HTTP requests.
+	TLS *tls.ConnectionState
+
+	inMemory bool // Whether Body is in memory, set by NewRequest
+}
+
+// Context returns the request's context. It is canceled when the
+// connection's handler returns; it defaults to context.Background.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// RawRest returns the rest of the path matched by the catch-all segment of
+// the route, such as "*path", still escaped as in the request target, so
+// it can be forwarded byte-exact. Its decoded form is in Params. It is
+// empty for other routes.
+func (r *Request) RawRest() string {
+	return r.rawRest
+}
+
+// rawRest returns the escaped form of rest, the end of the decoded path of
+// u following a slash.
+func rawRest(u *url.URL, rest string) string {
+	prefix, ok := strings.CutSuffix(u.Path, rest)
+	if !ok {
+		return rest
+	}
+	raw := u.EscapedPath()
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '/' {
+			continue
+		}
+		if p, err := url.PathUnescape(raw[:i+1]); err == nil && p == prefix {
+			return raw[i+1:]
+		}
+	}
+	return rest
+}
+
+// WithContext returns a shallow copy of r with its context changed to ctx.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+// Cookies returns the cookies of the request, parsed from its Cookie
+// headers, so requests built by tests and adapters get them too.
+func (r *Request) Cookies() []Cookie {
+	var cookies []Cookie
+	for _, value := range r.Header["Cookie"] {
+		cookies = append(cookies, parseCookies(value)...)
+	}
+	return cookies
+}
+
+// AddCookie adds the name and value of a cookie to the Cookie header of
+// the request, such as one sent with a Client.
+func (r *Request) AddCookie(c *Cookie) {
+	if r.Header == nil {
+		r.Header = make(Header)
+	}
+	pair := c.Name + "=" + c.Value
+	values := r.Header["Cookie"]
+	if len(values) == 0 {
+		r.Header.Add("Cookie", pair)
+		return
+	}
+	// The values may be shared with a copy of the header
+	last := len(values) - 1
+	r.Header["Cookie"] = append(values[:last:last], values[last]+"; "+pair)
 }
 
-// GetCookie returns a cookie by name.
-func (r *Request) GetCookie(name string) (*Cookie, error) {
-	for _, cookie := range r.Cookies {
+// GetCookie returns a cookie by name. When verifiers are given, the cookie
+// value must pass each of them and the verified value is returned.
+func (r *Request) GetCookie(name string, verifiers ...CookieVerifier) (*Cookie, error) {
+	for _, cookie := range r.Cookies() {
 		if cookie.Name == name {
+			for _, v := range verifiers {
+				value, err := v.Verify(cookie.Name, cookie.Value)
+				if err != nil {
+					return nil, err
+				}
+				cookie.Value = value
+			}
 			return &cookie, nil
 		}
 	}
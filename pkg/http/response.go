@@ -1,81 +1,498 @@
-package http
-
-import (
-	"fmt"
-	"net"
-)
-
-// Response represents the structure of an HTTP response.
-type Response struct {
-	StatusCode  int
-	Proto       string
-	Headers     Header
-	Body        []byte
-	conn        net.Conn
-	headersSent bool
-}
-
-// ResponseWriter is an interface for writing an HTTP response.
-type ResponseWriter interface {
-	Header() Header
-	Write([]byte) (int, error)
-	WriteHeader(int)
-	SetCookie(*Cookie)
-	DeleteCookie(string)
-}
-
-// Write writes the data to the connection as part of an HTTP reply.
-func (r *Response) Write(data []byte) (int, error) {
-	if !r.headersSent {
-		// If headers haven't been sent yet, send the headers first
-		r.WriteHeader(r.StatusCode)
-	}
-
-	// Write the body data to the connection
-	return r.conn.Write(data)
-}
-
-// WriteHeader sends an HTTP response header with the provided status code.
-func (r *Response) WriteHeader(statusCode int) {
-	if r.headersSent {
-		return
-	}
-	r.StatusCode = statusCode
-
-	// Write the status line and headers
-	statusText := StatusText(statusCode)
-	headerStr := fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, statusText)
-	for k, v := range r.Headers {
-		headerStr += fmt.Sprintf("%s: %s\r\n", k, v[0])
-	}
-	headerStr += "\r\n" // End of headers
-
-	// Write headers to the connection
-	r.conn.Write([]byte(headerStr))
-	r.headersSent = true
-}
-
-// Header returns the response headers.
-func (r *Response) Header() Header {
-	return r.Headers
-}
-
-// SetCookie adds a cookie to the response headers.
-func (r *Response) SetCookie(c *Cookie) {
-	r.Headers.Set("Set-Cookie", c.String())
-}
-
-// DeleteCookie deletes a cookie from the response headers.
-func (r *Response) DeleteCookie(name string) {
-	c := &Cookie{Name: name, Value: "", MaxAge: -1}
-	r.Headers.Set("Set-Cookie", c.String())
-}
-
-// NewResponseWriter creates a new ResponseWriter.
-func NewResponseWriter(conn net.Conn) ResponseWriter {
-	return &Response{
-		Proto:   "HTTP/1.1",
-		Headers: make(Header),
-		conn:    conn,
-	}
-}
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// responseBufferSize is how much of a body the server buffers before
+// sending the headers. Bodies that fit are sent together with the headers
+// in a single write and get a Content-Length, which keeps the connection
+// reusable.
+const responseBufferSize = 4 << 10
+
+// Response represents the structure of an HTTP response. Its methods are
+// safe to call from several goroutines, such as a handler streaming from
+// workers, though changing its Header map concurrently isn't.
+type Response struct {
+	StatusCode  int
+	Proto       string
+	Headers     Header
+	Body        []byte
+	conn        net.Conn
+	headersSent bool
+	reader      *bufio.Reader // Buffered reader of the request, handed over by Hijack
+	stopRead    func()        // Stops the server watching the connection, before Hijack
+	hijacked    bool
+	keepAlive   bool   // Whether the server may reuse the connection after this response
+	noBody      bool   // Whether the response to a HEAD request is being written
+	buffered    bool   // Whether headers and small bodies are held back until the handler is done
+	wroteHeader bool   // Whether the status code has been chosen
+	pending     []byte // Body buffered before the headers were sent
+	aborted     bool   // Whether the rest of the response is dropped, see abortResponse
+
+	date         bool   // Whether a Date header is added
+	serverHeader string // Server header added unless the handler set one
+
+	mu          sync.Mutex                                // Guards the state of the response
+	logf        func(format string, v ...any)             // Logs handler misuse, log.Printf when nil
+	reportPanic func(r *Request, err error, stack []byte) // Logs and reports panics recovered by middleware
+}
+
+// ResponseWriter is an interface for writing an HTTP response. SetCookie
+// adds a Set-Cookie header, keeping the cookies set before, which Cookies
+// returns.
+type ResponseWriter interface {
+	Header() Header
+	Write([]byte) (int, error)
+	WriteHeader(int)
+	SetCookie(*Cookie)
+	DeleteCookie(string)
+	Cookies() []*Cookie
+}
+
+// Flusher is implemented by ResponseWriters that allow a handler to flush
+// buffered data to the client, e.g. for streaming responses.
+type Flusher interface {
+	Flush()
+}
+
+// Hijacker is implemented by ResponseWriters that allow a handler to take
+// over the connection, e.g. for WebSockets. After Hijack the server no
+// longer writes to or closes the connection.
+type Hijacker interface {
+	Hijack() (net.Conn, *bufio.ReadWriter, error)
+}
+
+// InformationalWriter is implemented by ResponseWriters that can send
+// informational (1xx) responses ahead of the final one, such as 102
+// Processing during long operations or 103 Early Hints.
+type InformationalWriter interface {
+	WriteInformational(statusCode int, header Header) error
+}
+
+// Wrappers of a ResponseWriter, such as the ones of middleware, implement
+// Unwrap to return the writer they wrap:
+//
+//	func (w *myWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+//
+// Flush and Hijack then reach the optional interfaces of the writers
+// underneath, even when the wrapper doesn't implement them itself.
+type rwUnwrapper interface {
+	Unwrap() ResponseWriter
+}
+
+// serverResponse returns the Response at the bottom of the chain starting
+// at w, following Unwrap, or nil when there is none.
+func serverResponse(w ResponseWriter) *Response {
+	for w != nil {
+		if res, ok := w.(*Response); ok {
+			return res
+		}
+		u, ok := w.(rwUnwrapper)
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// Flush flushes the first writer of the chain starting at w that is a
+// Flusher, following Unwrap. It returns ErrNotSupported when none is.
+func Flush(w ResponseWriter) error {
+	for w != nil {
+		if f, ok := w.(Flusher); ok {
+			f.Flush()
+			return nil
+		}
+		u, ok := w.(rwUnwrapper)
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return ErrNotSupported
+}
+
+// Hijack hijacks the connection of the first writer of the chain starting
+// at w that is a Hijacker, following Unwrap. It returns ErrNotSupported
+// when none is.
+func Hijack(w ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	for w != nil {
+		if h, ok := w.(Hijacker); ok {
+			return h.Hijack()
+		}
+		u, ok := w.(rwUnwrapper)
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return nil, nil, ErrNotSupported
+}
+
+// WriteInformational sends an informational response with the header
+// through the first writer of the chain starting at w that is an
+// InformationalWriter, following Unwrap. It returns ErrNotSupported when
+// none is.
+func WriteInformational(w ResponseWriter, statusCode int, header Header) error {
+	for w != nil {
+		if iw, ok := w.(InformationalWriter); ok {
+			return iw.WriteInformational(statusCode, header)
+		}
+		u, ok := w.(rwUnwrapper)
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return ErrNotSupported
+}
+
+// detectContentType sets the Content-Type the handler didn't set from the
+// first bytes of the body, as DetectContentType does.
+func (r *Response) detectContentType(body []byte) {
+	if len(body) == 0 || r.headersSent || !bodyAllowed(r.StatusCode) {
+		return
+	}
+	if _, ok := r.Headers["Content-Type"]; ok {
+		return
+	}
+	r.Headers["Content-Type"] = []string{DetectContentType(body)}
+}
+
+// Hijack takes over the connection. The returned reader holds any request
+// data the server already buffered.
+func (r *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hijacked {
+		return nil, nil, ErrHijacked
+	}
+	// Whatever the handler already wrote goes out first
+	if r.wroteHeader {
+		r.sendHeader()
+	}
+	r.hijacked = true
+	if r.stopRead != nil {
+		r.stopRead()
+	}
+
+	reader := r.reader
+	if reader == nil {
+		reader = bufio.NewReader(r.conn)
+	}
+	return r.conn, bufio.NewReadWriter(reader, bufio.NewWriter(r.conn)), nil
+}
+
+// Write writes the data to the connection as part of an HTTP reply.
+func (r *Response) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hijacked {
+		return 0, ErrHijacked
+	}
+	if !r.wroteHeader {
+		// A body without a status is a 200 OK
+		if r.StatusCode == 0 {
+			r.StatusCode = StatusOK
+		}
+		if !r.buffered {
+			// The headers go out right away
+			r.detectContentType(data)
+		}
+		r.writeHeader(r.StatusCode)
+	}
+
+	if !r.headersSent {
+		// Small bodies are held back and sent along with the headers
+		if len(r.pending)+len(data) <= responseBufferSize {
+			r.pending = append(r.pending, data...)
+			return len(data), nil
+		}
+		r.detectContentType(append(r.pending[:len(r.pending):len(r.pending)], data[:min(len(data), sniffLen)]...))
+		if err := r.sendHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Responses to HEAD requests have no body
+	if r.noBody {
+		return len(data), nil
+	}
+
+	// Write the body data to the connection
+	return r.conn.Write(data)
+}
+
+// ReadFrom copies src to the response body after sending the headers. On
+// plain TCP connections the kernel sends files straight from the page cache
+// with sendfile, without copying them through user space.
+func (r *Response) ReadFrom(src io.Reader) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hijacked {
+		return 0, ErrHijacked
+	}
+	if !r.wroteHeader {
+		r.writeHeader(r.StatusCode)
+	}
+	if err := r.sendHeader(); err != nil {
+		return 0, err
+	}
+
+	// Responses to HEAD requests have no body
+	if r.noBody {
+		return io.Copy(io.Discard, src)
+	}
+	return io.Copy(r.conn, src)
+}
+
+// WriteHeader sets the status code of the response. The headers are sent
+// right away, or once the handler is done or its body outgrows the buffer
+// when the response is buffered by the server. Calls once the status was
+// chosen, such as after Write, are ignored with a warning, as the status
+// can't change anymore.
+func (r *Response) WriteHeader(statusCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.wroteHeader && !r.hijacked {
+		// The caller of WriteHeader is the one to fix
+		if _, file, line, ok := runtime.Caller(1); ok {
+			r.warnf("http: superfluous WriteHeader(%d) call from %s:%d, the status %d was already written", statusCode, file, line, r.StatusCode)
+		} else {
+			r.warnf("http: superfluous WriteHeader(%d) call, the status %d was already written", statusCode, r.StatusCode)
+		}
+		return
+	}
+	r.writeHeader(statusCode)
+}
+
+// writeHeader sets the status code of the response, as WriteHeader does,
+// with the lock held. Informational statuses are sent at once with the
+// headers set so far, leaving the final status to be written.
+func (r *Response) writeHeader(statusCode int) {
+	if r.wroteHeader || r.hijacked {
+		return
+	}
+	if isInformational(statusCode) {
+		r.writeInformational(statusCode, r.Headers)
+		return
+	}
+	r.StatusCode = statusCode
+	r.wroteHeader = true
+
+	if !r.buffered {
+		r.sendHeader()
+	}
+}
+
+// WriteInformational sends an informational (1xx) response with the
+// header, such as 102 Processing while a long operation runs, before the
+// final response. Any number of them can be sent until the final status
+// is written, after which ErrStatusWritten is returned. 101 Switching
+// Protocols is final, and written with WriteHeader before Hijack.
+func (r *Response) WriteInformational(statusCode int, header Header) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.hijacked:
+		return ErrHijacked
+	case !isInformational(statusCode):
+		return fmt.Errorf("http: invalid informational status %d", statusCode)
+	case r.wroteHeader:
+		return ErrStatusWritten
+	}
+	return r.writeInformational(statusCode, header)
+}
+
+// writeInformational writes an informational response to the connection,
+// with the lock held.
+func (r *Response) writeInformational(statusCode int, header Header) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", statusCode, StatusText(statusCode))
+	header.Write(buf)
+	buf.WriteString("\r\n")
+	_, err := r.conn.Write(buf.Bytes())
+	return err
+}
+
+// isInformational reports whether the status is of an interim response,
+// followed by the final one.
+func isInformational(statusCode int) bool {
+	return statusCode >= 100 && statusCode < 200 && statusCode != StatusSwitchingProtocols
+}
+
+// sendHeader writes the status line, the headers and any buffered body to
+// the connection in a single write.
+func (r *Response) sendHeader() error {
+	if r.headersSent {
+		return nil
+	}
+	r.detectContentType(r.pending)
+	r.headersSent = true
+
+	// The connection can only be reused when the client can tell where
+	// this response ends
+	if r.keepAlive && (!r.framed(r.StatusCode) || hasToken(r.Headers.Get("Connection"), "close")) {
+		r.keepAlive = false
+		r.Headers.Set("Connection", "close")
+	}
+
+	if _, ok := r.Headers["Date"]; r.date && !ok {
+		r.Headers["Date"] = []string{httpDate(time.Now())}
+	}
+	if _, ok := r.Headers["Server"]; r.serverHeader != "" && !ok {
+		r.Headers["Server"] = []string{r.serverHeader}
+	}
+
+	// Write the status line and headers
+	buf := getBuffer()
+	defer putBuffer(buf)
+	statusText := StatusText(r.StatusCode)
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", r.StatusCode, statusText)
+	r.Headers.Write(buf)
+	buf.WriteString("\r\n") // End of headers
+
+	// Write headers and buffered body to the connection at once
+	bufs := net.Buffers{buf.Bytes()}
+	if len(r.pending) > 0 && !r.noBody {
+		bufs = append(bufs, r.pending)
+	}
+	_, err := bufs.WriteTo(r.conn)
+	r.pending = r.pending[:0]
+	return err
+}
+
+// framed reports whether the end of a response with the given status can
+// be found without closing the connection.
+func (r *Response) framed(statusCode int) bool {
+	if r.noBody || !bodyAllowed(statusCode) {
+		return true
+	}
+	_, ok := r.Headers["Content-Length"]
+	return ok
+}
+
+// bodyAllowed reports whether a response with the given status may have a body.
+func bodyAllowed(statusCode int) bool {
+	return statusCode >= 200 && statusCode != StatusNoContent && statusCode != StatusNotModified
+}
+
+// warnf logs a misuse of the response by a handler.
+func (r *Response) warnf(format string, v ...any) {
+	if r.logf != nil {
+		r.logf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// reset prepares the response for the next request on the connection.
+func (r *Response) reset(head bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.StatusCode = 0
+	r.Body = nil
+	r.headersSent = false
+	r.wroteHeader = false
+	r.noBody = head
+	r.pending = r.pending[:0]
+	r.aborted = false
+	clear(r.Headers)
+}
+
+// finish completes the response once the handler returned: a body that
+// fit in the buffer is sent with its Content-Length, and an empty 200 OK
+// is sent when the handler wrote nothing.
+func (r *Response) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.headersSent || r.aborted {
+		return
+	}
+	if !r.wroteHeader {
+		if r.StatusCode == 0 {
+			r.StatusCode = StatusOK
+		}
+		r.wroteHeader = true
+	}
+	if _, ok := r.Headers["Content-Length"]; !ok && bodyAllowed(r.StatusCode) {
+		r.Headers["Content-Length"] = []string{strconv.Itoa(len(r.pending))}
+	}
+	r.sendHeader()
+}
+
+// Flush sends the response headers and any buffered body if they haven't
+// been sent yet. Later body writes go straight to the connection.
+func (r *Response) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.wroteHeader {
+		r.writeHeader(r.StatusCode)
+	}
+	r.sendHeader()
+}
+
+// Header returns the response headers.
+func (r *Response) Header() Header {
+	return r.Headers
+}
+
+// SetCookie adds a cookie to the response headers.
+func (r *Response) SetCookie(c *Cookie) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addCookie(r, r.Headers, c)
+}
+
+// DeleteCookie deletes a cookie from the response headers.
+func (r *Response) DeleteCookie(name string) {
+	r.SetCookie(&Cookie{Name: name, Value: "", MaxAge: -1})
+}
+
+// Cookies returns the cookies set on the response.
+func (r *Response) Cookies() []*Cookie {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return headerCookies(r.Headers)
+}
+
+// NewResponseWriter creates a new ResponseWriter.
+func NewResponseWriter(conn net.Conn) ResponseWriter {
+	return &Response{
+		Proto:   "HTTP/1.1",
+		Headers: make(Header),
+		conn:    conn,
+	}
+}
+
+// cachedDate is a Date header value and the second it was formatted for.
+type cachedDate struct {
+	unix  int64
+	value string
+}
+
+// dateCache holds the Date header of the current second, so it is
+// formatted once per second rather than once per response.
+var dateCache atomic.Pointer[cachedDate]
+
+// httpDate returns the Date header value for now.
+func httpDate(now time.Time) string {
+	if d := dateCache.Load(); d != nil && d.unix == now.Unix() {
+		return d.value
+	}
+	d := &cachedDate{unix: now.Unix(), value: now.UTC().Format(TimeFormat)}
+	dateCache.Store(d)
+	return d.value
+}
@@ -1,6 +1,12 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -59,3 +65,204 @@ func TestWriteHeaderAlreadySent(t *testing.T) {
 		t.Errorf("Expected output '%s', got '%s'", expectedOutput, actualOutput)
 	}
 }
+
+// TestWriteHeaderMultipleValues verifies that every value of a header is written on its own line.
+func TestWriteHeaderMultipleValues(t *testing.T) {
+	conn := &MockConn{}
+	writer := NewResponseWriter(conn)
+
+	writer.SetCookie(&Cookie{Name: "session", Value: "abc"})
+	writer.SetCookie(&Cookie{Name: "csrf", Value: "xyz"})
+	writer.WriteHeader(StatusOK)
+
+	actual := conn.writeBuffer.String()
+	for _, line := range []string{"Set-Cookie: session=abc\r\n", "Set-Cookie: csrf=xyz\r\n"} {
+		if !strings.Contains(actual, line) {
+			t.Errorf("Expected output to contain '%s', got '%s'", line, actual)
+		}
+	}
+}
+
+// TestWriteHeaderDeterministic verifies that headers are written in sorted order.
+func TestWriteHeaderDeterministic(t *testing.T) {
+	conn := &MockConn{}
+	writer := NewResponseWriter(conn)
+
+	writer.Header().Set("X-B", "2")
+	writer.Header().Set("X-A", "1")
+	writer.Header().Set("Content-Type", "text/plain")
+	writer.WriteHeader(StatusOK)
+
+	expected := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nX-A: 1\r\nX-B: 2\r\n\r\n"
+	if conn.writeBuffer.String() != expected {
+		t.Errorf("Expected '%q', got '%q'", expected, conn.writeBuffer.String())
+	}
+}
+
+// TestBufferedResponseContentLength verifies that small buffered bodies are sent with their Content-Length.
+func TestBufferedResponseContentLength(t *testing.T) {
+	conn := &MockConn{}
+	res := NewResponseWriter(conn).(*Response)
+	res.buffered = true
+
+	res.WriteHeader(StatusCreated)
+	res.Write([]byte("Hello, "))
+	res.Write([]byte("World"))
+	if conn.writeBuffer.Len() != 0 {
+		t.Fatalf("Expected nothing to be sent before the handler is done, got '%s'", conn.writeBuffer.String())
+	}
+
+	res.finish()
+
+	expected := "HTTP/1.1 201 Created\r\nContent-Length: 12\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nHello, World"
+	if conn.writeBuffer.String() != expected {
+		t.Errorf("Expected '%q', got '%q'", expected, conn.writeBuffer.String())
+	}
+}
+
+// TestBufferedResponseFlush verifies that Flush sends the buffered response right away.
+func TestBufferedResponseFlush(t *testing.T) {
+	conn := &MockConn{}
+	res := NewResponseWriter(conn).(*Response)
+	res.buffered = true
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.WriteHeader(StatusOK)
+	res.Write([]byte("data: 1\n\n"))
+	res.Flush()
+
+	if conn.writeBuffer.String() != "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\ndata: 1\n\n" {
+		t.Errorf("Expected the flushed response, got '%q'", conn.writeBuffer.String())
+	}
+}
+
+// TestWriteImpliesOK verifies that writing a body without a status sends 200 OK with a sniffed Content-Type.
+func TestWriteImpliesOK(t *testing.T) {
+	tests := []struct {
+		name     string
+		buffered bool
+		body     string
+		want     string
+	}{
+		{"unbuffered", false, "<html><body>hi", "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<html><body>hi"},
+		{"buffered", true, "%PDF-1.7", "HTTP/1.1 200 OK\r\nContent-Length: 8\r\nContent-Type: application/pdf\r\n\r\n%PDF-1.7"},
+		{"large", true, strings.Repeat("{}", responseBufferSize), "HTTP/1.1 200 OK\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + strings.Repeat("{}", responseBufferSize)},
+	}
+	for _, tt := range tests {
+		conn := &MockConn{}
+		res := NewResponseWriter(conn).(*Response)
+		res.buffered = tt.buffered
+		res.Write([]byte(tt.body))
+		res.finish()
+
+		if conn.writeBuffer.String() != tt.want {
+			t.Errorf("%s: expected '%.120q', got '%.120q'", tt.name, tt.want, conn.writeBuffer.String())
+		}
+	}
+}
+
+// TestConcurrentWrites verifies that writes from several goroutines don't interleave on the wire.
+func TestConcurrentWrites(t *testing.T) {
+	conn := &MockConn{}
+	writer := NewResponseWriter(conn)
+
+	line := strings.Repeat("x", 100) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			writer.WriteHeader(StatusOK)
+			writer.Write([]byte(line))
+		}()
+	}
+	wg.Wait()
+
+	header, body, _ := strings.Cut(conn.writeBuffer.String(), "\r\n\r\n")
+	if header != "HTTP/1.1 200 OK" {
+		t.Errorf("Expected a single status line, got '%s'", header)
+	}
+	if body != strings.Repeat(line, 50) {
+		t.Errorf("Expected 50 whole lines, got '%s'", body)
+	}
+}
+
+// TestSuperfluousWriteHeader verifies that WriteHeader after Write is ignored with a warning naming the caller.
+func TestSuperfluousWriteHeader(t *testing.T) {
+	conn := &MockConn{}
+	res := NewResponseWriter(conn).(*Response)
+	var warnings []string
+	res.logf = func(format string, v ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, v...))
+	}
+
+	res.Write([]byte("ok"))
+	res.WriteHeader(StatusInternalServerError)
+
+	if !strings.HasPrefix(conn.writeBuffer.String(), "HTTP/1.1 200 OK\r\n") {
+		t.Errorf("Expected the status to stay 200, got '%s'", conn.writeBuffer.String())
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "superfluous WriteHeader(500)") || !strings.Contains(warnings[0], "response_test.go") {
+		t.Errorf("Expected a warning naming the caller, got %v", warnings)
+	}
+}
+
+// TestWriteInformational verifies that informational responses precede the final one until its status is written.
+func TestWriteInformational(t *testing.T) {
+	conn := &MockConn{}
+	res := NewResponseWriter(conn).(*Response)
+
+	if err := res.WriteInformational(StatusProcessing, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := res.WriteInformational(StatusEarlyHints, Header{"Link": {"</style.css>; rel=preload"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, status := range []int{StatusOK, StatusSwitchingProtocols} {
+		if err := res.WriteInformational(status, nil); err == nil {
+			t.Errorf("Expected an error for status %d", status)
+		}
+	}
+	res.WriteHeader(StatusCreated)
+	res.Write([]byte("done"))
+	if err := res.WriteInformational(StatusProcessing, nil); !errors.Is(err, ErrStatusWritten) {
+		t.Errorf("Expected ErrStatusWritten, got %v", err)
+	}
+
+	expected := "HTTP/1.1 102 Processing\r\n\r\n" +
+		"HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n" +
+		"HTTP/1.1 201 Created\r\n\r\ndone"
+	if got := conn.writeBuffer.String(); got != expected {
+		t.Errorf("Expected output '%s', got '%s'", expected, got)
+	}
+}
+
+// TestWriteInformationalServer verifies that handlers behind wrappers send informational responses the client skips.
+func TestWriteInformationalServer(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w = NewInstrumentedWriter(w)
+		if err := WriteInformational(w, StatusProcessing, nil); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		w.Write([]byte("done"))
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	raw, _ := io.ReadAll(conn)
+	if !strings.HasPrefix(string(raw), "HTTP/1.1 102 Processing\r\n\r\nHTTP/1.1 200 OK\r\n") {
+		t.Errorf("Expected 102 Processing before 200 OK, got '%s'", raw)
+	}
+
+	res, err := (&Client{}).Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readBody(t, res); res.StatusCode != StatusOK || got != "done" {
+		t.Errorf("Expected 200 'done', got %d '%s'", res.StatusCode, got)
+	}
+}
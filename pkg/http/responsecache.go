@@ -0,0 +1,398 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCacheEntries is how many responses a ResponseCache holds when
+	// MaxEntries is zero.
+	defaultCacheEntries = 1000
+	// defaultCacheBodySize is the largest body a ResponseCache stores when
+	// MaxBodySize is zero.
+	defaultCacheBodySize = 1 << 20
+)
+
+// cacheableStatus are the statuses of the responses a ResponseCache
+// stores, the ones cacheable by default (RFC 9110 §15.1).
+var cacheableStatus = map[int]bool{
+	StatusOK: true, StatusNonAuthoritativeInfo: true, StatusNoContent: true,
+	StatusMultipleChoices: true, StatusMovedPermanently: true, StatusPermanentRedirect: true,
+	StatusNotFound: true, StatusMethodNotAllowed: true, StatusGone: true,
+	StatusRequestURITooLong: true, StatusNotImplemented: true,
+}
+
+// ResponseCachePolicy is how a route's responses are cached by a
+// ResponseCache. Zero durations are taken from the Cache-Control header of
+// the responses, so handlers can set them too.
+type ResponseCachePolicy struct {
+	// MaxAge is how long responses are fresh and served from the cache.
+	// Zero means the s-maxage or max-age directive.
+	MaxAge time.Duration
+	// StaleWhileRevalidate is how long stale responses are still served
+	// while they are refreshed in the background (RFC 5861). Zero means
+	// the stale-while-revalidate directive.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError is how long stale responses are served instead of the
+	// handler's when it fails with a 5xx status or a panic (RFC 5861).
+	// Zero means the stale-if-error directive.
+	StaleIfError time.Duration
+}
+
+// ResponseCache caches the responses of handlers in memory, serving stale
+// ones immediately while they are refreshed in the background, and in
+// place of server errors, as RFC 5861 describes:
+//
+//	cache := &http.ResponseCache{}
+//	mux.AddRoute("/products", []string{http.GET}, listProducts).Use(cache.Middleware(http.ResponseCachePolicy{
+//		MaxAge:               time.Minute,
+//		StaleWhileRevalidate: 10 * time.Minute,
+//		StaleIfError:         time.Hour,
+//	}))
+//
+// Only GET responses with a cacheable status are stored, keyed by host and
+// request target, and matched on the request headers their Vary header
+// names. Responses with Set-Cookie, or marked no-store, no-cache or
+// private, and the responses to requests with an Authorization header
+// aren't stored. Served responses get an Age header. The zero value is
+// ready to use.
+type ResponseCache struct {
+	// MaxEntries is how many responses are kept, the least recently used
+	// ones being evicted. Zero means 1000.
+	MaxEntries int
+	// MaxBodySize is the largest body stored. Zero means 1MB.
+	MaxBodySize int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List // Entries, most recently used first
+}
+
+// cacheEntry is a response held by a ResponseCache. Only refreshing
+// changes once it is stored, under the lock of the cache.
+type cacheEntry struct {
+	key        string
+	status     int
+	header     Header
+	body       []byte
+	stored     time.Time
+	fresh      time.Duration // How long it is fresh
+	swr        time.Duration // How long after that it is served while refreshed
+	sie        time.Duration // How long after that it is served on errors
+	vary       []string      // Request headers it varies on
+	varyValues []string      // Their values in the request it answers
+	refreshing bool
+}
+
+// Middleware returns middleware caching the responses of the handler with
+// the policy.
+func (c *ResponseCache) Middleware(policy ResponseCachePolicy) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if r.Method != GET && r.Method != HEAD {
+				next(w, r)
+				return
+			}
+
+			key := normalizeHost(r.Header.Get("Host")) + r.URL.RequestURI()
+			e := c.lookup(key, r)
+			if e == nil {
+				if r.Method == HEAD {
+					next(w, r)
+					return
+				}
+				rec := &cacheRecorder{w: w, header: w.Header(), limit: c.maxBodySize()}
+				next(rec, r)
+				c.store(key, r, rec, policy)
+				return
+			}
+
+			now := time.Now()
+			age := now.Sub(e.stored)
+			switch {
+			case age < e.fresh:
+				e.serve(w, now)
+			case age < e.fresh+e.swr:
+				e.serve(w, now)
+				c.refresh(key, e, next, r, policy, panicReporter(w))
+			default:
+				// Too stale to serve right away, the handler is given a
+				// chance first
+				rec := &cacheRecorder{header: make(Header), limit: c.maxBodySize(), replayed: true}
+				p, stack := runRecovering(next, rec, asGET(r))
+				if (p != nil || rec.status >= 500) && age < e.fresh+e.sie {
+					if p != nil {
+						panicReporter(w)(r, panicError(p), stack)
+					}
+					e.serve(w, now)
+					return
+				}
+				if p != nil {
+					panic(p)
+				}
+				c.store(key, r, rec, policy)
+				rec.replay(w)
+			}
+		}
+	}
+}
+
+// Purge removes every response from the cache.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.lru.Init()
+}
+
+// Len returns how many responses the cache holds.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// lookup returns the entry answering the request, or nil.
+func (c *ResponseCache) lookup(key string, r *Request) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	for i, name := range e.vary {
+		if strings.Join(r.Header.Values(name), ",") != e.varyValues[i] {
+			return nil
+		}
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// store adds the recorded response to the request to the cache, when it
+// can be stored.
+func (c *ResponseCache) store(key string, r *Request, rec *cacheRecorder, policy ResponseCachePolicy) {
+	if !cacheableStatus[rec.status] || rec.tooLarge || r.Header.Get("Authorization") != "" {
+		return
+	}
+	h := rec.snapshot
+	cc := strings.Join(h.Values("Cache-Control"), ",")
+	if hasToken(cc, "no-store") || hasToken(cc, "no-cache") || hasToken(cc, "private") || len(h.Values("Set-Cookie")) > 0 {
+		return
+	}
+
+	e := &cacheEntry{key: key, status: rec.status, header: h, body: rec.body.Bytes(), stored: time.Now()}
+	e.fresh = policy.MaxAge
+	if e.fresh == 0 {
+		var ok bool
+		if e.fresh, ok = cacheDirective(cc, "s-maxage"); !ok {
+			e.fresh, _ = parseMaxAge(cc)
+		}
+	}
+	if e.swr = policy.StaleWhileRevalidate; e.swr == 0 {
+		e.swr, _ = cacheDirective(cc, "stale-while-revalidate")
+	}
+	if e.sie = policy.StaleIfError; e.sie == 0 {
+		e.sie, _ = cacheDirective(cc, "stale-if-error")
+	}
+	if e.fresh+e.swr+e.sie <= 0 {
+		return
+	}
+	for _, value := range h.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				e.vary = append(e.vary, name)
+				e.varyValues = append(e.varyValues, strings.Join(r.Header.Values(name), ","))
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	maxEntries := c.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+	for c.lru.Len() > maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// refresh runs the handler in the background to replace the stale entry,
+// unless it is being refreshed already. Failed refreshes keep the entry,
+// and their panics are passed to report.
+func (c *ResponseCache) refresh(key string, e *cacheEntry, next func(ResponseWriter, *Request), r *Request, policy ResponseCachePolicy, report func(*Request, error, []byte)) {
+	c.mu.Lock()
+	refreshing := e.refreshing
+	e.refreshing = true
+	c.mu.Unlock()
+	if refreshing {
+		return
+	}
+
+	// The refresh outlives the request that triggered it
+	r = asGET(r).WithContext(context.WithoutCancel(r.Context()))
+	go func() {
+		rec := &cacheRecorder{header: make(Header), limit: c.maxBodySize()}
+		p, stack := runRecovering(next, rec, r)
+		if p != nil {
+			report(r, panicError(p), stack)
+		} else if rec.status < 500 {
+			c.store(key, r, rec, policy)
+		}
+		c.mu.Lock()
+		e.refreshing = false
+		c.mu.Unlock()
+	}()
+}
+
+// maxBodySize returns the largest body stored.
+func (c *ResponseCache) maxBodySize() int {
+	if c.MaxBodySize > 0 {
+		return c.MaxBodySize
+	}
+	return defaultCacheBodySize
+}
+
+// serve writes the cached response.
+func (e *cacheEntry) serve(w ResponseWriter, now time.Time) {
+	h := w.Header()
+	for key, values := range e.header {
+		h[key] = append([]string(nil), values...)
+	}
+	h["Age"] = []string{strconv.Itoa(int(now.Sub(e.stored) / time.Second))}
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// asGET returns a HEAD request as a GET one, so the whole response is
+// recorded.
+func asGET(r *Request) *Request {
+	if r.Method != HEAD {
+		return r
+	}
+	r2 := *r
+	r2.Method = GET
+	return &r2
+}
+
+// runRecovering calls the handler, returning the value of its panic and
+// the stack where it happened.
+func runRecovering(handler func(ResponseWriter, *Request), w ResponseWriter, r *Request) (p any, stack []byte) {
+	defer func() {
+		if p = recover(); p != nil {
+			stack = debug.Stack()
+		}
+	}()
+	handler(w, r)
+	return nil, nil
+}
+
+// cacheRecorder records a response for a ResponseCache, either while it
+// is written to w, or buffered when w is nil.
+type cacheRecorder struct {
+	w        ResponseWriter
+	header   Header
+	snapshot Header // Headers as sent with the status
+	status   int
+	body     bytes.Buffer
+	limit    int
+	tooLarge bool
+	replayed bool // Whether the whole buffered body is kept for replay
+}
+
+// Header returns the headers of the response.
+func (rec *cacheRecorder) Header() Header {
+	return rec.header
+}
+
+// WriteHeader records the status and headers.
+func (rec *cacheRecorder) WriteHeader(statusCode int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = statusCode
+	rec.snapshot = make(Header, len(rec.header))
+	for key, values := range rec.header {
+		rec.snapshot[key] = append([]string(nil), values...)
+	}
+	if rec.w != nil {
+		rec.w.WriteHeader(statusCode)
+	}
+}
+
+// Write records the body, unless it grew too large to be stored and isn't
+// replayed.
+func (rec *cacheRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(StatusOK)
+	}
+	if !rec.tooLarge && rec.body.Len()+len(p) > rec.limit {
+		rec.tooLarge = true
+		if !rec.replayed {
+			rec.body = bytes.Buffer{}
+		}
+	}
+	if !rec.tooLarge || rec.replayed {
+		rec.body.Write(p)
+	}
+	if rec.w != nil {
+		return rec.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// SetCookie adds a cookie to the headers, which makes the response
+// uncacheable.
+func (rec *cacheRecorder) SetCookie(c *Cookie) {
+	addCookie(rec.w, rec.header, c)
+}
+
+// DeleteCookie adds a Set-Cookie header deleting the cookie to the
+// headers.
+func (rec *cacheRecorder) DeleteCookie(name string) {
+	rec.SetCookie(&Cookie{Name: name, Value: "", MaxAge: -1})
+}
+
+// Cookies returns the cookies set in the headers.
+func (rec *cacheRecorder) Cookies() []*Cookie {
+	return headerCookies(rec.header)
+}
+
+// Unwrap returns the writer the response is written to, nil when it is
+// buffered.
+func (rec *cacheRecorder) Unwrap() ResponseWriter {
+	return rec.w
+}
+
+// replay writes a buffered response to w.
+func (rec *cacheRecorder) replay(w ResponseWriter) {
+	h := w.Header()
+	for key, values := range rec.snapshot {
+		h[key] = values
+	}
+	if rec.status == 0 {
+		rec.status = StatusOK
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ageEntry makes the cached response of the path older.
+func ageEntry(c *ResponseCache, path string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[path].Value.(*cacheEntry)
+	e.stored = e.stored.Add(-d)
+}
+
+// TestResponseCache verifies that fresh responses are served from the cache and uncacheable ones aren't stored.
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	c := &ResponseCache{}
+	handler := c.Middleware(ResponseCachePolicy{})(func(w ResponseWriter, r *Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/cookie":
+			w.SetCookie(&Cookie{Name: "id", Value: "1"})
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		default:
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(strconv.Itoa(int(n))))
+	})
+	serve := func(path string, header Header) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		if header == nil {
+			header = make(Header)
+		}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: path}, Header: header})
+		return res
+	}
+
+	serve("/items", nil)
+	ageEntry(c, "/items", 5*time.Second)
+	if res := serve("/items", nil); string(res.body) != "1" || res.headers.Get("Age") != "5" {
+		t.Errorf("Expected the cached response aged 5s, got '%s' aged '%s'", res.body, res.headers.Get("Age"))
+	}
+	if res := serve("/items", Header{"Accept-Language": {"es"}}); string(res.body) != "2" {
+		t.Errorf("Expected another language to miss the cache, got '%s'", res.body)
+	}
+
+	for _, tt := range []struct {
+		path   string
+		header Header
+	}{
+		{"/cookie", nil},
+		{"/no-store", nil},
+		{"/private", Header{"Authorization": {"Bearer token"}}},
+	} {
+		first := serve(tt.path, tt.header)
+		if second := serve(tt.path, tt.header); string(first.body) == string(second.body) {
+			t.Errorf("%s: expected the response not to be cached", tt.path)
+		}
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 cached response, got %d", c.Len())
+	}
+}
+
+// TestResponseCacheStaleWhileRevalidate verifies that stale responses are served while they are refreshed in the background.
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := &ResponseCache{}
+	handler := c.Middleware(ResponseCachePolicy{MaxAge: time.Minute})(func(w ResponseWriter, r *Request) {
+		if n := calls.Add(1); n > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "stale-while-revalidate=60")
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(strconv.Itoa(int(calls.Load()))))
+	})
+	serve := func() string {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/feed"}, Header: make(Header)})
+		return string(res.body)
+	}
+
+	serve()
+	ageEntry(c, "/feed", 90*time.Second)
+	if body := serve(); body != "1" {
+		t.Errorf("Expected the stale response, got '%s'", body)
+	}
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if body := serve(); body != "1" || calls.Load() != 2 {
+		t.Errorf("Expected a single refresh while the stale response is served, got '%s' after %d calls", body, calls.Load())
+	}
+
+	close(release)
+	deadline = time.Now().Add(time.Second)
+	for serve() != "2" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if body := serve(); body != "2" {
+		t.Errorf("Expected the refreshed response, got '%s'", body)
+	}
+}
+
+// TestResponseCacheStaleIfError verifies that stale responses replace server errors until they are too old.
+func TestResponseCacheStaleIfError(t *testing.T) {
+	var fail atomic.Value
+	fail.Store("")
+	c := &ResponseCache{}
+	handler := c.Middleware(ResponseCachePolicy{MaxAge: time.Minute, StaleIfError: time.Hour})(func(w ResponseWriter, r *Request) {
+		switch fail.Load() {
+		case "status":
+			w.WriteHeader(StatusServiceUnavailable)
+		case "panic":
+			panic("database down")
+		default:
+			w.WriteHeader(StatusOK)
+			w.Write([]byte("report"))
+		}
+	})
+	serve := func() *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/report"}, Header: make(Header)})
+		return res
+	}
+
+	serve()
+	ageEntry(c, "/report", 2*time.Minute)
+	for _, mode := range []string{"status", "panic"} {
+		fail.Store(mode)
+		if res := serve(); res.status != StatusOK || string(res.body) != "report" {
+			t.Errorf("%s: expected the stale response, got %d '%s'", mode, res.status, res.body)
+		}
+	}
+
+	fail.Store("status")
+	ageEntry(c, "/report", 2*time.Hour)
+	if res := serve(); res.status != StatusServiceUnavailable {
+		t.Errorf("Expected the error once the response is too stale, got %d", res.status)
+	}
+}
+
+// TestResponseCacheTooLargeReplay verifies that a response replacing a too stale one is sent whole even when it is too large to be stored.
+func TestResponseCacheTooLargeReplay(t *testing.T) {
+	body := "report"
+	c := &ResponseCache{MaxBodySize: 10}
+	handler := c.Middleware(ResponseCachePolicy{MaxAge: time.Minute})(func(w ResponseWriter, r *Request) {
+		w.Write([]byte(body))
+	})
+	serve := func() *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		handler(res, &Request{Method: GET, URL: &url.URL{Path: "/report"}, Header: make(Header)})
+		return res
+	}
+
+	serve()
+	ageEntry(c, "/report", 2*time.Minute)
+	body = strings.Repeat("x", 100)
+	if res := serve(); res.status != StatusOK || string(res.body) != body {
+		t.Errorf("Expected the %d bytes of the handler, got %d '%s'", len(body), res.status, res.body)
+	}
+	if res := serve(); string(res.body) != body {
+		t.Errorf("Expected the too large response not to be served from the cache, got '%s'", res.body)
+	}
+}
+
+// TestResponseCachePanicReported verifies that panics answered with a stale response or dropped by a background refresh reach the server's ReportPanic.
+func TestResponseCachePanicReported(t *testing.T) {
+	var failing atomic.Bool
+	c := &ResponseCache{}
+	handler := c.Middleware(ResponseCachePolicy{MaxAge: time.Minute})(func(w ResponseWriter, r *Request) {
+		if failing.Load() {
+			panic("database down")
+		}
+		if r.URL.Path == "/feed" {
+			w.Header().Set("Cache-Control", "stale-while-revalidate=60")
+		} else {
+			w.Header().Set("Cache-Control", "stale-if-error=3600")
+		}
+		w.Write([]byte("ok"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	reported := make(chan string, 2)
+	server := NewServer(ln.Addr().String(), HandlerFunc(handler))
+	server.ErrorLog = log.New(io.Discard, "", 0)
+	server.ReportPanic = func(r *Request, err error, stack []byte) {
+		if err.Error() != "database down" || !bytes.Contains(stack, []byte("TestResponseCachePanicReported")) {
+			t.Errorf("Expected the panic and its stack, got %v\n%s", err, stack)
+		}
+		reported <- r.URL.Path
+	}
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	get := func(path string) {
+		res, err := (&Client{}).Get("http://" + ln.Addr().String() + path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := readBody(t, res); res.StatusCode != StatusOK || got != "ok" {
+			t.Errorf("%s: expected the cached response, got %d '%s'", path, res.StatusCode, got)
+		}
+	}
+	get("/report")
+	get("/feed")
+	c.mu.Lock()
+	for _, el := range c.entries {
+		el.Value.(*cacheEntry).stored = time.Now().Add(-90 * time.Second)
+	}
+	c.mu.Unlock()
+
+	failing.Store(true)
+	get("/report")
+	get("/feed")
+	paths := make(map[string]bool)
+	for range 2 {
+		select {
+		case path := <-reported:
+			paths[path] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected both panics to be reported, got %v", paths)
+		}
+	}
+	if !paths["/report"] || !paths["/feed"] {
+		t.Errorf("Expected the panics of /report and /feed, got %v", paths)
+	}
+}
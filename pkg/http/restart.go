@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// listenerFDEnv names the environment variable through which a restarted
+// process learns the file descriptor of its inherited listening socket.
+const listenerFDEnv = "HTTP_LITE_LISTENER_FD"
+
+// Listen announces on the TCP address, unless the process was started by
+// Upgrade, in which case it returns the listening socket inherited from
+// the parent process.
+func Listen(addr string) (net.Listener, error) {
+	fdValue := os.Getenv(listenerFDEnv)
+	if fdValue == "" {
+		return net.Listen("tcp", addr)
+	}
+	// Children of this process must not try to reuse the descriptor
+	os.Unsetenv(listenerFDEnv)
+
+	fd, err := strconv.Atoi(fdValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", listenerFDEnv, err)
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// Upgrade starts a new instance of the running binary, with the same
+// arguments, that inherits ln and serves on it through Listen. Connections
+// keep being accepted by the kernel while the processes swap, so once the
+// new process is started the caller can Shutdown its server and exit
+// without dropping requests.
+func Upgrade(ln net.Listener) (*os.Process, error) {
+	fl, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("listener can't be passed to another process")
+	}
+	f, err := fl.File()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles start at descriptor 3, after stdin, stdout and stderr
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(), listenerFDEnv+"=3")
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
+
+// isUpgradeSignal reports whether sig requests a graceful restart.
+func isUpgradeSignal(sig os.Signal) bool {
+	for _, s := range upgradeSignals {
+		if sig == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package http
+
+import "os"
+
+// upgradeSignals is empty: passing sockets to a child process is only
+// supported on Unix systems.
+var upgradeSignals []os.Signal
@@ -0,0 +1,52 @@
+//go:build unix
+
+package http
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// TestListenInherited verifies that Listen reuses a socket passed through the environment.
+func TestListenInherited(t *testing.T) {
+	parent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer parent.Close()
+
+	f, err := parent.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get listener file: %v", err)
+	}
+	// Listen takes ownership of the descriptor, as a child process would
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatalf("Failed to duplicate descriptor: %v", err)
+	}
+	t.Setenv(listenerFDEnv, strconv.Itoa(fd))
+
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer ln.Close()
+
+	if ln.Addr().String() != parent.Addr().String() {
+		t.Errorf("Expected the inherited address %s, got %s", parent.Addr(), ln.Addr())
+	}
+	if os.Getenv(listenerFDEnv) != "" {
+		t.Error("Expected the environment variable to be cleared")
+	}
+}
+
+// TestUpgradeUnsupportedListener verifies that listeners without a file descriptor can't be handed over.
+func TestUpgradeUnsupportedListener(t *testing.T) {
+	if _, err := Upgrade(&erroringListener{}); err == nil {
+		t.Error("Expected an error for a listener without a file descriptor")
+	}
+}
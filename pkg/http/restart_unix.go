@@ -0,0 +1,11 @@
+//go:build unix
+
+package http
+
+import (
+	"os"
+	"syscall"
+)
+
+// upgradeSignals trigger a graceful restart in Run.
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// Default backoff bounds of a RetryPolicy.
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy makes a Client retry requests that failed with a connection
+// error or a 5xx status, waiting with an exponential backoff and jitter
+// between attempts. Only idempotent methods are retried, since a failed
+// POST may already have had its effect, unless AllMethods is set.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry, doubled for every
+	// further retry up to MaxBackoff. Zero values mean 100ms and 5s. A
+	// Retry-After header in seconds extends the delay, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// AllMethods retries non-idempotent methods too, e.g. when requests
+	// carry an idempotency key.
+	AllMethods bool
+}
+
+// isIdempotent reports whether sending a request with the method twice
+// has the same effect as sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case GET, HEAD, PUT, DELETE, "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+// sendWithRetries sends a request, retrying it as allowed by the Client's
+// RetryPolicy. The last response or error is returned once the attempts
+// run out.
+func (c *Client) sendWithRetries(ctx context.Context, req *Request, body []byte, replayable bool) (*ClientResponse, error) {
+	p := c.Retry
+	if p == nil || p.MaxAttempts < 2 || !replayable || !(p.AllMethods || isIdempotent(req.Method)) {
+		return c.send(ctx, req, body, replayable)
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.send(ctx, req, body, replayable)
+		if ctx.Err() != nil || attempt == p.MaxAttempts || (err == nil && res.StatusCode < 500) {
+			return res, err
+		}
+
+		delay := p.backoff(attempt)
+		if res != nil {
+			if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+				delay = min(max(delay, time.Duration(seconds)*time.Second), p.maxBackoff())
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt:
+// a random duration between half and all of the exponential backoff.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	minBackoff := p.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+
+	d := minBackoff
+	for i := 1; i < attempt && d < p.maxBackoff(); i++ {
+		d *= 2
+	}
+	d = min(d, p.maxBackoff())
+	return d/2 + rand.N(d/2+1)
+}
+
+// maxBackoff returns the longest delay between attempts.
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return defaultMaxBackoff
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer starts a server failing the first failures requests with 503.
+func flakyServer(t *testing.T, failures int32) (string, *atomic.Int32) {
+	var attempts atomic.Int32
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if attempts.Add(1) <= failures {
+			Error(w, "unavailable", StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	return "http://" + addr, &attempts
+}
+
+// TestClientRetry verifies that idempotent requests are retried on 5xx responses.
+func TestClientRetry(t *testing.T) {
+	base, attempts := flakyServer(t, 2)
+	client := &Client{Retry: &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}}
+
+	res, err := client.Get(base + "/")
+	if err != nil || res.StatusCode != StatusOK {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
+
+// TestClientRetryExhausted verifies that the last response is returned once attempts run out.
+func TestClientRetryExhausted(t *testing.T) {
+	base, attempts := flakyServer(t, 5)
+	client := &Client{Retry: &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}}
+
+	res, err := client.Get(base + "/")
+	if err != nil || res.StatusCode != StatusServiceUnavailable {
+		t.Fatalf("Expected the last 503 response, got %v", err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+}
+
+// TestClientRetryNonIdempotent verifies that POST requests are only retried with AllMethods.
+func TestClientRetryNonIdempotent(t *testing.T) {
+	base, attempts := flakyServer(t, 1)
+	client := &Client{Retry: &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}}
+
+	res, err := client.Post(base+"/", "text/plain", strings.NewReader("x"))
+	if err != nil || res.StatusCode != StatusServiceUnavailable || attempts.Load() != 1 {
+		t.Errorf("Expected a single POST attempt, got %d attempts (%v)", attempts.Load(), err)
+	}
+
+	client.Retry.AllMethods = true
+	res, err = client.Post(base+"/", "text/plain", strings.NewReader("x"))
+	if err != nil || res.StatusCode != StatusOK {
+		t.Errorf("Expected the POST to succeed, got %v", err)
+	}
+}
+
+// TestRetryBackoff verifies that delays grow exponentially within their bounds.
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, full := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < full/2 || d > full {
+				t.Errorf("Attempt %d: delay %v outside [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule rewrites the paths matching From to To, either internally,
+// before the request is routed, or with an external redirect.
+//
+// From is a pattern like the ones of routes, where ":name" segments match
+// any single segment, and To may use the captured segments:
+//
+//	{From: "/old/:id", To: "/api/v2/items/:id"}
+//
+// When Regexp is set, From is a regular expression matched against the
+// escaped path, such as "/a%2Fb" for the segment "a/b", and To may refer
+// to its groups as in regexp.Expand:
+//
+//	{From: `^/blog/(\d+)/(.*)$`, To: "/posts/$2?year=$1", Regexp: true}
+//
+// Captured values stay escaped in the path of To and are escaped as query
+// values in its query string, which is added to the one of the request.
+type RewriteRule struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Regexp bool   `json:"regexp,omitempty"`
+	// Redirect is the status of an external redirect, such as 301 or 308.
+	// Zero rewrites the request internally.
+	Redirect int `json:"redirect,omitempty"`
+}
+
+// rewriteRule is a compiled RewriteRule.
+type rewriteRule struct {
+	RewriteRule
+	re       *regexp.Regexp
+	template string // To in regexp.Expand syntax
+}
+
+// segmentParam matches the ":name" segments of patterns.
+var segmentParam = regexp.MustCompile(`:(\w+)`)
+
+// compile prepares the rule for matching.
+func (rule RewriteRule) compile() (*rewriteRule, error) {
+	if rule.Redirect != 0 && !isRedirect(rule.Redirect) {
+		return nil, fmt.Errorf("http: rewrite of %s: %d is not a redirect status", rule.From, rule.Redirect)
+	}
+
+	if rule.Regexp {
+		re, err := regexp.Compile(rule.From)
+		if err != nil {
+			return nil, fmt.Errorf("http: rewrite of %s: %w", rule.From, err)
+		}
+		return &rewriteRule{RewriteRule: rule, re: re, template: rule.To}, nil
+	}
+
+	names := make(map[string]bool)
+	segments := strings.Split(rule.From, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok && name != "" {
+			segments[i] = "(?P<" + name + ">[^/]+)"
+			names[name] = true
+		} else {
+			segments[i] = regexp.QuoteMeta((&url.URL{Path: segment}).EscapedPath())
+		}
+	}
+	re, err := regexp.Compile("^" + strings.Join(segments, "/") + "$")
+	if err != nil {
+		return nil, fmt.Errorf("http: rewrite of %s: %w", rule.From, err)
+	}
+	// Only the captured names are replaced, so ports and such are kept
+	template := segmentParam.ReplaceAllStringFunc(strings.ReplaceAll(rule.To, "$", "$$"), func(param string) string {
+		if names[param[1:]] {
+			return "${" + param[1:] + "}"
+		}
+		return param
+	})
+	return &rewriteRule{RewriteRule: rule, re: re, template: template}, nil
+}
+
+// apply returns the target of an escaped path, or false when the rule
+// doesn't match.
+func (rule *rewriteRule) apply(path string) (string, bool) {
+	match := rule.re.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", false
+	}
+
+	// Captured values are escaped paths already, while in the query they
+	// must not add parameters
+	template, query, hasQuery := strings.Cut(rule.template, "?")
+	target := rule.re.ExpandString(nil, template, path, match)
+	if hasQuery {
+		target = append(target, '?')
+		target = rule.expandEscaped(target, query, path, match, func(v string) string {
+			if unescaped, err := url.PathUnescape(v); err == nil {
+				v = unescaped
+			}
+			return url.QueryEscape(v)
+		})
+	}
+	return string(target), true
+}
+
+// expandEscaped is like ExpandString, with the captured values escaped.
+func (rule *rewriteRule) expandEscaped(dst []byte, template, src string, match []int, escape func(string) string) []byte {
+	var escaped strings.Builder
+	indices := make([]int, len(match))
+	for i := 0; i < len(match); i += 2 {
+		if match[i] < 0 {
+			indices[i], indices[i+1] = -1, -1
+			continue
+		}
+		indices[i] = escaped.Len()
+		escaped.WriteString(escape(src[match[i]:match[i+1]]))
+		indices[i+1] = escaped.Len()
+	}
+	return rule.re.ExpandString(dst, template, escaped.String(), indices)
+}
+
+// Rewrite returns a handler that rewrites request paths with the first
+// matching rule before passing the request to next, typically a ServeMux
+// so the rewritten path is routed:
+//
+//	handler, err := http.Rewrite(mux,
+//		http.RewriteRule{From: "/old/:id", To: "/api/v2/items/:id"},
+//		http.RewriteRule{From: "/home", To: "/", Redirect: http.StatusMovedPermanently},
+//	)
+func Rewrite(next Handler, rules ...RewriteRule) (Handler, error) {
+	compiled := make([]*rewriteRule, len(rules))
+	for i, rule := range rules {
+		c, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = c
+	}
+
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		for _, rule := range compiled {
+			target, ok := rule.apply(r.URL.EscapedPath())
+			if !ok {
+				continue
+			}
+			u, err := url.Parse(target)
+			if err != nil {
+				Error(w, StatusText(StatusInternalServerError), StatusInternalServerError)
+				return
+			}
+			u.RawQuery = joinQuery(u.RawQuery, r.URL.RawQuery)
+
+			if rule.Redirect != 0 {
+				w.Header()["Location"] = []string{u.String()}
+				w.WriteHeader(rule.Redirect)
+				return
+			}
+
+			r2 := *r
+			u2 := *r.URL
+			u2.Path, u2.RawPath, u2.RawQuery = u.Path, u.RawPath, u.RawQuery
+			r2.URL = &u2
+			next.ServeHTTP(w, &r2)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// joinQuery joins two raw query strings.
+func joinQuery(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "&" + b
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+)
+
+// TestRewrite verifies internal rewrites, redirects and the precedence of rules.
+func TestRewrite(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.AddRoute("/api/v2/items/:id", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.Params["id"] + " " + r.URL.RawQuery))
+	})
+
+	handler, err := Rewrite(mux,
+		RewriteRule{From: "/old/:id", To: "/api/v2/items/:id"},
+		RewriteRule{From: `^/blog/(\d+)$`, To: "/api/v2/items/$1?kind=post", Regexp: true},
+		RewriteRule{From: `^/tags/([^/]+)$`, To: "/api/v2/items/tagged?tag=$1", Regexp: true},
+		RewriteRule{From: "/moved/:id", To: "https://example.test:8443/items/:id", Redirect: StatusPermanentRedirect},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		target   string
+		status   int
+		body     string
+		location string
+	}{
+		{"/old/42?full=1", StatusOK, "42 full=1", ""},
+		{"/blog/7?page=2", StatusOK, "7 kind=post&page=2", ""},
+		{"/api/v2/items/9", StatusOK, "9 ", ""},
+		{"/moved/5?x=y", StatusPermanentRedirect, "", "https://example.test:8443/items/5?x=y"},
+		{"/old/1/extra", StatusNotFound, "", ""},
+		{"/old/100%25", StatusOK, "100% ", ""},
+		{"/old/a%3Fadmin=1", StatusOK, "a?admin=1 ", ""},
+		{"/tags/a&b=c", StatusOK, "tagged tag=a%26b%3Dc", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.target)
+		res := &MockResponseWriter{headers: make(Header)}
+		handler.ServeHTTP(res, &Request{Method: GET, URL: u})
+
+		if res.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.status, res.status)
+		}
+		if tt.status == StatusOK && string(res.body) != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.target, tt.body, res.body)
+		}
+		if got := res.headers.Get("Location"); got != tt.location {
+			t.Errorf("%s: expected Location '%s', got '%s'", tt.target, tt.location, got)
+		}
+	}
+}
+
+// TestRewriteInvalidRules verifies that broken rules are reported when the handler is built.
+func TestRewriteInvalidRules(t *testing.T) {
+	for _, rule := range []RewriteRule{
+		{From: "(", To: "/", Regexp: true},
+		{From: "/a", To: "/b", Redirect: StatusOK},
+	} {
+		if _, err := Rewrite(NewServeMux(nil), rule); err == nil {
+			t.Errorf("Expected an error for %+v", rule)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import "time"
+
+// Route is a route added to a ServeMux with AddRoute or Handle. Its
+// methods set options of the route for the methods it was added with, and
+// return the route so they can be chained. They are meant to be called
+// before the mux serves requests.
+type Route struct {
+	node    *RouteNode
+	methods []string
+}
+
+// Pattern returns the pattern of the route.
+func (rt *Route) Pattern() string {
+	return rt.node.pattern
+}
+
+// WithTimeout sets the timeout of the route, overriding the default of
+// the mux set with SetTimeout, so long-running endpoints such as exports
+// get more time than the rest:
+//
+//	mux.AddRoute("/export", []string{http.GET}, export).WithTimeout(5 * time.Minute)
+//
+// The request context of the route's middleware and handler is canceled
+// once it elapses. A negative timeout disables the mux default.
+func (rt *Route) WithTimeout(timeout time.Duration) *Route {
+	if rt.node.timeouts == nil {
+		rt.node.timeouts = make(map[string]time.Duration)
+	}
+	for _, method := range rt.methods {
+		rt.node.timeouts[method] = timeout
+	}
+	return rt
+}
+
+// SetTimeout sets the default timeout of the routes, after which their
+// request context is canceled. Zero, the default, means no timeout.
+// Routes override it with Route.WithTimeout.
+func (mux *ServeMux) SetTimeout(timeout time.Duration) {
+	mux.timeout = timeout
+}
+
+// routeTimeout returns the timeout of the route's method, zero when it has
+// none.
+func (mux *ServeMux) routeTimeout(node *RouteNode, method string) time.Duration {
+	if timeout, ok := node.timeouts[method]; ok {
+		return max(timeout, 0)
+	}
+	return max(mux.timeout, 0)
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestRouteTimeout verifies that route timeouts override the default of the mux in the request context.
+func TestRouteTimeout(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.SetTimeout(time.Second)
+
+	var remaining time.Duration
+	var hasDeadline bool
+	handler := func(w ResponseWriter, r *Request) {
+		var deadline time.Time
+		deadline, hasDeadline = r.Context().Deadline()
+		remaining = time.Until(deadline)
+	}
+	mux.AddRoute("/api", []string{GET}, handler)
+	mux.AddRoute("/export", []string{GET}, handler).WithTimeout(time.Minute)
+	mux.AddRoute("/stream", []string{GET}, handler).WithTimeout(-1)
+
+	tests := []struct {
+		path     string
+		deadline bool
+		min, max time.Duration
+	}{
+		{"/api", true, 0, time.Second},
+		{"/export", true, time.Second, time.Minute},
+		{"/stream", false, 0, 0},
+	}
+	for _, tt := range tests {
+		mux.ServeHTTP(&MockResponseWriter{headers: make(Header)}, &Request{Method: GET, URL: &url.URL{Path: tt.path}})
+		if hasDeadline != tt.deadline {
+			t.Errorf("%s: expected a deadline %v, got %v", tt.path, tt.deadline, hasDeadline)
+			continue
+		}
+		if tt.deadline && (remaining <= tt.min || remaining > tt.max) {
+			t.Errorf("%s: expected a deadline within (%s, %s], got %s", tt.path, tt.min, tt.max, remaining)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RouteInfo describes a route of a ServeMux.
+type RouteInfo struct {
+	// Pattern is the route pattern, e.g. "/users/:id".
+	Pattern string `json:"pattern"`
+	// Methods are the methods the route answers, sorted.
+	Methods []string `json:"methods"`
+	// Params are the names of the dynamic segments of the pattern, the
+	// catch-all one included, in order.
+	Params []string `json:"params,omitempty"`
+	// Timeouts are the timeouts set with Route.WithTimeout, by method.
+	Timeouts map[string]time.Duration `json:"timeouts,omitempty"`
+	// Middleware are the names of the middleware wrapping the handler of
+	// each method, in the order requests go through them. See Chain.
+	Middleware map[string][]string `json:"middleware,omitempty"`
+}
+
+// Routes returns the routes of the mux, sorted by pattern.
+func (mux *ServeMux) Routes() []RouteInfo {
+	var routes []RouteInfo
+	walkTree(mux.root, func(node *RouteNode, _ int) {
+		if len(node.handler) == 0 {
+			return
+		}
+		info := RouteInfo{Pattern: node.pattern, Methods: nodeMethods(node)}
+		for _, segment := range strings.Split(node.pattern, "/") {
+			if isParamSegment(segment) {
+				info.Params = append(info.Params, segment[1:])
+			}
+		}
+		if len(node.timeouts) > 0 {
+			info.Timeouts = make(map[string]time.Duration, len(node.timeouts))
+			for method, timeout := range node.timeouts {
+				info.Timeouts[method] = timeout
+			}
+		}
+		for _, method := range info.Methods {
+			chain := mux.chain(node, method)
+			if len(chain) == 0 {
+				continue
+			}
+			if info.Middleware == nil {
+				info.Middleware = make(map[string][]string, len(info.Methods))
+			}
+			for _, mw := range chain {
+				info.Middleware[method] = append(info.Middleware[method], mw.Name)
+			}
+		}
+		routes = append(routes, info)
+	})
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+// DumpTree writes the route tree of the mux as indented text, one segment
+// per line, with the methods, timeouts and own middleware of the routes
+// ending there, after the names of the mux's middleware in the order
+// requests go through them:
+//
+//	middleware: http.LoggingMiddleware
+//	/api
+//	  /items  GET
+//	    /:id  GET, PUT  use=auth
+//	  /export  GET  timeout=5m0s
+//
+// Static segments are listed before dynamic ones, which are tried in that
+// order, so the dump tells which route a path ends up at.
+func (mux *ServeMux) DumpTree(w io.Writer) {
+	if names := mux.middlewareNames(); len(names) > 0 {
+		fmt.Fprintf(w, "middleware: %s\n", strings.Join(names, ", "))
+	}
+	walkTree(mux.root, func(node *RouteNode, depth int) {
+		if depth == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s/%s", strings.Repeat("  ", depth-1), node.pathSegment)
+		if methods := nodeMethods(node); len(methods) > 0 {
+			fmt.Fprintf(w, "  %s", strings.Join(methods, ", "))
+		}
+		timeouts := make(map[string]string, len(node.timeouts))
+		for method, timeout := range node.timeouts {
+			timeouts[method] = timeout.String()
+		}
+		writeOption(w, "timeout", timeouts, len(node.handler))
+		uses := make(map[string]string, len(node.middleware))
+		for method, mws := range node.middleware {
+			names := make([]string, len(mws))
+			for i, mw := range mws {
+				names[len(names)-1-i] = mw.Name
+			}
+			uses[method] = strings.Join(names, ",")
+		}
+		writeOption(w, "use", uses, len(node.handler))
+		fmt.Fprintln(w)
+	})
+}
+
+// writeOption writes the values of a route option by method, once when
+// all of the route's methods share it.
+func writeOption(w io.Writer, name string, values map[string]string, methods int) {
+	keys := sortedKeys(values)
+	shared := len(keys) == methods
+	for _, method := range keys {
+		shared = shared && values[method] == values[keys[0]]
+	}
+	if shared && len(keys) > 0 {
+		fmt.Fprintf(w, "  %s=%s", name, values[keys[0]])
+		return
+	}
+	for _, method := range keys {
+		fmt.Fprintf(w, "  %s(%s)=%s", name, method, values[method])
+	}
+}
+
+// ServeRoutes writes the route tree, as DumpTree does, or as JSON for
+// clients that accept it or ask for ?format=json, to be registered as a
+// debugging endpoint:
+//
+//	mux.AddRoute("/debug/routes", []string{http.GET}, mux.ServeRoutes)
+//
+// The routes reveal the layout of the application, so production servers
+// should restrict access to it.
+func (mux *ServeMux) ServeRoutes(w ResponseWriter, r *Request) {
+	asJSON := r.URL.Query().Get("format") == "json"
+	if !asJSON && r.URL.Query().Get("format") == "" {
+		asJSON = negotiate(r.Header.Get("Accept"), []string{"text/plain", "application/json"}) == "application/json"
+	}
+	if asJSON {
+		writeJSON(w, StatusOK, struct {
+			Middleware []string    `json:"middleware"`
+			Routes     []RouteInfo `json:"routes"`
+		}{mux.middlewareNames(), mux.Routes()})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(StatusOK)
+	mux.DumpTree(w)
+}
+
+// MarshalJSON encodes the timeouts of the route as duration strings, such
+// as "30s".
+func (info RouteInfo) MarshalJSON() ([]byte, error) {
+	type plain RouteInfo
+	var timeouts map[string]string
+	if len(info.Timeouts) > 0 {
+		timeouts = make(map[string]string, len(info.Timeouts))
+		for method, timeout := range info.Timeouts {
+			timeouts[method] = timeout.String()
+		}
+	}
+	return json.Marshal(struct {
+		plain
+		Timeouts map[string]string `json:"timeouts,omitempty"`
+	}{plain(info), timeouts})
+}
+
+// walkTree calls fn for node and its descendants, depth first, with their
+// depth below node. Children are visited in the order they are matched:
+// static segments sorted, then the dynamic and the catch-all one.
+func walkTree(node *RouteNode, fn func(node *RouteNode, depth int)) {
+	var walk func(node *RouteNode, depth int)
+	walk = func(node *RouteNode, depth int) {
+		fn(node, depth)
+		var children []*RouteNode
+		node.children.Range(func(_, value any) bool {
+			children = append(children, value.(*RouteNode))
+			return true
+		})
+		sort.Slice(children, func(i, j int) bool {
+			a, b := segmentRank(children[i].pathSegment), segmentRank(children[j].pathSegment)
+			if a != b {
+				return a < b
+			}
+			return children[i].pathSegment < children[j].pathSegment
+		})
+		for _, child := range children {
+			walk(child, depth+1)
+		}
+	}
+	walk(node, 0)
+}
+
+// segmentRank orders static, dynamic and catch-all segments.
+func segmentRank(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, "*"):
+		return 2
+	case strings.HasPrefix(segment, ":"):
+		return 1
+	}
+	return 0
+}
+
+// isParamSegment reports whether a pattern segment is a dynamic or
+// catch-all one.
+func isParamSegment(segment string) bool {
+	return len(segment) > 1 && segmentRank(segment) > 0
+}
+
+// nodeMethods returns the sorted methods of the route ending at node.
+func nodeMethods(node *RouteNode) []string {
+	return sortedKeys(node.handler)
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// middlewareNames returns the names of the mux's middleware, outermost
+// first.
+func (mux *ServeMux) middlewareNames() []string {
+	names := make([]string, len(mux.middleware))
+	for i, mw := range mux.middleware {
+		names[len(names)-1-i] = mw.Name
+	}
+	return names
+}
+
+// funcName returns the name of a function qualified by its package name,
+// such as "http.RealIP.func1" for the closures of middleware constructors.
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRoutesMux returns a mux with a few routes of each kind.
+func newRoutesMux() *ServeMux {
+	mux := NewServeMux(nil)
+	mux.Use(LoggingMiddleware)
+	noop := func(w ResponseWriter, r *Request) {}
+	mux.AddRoute("/api/items", []string{GET}, noop)
+	mux.AddRoute("/api/items/:id", []string{PUT, GET}, noop)
+	mux.AddRoute("/api/export", []string{GET}, noop).WithTimeout(5 * time.Minute)
+	mux.AddRoute("/files/*path", []string{GET}, noop)
+	return mux
+}
+
+// TestDumpTree verifies the text dump of the route tree.
+func TestDumpTree(t *testing.T) {
+	var b strings.Builder
+	newRoutesMux().DumpTree(&b)
+
+	expected := "middleware: http.LoggingMiddleware\n" +
+		"/api\n" +
+		"  /export  GET  timeout=5m0s\n" +
+		"  /items  GET\n" +
+		"    /:id  GET, PUT\n" +
+		"/files\n" +
+		"  /*path  GET\n"
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+// TestRoutes verifies the routes listed by the mux and served as JSON.
+func TestRoutes(t *testing.T) {
+	mux := newRoutesMux()
+
+	routes := mux.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("Expected 4 routes, got %v", routes)
+	}
+	if r := routes[2]; r.Pattern != "/api/items/:id" || strings.Join(r.Methods, ",") != "GET,PUT" || strings.Join(r.Params, ",") != "id" {
+		t.Errorf("Unexpected route %+v", r)
+	}
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeRoutes(res, &Request{Method: GET, URL: &url.URL{Path: "/debug/routes"}, Header: Header{"Accept": {"application/json"}}})
+	var body struct {
+		Middleware []string
+		Routes     []struct {
+			Pattern  string
+			Params   []string
+			Timeouts map[string]string
+		}
+	}
+	if err := json.Unmarshal(res.body, &body); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", res.body, err)
+	}
+	if len(body.Middleware) != 1 || len(body.Routes) != 4 || body.Routes[0].Timeouts["GET"] != "5m0s" || body.Routes[3].Params[0] != "path" {
+		t.Errorf("Unexpected routes %s", res.body)
+	}
+
+	res = &MockResponseWriter{headers: make(Header)}
+	mux.ServeRoutes(res, &Request{Method: GET, URL: &url.URL{Path: "/debug/routes"}, Header: Header{}})
+	if ct := res.headers.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") || !strings.Contains(string(res.body), "/*path  GET") {
+		t.Errorf("Expected the text dump, got %s '%s'", ct, res.body)
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestSecureCookieRoundTrip verifies that a signed value verifies back to the original.
+func TestSecureCookieRoundTrip(t *testing.T) {
+	sc := NewSecureCookie([]byte("secret-key"))
+
+	signed := sc.Sign("session_id", "abc123")
+	if !strings.HasPrefix(signed, "abc123.") {
+		t.Errorf("Expected signed value to start with 'abc123.', got '%s'", signed)
+	}
+
+	value, err := sc.Verify("session_id", signed)
+	if err != nil || value != "abc123" {
+		t.Errorf("Expected 'abc123', got '%s' (%v)", value, err)
+	}
+}
+
+// TestSecureCookieTampered verifies that modified values and swapped names are rejected.
+func TestSecureCookieTampered(t *testing.T) {
+	sc := NewSecureCookie([]byte("secret-key"))
+	signed := sc.Sign("session_id", "abc123")
+
+	tampered := "admin" + signed[len("abc123"):]
+	if _, err := sc.Verify("session_id", tampered); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for tampered value, got %v", err)
+	}
+	if _, err := sc.Verify("other", signed); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for another cookie name, got %v", err)
+	}
+	if _, err := sc.Verify("session_id", "unsigned"); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for unsigned value, got %v", err)
+	}
+}
+
+// TestSecureCookieRotate verifies that old keys keep verifying until they are dropped.
+func TestSecureCookieRotate(t *testing.T) {
+	sc := NewSecureCookie([]byte("old-key"))
+	signed := sc.Sign("session_id", "abc123")
+
+	sc.Rotate([]byte("new-key"), 2)
+	if _, err := sc.Verify("session_id", signed); err != nil {
+		t.Errorf("Expected value signed with the old key to verify, got %v", err)
+	}
+
+	sc.Rotate([]byte("newest-key"), 2)
+	if _, err := sc.Verify("session_id", signed); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature after the old key was dropped, got %v", err)
+	}
+}
+
+// TestGetCookieWithVerifier verifies that GetCookie verifies and decodes signed cookies.
+func TestGetCookieWithVerifier(t *testing.T) {
+	sc := NewSecureCookie([]byte("secret-key"))
+
+	res := &MockResponseWriter{headers: make(Header)}
+	sc.SetCookie(res, &Cookie{Name: "session_id", Value: "abc123"})
+	setCookie := res.Header().Get("Set-Cookie")
+	signed := strings.TrimPrefix(setCookie, "session_id=")
+
+	req := &Request{
+		Method: GET,
+		URL:    &url.URL{Path: "/"},
+		Header: Header{"Cookie": {"session_id=" + signed + "; forged=x.y"}},
+	}
+
+	cookie, err := req.GetCookie("session_id", sc)
+	if err != nil || cookie.Value != "abc123" {
+		t.Errorf("Expected verified value 'abc123', got %v (%v)", cookie, err)
+	}
+
+	if _, err := req.GetCookie("forged", sc); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
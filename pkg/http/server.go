@@ -3,16 +3,20 @@ package http
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -22,6 +26,11 @@ type HandlerFunc func(ResponseWriter, *Request)
 
 // ServeHTTP calls f(w, r).
 // It's used to satisfy the Handler interface.
+func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) {
+	f(w, r)
+}
+
+// Handler responds to an HTTP request.
 type Handler interface {
 	ServeHTTP(ResponseWriter, *Request)
 }
@@ -29,11 +38,116 @@ type Handler interface {
 // Middleware is a function that wraps an HTTP handler.
 type Middleware func(func(ResponseWriter, *Request)) func(ResponseWriter, *Request)
 
+// shutdownTimeout is how long Run waits for in-flight connections after a
+// shutdown signal before closing them.
+const shutdownTimeout = 10 * time.Second
+
+// defaultServerHeader is the Server header of responses when
+// Server.ServerHeader isn't set.
+const defaultServerHeader = "http-lite"
+
+// requestReadTimeout is how long a client has to send the first request on
+// a connection, and the default IdleTimeout.
+const requestReadTimeout = 5 * time.Second
+
+// defaultMaxBodyDrain is how many unread request body bytes are discarded
+// when Server.MaxBodyDrain is zero.
+const defaultMaxBodyDrain = 256 << 10
+
+// Bounds of the backoff applied when accepting a connection fails temporarily.
+const (
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = 1 * time.Second
+)
+
 type Server struct {
 	Addr    string
 	Handler Handler
-	mu      sync.Mutex
-	wg      sync.WaitGroup
+
+	// MaxConcurrentConnections limits how many connections are handled at
+	// once. Zero means no limit.
+	MaxConcurrentConnections int
+	// ConnectionQueueTimeout is how long an accepted connection waits for a
+	// free slot once MaxConcurrentConnections is reached before it is
+	// rejected with 503 Service Unavailable. Zero rejects immediately.
+	ConnectionQueueTimeout time.Duration
+
+	// Workers, when positive, handles connections on a fixed pool of that
+	// many goroutines per listener instead of one goroutine per connection.
+	Workers int
+	// QueueSize is how many accepted connections may wait for a free worker
+	// before the accept loop blocks. It is only used when Workers is set.
+	QueueSize int
+
+	// IdleTimeout is how long a kept-alive connection may wait for its next
+	// request before it is closed. Zero means 5 seconds.
+	IdleTimeout time.Duration
+
+	// ErrorLog receives errors such as malformed requests and failed
+	// accepts. When nil, the standard logger is used.
+	ErrorLog Logger
+
+	// ConnState, when set, is called whenever a connection changes state.
+	ConnState func(net.Conn, ConnState)
+
+	// TrustedProxies are the addresses of the proxies in front of the
+	// server, such as netip.MustParsePrefix("10.0.0.0/8"). For requests
+	// from them, Request.ClientIP returns the address they forwarded, the
+	// nearest one not belonging to a trusted proxy, from the Forwarded
+	// header, X-Forwarded-For or X-Real-IP in that order of precedence.
+	// These headers are ignored from other peers.
+	TrustedProxies []netip.Prefix
+
+	// AllowedHosts, when set, are the hosts the server answers for, such
+	// as "example.com" or "*.example.com", which matches its subdomains.
+	// Requests without a Host header get 400 Bad Request and requests for
+	// other hosts 421 Misdirected Request, guarding against DNS rebinding
+	// and poisoned caches. Hosts are matched without their port and case
+	// insensitively.
+	AllowedHosts []string
+
+	// MaxBodyDrain is how many bytes of a request body the handler left
+	// unread are discarded after it returns, so the connection can be kept
+	// alive. Connections with longer leftovers are closed. Zero means
+	// 256KB, a negative value closes the connection whenever a body is
+	// left unread.
+	MaxBodyDrain int64
+
+	// LenientHeaders accepts headers of legacy clients that are rejected
+	// with 400 Bad Request by default: obsolete line folding is unfolded
+	// and control characters in field values are replaced by spaces. Field
+	// names must be tokens either way, without whitespace before the colon.
+	LenientHeaders bool
+
+	// ServerHeader is the Server header of the responses that don't set
+	// one. Empty means "http-lite".
+	ServerHeader string
+	// NoServerHeader omits the Server header, so the software isn't
+	// advertised.
+	NoServerHeader bool
+
+	// ReportPanic, when set, receives the value and stack of the panics
+	// recovered from the Handler, e.g. to send them to an error tracker,
+	// before the request is answered with 500 Internal Server Error.
+	ReportPanic func(r *Request, err error, stack []byte)
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+
+	onStart      []func()
+	onShutdown   []func()
+	startOnce    sync.Once
+	shutdownOnce sync.Once
+
+	slots      chan struct{}
+	rejected   atomic.Uint64
+	listeners  map[net.Listener]struct{}
+	conns      map[net.Conn]time.Time // Idle since, zero while active
+	inShutdown bool
+	reaperOnce sync.Once
+
+	baseCtx    context.Context // Parent of the request contexts
+	cancelBase context.CancelFunc
 }
 
 // NewServer creates a new HTTP server with the given address and handler.
@@ -46,8 +160,13 @@ func NewServer(addr string, handler Handler) *Server {
 
 // parseRequest reads and parses an HTTP request from a connection.
 func parseRequest(ctx context.Context, conn net.Conn) (*Request, error) {
-	reader := bufio.NewReader(conn)
+	return readRequest(ctx, newBufioReader(conn), false)
+}
 
+// readRequest reads and parses an HTTP request from a buffered connection,
+// giving up when ctx is done. Malformed headers are accepted when possible
+// if lenient, see Server.LenientHeaders.
+func readRequest(ctx context.Context, reader *bufio.Reader, lenient bool) (*Request, error) {
 	// Create a channel to signal when the request parsing is done
 	done := make(chan struct{})
 	var req *Request
@@ -55,7 +174,7 @@ func parseRequest(ctx context.Context, conn net.Conn) (*Request, error) {
 
 	go func() {
 		defer close(done)
-		req, err = parseRequestWithTimeout(reader)
+		req, err = readRequestMessage(reader, lenient)
 	}()
 
 	select {
@@ -68,8 +187,16 @@ func parseRequest(ctx context.Context, conn net.Conn) (*Request, error) {
 
 // parseRequestWithTimeout reads and parses an HTTP request from a connection with a timeout.
 func parseRequestWithTimeout(reader *bufio.Reader) (*Request, error) {
-	// Read the request line (e.g., "GET /path HTTP/1.1")
-	line, err := reader.ReadString('\n')
+	return readRequestMessage(reader, false)
+}
+
+// readRequestMessage reads and parses an HTTP request, with lenient header
+// parsing if lenient.
+func readRequestMessage(reader *bufio.Reader, lenient bool) (*Request, error) {
+	// Read the request line (e.g., "GET /path HTTP/1.1"). Lines are parsed
+	// in place in the reader's buffer, so only what the Request keeps is
+	// copied out of it
+	line, err := readLine(reader)
 	if err != nil {
 		if err == io.EOF {
 			return nil, err
@@ -79,162 +206,571 @@ func parseRequestWithTimeout(reader *bufio.Reader) (*Request, error) {
 	}
 
 	// Parse the request line
-	parts := strings.Fields(line)
-	if len(parts) < 3 {
+	method, target, proto, ok := parseRequestLine(line)
+	if !ok {
 		return nil, fmt.Errorf("malformed request line")
 	}
 
-	method := parts[0]
-	rawURL := parts[1]
-	proto := parts[2]
-
 	// XXX: Currently only support HTTP/1.1
-	if proto != "HTTP/1.1" {
+	if string(proto) != "HTTP/1.1" {
 		return nil, fmt.Errorf("unsupported protocol: %s", proto)
 	}
 
 	// Parse the URL
-	parsedURL, err := url.Parse(rawURL)
+	parsedURL, err := url.Parse(string(target))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	// Parse headers
-	headers := make(Header)
-	var cookies []Cookie
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, fmt.Errorf("failed to read header: %w", err)
-		}
-
-		// An empty line marks the end of headers
-		if line == "\r\n" {
-			break
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("malformed header line")
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		headers[key] = append(headers[key], value)
-
-		if key == "Cookie" {
-			cookies = append(cookies, parseCookies(value)...)
-		}
+	headers, err := readHeader(reader, lenient)
+	if err != nil {
+		return nil, err
 	}
 
-	// The request body is the remaining data in the reader
-	// Convert the reader to an io.ReadCloser
-	body := io.NopCloser(reader)
+	// The request body is read from the remaining data in the reader
+	body, contentLength, err := requestBody(headers, reader)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Request{
-		Method:  method,
-		URL:     parsedURL,
-		Proto:   proto,
-		Header:  headers,
-		Cookies: cookies,
-		Body:    body,
+		Method:        method,
+		URL:           parsedURL,
+		Proto:         "HTTP/1.1",
+		Header:        headers,
+		Body:          body,
+		ContentLength: contentLength,
 	}, nil
 }
 
 // parseCookies parses a cookie header string and returns a slice of cookies.
 func parseCookies(cookieHeader string) []Cookie {
 	var cookies []Cookie
-	parts := strings.Split(cookieHeader, ";")
-	for _, part := range parts {
-		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
-		if len(kv) == 2 {
-			cookies = append(cookies, Cookie{Name: kv[0], Value: kv[1]})
+	for rest := cookieHeader; rest != ""; {
+		var part string
+		part, rest, _ = strings.Cut(rest, ";")
+		if name, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			cookies = append(cookies, Cookie{Name: name, Value: value})
 		}
 	}
 	return cookies
 }
 
-// handleConn reads and parses an HTTP request from a connection and calls the handler.
+// handleConn reads requests from a connection and calls the handler for
+// each of them. Connections are kept alive between requests as long as
+// both sides allow it and every response is framed by a Content-Length.
+// ctx bounds the reading of the first request; later requests get
+// requestReadTimeout each.
 func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
-	defer conn.Close()
-	s.wg.Add(1)
-	defer s.wg.Done()
+	cr := newConnReader(conn)
+	reader := newBufioReader(cr)
+	// Create a ResponseWriter tied to the current connection, reused for
+	// every request on it
+	res := NewResponseWriter(conn).(*Response)
+	res.reader = reader
+	res.stopRead = cr.stopBackgroundRead
+	res.buffered = true
+	res.date = true
+	res.logf = s.logf
+	res.reportPanic = s.reportPanic
+	if !s.NoServerHeader {
+		res.serverHeader = s.ServerHeader
+		if res.serverHeader == "" {
+			res.serverHeader = defaultServerHeader
+		}
+	}
 
-	req, err := parseRequest(ctx, conn)
-	if err != nil {
-		if err == io.EOF {
+	defer func() {
+		if res.hijacked {
+			// The reader was handed over with the connection
+			s.setState(conn, StateHijacked)
+			return
+		}
+		conn.Close()
+		if reader != nil {
+			putBufioReader(reader)
+		}
+		s.setState(conn, StateClosed)
+	}()
+
+	// TLS connections complete their handshake before the request is read
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if isTLS {
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			s.logf("http: TLS handshake error from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+
+	for first := true; ; first = false {
+		// Kept-alive connections wait for their next request until the idle
+		// reaper closes them
+		readCtx := ctx
+		if !first {
+			readCtx = context.Background()
+		}
+		req, err := readRequest(readCtx, reader, s.LenientHeaders)
+		timedOut := readCtx.Err() != nil
+
+		if err != nil {
+			// A timed out read may still be using the reader
+			if timedOut {
+				reader = nil
+			}
+
+			// The client closed the connection, or went quiet between
+			// requests, there is nobody to answer
+			if errors.Is(err, io.EOF) || (!first && isReadError(err)) {
+				return
+			}
+
+			s.logf("http: error parsing request: %v", err)
+			conn.Write([]byte(fmt.Sprintf("HTTP/1.1 %d %s\r\n\r\n", StatusBadRequest, StatusText(StatusBadRequest))))
+			return
+		}
+
+		// The handler's context lives as long as the handler itself, or
+		// until the client goes away. That is found out by reading the
+		// connection in the background once the body is read, unless the
+		// next request is already buffered
+		reqCtx, cancel := context.WithCancel(s.baseContext())
+		req.ctx = reqCtx
+		watch := func() {
+			if reader.Buffered() == 0 {
+				cr.startBackgroundRead(cancel)
+			}
+		}
+		if b, ok := req.Body.(*connBody); ok && b.remaining() != 0 {
+			b.onEOF = watch
+		} else {
+			watch()
+		}
+		if addr := conn.RemoteAddr(); addr != nil {
+			req.RemoteAddr = addr.String()
+			if len(s.TrustedProxies) > 0 {
+				req.clientIP = resolveClientIP(req.ClientIP(), req.Header, s.TrustedProxies)
+			}
+		}
+
+		if isTLS {
+			state := tlsConn.ConnectionState()
+			req.TLS = &state
+		}
+
+		res.reset(req.Method == HEAD)
+		res.keepAlive = !hasToken(req.Header.Get("Connection"), "close")
+		body := req.Body
+
+		s.setState(conn, StateActive)
+
+		// Pass the ResponseWriter and Request to the handler, unless the
+		// request is for a host the server doesn't answer for
+		if code := s.checkHost(req); code != 0 {
+			Error(res, StatusText(code), code)
+		} else {
+			s.serveRequest(res, req)
+		}
+		cancel()
+
+		if res.hijacked {
 			return
 		}
+		// What the handler left of the body would be parsed as the next
+		// request
+		if res.keepAlive && !drainBody(body, s.maxBodyDrain()) {
+			res.keepAlive = false
+			if !res.headersSent {
+				res.Headers.Set("Connection", "close")
+			}
+		}
+		if b, ok := body.(*connBody); ok {
+			b.stop()
+		}
+		cr.stopBackgroundRead()
+		res.finish()
 
-		fmt.Println("Error parsing request:", err)
-		conn.Write([]byte(fmt.Sprintf("HTTP/1.1 %d %s\r\n\r\n", http.StatusBadRequest, http.StatusText(http.StatusBadRequest))))
-		return
+		if !res.keepAlive || s.ShuttingDown() {
+			return
+		}
+		s.setState(conn, StateIdle)
 	}
+}
 
-	// Create a ResponseWriter tied to the current connection
-	res := NewResponseWriter(conn)
+// maxBodyDrain returns how much of an unread request body is discarded.
+func (s *Server) maxBodyDrain() int64 {
+	if s.MaxBodyDrain == 0 {
+		return defaultMaxBodyDrain
+	}
+	return max(s.MaxBodyDrain, 0)
+}
 
-	// Pass the ResponseWriter and Request to the handler
+// checkHost returns the status rejecting a request for a host outside
+// AllowedHosts, or zero.
+func (s *Server) checkHost(req *Request) int {
+	if len(s.AllowedHosts) == 0 {
+		return 0
+	}
+	return checkHost(req.Header.Get("Host"), s.AllowedHosts)
+}
+
+// recoveredKey is the context key of the error recovered from a handler.
+type recoveredKey struct{}
+
+// RecoveredError returns the error recovered from the panic of the
+// handler, for the 500 error handler of a request. It returns nil
+// otherwise.
+func RecoveredError(r *Request) error {
+	err, _ := r.Context().Value(recoveredKey{}).(error)
+	return err
+}
+
+// serveRequest calls the Handler, recovering its panics. A panic is
+// logged and reported, then answered with the 500 handler of the Handler
+// when it has one, like a ServeMux, unless the response had started; the
+// connection is closed either way. The handler finds the panic with
+// RecoveredError.
+func (s *Server) serveRequest(res *Response, req *Request) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		err := panicError(v)
+		s.reportPanic(req, err, debug.Stack())
+
+		if res.hijacked {
+			return
+		}
+		res.keepAlive = false
+		if res.headersSent {
+			// Part of the response is out, the client will see it cut short
+			return
+		}
+		res.reset(res.noBody)
+		req = req.WithContext(context.WithValue(req.Context(), recoveredKey{}, err))
+		if eh, ok := s.Handler.(interface {
+			ServeError(ResponseWriter, *Request, int)
+		}); ok {
+			eh.ServeError(res, req, StatusInternalServerError)
+		} else {
+			Error(res, StatusText(StatusInternalServerError), StatusInternalServerError)
+		}
+	}()
 	s.Handler.ServeHTTP(res, req)
 }
 
-// listenAndServe listens on the TCP network address and handles incoming connections.
-func (s *Server) listenAndServe() error {
-	ln, err := net.Listen("tcp", s.Addr)
-	if err != nil {
+// reportPanic logs a panic recovered from the Handler and passes it to
+// ReportPanic.
+func (s *Server) reportPanic(r *Request, err error, stack []byte) {
+	s.logf("http: panic serving %s: %v\n%s", r.URL.Path, err, stack)
+	if s.ReportPanic != nil {
+		s.ReportPanic(r, err, stack)
+	}
+}
+
+// panicReporter returns how middleware answering a recovered panic itself
+// reports it: through the server of the Response at the bottom of w,
+// following Unwrap, or to the standard logger without one.
+func panicReporter(w ResponseWriter) func(r *Request, err error, stack []byte) {
+	if res := serverResponse(w); res != nil && res.reportPanic != nil {
+		return res.reportPanic
+	}
+	return func(r *Request, err error, stack []byte) {
+		log.Printf("http: panic serving %s: %v\n%s", r.URL.Path, err, stack)
+	}
+}
+
+// panicError returns the value of a panic as an error.
+func panicError(v any) error {
+	if err, ok := v.(error); ok {
 		return err
 	}
-	defer ln.Close()
+	return fmt.Errorf("%v", v)
+}
+
+// isReadError reports whether err comes from reading the connection rather
+// than from a malformed request.
+func isReadError(err error) bool {
+	var ne net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrNoProgress) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, net.ErrClosed) || errors.As(err, &ne)
+}
+
+// hasToken reports whether a comma separated header value contains token,
+// ignoring case.
+func hasToken(value, token string) bool {
+	for _, v := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve accepts connections on l and handles them until the server is shut
+// down, in which case it returns ErrServerClosed. Other accept errors are
+// returned, except temporary ones which are retried with a backoff. It
+// lets callers bring their own listener, such as a TLS listener or an
+// in-memory one in tests. Serve takes ownership of l and closes it on
+// return.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+
+	if !s.trackListener(l, true) {
+		return ErrServerClosed
+	}
+	defer s.trackListener(l, false)
+
+	s.runStartHooks()
+	s.startReaper()
+
+	queue := s.startWorkers()
+	if queue != nil {
+		defer close(queue)
+	}
 
+	var tempDelay time.Duration // How long to sleep on accept failure
 	for {
-		conn, err := ln.Accept()
+		conn, err := l.Accept()
 		if err != nil {
-			log.Println("Error accepting connection:", err)
+			if s.ShuttingDown() {
+				return ErrServerClosed
+			}
+			// Temporary errors such as running out of file descriptors are
+			// retried with an exponential backoff instead of spinning
+			if isTemporary(err) {
+				if tempDelay == 0 {
+					tempDelay = minAcceptDelay
+				} else {
+					tempDelay = min(2*tempDelay, maxAcceptDelay)
+				}
+				s.logf("http: error accepting connection: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+
+		s.setState(conn, StateNew)
+
+		if !s.acquireSlot() {
+			s.rejectConn(conn)
+			s.setState(conn, StateClosed)
 			continue
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.trackConn(conn, true)
+		s.wg.Add(1)
 
-		go func() {
-			defer cancel()
-			s.handleConn(ctx, conn)
-		}()
+		if queue != nil {
+			queue <- conn
+			continue
+		}
+		go s.serveConn(conn)
 	}
 }
 
-// Shutdown gracefully closes the server and waits for ongoing connections to finish
-func (s *Server) Shutdown() {
+// serveConn handles an accepted connection and releases what was reserved
+// for it when it was accepted.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.releaseSlot()
+	defer s.trackConn(conn, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestReadTimeout)
+	defer cancel()
+	s.handleConn(ctx, conn)
+}
+
+// isTemporary reports whether an accept error is worth retrying.
+func isTemporary(err error) bool {
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) {
+		return true
+	}
+	var ne interface{ Temporary() bool }
+	return errors.As(err, &ne) && ne.Temporary()
+}
+
+// trackListener adds or removes a listener from the set closed by Shutdown.
+// It reports false when the server is already shutting down.
+func (s *Server) trackListener(l net.Listener, add bool) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fmt.Println("Shutting down server...")
-	s.wg.Wait() // Wait for all connections to finish
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	if add {
+		if s.inShutdown {
+			return false
+		}
+		s.listeners[l] = struct{}{}
+	} else {
+		delete(s.listeners, l)
+	}
+	return true
 }
 
-// handleSignals listens for SIGINT and SIGTERM signals to gracefully shutdown the server
-func (s *Server) handleSignals(quit chan os.Signal) {
-	<-quit
-	s.Shutdown()
-	os.Exit(0)
+// trackConn adds or removes a connection from the set of open connections.
+func (s *Server) trackConn(conn net.Conn, add bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]time.Time)
+	}
+	if add {
+		s.conns[conn] = time.Time{}
+	} else {
+		delete(s.conns, conn)
+	}
 }
 
-// Run starts an HTTP server with the given address and handler.
+// baseContext returns the context the request contexts derive from,
+// canceled when Shutdown gives up waiting for them.
+func (s *Server) baseContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.baseCtx == nil {
+		s.baseCtx, s.cancelBase = context.WithCancel(context.Background())
+	}
+	return s.baseCtx
+}
+
+// ShuttingDown reports whether Shutdown has been called, e.g. so readiness
+// checks can fail while the server drains.
+func (s *Server) ShuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inShutdown
+}
+
+// Shutdown gracefully shuts down the server: it closes the listeners and
+// idle connections, then waits for active ones to finish. If ctx is done
+// first, the contexts of the requests still running are canceled, the
+// remaining connections are closed and the context's error is returned.
+// Hooks registered with OnShutdown run last.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.inShutdown = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.mu.Unlock()
+
+	// Idle connections would only be closed by the reaper
+	s.closeIdleConns(0)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait() // Wait for all connections to finish
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if errors.Is(err, net.ErrClosed) {
+			err = nil
+		}
+	case <-ctx.Done():
+		s.mu.Lock()
+		if s.cancelBase != nil {
+			s.cancelBase()
+		}
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		err = ctx.Err()
+	}
+
+	s.runShutdownHooks()
+	return err
+}
+
+// handleSignals waits for SIGINT or SIGTERM and gracefully shuts down the
+// server. On the upgrade signal it first hands ln over to a new process,
+// then drains this one.
+func (s *Server) handleSignals(quit chan os.Signal, ln net.Listener) error {
+	for {
+		sig := <-quit
+		if isUpgradeSignal(sig) {
+			if _, err := Upgrade(ln); err != nil {
+				s.logf("http: graceful restart failed: %v", err)
+				continue
+			}
+			fmt.Println("Restarted, draining connections...")
+		} else {
+			fmt.Println("Shutting down server...")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return s.Shutdown(ctx)
+	}
+}
+
+// Run starts an HTTP server with the given address and handler. It returns
+// once the server has been shut down by SIGINT or SIGTERM. On SIGUSR2 the
+// server restarts without downtime: the binary is executed again, taking
+// over the listening socket, and this process exits once drained.
 func Run(addr string, handler Handler) error {
+	return run(addr, handler, nil)
+}
+
+// RunTLS is like Run but serves HTTPS with the certificate and key read
+// from the given PEM files.
+func RunTLS(addr, certFile, keyFile string, handler Handler) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return run(addr, handler, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+// run serves handler on addr until a shutdown signal, over TLS when
+// tlsConfig is set.
+func run(addr string, handler Handler, tlsConfig *tls.Config) error {
 	server := NewServer(addr, handler)
 
+	ln, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+
 	// Set up signal catching for graceful shutdown
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(quit, append([]os.Signal{syscall.SIGINT, syscall.SIGTERM}, upgradeSignals...)...)
+	defer signal.Stop(quit)
 
-	go server.handleSignals(quit)
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.handleSignals(quit, ln)
+	}()
 
-	// Start server
+	// Start server, restarts hand over the plain listener
+	serveLn := ln
+	if tlsConfig != nil {
+		serveLn = tls.NewListener(ln, tlsConfig)
+	}
 	fmt.Println("Server listening on", addr)
-	return server.listenAndServe()
+	if err := server.Serve(serveLn); err != ErrServerClosed {
+		return err
+	}
+	return <-shutdown
 }
 
-// Error writes an HTTP error response with the given message and status code.
+// Error writes an HTTP error response with the given message and status
+// code, as an application/problem+json body titled with the status text.
+// The message is its detail, unless it just repeats the status text.
 func Error(w ResponseWriter, m string, statusCode int) {
-	w.WriteHeader(statusCode)
-	fmt.Fprintln(w, m)
+	if m == StatusText(statusCode) {
+		m = ""
+	}
+	WriteProblem(w, NewProblem(statusCode, m))
 }
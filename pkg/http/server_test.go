@@ -4,8 +4,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
+	"go/parser"
+	"go/token"
 	"io"
+	"log"
 	"net"
+	"net/netip"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -43,6 +51,16 @@ func (m *MockConnWithReader) Close() error {
 	return nil
 }
 
+// RemoteAddr returns a simulated client address.
+func (m *MockConnWithReader) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 51000}
+}
+
+// SetReadDeadline does nothing, simulated reads never block.
+func (m *MockConnWithReader) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
 // TestParseRequest_Successful verifies that valid requests are parsed correctly.
 func TestParseRequest_Successful(t *testing.T) {
 	rawRequest := "GET / HTTP/1.1\r\nHost: localhost\r\nUser-Agent: GoTest\r\nCookie: session_id=abc123\r\n\r\n"
@@ -70,8 +88,8 @@ func TestParseRequest_Successful(t *testing.T) {
 	}
 
 	// Verify that the cookies were parsed correctly.
-	if len(req.Cookies) != 1 || req.Cookies[0].Value != "abc123" {
-		t.Errorf("Expected cookie session_id=abc123, got '%v'", req.Cookies)
+	if len(req.Cookies()) != 1 || req.Cookies()[0].Value != "abc123" {
+		t.Errorf("Expected cookie session_id=abc123, got '%v'", req.Cookies())
 	}
 }
 
@@ -430,3 +448,515 @@ func TestHandleConn_OverloadedServer(t *testing.T) {
 		t.Errorf("Expected some responses even under load, but got empty output")
 	}
 }
+
+// TestParseRequest_CanonicalHeaders verifies that parsed header keys are canonicalized.
+func TestParseRequest_CanonicalHeaders(t *testing.T) {
+	rawRequest := "GET / HTTP/1.1\r\nhost: localhost\r\ncookie: session_id=abc123\r\n\r\n"
+	conn := &MockConnWithReader{reader: bufio.NewReader(strings.NewReader(rawRequest))}
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	req, err := parseRequest(ctx, conn)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := req.Header["Host"]; !ok {
+		t.Errorf("Expected canonical 'Host' key, got %v", req.Header)
+	}
+	if len(req.Cookies()) != 1 || req.Cookies()[0].Name != "session_id" {
+		t.Errorf("Expected cookie from lower-case header, got '%v'", req.Cookies())
+	}
+}
+
+// TestNoStdHTTPImport verifies that the package doesn't depend on net/http.
+func TestNoStdHTTPImport(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("Failed to list package files: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		for _, imp := range f.Imports {
+			if imp.Path.Value == `"net/http"` {
+				t.Errorf("%s imports net/http", file)
+			}
+		}
+	}
+}
+
+// startServer runs a server on a random local port and returns its address
+// and a channel receiving the result of the serve loop.
+func startServer(t *testing.T, handler Handler) (*Server, string, chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := NewServer(ln.Addr().String(), handler)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Serve(ln)
+	}()
+	return server, ln.Addr().String(), errc
+}
+
+// TestShutdown verifies that Shutdown stops accepting and waits for in-flight requests.
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server, addr, errc := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		close(started)
+		<-release
+		w.WriteHeader(StatusOK)
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- server.Shutdown(context.Background())
+	}()
+
+	if err := <-errc; err != ErrServerClosed {
+		t.Errorf("Expected ErrServerClosed from the serve loop, got %v", err)
+	}
+	if _, err := net.DialTimeout("tcp", addr, 100*time.Millisecond); err == nil {
+		t.Error("Expected new connections to be refused after Shutdown")
+	}
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned before the in-flight request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+// TestShutdownDeadline verifies that Shutdown closes lingering connections when its context ends.
+func TestShutdownDeadline(t *testing.T) {
+	started := make(chan struct{})
+	returned := make(chan struct{})
+	server, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		close(started)
+		<-r.Context().Done()
+		close(returned)
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected the connection to be closed by Shutdown")
+	}
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Error("Expected the request context to be canceled by Shutdown")
+	}
+}
+
+// TestClientDisconnect verifies that the request context is canceled when the client closes the connection.
+func TestClientDisconnect(t *testing.T) {
+	for _, raw := range []string{
+		"GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 4\r\n\r\nbody",
+		"POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nbody\r\n0\r\n\r\n",
+	} {
+		started := make(chan struct{})
+		returned := make(chan error, 1)
+		server, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+			io.ReadAll(r.Body)
+			close(started)
+			select {
+			case <-r.Context().Done():
+				returned <- nil
+			case <-time.After(2 * time.Second):
+				returned <- errors.New("the context wasn't canceled")
+			}
+		}))
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		conn.Write([]byte(raw))
+		<-started
+		conn.Close()
+
+		if err := <-returned; err != nil {
+			t.Errorf("%q: %v", raw, err)
+		}
+		server.Shutdown(context.Background())
+	}
+}
+
+// TestServe verifies that Serve handles connections from a caller-provided listener.
+func TestServe(t *testing.T) {
+	server, addr, errc := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("served"))
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	response, _ := io.ReadAll(conn)
+	if !strings.HasPrefix(string(response), "HTTP/1.1 200 OK") || !strings.HasSuffix(string(response), "served") {
+		t.Errorf("Unexpected response '%s'", string(response))
+	}
+
+	server.Shutdown(context.Background())
+	if err := <-errc; err != ErrServerClosed {
+		t.Errorf("Expected ErrServerClosed, got %v", err)
+	}
+}
+
+// TestServeAfterShutdown verifies that Serve refuses to start on a shut down server.
+func TestServeAfterShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := NewServer(ln.Addr().String(), &MockHandler{})
+	server.Shutdown(context.Background())
+
+	if err := server.Serve(ln); err != ErrServerClosed {
+		t.Errorf("Expected ErrServerClosed, got %v", err)
+	}
+}
+
+// temporaryError is an accept error that should be retried.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "too many open files" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+// erroringListener returns its errors from Accept one at a time.
+type erroringListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *erroringListener) Accept() (net.Conn, error) {
+	err := l.errs[0]
+	if len(l.errs) > 1 {
+		l.errs = l.errs[1:]
+	}
+	return nil, err
+}
+
+func (l *erroringListener) Close() error { return nil }
+
+// TestServeAcceptBackoff verifies that temporary accept errors are retried and others end Serve.
+func TestServeAcceptBackoff(t *testing.T) {
+	logger := &recordingLogger{}
+	server := NewServer("", &MockHandler{})
+	server.ErrorLog = logger
+
+	l := &erroringListener{errs: []error{temporaryError{}, temporaryError{}, net.ErrClosed}}
+	if err := server.Serve(l); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected net.ErrClosed, got %v", err)
+	}
+
+	if len(logger.messages) != 2 {
+		t.Fatalf("Expected 2 retries to be logged, got %v", logger.messages)
+	}
+	if !strings.Contains(logger.messages[1], "retrying in 10ms") {
+		t.Errorf("Expected the delay to double, got '%s'", logger.messages[1])
+	}
+}
+
+// TestKeepAlive verifies that framed responses keep the connection open for the next request.
+func TestKeepAlive(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body := "path " + r.URL.Path
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(body))
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(conn)
+
+	for _, path := range []string{"/one", "/two"} {
+		conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+
+		status, _ := reader.ReadString('\n')
+		if status != "HTTP/1.1 200 OK\r\n" {
+			t.Fatalf("%s: unexpected status line '%s'", path, status)
+		}
+		for line, _ := reader.ReadString('\n'); line != "\r\n"; line, _ = reader.ReadString('\n') {
+		}
+		body := make([]byte, len("path "+path))
+		if _, err := io.ReadFull(reader, body); err != nil || string(body) != "path "+path {
+			t.Errorf("%s: expected body 'path %s', got '%s' (%v)", path, path, string(body), err)
+		}
+	}
+}
+
+// TestKeepAliveUnframed verifies that responses too large to buffer and without a length close the connection.
+func TestKeepAliveUnframed(t *testing.T) {
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(strings.Repeat("x", responseBufferSize+1)))
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\nGET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Expected the server to close the connection, got %v", err)
+	}
+	if withoutDate(string(response)) != "HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Type: text/plain; charset=utf-8\r\nServer: http-lite\r\n\r\n"+strings.Repeat("x", responseBufferSize+1) {
+		t.Errorf("Expected a single response closing the connection, got '%q'", string(response))
+	}
+}
+
+// TestHandleConn_EmptyResponse verifies that a handler writing nothing still answers 200.
+func TestHandleConn_EmptyResponse(t *testing.T) {
+	server := NewServer(":8080", HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+		reader: bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+	}}
+
+	server.handleConn(context.Background(), conn)
+
+	if withoutDate(conn.written.String()) != "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nServer: http-lite\r\n\r\n" {
+		t.Errorf("Expected an empty 200 response, got '%q'", conn.written.String())
+	}
+}
+
+// dateHeader matches the Date header line of a response.
+var dateHeader = regexp.MustCompile("Date: [^\r]*\r\n")
+
+// withoutDate removes the Date header from a raw response.
+func withoutDate(response string) string {
+	return dateHeader.ReplaceAllString(response, "")
+}
+
+// TestDateAndServerHeaders verifies that responses carry a Date and a configurable Server header.
+func TestDateAndServerHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*Server)
+		handler func(ResponseWriter, *Request)
+		server  string
+	}{
+		{"default", func(*Server) {}, func(ResponseWriter, *Request) {}, "Server: http-lite\r\n"},
+		{"custom", func(s *Server) { s.ServerHeader = "lamp/1.0" }, func(ResponseWriter, *Request) {}, "Server: lamp/1.0\r\n"},
+		{"hidden", func(s *Server) { s.NoServerHeader = true }, func(ResponseWriter, *Request) {}, ""},
+		{"handler", func(*Server) {}, func(w ResponseWriter, r *Request) { w.Header().Set("Server", "mine") }, "Server: mine\r\n"},
+	}
+	for _, tt := range tests {
+		server := NewServer(":8080", HandlerFunc(tt.handler))
+		tt.setup(server)
+		conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+			reader: bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+		}}
+		server.handleConn(context.Background(), conn)
+
+		response := conn.written.String()
+		if want := "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n" + tt.server + "\r\n"; withoutDate(response) != want {
+			t.Errorf("%s: expected '%q', got '%q'", tt.name, want, withoutDate(response))
+		}
+		date := strings.TrimSuffix(strings.TrimPrefix(dateHeader.FindString(response), "Date: "), "\r\n")
+		if d, err := time.Parse(TimeFormat, date); err != nil || time.Since(d) > time.Minute {
+			t.Errorf("%s: expected a current Date header, got '%s'", tt.name, date)
+		}
+	}
+}
+
+// MockConnWithWriter is a MockConnWithReader that records what is written.
+type MockConnWithWriter struct {
+	MockConnWithReader
+	written bytes.Buffer
+}
+
+// Write records the written data.
+func (m *MockConnWithWriter) Write(b []byte) (int, error) {
+	return m.written.Write(b)
+}
+
+// BenchmarkParseRequest measures parsing a typical request without a body.
+func BenchmarkParseRequest(b *testing.B) {
+	raw := "GET /api/items?page=2 HTTP/1.1\r\nHost: localhost\r\nUser-Agent: bench\r\nAccept: */*\r\nCookie: session=abc\r\n\r\n"
+	src := strings.NewReader(raw)
+	reader := bufio.NewReader(src)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		src.Reset(raw)
+		reader.Reset(src)
+		if _, err := parseRequestWithTimeout(reader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkHandleConnKeepAlive measures serving pipelined requests on one kept-alive connection.
+func BenchmarkHandleConnKeepAlive(b *testing.B) {
+	server := NewServer(":8080", HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Length", "2")
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	raw := strings.Repeat("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", b.N)
+	conn := &MockConnWithReader{reader: bufio.NewReader(strings.NewReader(raw))}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	server.handleConn(context.Background(), conn)
+}
+
+// TestPanicRecovery verifies that handler panics are reported and answered by the 500 handler of the mux.
+func TestPanicRecovery(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.AddRoute("/boom", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Partial", "yes")
+		panic("out of lamps")
+	})
+	mux.HandleStatus(StatusInternalServerError, func(w ResponseWriter, r *Request, statusCode int) {
+		w.WriteHeader(statusCode)
+		w.Write([]byte("sorry: " + RecoveredError(r).Error()))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	reported := make(chan string, 1)
+	server := NewServer(ln.Addr().String(), mux)
+	server.ErrorLog = log.New(io.Discard, "", 0)
+	server.ReportPanic = func(r *Request, err error, stack []byte) {
+		reported <- r.URL.Path + " " + err.Error() + " " + strconv.FormatBool(bytes.Contains(stack, []byte("TestPanicRecovery")))
+	}
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /boom HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	response, _ := io.ReadAll(conn)
+
+	if !bytes.HasPrefix(response, []byte("HTTP/1.1 500")) || !bytes.HasSuffix(response, []byte("sorry: out of lamps")) {
+		t.Errorf("Expected the 500 handler's response, got '%s'", response)
+	}
+	if bytes.Contains(response, []byte("X-Partial")) {
+		t.Errorf("Expected the headers of the panicking handler to be discarded, got '%s'", response)
+	}
+	if got := <-reported; got != "/boom out of lamps true" {
+		t.Errorf("Expected the panic to be reported with its stack, got '%s'", got)
+	}
+}
+
+// TestServerTrustedProxies verifies that the server resolves the client address of requests from trusted proxies.
+func TestServerTrustedProxies(t *testing.T) {
+	for _, trusted := range []bool{false, true} {
+		var remoteAddr, clientIP string
+		server := NewServer(":8080", HandlerFunc(func(w ResponseWriter, r *Request) {
+			remoteAddr, clientIP = r.RemoteAddr, r.ClientIP()
+		}))
+		want := "10.0.0.1"
+		if trusted {
+			server.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+			want = "203.0.113.7"
+		}
+		conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+			reader: bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost\r\nX-Forwarded-For: 203.0.113.7\r\n\r\n")),
+		}}
+		server.handleConn(context.Background(), conn)
+
+		if remoteAddr != "10.0.0.1:51000" || clientIP != want {
+			t.Errorf("Trusted %v: expected %s from 10.0.0.1:51000, got %s from %s", trusted, want, clientIP, remoteAddr)
+		}
+	}
+}
+
+// TestUnreadBodyDrained verifies that bodies left unread are discarded so the connection serves the next request.
+func TestUnreadBodyDrained(t *testing.T) {
+	requests := "POST /one HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello" +
+		"POST /two HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n" +
+		"GET /three HTTP/1.1\r\nHost: localhost\r\n\r\n"
+
+	tests := []struct {
+		maxDrain int64
+		want     string
+		close    bool
+	}{
+		{0, "/one /two /three ", false},
+		{4, "/one ", true},
+		{-1, "/one ", true},
+	}
+	for _, tt := range tests {
+		var served strings.Builder
+		server := NewServer(":8080", HandlerFunc(func(w ResponseWriter, r *Request) {
+			served.WriteString(r.URL.Path + " ")
+		}))
+		server.MaxBodyDrain = tt.maxDrain
+		conn := &MockConnWithWriter{MockConnWithReader: MockConnWithReader{
+			reader: bufio.NewReader(strings.NewReader(requests)),
+		}}
+		server.handleConn(context.Background(), conn)
+
+		if served.String() != tt.want {
+			t.Errorf("MaxBodyDrain %d: expected %q to be served, got %q", tt.maxDrain, tt.want, served.String())
+		}
+		if got := strings.Contains(conn.written.String(), "Connection: close\r\n"); got != tt.close {
+			t.Errorf("MaxBodyDrain %d: expected Connection: close %v, got %v", tt.maxDrain, tt.close, got)
+		}
+	}
+}
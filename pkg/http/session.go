@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionStore persists session data by session ID. Implementations must
+// be safe for concurrent use, return ErrSessionNotFound for missing or
+// expired sessions and treat a non-positive TTL as "never expires". Stores
+// backed by external services (Redis, SQL, ...) only need to implement
+// this interface.
+type SessionStore interface {
+	Load(ctx context.Context, id string) ([]byte, error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// NewSessionID returns a random, URL-safe session identifier.
+func NewSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sessionExpiry returns the expiry time for a TTL, or the zero time when
+// the session never expires.
+func sessionExpiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// memorySession is a session held by a MemorySessionStore.
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemorySessionStore keeps sessions in memory. Sessions are lost when the
+// process exits.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load returns the data of a session.
+func (s *MemorySessionStore) Load(_ context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if !session.expires.IsZero() && time.Now().After(session.expires) {
+		delete(s.sessions, id)
+		return nil, ErrSessionNotFound
+	}
+	return append([]byte(nil), session.data...), nil
+}
+
+// Save stores the data of a session for the given TTL.
+func (s *MemorySessionStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[id] = memorySession{data: append([]byte(nil), data...), expires: sessionExpiry(ttl)}
+	return nil
+}
+
+// Delete removes a session.
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// Cleanup removes every expired session. Expired sessions are also removed
+// lazily by Load, so calling Cleanup periodically only bounds memory usage.
+func (s *MemorySessionStore) Cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, session := range s.sessions {
+		if !session.expires.IsZero() && now.After(session.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// FileSessionStore keeps each session in its own file inside a directory,
+// so sessions survive restarts. File names are derived from a hash of the
+// session ID, so IDs can never address files outside the directory.
+type FileSessionStore struct {
+	dir string
+}
+
+// NewFileSessionStore creates a file-backed session store in dir, creating
+// the directory if needed.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path returns the file that holds a session.
+func (s *FileSessionStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".session")
+}
+
+// Load returns the data of a session.
+func (s *FileSessionStore) Load(_ context.Context, id string) ([]byte, error) {
+	b, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 8 {
+		return nil, ErrSessionNotFound
+	}
+
+	// The first 8 bytes hold the expiry as Unix nanoseconds, 0 for none
+	if expires := int64(binary.BigEndian.Uint64(b[:8])); expires != 0 && time.Now().UnixNano() > expires {
+		os.Remove(s.path(id))
+		return nil, ErrSessionNotFound
+	}
+	return b[8:], nil
+}
+
+// Save stores the data of a session for the given TTL. The file is written
+// to a temporary name first and renamed, so readers never see partial data.
+func (s *FileSessionStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	var expires int64
+	if t := sessionExpiry(ttl); !t.IsZero() {
+		expires = t.UnixNano()
+	}
+
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(expires))
+	b = append(b, data...)
+
+	f, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(id))
+}
+
+// Delete removes a session.
+func (s *FileSessionStore) Delete(_ context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
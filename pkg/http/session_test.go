@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testSessionStore runs the SessionStore contract against a store.
+func testSessionStore(t *testing.T, store SessionStore) {
+	ctx := context.Background()
+
+	if _, err := store.Load(ctx, "missing"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound for a missing session, got %v", err)
+	}
+
+	if err := store.Save(ctx, "abc", []byte(`{"user":"john"}`), time.Hour); err != nil {
+		t.Fatalf("Unexpected error saving session: %v", err)
+	}
+	data, err := store.Load(ctx, "abc")
+	if err != nil || string(data) != `{"user":"john"}` {
+		t.Errorf("Expected saved data, got '%s' (%v)", string(data), err)
+	}
+
+	if err := store.Save(ctx, "forever", []byte("x"), 0); err != nil {
+		t.Fatalf("Unexpected error saving session: %v", err)
+	}
+	if _, err := store.Load(ctx, "forever"); err != nil {
+		t.Errorf("Expected session without TTL to load, got %v", err)
+	}
+
+	if err := store.Save(ctx, "short", []byte("x"), time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error saving session: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.Load(ctx, "short"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound for an expired session, got %v", err)
+	}
+
+	if err := store.Delete(ctx, "abc"); err != nil {
+		t.Fatalf("Unexpected error deleting session: %v", err)
+	}
+	if _, err := store.Load(ctx, "abc"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound after Delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "abc"); err != nil {
+		t.Errorf("Expected deleting a missing session to succeed, got %v", err)
+	}
+}
+
+// TestMemorySessionStore verifies the in-memory session store.
+func TestMemorySessionStore(t *testing.T) {
+	testSessionStore(t, NewMemorySessionStore())
+}
+
+// TestFileSessionStore verifies the file-backed session store.
+func TestFileSessionStore(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file session store: %v", err)
+	}
+	testSessionStore(t, store)
+}
+
+// TestFileSessionStoreUnsafeID verifies that IDs can't address files outside the store directory.
+func TestFileSessionStoreUnsafeID(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create file session store: %v", err)
+	}
+
+	if err := store.Save(context.Background(), "../../escape", []byte("x"), 0); err != nil {
+		t.Fatalf("Unexpected error saving session: %v", err)
+	}
+	if p := store.path("../../escape"); len(p) <= len(dir) || p[:len(dir)] != dir {
+		t.Errorf("Expected session file inside %s, got %s", dir, p)
+	}
+}
+
+// TestNewSessionID verifies that session IDs are unique.
+func TestNewSessionID(t *testing.T) {
+	a, err := NewSessionID()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, _ := NewSessionID()
+
+	if a == b || len(a) != 43 {
+		t.Errorf("Expected two distinct 43-character IDs, got '%s' and '%s'", a, b)
+	}
+}
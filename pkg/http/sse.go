@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a single Server-Sent Events message.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// String returns the wire representation of the event, terminated by a blank line.
+func (e Event) String() string {
+	var sb strings.Builder
+	if e.ID != "" {
+		sb.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		sb.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	// Multi-line payloads are sent as one data field per line
+	for _, line := range strings.Split(e.Data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// EventStream writes Server-Sent Events to a single client.
+type EventStream struct {
+	w         ResponseWriter
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewEventStream sends the event stream response headers and returns a
+// stream ready to send events.
+func NewEventStream(w ResponseWriter) *EventStream {
+	w.Header()["Content-Type"] = []string{"text/event-stream"}
+	w.Header()["Cache-Control"] = []string{"no-cache"}
+	w.Header()["Connection"] = []string{"keep-alive"}
+	w.WriteHeader(StatusOK)
+	flush(w)
+
+	return &EventStream{w: w, done: make(chan struct{})}
+}
+
+// Send writes an event to the client and flushes it. A failed write means
+// the client went away, so the stream is closed.
+func (s *EventStream) Send(e Event) error {
+	return s.write(e.String())
+}
+
+// Comment writes a comment line, which clients ignore. It is useful as a
+// heartbeat to keep intermediaries from closing an idle stream.
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// write sends raw data to the client while holding the stream lock.
+func (s *EventStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return ErrStreamClosed
+	default:
+	}
+
+	if _, err := s.w.Write([]byte(data)); err != nil {
+		s.Close()
+		return err
+	}
+	flush(s.w)
+	return nil
+}
+
+// Close marks the stream as closed. Further sends return ErrStreamClosed.
+func (s *EventStream) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Done returns a channel that is closed when the stream is closed.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until ctx is done or the stream is closed, whichever
+// happens first. Handlers pass the request context to detect clients
+// that disconnected.
+func (s *EventStream) Wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.Close()
+	case <-s.done:
+	}
+}
+
+// flush flushes w if it supports flushing.
+func flush(w ResponseWriter) {
+	Flush(w)
+}
+
+// EventHub broadcasts events to every subscribed event stream.
+type EventHub struct {
+	// KeepAlive, when positive, is the interval at which a heartbeat
+	// comment is sent to each subscriber, which also detects dead clients.
+	KeepAlive time.Duration
+
+	mu      sync.Mutex
+	streams map[*EventStream]struct{}
+}
+
+// NewEventHub creates an empty EventHub.
+func NewEventHub() *EventHub {
+	return &EventHub{streams: make(map[*EventStream]struct{})}
+}
+
+// ServeHTTP subscribes the client to the hub and keeps the stream open
+// until the request context is done or the client disconnects.
+func (h *EventHub) ServeHTTP(w ResponseWriter, r *Request) {
+	s := NewEventStream(w)
+
+	h.mu.Lock()
+	h.streams[s] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.streams, s)
+		h.mu.Unlock()
+		s.Close()
+	}()
+
+	if h.KeepAlive > 0 {
+		ticker := time.NewTicker(h.KeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-s.Done():
+				return
+			case <-ticker.C:
+				s.Comment("keep-alive")
+			}
+		}
+	}
+
+	s.Wait(r.Context())
+}
+
+// Broadcast sends the event to every subscriber. Subscribers whose write
+// fails are closed and removed when their handler returns.
+func (h *EventHub) Broadcast(e Event) {
+	h.mu.Lock()
+	streams := make([]*EventStream, 0, len(h.streams))
+	for s := range h.streams {
+		streams = append(streams, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range streams {
+		s.Send(e)
+	}
+}
+
+// Subscribers returns the number of currently subscribed streams.
+func (h *EventHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestEventString verifies the wire format of an event, including multi-line data.
+func TestEventString(t *testing.T) {
+	e := Event{ID: "42", Event: "update", Data: "line one\nline two", Retry: 3 * time.Second}
+
+	expected := "id: 42\nevent: update\nretry: 3000\ndata: line one\ndata: line two\n\n"
+	if e.String() != expected {
+		t.Errorf("Expected '%q', got '%q'", expected, e.String())
+	}
+}
+
+// TestEventStream verifies that NewEventStream sets the stream headers and Send writes frames.
+func TestEventStream(t *testing.T) {
+	res := &MockResponseWriter{headers: make(Header)}
+
+	stream := NewEventStream(res)
+	if err := stream.Send(Event{Data: "hello"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.status != StatusOK {
+		t.Errorf("Expected status %d, got %d", StatusOK, res.status)
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected Content-Type 'text/event-stream', got '%s'", ct)
+	}
+	if string(res.body) != "data: hello\n\n" {
+		t.Errorf("Expected body 'data: hello\\n\\n', got '%q'", string(res.body))
+	}
+}
+
+// TestEventStreamClosed verifies that sending on a closed stream fails.
+func TestEventStreamClosed(t *testing.T) {
+	stream := NewEventStream(&MockResponseWriter{headers: make(Header)})
+	stream.Close()
+
+	if err := stream.Send(Event{Data: "late"}); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Expected ErrStreamClosed, got %v", err)
+	}
+}
+
+// TestEventHubBroadcast verifies that subscribers receive broadcasts and leave when their context ends.
+func TestEventHubBroadcast(t *testing.T) {
+	hub := NewEventHub()
+	res := &MockResponseWriter{headers: make(Header)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := (&Request{Method: GET, URL: &url.URL{Path: "/events"}}).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.ServeHTTP(res, req)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for hub.Subscribers() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	hub.Broadcast(Event{Event: "tick", Data: "1"})
+	cancel()
+	<-done
+
+	if hub.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers after disconnect, got %d", hub.Subscribers())
+	}
+	if string(res.body) != "event: tick\ndata: 1\n\n" {
+		t.Errorf("Expected broadcast event in body, got '%q'", string(res.body))
+	}
+}
+
+// TestEventHubClientDisconnect verifies that subscribers leave when their client closes the connection, without heartbeats.
+func TestEventHubClientDisconnect(t *testing.T) {
+	hub := NewEventHub()
+	server, addr, _ := startServer(t, hub)
+	defer server.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn.Write([]byte("GET /events HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	if !waitFor(t, func() bool { return hub.Subscribers() == 1 }) {
+		t.Fatal("Subscriber never registered")
+	}
+	conn.Close()
+	if !waitFor(t, func() bool { return hub.Subscribers() == 0 }) {
+		t.Errorf("Expected no subscribers after disconnect, got %d", hub.Subscribers())
+	}
+}
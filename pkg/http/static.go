@@ -0,0 +1,294 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymlinkPolicy controls how static file serving treats symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinksContained follows symbolic links only when their target is
+	// inside the static directory. This is the default.
+	SymlinksContained SymlinkPolicy = iota
+	// SymlinksFollow follows every symbolic link, wherever it points.
+	SymlinksFollow
+	// SymlinksDeny never serves a file reached through a symbolic link.
+	SymlinksDeny
+)
+
+// SetSymlinkPolicy sets how static file serving treats symbolic links.
+func (mux *ServeMux) SetSymlinkPolicy(policy SymlinkPolicy) {
+	mux.symlinkPolicy = policy
+}
+
+// cleanStaticPath validates a decoded URL path for static file lookup. It
+// rejects NUL bytes and any ".." segment (also with backslash separators,
+// which Windows treats as path separators) and returns the cleaned path.
+func cleanStaticPath(urlPath string) (string, bool) {
+	if strings.IndexByte(urlPath, 0) >= 0 {
+		return "", false
+	}
+	for _, segment := range strings.FieldsFunc(urlPath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return "", false
+		}
+	}
+	return filepath.ToSlash(filepath.Clean("/" + urlPath)), true
+}
+
+// containedInRoot reports whether filePath may be served from root under the
+// mux's symlink policy. Paths that don't exist are reported as contained so
+// the caller's normal not-found handling applies.
+func (mux *ServeMux) containedInRoot(root, filePath string) bool {
+	if mux.symlinkPolicy == SymlinksFollow {
+		return true
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	if mux.symlinkPolicy == SymlinksDeny {
+		// Without links, the resolved path is the literal one under the resolved root
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return false
+		}
+		return resolved == filepath.Join(resolvedRoot, rel)
+	}
+
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// StaticMount maps a URL prefix to a directory on disk or an fs.FS.
+type StaticMount struct {
+	prefix      string
+	dir         string
+	fsys        fs.FS
+	cachePolicy *CachePolicy
+}
+
+// Static serves the files in dir under the URL prefix, e.g.
+// mux.Static("/assets", "./public/assets"). It can be called multiple
+// times; when several mounts match a request the longest prefix wins, and
+// routes registered on the mux take precedence over mounts.
+func (mux *ServeMux) Static(prefix, dir string) *StaticMount {
+	return mux.addMount(&StaticMount{prefix: prefix, dir: dir})
+}
+
+// StaticFS is like Static but serves the files of fsys.
+func (mux *ServeMux) StaticFS(prefix string, fsys fs.FS) *StaticMount {
+	return mux.addMount(&StaticMount{prefix: prefix, fsys: fsys})
+}
+
+// WithCachePolicy sets the caching headers sent with the mount's files,
+// overriding the mux's policy.
+func (m *StaticMount) WithCachePolicy(policy CachePolicy) *StaticMount {
+	m.cachePolicy = &policy
+	return m
+}
+
+// addMount registers a mount, keeping mounts sorted by descending prefix
+// length so the most specific one is tried first.
+func (mux *ServeMux) addMount(m *StaticMount) *StaticMount {
+	m.prefix = "/" + strings.Trim(m.prefix, "/")
+
+	mux.mountsMu.Lock()
+	defer mux.mountsMu.Unlock()
+
+	mux.mounts = append(mux.mounts, m)
+	sort.SliceStable(mux.mounts, func(i, j int) bool {
+		return len(mux.mounts[i].prefix) > len(mux.mounts[j].prefix)
+	})
+	return m
+}
+
+// serveMounts serves the request from the most specific matching mount,
+// reporting whether a response was written.
+func (mux *ServeMux) serveMounts(w ResponseWriter, r *Request) bool {
+	mux.mountsMu.RLock()
+	mounts := mux.mounts
+	mux.mountsMu.RUnlock()
+
+	for _, m := range mounts {
+		relPath, ok := mountRelPath(m.prefix, r.URL.Path)
+		if ok && mux.serveMount(w, r, m, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// mountRelPath returns the request path relative to a mount prefix, or
+// false when the prefix doesn't match on a segment boundary.
+func mountRelPath(prefix, urlPath string) (string, bool) {
+	if prefix == "/" {
+		return urlPath, true
+	}
+	if urlPath == prefix {
+		return "/", true
+	}
+	if strings.HasPrefix(urlPath, prefix+"/") {
+		return urlPath[len(prefix):], true
+	}
+	return "", false
+}
+
+// serveStaticFile serves the file matching the request path from the static
+// directory or file system set on the mux, reporting whether a file was found.
+func (mux *ServeMux) serveStaticFile(w ResponseWriter, r *Request) bool {
+	// Check if a static directory or file system is set
+	if mux.staticDir == nil && mux.fileSystem == nil {
+		return false
+	}
+
+	root := &StaticMount{prefix: "/", fsys: mux.fileSystem}
+	if mux.staticDir != nil {
+		root.dir = *mux.staticDir
+	}
+	return mux.serveMount(w, r, root, r.URL.Path)
+}
+
+// serveMount serves the file at relPath inside a mount, reporting whether a
+// response was written. The file is streamed to the client rather than
+// loaded into memory.
+func (mux *ServeMux) serveMount(w ResponseWriter, r *Request, m *StaticMount, relPath string) bool {
+	// Reject paths that try to climb out of the static directory
+	urlPath, ok := cleanStaticPath(relPath)
+	if !ok {
+		Error(w, StatusText(StatusBadRequest), StatusBadRequest)
+		return true
+	}
+
+	// When the URL ends with a "/", serve the index.html file
+	isDir := strings.HasSuffix(relPath, "/")
+
+	policy := m.cachePolicy
+	if policy == nil {
+		policy = mux.cachePolicy
+	}
+
+	if m.fsys != nil {
+		return mux.serveFSFile(w, r, m.fsys, policy, urlPath, isDir)
+	}
+	return mux.serveDiskFile(w, r, m.dir, policy, urlPath, isDir)
+}
+
+// serveDiskFile serves the file at the cleaned URL path from a directory
+// on disk, reporting whether a response was written.
+func (mux *ServeMux) serveDiskFile(w ResponseWriter, r *Request, root string, policy *CachePolicy, urlPath string, isDir bool) bool {
+	// Get the file path from the URL
+	dirPath := filepath.Join(root, filepath.FromSlash(urlPath))
+	filePath := dirPath
+	if isDir {
+		filePath = filepath.Join(filePath, "index.html")
+	}
+
+	// Symlinks may still point outside of the static directory
+	if !mux.containedInRoot(root, filePath) {
+		return false
+	}
+
+	// Open the file, falling back to a directory listing when enabled
+	f, err := os.Open(filePath)
+	if err != nil {
+		if mux.dirListing != nil && isDir && mux.containedInRoot(root, dirPath) {
+			if entries, err := os.ReadDir(dirPath); err == nil {
+				mux.serveDirListing(w, r, entries)
+				return true
+			}
+		}
+		mux.logFileError(filePath, err)
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		mux.logFileError(filePath, err)
+		return false
+	}
+	if info.IsDir() {
+		return mux.redirectToDir(w, r)
+	}
+
+	policy.apply(w.Header(), filePath)
+	ServeContent(w, r, filePath, info.ModTime(), f)
+	return true
+}
+
+// SetFileSystem serves static files from fsys instead of a directory on
+// disk, so binaries can embed their website with go:embed. Use fs.Sub to
+// serve a subdirectory of an embed.FS. It takes precedence over the static
+// directory.
+func (mux *ServeMux) SetFileSystem(fsys fs.FS) {
+	mux.fileSystem = fsys
+}
+
+// serveFSFile serves the file at the cleaned URL path from fsys, reporting
+// whether a response was written.
+func (mux *ServeMux) serveFSFile(w ResponseWriter, r *Request, fsys fs.FS, policy *CachePolicy, urlPath string, isDir bool) bool {
+	dirName := strings.TrimPrefix(urlPath, "/")
+	if dirName == "" {
+		dirName = "."
+	}
+	name := dirName
+	if isDir {
+		name = path.Join(dirName, "index.html")
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		if mux.dirListing != nil && isDir {
+			if entries, err := fs.ReadDir(fsys, dirName); err == nil {
+				mux.serveDirListing(w, r, entries)
+				return true
+			}
+		}
+		mux.logFileError(name, err)
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		mux.logFileError(name, err)
+		return false
+	}
+	if info.IsDir() {
+		return mux.redirectToDir(w, r)
+	}
+
+	// Files that can't seek are buffered so ranges still work; files from
+	// embed.FS and os.DirFS can seek
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			mux.logFileError(name, err)
+			return false
+		}
+		content = bytes.NewReader(data)
+	}
+
+	policy.apply(w.Header(), name)
+	ServeContent(w, r, name, info.ModTime(), content)
+	return true
+}
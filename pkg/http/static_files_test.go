@@ -1,11 +1,20 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 // TestServeStaticFile verifies that the server can serve a static file.
@@ -39,7 +48,7 @@ func TestServeStaticFile(t *testing.T) {
 		t.Errorf("Expected body '%s', got '%s'", string(content), string(res.body))
 	}
 
-	expectedContentType := "text/html"
+	expectedContentType := "text/html; charset=utf-8"
 	actualContentType := res.Header().Get("Content-Type")
 	if actualContentType != expectedContentType {
 		t.Errorf("Expected Content-Type '%s', got '%s'", expectedContentType, actualContentType)
@@ -65,7 +74,7 @@ func TestServeStaticFileNotFound(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
 	}
 
-	expectedBody := "Not Found\n"
+	expectedBody := `{"title":"Not Found","status":404,"instance":"/nonexistentfile.html"}`
 	if string(res.body) != expectedBody {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(res.body))
 	}
@@ -134,7 +143,7 @@ func TestServeStaticFileWithCustomExtension(t *testing.T) {
 		t.Errorf("Expected body '%s', got '%s'", string(content), string(res.body))
 	}
 
-	expectedContentType := "application/octet-stream"
+	expectedContentType := "text/plain; charset=utf-8"
 	actualContentType := res.Header().Get("Content-Type")
 	if actualContentType != expectedContentType {
 		t.Errorf("Expected Content-Type '%s', got '%s'", expectedContentType, actualContentType)
@@ -158,7 +167,7 @@ func TestServeStaticFileWithError(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
 	}
 
-	expectedBody := "Not Found\n"
+	expectedBody := `{"title":"Not Found","status":404,"instance":"/testfile.html"}`
 	if string(res.body) != expectedBody {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(res.body))
 	}
@@ -183,8 +192,518 @@ func TestServeEmptyStaticDir(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
 	}
 
-	expectedBody := "Not Found\n"
+	expectedBody := `{"title":"Not Found","status":404,"instance":"/emptyfile.html"}`
 	if string(res.body) != expectedBody {
 		t.Errorf("Expected body '%s', got '%s'", expectedBody, string(res.body))
 	}
 }
+
+// TestServeStaticFileStreaming verifies that large files are streamed with a Content-Length.
+func TestServeStaticFileStreaming(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := bytes.Repeat([]byte("0123456789"), 10000) // Larger than one copy chunk
+	if err := os.WriteFile(filepath.Join(tmpDir, "large.bin"), content, 0644); err != nil {
+		t.Fatalf("Failed to create large static file: %v", err)
+	}
+
+	mux := NewServeMux(&tmpDir)
+
+	req := &Request{
+		Method: GET,
+		URL:    &url.URL{Path: "/large.bin"},
+	}
+
+	res := &MockResponseWriter{headers: make(Header)}
+
+	mux.ServeHTTP(res, req)
+
+	if !bytes.Equal(res.body, content) {
+		t.Errorf("Expected %d bytes of body, got %d", len(content), len(res.body))
+	}
+
+	if cl := res.Header().Get("Content-Length"); cl != "100000" {
+		t.Errorf("Expected Content-Length '100000', got '%s'", cl)
+	}
+}
+
+// failingWriter is a ResponseWriter whose writes fail after a number of bytes, like a client that disconnects.
+type failingWriter struct {
+	MockResponseWriter
+	limit int
+}
+
+func (f *failingWriter) Write(b []byte) (int, error) {
+	if len(f.body)+len(b) > f.limit {
+		return 0, errors.New("connection reset by peer")
+	}
+	return f.MockResponseWriter.Write(b)
+}
+
+// TestServeStaticFileClientDisconnect verifies that streaming stops once the client goes away.
+func TestServeStaticFileClientDisconnect(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := bytes.Repeat([]byte("x"), 4*copyBufferSize)
+	if err := os.WriteFile(filepath.Join(tmpDir, "large.bin"), content, 0644); err != nil {
+		t.Fatalf("Failed to create large static file: %v", err)
+	}
+
+	mux := NewServeMux(&tmpDir)
+
+	req := &Request{
+		Method: GET,
+		URL:    &url.URL{Path: "/large.bin"},
+	}
+
+	res := &failingWriter{MockResponseWriter: MockResponseWriter{headers: make(Header)}, limit: copyBufferSize}
+
+	mux.ServeHTTP(res, req)
+
+	if len(res.body) != copyBufferSize {
+		t.Errorf("Expected streaming to stop after %d bytes, got %d", copyBufferSize, len(res.body))
+	}
+}
+
+// TestCopyContentCanceled verifies that copying stops when the context is done.
+func TestCopyContentCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	n, err := copyContent(ctx, &buf, strings.NewReader("data"), 4)
+	if err != context.Canceled || n != 0 {
+		t.Errorf("Expected nothing copied and context.Canceled, got %d bytes and %v", n, err)
+	}
+}
+
+// TestServeStaticFilePathTraversal verifies that plain and encoded traversal attempts are rejected.
+func TestServeStaticFilePathTraversal(t *testing.T) {
+	parent := t.TempDir()
+	staticDir := filepath.Join(parent, "public")
+	if err := os.Mkdir(staticDir, 0755); err != nil {
+		t.Fatalf("Failed to create static dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("top secret"), 0644); err != nil {
+		t.Fatalf("Failed to create secret file: %v", err)
+	}
+
+	mux := NewServeMux(&staticDir)
+
+	for _, rawURL := range []string{
+		"/../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/..%2fsecret.txt",
+		"/assets/../../secret.txt",
+		"/..%5csecret.txt",
+		"/secret.txt%00.html",
+	} {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", rawURL, err)
+		}
+
+		req := &Request{Method: GET, URL: u}
+		res := &MockResponseWriter{headers: make(Header)}
+
+		mux.ServeHTTP(res, req)
+
+		if res.status != StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", rawURL, StatusBadRequest, res.status)
+		}
+		if strings.Contains(string(res.body), "top secret") {
+			t.Errorf("%s: secret file was served", rawURL)
+		}
+	}
+}
+
+// TestServeStaticFileSymlinkEscape verifies the symlink policies for links pointing outside the static root.
+func TestServeStaticFileSymlinkEscape(t *testing.T) {
+	parent := t.TempDir()
+	staticDir := filepath.Join(parent, "public")
+	if err := os.Mkdir(staticDir, 0755); err != nil {
+		t.Fatalf("Failed to create static dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("top secret"), 0644); err != nil {
+		t.Fatalf("Failed to create secret file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(staticDir, "inside.txt"), []byte("inside"), 0644); err != nil {
+		t.Fatalf("Failed to create inside file: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(parent, "secret.txt"), filepath.Join(staticDir, "escape.txt")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(staticDir, "inside.txt"), filepath.Join(staticDir, "alias.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		policy   SymlinkPolicy
+		path     string
+		expected int
+	}{
+		{SymlinksContained, "/escape.txt", StatusNotFound},
+		{SymlinksContained, "/alias.txt", StatusOK},
+		{SymlinksFollow, "/escape.txt", StatusOK},
+		{SymlinksDeny, "/alias.txt", StatusNotFound},
+		{SymlinksDeny, "/inside.txt", StatusOK},
+	}
+
+	for _, tt := range tests {
+		mux := NewServeMux(&staticDir)
+		mux.SetSymlinkPolicy(tt.policy)
+
+		req := &Request{Method: GET, URL: &url.URL{Path: tt.path}}
+		res := &MockResponseWriter{headers: make(Header)}
+
+		mux.ServeHTTP(res, req)
+
+		if res.status != tt.expected {
+			t.Errorf("Policy %d, %s: expected status %d, got %d", tt.policy, tt.path, tt.expected, res.status)
+		}
+	}
+}
+
+// TestServeStaticFileConditionalGet verifies that static files send Last-Modified and honor If-Modified-Since.
+func TestServeStaticFileConditionalGet(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "style.css")
+	if err := os.WriteFile(filePath, []byte("body{}"), 0644); err != nil {
+		t.Fatalf("Failed to create static file: %v", err)
+	}
+	modtime := time.Date(2024, 10, 4, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filePath, modtime, modtime); err != nil {
+		t.Fatalf("Failed to set file times: %v", err)
+	}
+
+	mux := NewServeMux(&tmpDir)
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/style.css"}})
+
+	lastModified := res.Header().Get("Last-Modified")
+	if lastModified != "Fri, 04 Oct 2024 12:00:00 GMT" {
+		t.Fatalf("Expected Last-Modified 'Fri, 04 Oct 2024 12:00:00 GMT', got '%s'", lastModified)
+	}
+
+	res = &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/style.css"}, Header: Header{"If-Modified-Since": {lastModified}}})
+
+	if res.status != StatusNotModified {
+		t.Errorf("Expected status %d, got %d", StatusNotModified, res.status)
+	}
+	if len(res.body) != 0 {
+		t.Errorf("Expected empty body, got '%s'", string(res.body))
+	}
+}
+
+// TestServeStaticFileFromFS verifies that static files can be served from an fs.FS.
+func TestServeStaticFileFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>embedded</h1>")},
+		"css/style.css":   {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>")},
+	}
+
+	mux := NewServeMux(nil)
+	mux.SetFileSystem(fsys)
+
+	tests := []struct {
+		path        string
+		status      int
+		body        string
+		contentType string
+	}{
+		{"/", StatusOK, "<h1>embedded</h1>", "text/html; charset=utf-8"},
+		{"/css/style.css", StatusOK, "body{}", "text/css; charset=utf-8"},
+		{"/docs/", StatusOK, "<h1>docs</h1>", "text/html; charset=utf-8"},
+		{"/css", StatusNotFound, `{"title":"Not Found","status":404,"instance":"/css"}`, ""},
+		{"/missing.js", StatusNotFound, `{"title":"Not Found","status":404,"instance":"/missing.js"}`, ""},
+	}
+
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: tt.path}})
+
+		if res.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, res.status)
+		}
+		if string(res.body) != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.path, tt.body, string(res.body))
+		}
+		if tt.contentType != "" && res.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: expected Content-Type '%s', got '%s'", tt.path, tt.contentType, res.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// newListingDir creates a static directory with files and a subdirectory for listing tests.
+func newListingDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.txt":       "bb",
+		"a.txt":       "aaaa",
+		".hidden":     "h",
+		"sub/c.txt":   "c",
+		"<script>.js": "x",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	return dir
+}
+
+// TestDirListingDisabled verifies that directories without index.html are not listed by default.
+func TestDirListingDisabled(t *testing.T) {
+	dir := newListingDir(t)
+	mux := NewServeMux(&dir)
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}})
+
+	if res.status != StatusNotFound {
+		t.Errorf("Expected status %d, got %d", StatusNotFound, res.status)
+	}
+}
+
+// TestDirListingHTML verifies the HTML listing, including hidden-file filtering and escaping.
+func TestDirListingHTML(t *testing.T) {
+	dir := newListingDir(t)
+	mux := NewServeMux(&dir)
+	mux.EnableDirListing(DirListingOptions{})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}})
+
+	body := string(res.body)
+	if res.status != StatusOK {
+		t.Fatalf("Expected status %d, got %d", StatusOK, res.status)
+	}
+	if !strings.Contains(body, `<a href="a.txt">a.txt</a>`) || !strings.Contains(body, `<a href="sub/">sub/</a>`) {
+		t.Errorf("Expected entries in listing, got '%s'", body)
+	}
+	if strings.Contains(body, ".hidden") {
+		t.Errorf("Expected hidden files to be filtered, got '%s'", body)
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("Expected file names to be escaped, got '%s'", body)
+	}
+	if strings.Index(body, "sub/") > strings.Index(body, "a.txt") {
+		t.Errorf("Expected directories to be listed first, got '%s'", body)
+	}
+}
+
+// TestDirListingJSON verifies the JSON listing and its sorting options.
+func TestDirListingJSON(t *testing.T) {
+	dir := newListingDir(t)
+	mux := NewServeMux(&dir)
+	mux.EnableDirListing(DirListingOptions{ShowHidden: true})
+
+	u, _ := url.Parse("/?format=json&sort=size&order=desc")
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: u})
+
+	var entries []dirListingEntry
+	if err := json.Unmarshal(res.body, &entries); err != nil {
+		t.Fatalf("Expected a JSON listing, got '%s' (%v)", string(res.body), err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	expected := "sub a.txt b.txt <script>.js .hidden"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("Expected order '%s', got '%s'", expected, strings.Join(names, " "))
+	}
+}
+
+// TestDirListingAccept verifies that the listing is JSON for requests accepting it, with the type of each entry.
+func TestDirListingAccept(t *testing.T) {
+	dir := newListingDir(t)
+	mux := NewServeMux(&dir)
+	mux.EnableDirListing(DirListingOptions{})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{"Accept": {"application/json"}}})
+
+	if ct := res.headers.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+	}
+	if vary := res.headers.Get("Vary"); vary != "Accept" {
+		t.Errorf("Expected Vary 'Accept', got '%s'", vary)
+	}
+	var entries []dirListingEntry
+	if err := json.Unmarshal(res.body, &entries); err != nil {
+		t.Fatalf("Expected a JSON listing, got '%s' (%v)", string(res.body), err)
+	}
+	types := make(map[string]string)
+	for _, e := range entries {
+		types[e.Name] = e.Type
+	}
+	if types["sub"] != "dir" || types["a.txt"] != "file" {
+		t.Errorf("Expected the types of sub and a.txt to be dir and file, got %v", types)
+	}
+
+	res = &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: Header{
+		"Accept": {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+	}})
+	if ct := res.headers.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an HTML listing for browsers, got '%s'", ct)
+	}
+}
+
+// TestDirListingRedirect verifies that directories requested without a trailing slash are redirected.
+func TestDirListingRedirect(t *testing.T) {
+	dir := newListingDir(t)
+	mux := NewServeMux(&dir)
+	mux.EnableDirListing(DirListingOptions{})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/sub"}})
+
+	if res.status != StatusMovedPermanently || res.Header().Get("Location") != "/sub/" {
+		t.Errorf("Expected redirect to '/sub/', got %d '%s'", res.status, res.Header().Get("Location"))
+	}
+}
+
+// TestDirListingFS verifies that directory listings work for fs.FS file systems.
+func TestDirListingFS(t *testing.T) {
+	mux := NewServeMux(nil)
+	mux.SetFileSystem(fstest.MapFS{"docs/guide.md": {Data: []byte("# guide")}})
+	mux.EnableDirListing(DirListingOptions{})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/docs/"}})
+
+	if res.status != StatusOK || !strings.Contains(string(res.body), "guide.md") {
+		t.Errorf("Expected listing with guide.md, got %d '%s'", res.status, string(res.body))
+	}
+}
+
+// TestStaticMounts verifies that several mounts serve their own directories and the longest prefix wins.
+func TestStaticMounts(t *testing.T) {
+	assets := t.TempDir()
+	if err := os.WriteFile(filepath.Join(assets, "app.js"), []byte("assets"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	images := t.TempDir()
+	if err := os.WriteFile(filepath.Join(images, "app.js"), []byte("images"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	mux := NewServeMux(nil)
+	mux.Static("/assets", assets)
+	mux.Static("/assets/images/", images)
+	mux.StaticFS("/docs", fstest.MapFS{"index.html": {Data: []byte("<h1>docs</h1>")}})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/assets/app.js", StatusOK, "assets"},
+		{"/assets/images/app.js", StatusOK, "images"},
+		{"/docs/", StatusOK, "<h1>docs</h1>"},
+		{"/assetsapp.js", StatusNotFound, `{"title":"Not Found","status":404,"instance":"/assetsapp.js"}`},
+		{"/assets/missing.js", StatusNotFound, `{"title":"Not Found","status":404,"instance":"/assets/missing.js"}`},
+		{"/assets/../secret", StatusBadRequest, `{"title":"Bad Request","status":400}`},
+	}
+
+	for _, tt := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: tt.path}})
+
+		if res.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, res.status)
+		}
+		if string(res.body) != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.path, tt.body, string(res.body))
+		}
+	}
+}
+
+// TestStaticMountRoutePrecedence verifies that registered routes win over static mounts.
+func TestStaticMountRoutePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "status"), []byte("file"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	mux := NewServeMux(nil)
+	mux.Static("/api", dir)
+	mux.AddRoute("/api/status", []string{GET}, func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("route"))
+	})
+
+	res := &MockResponseWriter{headers: make(Header)}
+	mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/api/status"}})
+
+	if string(res.body) != "route" {
+		t.Errorf("Expected the route to win, got '%s'", string(res.body))
+	}
+}
+
+// TestStaticMountCachePolicy verifies that a mount's cache policy overrides the mux policy.
+func TestStaticMountCachePolicy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	mux := NewServeMux(nil)
+	mux.SetCachePolicy(CachePolicy{CacheControl: "no-cache"})
+	mux.Static("/fingerprinted", dir).WithCachePolicy(CachePolicy{CacheControl: "public, immutable"})
+	mux.Static("/plain", dir)
+
+	tests := map[string]string{
+		"/fingerprinted/app.js": "public, immutable",
+		"/plain/app.js":         "no-cache",
+	}
+	for path, expected := range tests {
+		res := &MockResponseWriter{headers: make(Header)}
+		mux.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: path}})
+
+		if cc := res.Header().Get("Cache-Control"); cc != expected {
+			t.Errorf("%s: expected Cache-Control '%s', got '%s'", path, expected, cc)
+		}
+	}
+}
+
+// TestServeStaticFileSendfile verifies that large files are transmitted intact over a real connection.
+func TestServeStaticFileSendfile(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	if err := os.WriteFile(filepath.Join(dir, "large.bin"), content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	_, addr, _ := startServer(t, NewServeMux(&dir))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /large.bin HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	_, body, found := bytes.Cut(response, []byte("\r\n\r\n"))
+	if !found || !bytes.Equal(body, content) {
+		t.Errorf("Expected the %d byte file, got %d bytes", len(content), len(body))
+	}
+	if !bytes.Contains(response, []byte("Content-Length: 1048576\r\n")) {
+		t.Errorf("Expected a Content-Length header, got '%s'", response[:bytes.Index(response, []byte("\r\n\r\n"))])
+	}
+}
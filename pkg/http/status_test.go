@@ -0,0 +1,58 @@
+package http
+
+import (
+	"testing"
+)
+
+// TestStatusText verifies the reason phrases of a sample of status codes from every class.
+func TestStatusText(t *testing.T) {
+	tests := map[int]string{
+		StatusContinue:                      "Continue",
+		StatusEarlyHints:                    "Early Hints",
+		StatusOK:                            "OK",
+		StatusNoContent:                     "No Content",
+		StatusPartialContent:                "Partial Content",
+		StatusMovedPermanently:              "Moved Permanently",
+		StatusPermanentRedirect:             "Permanent Redirect",
+		StatusBadRequest:                    "Bad Request",
+		StatusNotFound:                      "Not Found",
+		StatusTeapot:                        "I'm a teapot",
+		StatusTooManyRequests:               "Too Many Requests",
+		StatusInternalServerError:           "Internal Server Error",
+		StatusServiceUnavailable:            "Service Unavailable",
+		StatusNetworkAuthenticationRequired: "Network Authentication Required",
+	}
+
+	for code, expected := range tests {
+		if actual := StatusText(code); actual != expected {
+			t.Errorf("StatusText(%d): expected '%s', got '%s'", code, expected, actual)
+		}
+	}
+}
+
+// TestStatusTextComplete verifies that every registered status code has a reason phrase.
+func TestStatusTextComplete(t *testing.T) {
+	codes := []int{
+		100, 101, 102, 103,
+		200, 201, 202, 203, 204, 205, 206, 207, 208, 226,
+		300, 301, 302, 303, 304, 305, 307, 308,
+		400, 401, 402, 403, 404, 405, 406, 407, 408, 409, 410, 411, 412, 413, 414, 415, 416, 417, 418,
+		421, 422, 423, 424, 425, 426, 428, 429, 431, 451,
+		500, 501, 502, 503, 504, 505, 506, 507, 508, 510, 511,
+	}
+
+	for _, code := range codes {
+		if StatusText(code) == "" {
+			t.Errorf("Expected a reason phrase for status %d", code)
+		}
+	}
+}
+
+// TestStatusTextUnknown verifies that unknown codes have no reason phrase.
+func TestStatusTextUnknown(t *testing.T) {
+	for _, code := range []int{0, 99, 306, 299, 600} {
+		if actual := StatusText(code); actual != "" {
+			t.Errorf("StatusText(%d): expected empty string, got '%s'", code, actual)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package stdhttp
+
+import (
+	"io"
+	stdhttp "net/http"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// FromStdHandler adapts a net/http handler so it can be registered on an
+// http-lite mux.
+func FromStdHandler(h stdhttp.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&stdResponseWriter{w: w, header: ToStdHeader(w.Header())}, toStdRequest(r))
+	}
+}
+
+// ToStdHandler adapts an http-lite handler so it can be served by net/http
+// or wrapped by net/http middleware.
+func ToStdHandler(h http.Handler) stdhttp.Handler {
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		h.ServeHTTP(&liteResponseWriter{w: w, header: FromStdHeader(w.Header())}, fromStdRequest(r))
+	})
+}
+
+// FromStdMiddleware adapts a net/http middleware into an http-lite middleware.
+func FromStdMiddleware(mw func(stdhttp.Handler) stdhttp.Handler) http.Middleware {
+	return func(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return FromStdHandler(mw(ToStdHandler(http.HandlerFunc(next))))
+	}
+}
+
+// toStdRequest translates an http-lite request into a net/http request.
+func toStdRequest(r *http.Request) *stdhttp.Request {
+	var body io.ReadCloser = stdhttp.NoBody
+	if r.Body != nil {
+		body = r.Body
+	}
+
+	req := &stdhttp.Request{
+		Method:        r.Method,
+		URL:           r.URL,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ToStdHeader(r.Header),
+		Body:          body,
+		ContentLength: r.ContentLength,
+		Host:          r.Header.Get("Host"),
+		RequestURI:    r.URL.RequestURI(),
+		RemoteAddr:    r.RemoteAddr,
+		TLS:           r.TLS,
+	}
+	return req.WithContext(r.Context())
+}
+
+// fromStdRequest translates a net/http request into an http-lite request.
+func fromStdRequest(r *stdhttp.Request) *http.Request {
+	header := FromStdHeader(r.Header)
+	if r.Host != "" && header.Get("Host") == "" {
+		header.Set("Host", r.Host)
+	}
+
+	req := &http.Request{
+		Method: r.Method,
+		URL:    r.URL,
+		Proto:  r.Proto,
+		Header: header,
+		Body:   r.Body,
+
+		ContentLength: r.ContentLength,
+		RemoteAddr:    r.RemoteAddr,
+		TLS:           r.TLS,
+	}
+	return req.WithContext(r.Context())
+}
+
+// stdResponseWriter exposes an http-lite ResponseWriter as a net/http one.
+// Headers are kept in a net/http header and copied over when the status
+// line is written.
+type stdResponseWriter struct {
+	w           http.ResponseWriter
+	header      stdhttp.Header
+	wroteHeader bool
+}
+
+func (sw *stdResponseWriter) Header() stdhttp.Header {
+	return sw.header
+}
+
+func (sw *stdResponseWriter) WriteHeader(statusCode int) {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+
+	h := sw.w.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	for k, values := range sw.header {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	sw.w.WriteHeader(statusCode)
+}
+
+func (sw *stdResponseWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(stdhttp.StatusOK)
+	}
+	return sw.w.Write(b)
+}
+
+func (sw *stdResponseWriter) Flush() {
+	if !sw.wroteHeader {
+		sw.WriteHeader(stdhttp.StatusOK)
+	}
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// liteResponseWriter exposes a net/http ResponseWriter as an http-lite one.
+type liteResponseWriter struct {
+	w           stdhttp.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+}
+
+func (lw *liteResponseWriter) Header() http.Header {
+	return lw.header
+}
+
+func (lw *liteResponseWriter) WriteHeader(statusCode int) {
+	if lw.wroteHeader {
+		return
+	}
+	lw.wroteHeader = true
+
+	h := lw.w.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	for k, values := range lw.header {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	lw.w.WriteHeader(statusCode)
+}
+
+func (lw *liteResponseWriter) Write(b []byte) (int, error) {
+	if !lw.wroteHeader {
+		lw.WriteHeader(stdhttp.StatusOK)
+	}
+	return lw.w.Write(b)
+}
+
+func (lw *liteResponseWriter) SetCookie(c *http.Cookie) {
+	if err := c.Valid(); err != nil {
+		if http.StrictCookies {
+			panic(err)
+		}
+		return
+	}
+	lw.header.Add("Set-Cookie", c.String())
+}
+
+func (lw *liteResponseWriter) DeleteCookie(name string) {
+	c := &http.Cookie{Name: name, Value: "", MaxAge: -1}
+	lw.header.Add("Set-Cookie", c.String())
+}
+
+func (lw *liteResponseWriter) Cookies() []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, line := range lw.header.Values("Set-Cookie") {
+		if c, err := http.ParseSetCookie(line); err == nil {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+func (lw *liteResponseWriter) Flush() {
+	if !lw.wroteHeader {
+		lw.WriteHeader(stdhttp.StatusOK)
+	}
+	if f, ok := lw.w.(stdhttp.Flusher); ok {
+		f.Flush()
+	}
+}
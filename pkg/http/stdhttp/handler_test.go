@@ -0,0 +1,100 @@
+package stdhttp
+
+import (
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// TestToStdHandler verifies that an http-lite handler can be served through net/http.
+func TestToStdHandler(t *testing.T) {
+	lite := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.GetCookie("session")
+		if err != nil {
+			t.Errorf("Expected session cookie, got %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.SetCookie(&http.Cookie{Name: "seen", Value: "1"})
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + session.Value))
+	})
+
+	req := httptest.NewRequest(stdhttp.MethodPost, "/items?x=1", nil)
+	req.AddCookie(&stdhttp.Cookie{Name: "session", Value: "abc"})
+	rec := httptest.NewRecorder()
+
+	ToStdHandler(lite).ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", stdhttp.StatusAccepted, rec.Code)
+	}
+	if rec.Body.String() != "POST /items abc" {
+		t.Errorf("Expected body 'POST /items abc', got '%s'", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Set-Cookie") != "seen=1" {
+		t.Errorf("Expected headers to be copied, got %v", rec.Header())
+	}
+}
+
+// TestFromStdHandler verifies that a net/http handler can run behind the http-lite mux.
+func TestFromStdHandler(t *testing.T) {
+	std := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", r.Header.Get("X-Input"))
+		w.WriteHeader(stdhttp.StatusCreated)
+		w.Write(body)
+	})
+
+	mux := http.NewServeMux(nil)
+	mux.AddRoute("/echo", []string{http.POST}, FromStdHandler(std))
+
+	// Serve the mux through net/http so the whole chain runs on real types
+	req := httptest.NewRequest(stdhttp.MethodPost, "/echo", strings.NewReader("payload"))
+	req.Header.Set("X-Input", "hello")
+	rec := httptest.NewRecorder()
+
+	ToStdHandler(mux).ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusCreated {
+		t.Errorf("Expected status %d, got %d", stdhttp.StatusCreated, rec.Code)
+	}
+	if rec.Body.String() != "payload" {
+		t.Errorf("Expected body 'payload', got '%s'", rec.Body.String())
+	}
+	if rec.Header().Get("X-Echo") != "hello" {
+		t.Errorf("Expected X-Echo 'hello', got '%s'", rec.Header().Get("X-Echo"))
+	}
+}
+
+// TestFromStdMiddleware verifies that net/http middleware can wrap http-lite routes.
+func TestFromStdMiddleware(t *testing.T) {
+	addHeader := func(next stdhttp.Handler) stdhttp.Handler {
+		return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			w.Header().Set("X-Middleware", "std")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	mux := http.NewServeMux(nil)
+	mux.Use(FromStdMiddleware(addHeader))
+	mux.AddRoute("/", []string{http.GET}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	ToStdHandler(mux).ServeHTTP(rec, httptest.NewRequest(stdhttp.MethodGet, "/", nil))
+
+	if rec.Header().Get("X-Middleware") != "std" {
+		t.Errorf("Expected X-Middleware 'std', got '%s'", rec.Header().Get("X-Middleware"))
+	}
+	if rec.Body.String() != "ok" {
+		t.Errorf("Expected body 'ok', got '%s'", rec.Body.String())
+	}
+}
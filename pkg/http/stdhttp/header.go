@@ -0,0 +1,33 @@
+// Package stdhttp bridges http-lite and the standard library's net/http
+// package. It lives outside pkg/http so that the core package stays free
+// of any net/http dependency; only applications that need interoperability
+// pay for it.
+package stdhttp
+
+import (
+	stdhttp "net/http"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// FromStdHeader converts a net/http header into an http-lite header.
+func FromStdHeader(h stdhttp.Header) http.Header {
+	header := make(http.Header, len(h))
+	for k, values := range h {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	return header
+}
+
+// ToStdHeader converts an http-lite header into a net/http header.
+func ToStdHeader(h http.Header) stdhttp.Header {
+	header := make(stdhttp.Header, len(h))
+	for k, values := range h {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	return header
+}
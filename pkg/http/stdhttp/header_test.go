@@ -0,0 +1,38 @@
+package stdhttp
+
+import (
+	stdhttp "net/http"
+	"testing"
+
+	"github.com/Johanx22x/http-lite/pkg/http"
+)
+
+// TestFromStdHeader verifies that every value of a net/http header is copied.
+func TestFromStdHeader(t *testing.T) {
+	std := stdhttp.Header{}
+	std.Add("Set-Cookie", "a=1")
+	std.Add("Set-Cookie", "b=2")
+	std.Set("Content-Type", "text/plain")
+
+	h := FromStdHeader(std)
+
+	if values := h.Values("Set-Cookie"); len(values) != 2 || values[0] != "a=1" || values[1] != "b=2" {
+		t.Errorf("Expected Set-Cookie [a=1 b=2], got %v", values)
+	}
+	if h.Get("content-type") != "text/plain" {
+		t.Errorf("Expected Content-Type 'text/plain', got '%s'", h.Get("content-type"))
+	}
+}
+
+// TestToStdHeader verifies that every value of an http-lite header is copied.
+func TestToStdHeader(t *testing.T) {
+	h := make(http.Header)
+	h.Add("Accept", "text/html")
+	h.Add("Accept", "application/json")
+
+	std := ToStdHeader(h)
+
+	if values := std.Values("Accept"); len(values) != 2 || values[0] != "text/html" || values[1] != "application/json" {
+		t.Errorf("Expected Accept [text/html application/json], got %v", values)
+	}
+}
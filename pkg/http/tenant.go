@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// TenantResolver returns the tenant a request is for, or "" when it
+// names none.
+type TenantResolver func(*Request) string
+
+// TenantHeader returns a resolver reading the tenant from a header, such
+// as "X-Tenant-ID". Clients can set any header, so it should only be used
+// behind a gateway setting or stripping it.
+func TenantHeader(name string) TenantResolver {
+	return func(r *Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// TenantSubdomain returns a resolver reading the tenant from the subdomain
+// of domain in the Host header: "acme" for acme.example.com with domain
+// "example.com". Hosts outside domain, the domain itself and deeper
+// subdomains such as a.acme.example.com name no tenant.
+func TenantSubdomain(domain string) TenantResolver {
+	suffix := "." + normalizeHost(domain)
+	return func(r *Request) string {
+		tenant, ok := strings.CutSuffix(normalizeHost(r.Header.Get("Host")), suffix)
+		if !ok || strings.Contains(tenant, ".") {
+			return ""
+		}
+		return tenant
+	}
+}
+
+// tenantKey is the context key of the tenant of a request.
+type tenantKey struct{}
+
+// Tenant returns the tenant of the request resolved by a TenantRouter or
+// the WithTenant middleware, or "" when there is none.
+func (r *Request) Tenant() string {
+	tenant, _ := r.Context().Value(tenantKey{}).(string)
+	return tenant
+}
+
+// withTenant returns r with the tenant in its context.
+func withTenant(r *Request, tenant string) *Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+}
+
+// WithTenant returns middleware resolving the tenant of requests for
+// handlers shared by every tenant, which read it with Request.Tenant.
+// Requests without a tenant are passed on as they are.
+func WithTenant(resolve TenantResolver) Middleware {
+	return func(next func(ResponseWriter, *Request)) func(ResponseWriter, *Request) {
+		return func(w ResponseWriter, r *Request) {
+			if tenant := resolve(r); tenant != "" {
+				r = withTenant(r, tenant)
+			}
+			next(w, r)
+		}
+	}
+}
+
+// TenantRouter passes requests to the handler of their tenant, usually a
+// ServeMux of its own, for SaaS-style deployments:
+//
+//	tenants := http.NewTenantRouter(http.TenantSubdomain("example.com"))
+//	tenants.Handle("acme", acmeMux)
+//	tenants.Handle("globex", globexMux)
+//	server := http.NewServer(":8080", tenants)
+//
+// Tenants can be added and removed while the server runs. Handlers read
+// the tenant with Request.Tenant.
+type TenantRouter struct {
+	// Resolve returns the tenant of a request.
+	Resolve TenantResolver
+	// Default serves the requests without a tenant or for an unknown one.
+	// When nil, they are answered with 404 Not Found.
+	Default Handler
+
+	mu      sync.RWMutex
+	tenants map[string]Handler
+}
+
+// NewTenantRouter creates a router without tenants.
+func NewTenantRouter(resolve TenantResolver) *TenantRouter {
+	return &TenantRouter{Resolve: resolve, tenants: make(map[string]Handler)}
+}
+
+// Handle registers the handler of a tenant, replacing any previous one.
+func (tr *TenantRouter) Handle(tenant string, handler Handler) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.tenants[tenant] = handler
+}
+
+// Remove unregisters a tenant.
+func (tr *TenantRouter) Remove(tenant string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	delete(tr.tenants, tenant)
+}
+
+// Tenants returns the registered tenants, sorted.
+func (tr *TenantRouter) Tenants() []string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return sortedKeys(tr.tenants)
+}
+
+// ServeHTTP resolves the tenant of the request and passes it to its
+// handler.
+func (tr *TenantRouter) ServeHTTP(w ResponseWriter, r *Request) {
+	tenant := tr.Resolve(r)
+	tr.mu.RLock()
+	handler, ok := tr.tenants[tenant]
+	tr.mu.RUnlock()
+
+	switch {
+	case ok && tenant != "":
+		handler.ServeHTTP(w, withTenant(r, tenant))
+	case tr.Default != nil:
+		if tenant != "" {
+			r = withTenant(r, tenant)
+		}
+		tr.Default.ServeHTTP(w, r)
+	default:
+		Error(w, StatusText(StatusNotFound), StatusNotFound)
+	}
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+)
+
+// TestTenantResolvers verifies that tenants are read from subdomains and headers.
+func TestTenantResolvers(t *testing.T) {
+	subdomain := TenantSubdomain("Example.com")
+	for host, want := range map[string]string{
+		"acme.example.com":      "acme",
+		"ACME.example.com:8080": "acme",
+		"acme.example.com.":     "acme",
+		"example.com":           "",
+		"a.acme.example.com":    "",
+		"acme.example.org":      "",
+		"":                      "",
+	} {
+		r := &Request{Header: Header{"Host": {host}}}
+		if got := subdomain(r); got != want {
+			t.Errorf("%s: expected tenant '%s', got '%s'", host, want, got)
+		}
+	}
+
+	r := &Request{Header: Header{"X-Tenant-Id": {" acme "}}}
+	if got := TenantHeader("X-Tenant-ID")(r); got != "acme" {
+		t.Errorf("Expected tenant 'acme' from the header, got '%s'", got)
+	}
+}
+
+// TestTenantRouter verifies that requests are passed to the handler of their tenant.
+func TestTenantRouter(t *testing.T) {
+	tenantHandler := func(name string) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.WriteHeader(StatusOK)
+			w.Write([]byte(name + ":" + r.Tenant()))
+		})
+	}
+	tenants := NewTenantRouter(TenantHeader("X-Tenant-ID"))
+	tenants.Handle("acme", tenantHandler("acme"))
+	tenants.Handle("globex", tenantHandler("globex"))
+	serve := func(tenant string) *MockResponseWriter {
+		res := &MockResponseWriter{headers: make(Header)}
+		header := make(Header)
+		if tenant != "" {
+			header.Set("X-Tenant-ID", tenant)
+		}
+		tenants.ServeHTTP(res, &Request{Method: GET, URL: &url.URL{Path: "/"}, Header: header})
+		return res
+	}
+
+	if res := serve("globex"); string(res.body) != "globex:globex" {
+		t.Errorf("Expected the globex handler, got '%s'", res.body)
+	}
+	for _, tenant := range []string{"", "initech"} {
+		if res := serve(tenant); res.status != StatusNotFound {
+			t.Errorf("Tenant '%s': expected %d, got %d", tenant, StatusNotFound, res.status)
+		}
+	}
+
+	tenants.Default = tenantHandler("default")
+	if res := serve("initech"); string(res.body) != "default:initech" {
+		t.Errorf("Expected the default handler, got '%s'", res.body)
+	}
+
+	tenants.Remove("acme")
+	if got := tenants.Tenants(); len(got) != 1 || got[0] != "globex" {
+		t.Errorf("Expected only globex to be left, got %v", got)
+	}
+}
+
+// TestWithTenant verifies that the middleware exposes the tenant to shared handlers.
+func TestWithTenant(t *testing.T) {
+	var got []string
+	handler := WithTenant(TenantSubdomain("example.com"))(func(w ResponseWriter, r *Request) {
+		got = append(got, r.Tenant())
+	})
+	for _, host := range []string{"acme.example.com", "example.com"} {
+		handler(&MockResponseWriter{headers: make(Header)}, &Request{Header: Header{"Host": {host}}})
+	}
+	if len(got) != 2 || got[0] != "acme" || got[1] != "" {
+		t.Errorf("Expected tenants [acme ], got %v", got)
+	}
+}
@@ -84,7 +84,7 @@ func (m *MockResponseWriter) WriteHeader(statusCode int) {
 }
 
 func (m *MockResponseWriter) SetCookie(cookie *Cookie) {
-	m.headers.Set("Set-Cookie", cookie.String())
+	addCookie(m, m.headers, cookie)
 }
 
 func (m *MockResponseWriter) DeleteCookie(name string) {
@@ -93,5 +93,9 @@ func (m *MockResponseWriter) DeleteCookie(name string) {
 		Value:  "",
 		MaxAge: -1,
 	}
-	m.headers.Set("Set-Cookie", cookie.String())
+	m.headers.Add("Set-Cookie", cookie.String())
+}
+
+func (m *MockResponseWriter) Cookies() []*Cookie {
+	return headerCookies(m.headers)
 }
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a self-signed certificate for localhost.
+func newTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost", "example.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestRequestTLS verifies that requests served over TLS carry the connection state.
+func TestRequestTLS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	tlsListener := tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t)}})
+
+	states := make(chan *tls.ConnectionState, 1)
+	server := NewServer(ln.Addr().String(), HandlerFunc(func(w ResponseWriter, r *Request) {
+		states <- r.TLS
+		w.WriteHeader(StatusOK)
+	}))
+	go server.Serve(tlsListener)
+	defer server.Shutdown(context.Background())
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{ServerName: "example.test", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.test\r\nConnection: close\r\n\r\n"))
+	io.ReadAll(conn)
+
+	state := <-states
+	if state == nil {
+		t.Fatal("Expected the TLS connection state on the request")
+	}
+	if state.ServerName != "example.test" {
+		t.Errorf("Expected SNI 'example.test', got '%s'", state.ServerName)
+	}
+	if !state.HandshakeComplete || state.Version < tls.VersionTLS12 {
+		t.Errorf("Expected a completed modern handshake, got version %x", state.Version)
+	}
+}
+
+// TestRequestTLSPlain verifies that plain HTTP requests have no TLS state.
+func TestRequestTLSPlain(t *testing.T) {
+	states := make(chan *tls.ConnectionState, 1)
+	_, addr, _ := startServer(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		states <- r.TLS
+		w.WriteHeader(StatusOK)
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+
+	if state := <-states; state != nil {
+		t.Errorf("Expected no TLS state, got %+v", state)
+	}
+}
+
+// startTLSServer starts a server over TLS with a test certificate and
+// returns the certificate and the server's port.
+func startTLSServer(t *testing.T, config *tls.Config, handler Handler) (tls.Certificate, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	cert := newTestCertificate(t)
+	config.Certificates = []tls.Certificate{cert}
+
+	server := NewServer(ln.Addr().String(), handler)
+	server.ErrorLog = &recordingLogger{} // Rejected handshakes are expected
+	go server.Serve(tls.NewListener(ln, config))
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return cert, port
+}
+
+// TestClientTLS verifies that the client verifies the server's certificate against its roots.
+func TestClientTLS(t *testing.T) {
+	cert, port := startTLSServer(t, &tls.Config{}, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("secure"))
+	}))
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	client := &Client{TLSConfig: &tls.Config{RootCAs: roots}}
+	res, err := client.Get("https://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := readBody(t, res)
+	if got != "secure" {
+		t.Errorf("Expected the body over TLS, got '%s'", got)
+	}
+	if res.TLS == nil || !res.TLS.HandshakeComplete {
+		t.Errorf("Expected the TLS state of the connection, got %v", res.TLS)
+	}
+
+	// The certificate isn't valid for 127.0.0.1
+	if _, err := client.Get("https://127.0.0.1:" + port + "/"); err == nil {
+		t.Error("Expected a host name verification error")
+	}
+
+	// Without the root, the certificate isn't trusted
+	if _, err := (&Client{}).Get("https://localhost:" + port + "/"); err == nil {
+		t.Error("Expected an unknown authority error")
+	}
+
+	insecure := &Client{TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+	if _, err := insecure.Get("https://127.0.0.1:" + port + "/"); err != nil {
+		t.Errorf("Expected InsecureSkipVerify to accept the certificate, got %v", err)
+	}
+}
+
+// TestClientTLSCertificate verifies that client certificates are presented to the server.
+func TestClientTLSCertificate(t *testing.T) {
+	_, port := startTLSServer(t, &tls.Config{ClientAuth: tls.RequireAnyClientCert}, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusOK)
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+
+	client := &Client{TLSConfig: &tls.Config{
+		Certificates:       []tls.Certificate{newTestCertificate(t)},
+		InsecureSkipVerify: true,
+	}}
+	res, err := client.Get("https://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := readBody(t, res)
+	if got != "localhost" {
+		t.Errorf("Expected the client certificate's name, got '%s'", got)
+	}
+}
+
+// TestRunTLSMissingCertificate verifies that RunTLS fails before listening when the certificate cannot be loaded.
+func TestRunTLSMissingCertificate(t *testing.T) {
+	dir := t.TempDir()
+	err := RunTLS("127.0.0.1:0", dir+"/cert.pem", dir+"/key.pem", HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	if err == nil {
+		t.Fatal("Expected an error for missing certificate files")
+	}
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StatusCoder is implemented by values that choose the status code of
+// their response, such as the results and errors of Typed handlers.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// StatusError is an error answered with its status code.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+// NewError returns an error answered with the status code and message.
+func NewError(statusCode int, message string) *StatusError {
+	return &StatusError{Code: statusCode, Message: message}
+}
+
+// Error returns the message of the error.
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the status code of the error.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// Typed adapts fn into a route handler that decodes its input from the
+// request and encodes its output as JSON:
+//
+//	type getItem struct {
+//		ID   int  `path:"id"`
+//		Full bool `query:"full"`
+//	}
+//
+//	mux.AddRoute("/items/:id", []string{http.GET}, http.Typed(
+//		func(ctx context.Context, in getItem) (*Item, error) {
+//			return store.Get(ctx, in.ID, in.Full)
+//		},
+//	))
+//
+// A JSON body is decoded into In, then the fields of a struct In tagged
+// `path:"name"` and `query:"name"` are set from the route parameters and
+// the query string. Malformed input is answered with 400 Bad Request, a
+// non-JSON body with 415 Unsupported Media Type.
+//
+// The output is answered with 200 OK, or the status of its StatusCode
+// method, without a body for 204 No Content. Errors are answered with
+// problem details (see Problem), with the status of their StatusCode
+// method, such as the ones of NewError, and 500 Internal Server Error
+// otherwise, without revealing their message. A *Problem error is written
+// as is.
+func Typed[In, Out any](fn func(context.Context, In) (Out, error)) func(ResponseWriter, *Request) {
+	return func(w ResponseWriter, r *Request) {
+		var in In
+		if err := decodeInput(r, &in); err != nil {
+			writeTypedError(w, err)
+			return
+		}
+
+		out, err := fn(r.Context(), in)
+		if err != nil {
+			writeTypedError(w, err)
+			return
+		}
+
+		status := StatusOK
+		if sc, ok := any(out).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+		if !bodyAllowed(status) {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, status, out)
+	}
+}
+
+// decodeInput decodes the body, route parameters and query of the request
+// into in.
+func decodeInput(r *Request, in any) error {
+	if r.Body != nil && r.ContentLength != 0 {
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			mediaType, _, err := mime.ParseMediaType(ct)
+			if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+				return NewError(StatusUnsupportedMediaType, "request body must be JSON")
+			}
+		}
+		dec := json.NewDecoder(r.Body)
+		if err := dec.Decode(in); err != nil {
+			return NewError(StatusBadRequest, "invalid JSON body: "+err.Error())
+		}
+	}
+
+	v := reflect.ValueOf(in).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var query map[string][]string
+	if r.URL != nil {
+		query = r.URL.Query()
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if name, ok := field.Tag.Lookup("path"); ok {
+			if value, ok := r.Params[name]; ok {
+				if err := setField(v.Field(i), value); err != nil {
+					return NewError(StatusBadRequest, fmt.Sprintf("invalid path parameter %s: %v", name, err))
+				}
+			}
+		}
+		if name, ok := field.Tag.Lookup("query"); ok {
+			if values, ok := query[name]; ok && len(values) > 0 {
+				if err := setField(v.Field(i), values[0]); err != nil {
+					return NewError(StatusBadRequest, fmt.Sprintf("invalid query parameter %s: %v", name, err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setField parses value into a string, boolean or numeric field.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// writeTypedError answers err as a problem details object. A *Problem is
+// written as is.
+func writeTypedError(w ResponseWriter, err error) {
+	var p *Problem
+	if errors.As(err, &p) {
+		WriteProblem(w, p)
+		return
+	}
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		WriteProblem(w, NewProblem(sc.StatusCode(), err.Error()))
+		return
+	}
+	WriteProblem(w, NewProblem(StatusInternalServerError, ""))
+}
+
+// writeJSON writes v encoded as JSON with the given status code.
+func writeJSON(w ResponseWriter, statusCode int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		Error(w, StatusText(StatusInternalServerError), StatusInternalServerError)
+		return
+	}
+	w.Header()["Content-Type"] = []string{"application/json; charset=utf-8"}
+	w.Header()["Content-Length"] = []string{strconv.Itoa(len(data))}
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type typedInput struct {
+	ID   int    `path:"id"`
+	Full bool   `query:"full"`
+	Name string `json:"name"`
+}
+
+type typedOutput struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Full    bool   `json:"full"`
+	created bool
+}
+
+func (o typedOutput) StatusCode() int {
+	if o.created {
+		return StatusCreated
+	}
+	return StatusOK
+}
+
+// serveTyped sends a request with the given path parameters to a Typed handler.
+func serveTyped(handler func(ResponseWriter, *Request), target, contentType, body string, params map[string]string) *MockResponseWriter {
+	u, _ := url.Parse(target)
+	req := &Request{Method: POST, URL: u, Header: make(Header), Params: params}
+	if body != "" {
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", contentType)
+	}
+	res := &MockResponseWriter{headers: make(Header)}
+	handler(res, req)
+	return res
+}
+
+// TestTyped verifies that input is decoded from the body, path and query, and output encoded as JSON.
+func TestTyped(t *testing.T) {
+	handler := Typed(func(ctx context.Context, in typedInput) (typedOutput, error) {
+		switch in.Name {
+		case "missing":
+			return typedOutput{}, NewError(StatusNotFound, "no such item")
+		case "broken":
+			return typedOutput{}, errors.New("database password is hunter2")
+		}
+		return typedOutput{ID: in.ID, Name: in.Name, Full: in.Full, created: true}, nil
+	})
+
+	tests := []struct {
+		target, contentType, body string
+		params                    map[string]string
+		status                    int
+		response                  string
+	}{
+		{"/items/7?full=true", "application/json", `{"name": "lamp"}`, map[string]string{"id": "7"}, StatusCreated, `{"id":7,"name":"lamp","full":true}`},
+		{"/items/x", "", "", map[string]string{"id": "x"}, StatusBadRequest, `{"title":"Bad Request","status":400,"detail":"invalid path parameter id: strconv.ParseInt: parsing \"x\": invalid syntax"}`},
+		{"/items/1", "text/plain", "lamp", nil, StatusUnsupportedMediaType, `{"title":"Unsupported Media Type","status":415,"detail":"request body must be JSON"}`},
+		{"/items/1", "application/json", `{"name":`, nil, StatusBadRequest, `{"title":"Bad Request","status":400,"detail":"invalid JSON body: unexpected EOF"}`},
+		{"/items/1", "application/json", `{"name": "missing"}`, nil, StatusNotFound, `{"title":"Not Found","status":404,"detail":"no such item"}`},
+		{"/items/1", "application/json", `{"name": "broken"}`, nil, StatusInternalServerError, `{"title":"Internal Server Error","status":500}`},
+	}
+	for _, tt := range tests {
+		res := serveTyped(handler, tt.target, tt.contentType, tt.body, tt.params)
+		if res.status != tt.status || string(res.body) != tt.response {
+			t.Errorf("%s %s: expected %d '%s', got %d '%s'", tt.target, tt.body, tt.status, tt.response, res.status, res.body)
+		}
+		want := "application/json; charset=utf-8"
+		if tt.status >= 400 {
+			want = "application/problem+json"
+		}
+		if ct := res.Header().Get("Content-Type"); ct != want {
+			t.Errorf("%s: expected content type '%s', got '%s'", tt.target, want, ct)
+		}
+	}
+}
+
+// deleted is answered with 204 No Content.
+type deleted struct{}
+
+func (deleted) StatusCode() int { return StatusNoContent }
+
+// TestTypedNoContent verifies that 204 outputs are answered without a body.
+func TestTypedNoContent(t *testing.T) {
+	handler := Typed(func(ctx context.Context, in struct{}) (deleted, error) {
+		return deleted{}, nil
+	})
+
+	res := serveTyped(handler, "/items/1", "", "", nil)
+	if res.status != StatusNoContent || len(res.body) != 0 {
+		t.Errorf("Expected an empty 204, got %d '%s'", res.status, res.body)
+	}
+}
@@ -0,0 +1,22 @@
+package http
+
+import "net"
+
+// startWorkers starts the worker pool for a listener when Workers is set and
+// returns the queue feeding it, or nil when each connection gets its own
+// goroutine. Closing the queue stops the workers once it is drained.
+func (s *Server) startWorkers() chan net.Conn {
+	if s.Workers <= 0 {
+		return nil
+	}
+
+	queue := make(chan net.Conn, s.QueueSize)
+	for i := 0; i < s.Workers; i++ {
+		go func() {
+			for conn := range queue {
+				s.serveConn(conn)
+			}
+		}()
+	}
+	return queue
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWorkerPool verifies that the worker pool bounds concurrency and still serves every connection.
+func TestWorkerPool(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	server := NewServer(addr, HandlerFunc(func(w ResponseWriter, r *Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		w.WriteHeader(StatusOK)
+	}))
+	server.Workers = 2
+	server.QueueSize = 4
+	go server.Serve(ln)
+	defer server.Shutdown(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Errorf("Failed to dial: %v", err)
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			response, _ := io.ReadAll(conn)
+			if !strings.HasPrefix(string(response), "HTTP/1.1 200 OK") {
+				t.Errorf("Expected 200, got '%s'", string(response))
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxActive > 2 {
+		t.Errorf("Expected at most 2 concurrent handlers, got %d", maxActive)
+	}
+}